@@ -2,12 +2,14 @@ package admin
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -35,11 +37,72 @@ type Handler interface {
 	UpdateProductCategory(c *gin.Context)
 	DeleteProductCategory(c *gin.Context)
 	ListActiveProductCategories(c *gin.Context)
+	UpdateProductCategoryAttributes(c *gin.Context)
 	AddProduct(c *gin.Context)
 	ListProducts(c *gin.Context)
 	GetProductBySKU(c *gin.Context)
 	UpdateProduct(c *gin.Context)
 	DeleteProduct(c *gin.Context)
+	AddProductImages(c *gin.Context)
+	UpdateProductImage(c *gin.Context)
+	DeleteProductImage(c *gin.Context)
+	ReorderProductImages(c *gin.Context)
+	SetPrimaryProductImage(c *gin.Context)
+	ListCompatibleParts(c *gin.Context)
+	SetCompatibleParts(c *gin.Context)
+
+	ListCompanies(c *gin.Context)
+	GetCompanyDetail(c *gin.Context)
+	UpdateWarrantySerialNumber(c *gin.Context)
+	RegisterProductUnits(c *gin.Context)
+	ListProductUnits(c *gin.Context)
+	DeleteProductUnit(c *gin.Context)
+	LookupProductUnit(c *gin.Context)
+	AssignOrderUnits(c *gin.Context)
+	CreateInstallationJob(c *gin.Context)
+	ListInstallationJobs(c *gin.Context)
+	GetInstallationJob(c *gin.Context)
+	ScheduleInstallationJob(c *gin.Context)
+	SetInstallationChecklist(c *gin.Context)
+	UpdateInstallationChecklistItem(c *gin.Context)
+	UpdateInstallationJobStatus(c *gin.Context)
+	CompleteInstallationJob(c *gin.Context)
+	AddRentalUnits(c *gin.Context)
+	ListRentalUnits(c *gin.Context)
+	UpdateRentalUnit(c *gin.Context)
+	ListRentalBookings(c *gin.Context)
+	GetRentalBooking(c *gin.Context)
+	UpdateRentalBookingStatus(c *gin.Context)
+	InspectRentalReturn(c *gin.Context)
+	ListRefunds(c *gin.Context)
+	GetRefund(c *gin.Context)
+	UpdateRefund(c *gin.Context)
+	ListReturnRequests(c *gin.Context)
+	GetReturnRequest(c *gin.Context)
+	ReviewReturnRequest(c *gin.Context)
+	ReceiveReturnRequest(c *gin.Context)
+	InspectReturnRequest(c *gin.Context)
+	ResolveReturnRequest(c *gin.Context)
+	ListStockMovements(c *gin.Context)
+	CreateShipment(c *gin.Context)
+	ConfirmShipmentDelivery(c *gin.Context)
+	GetShipmentTracking(c *gin.Context)
+	ListWarrantyClaims(c *gin.Context)
+	GetWarrantyClaim(c *gin.Context)
+	UpdateWarrantyClaim(c *gin.Context)
+	ListServiceTickets(c *gin.Context)
+	GetServiceTicket(c *gin.Context)
+	AssignServiceTicket(c *gin.Context)
+	UpdateServiceTicketStatus(c *gin.Context)
+	AddServiceTicketMessage(c *gin.Context)
+
+	SetPaymentSchedule(c *gin.Context)
+	VerifyPaymentMilestone(c *gin.Context)
+	ListOverdueInstallments(c *gin.Context)
+
+	ListQuotationRequests(c *gin.Context)
+	GetQuotationRequest(c *gin.Context)
+	UpsertQuotation(c *gin.Context)
 	ListAllOrders(c *gin.Context)
 	GetOrderDetailForAdmin(c *gin.Context)
 	UpdateOrderStatus(c *gin.Context)
@@ -337,37 +400,17 @@ func (h *handler) UpdateEmployee(c *gin.Context) {
 	}
 	log.Printf("[Handler UpdateEmployee] Data employee dari jsonData: %+v\n", inputDTO)
 
-	var newImagePath *string
+	// File gambar baru (opsional) diteruskan ke service, sama seperti AddEmployee
+	var imageFileHeader *multipart.FileHeader
 	fileHeader, errFile := c.FormFile("imageFile")
-
 	if errFile == nil && fileHeader != nil {
-		ext := filepath.Ext(fileHeader.Filename)
-		uniqueFilename := uuid.New().String() + ext
-		uploadDir := "./uploads/images/profile/"
-		savePathOnDisk := filepath.Join(uploadDir, uniqueFilename)
-
-		if errMkdir := os.MkdirAll(uploadDir, os.ModePerm); errMkdir != nil {
-			log.Printf("[Handler UpdateEmployee] Error membuat direktori uploads: %v\n", errMkdir)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyiapkan penyimpanan file."})
-			return
-		}
-
-		if errSave := c.SaveUploadedFile(fileHeader, savePathOnDisk); errSave != nil {
-			log.Printf("[Handler UpdateEmployee] Error menyimpan file upload baru: %v\n", errSave)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan file gambar baru."})
-			return
-		}
-		log.Printf("[Handler UpdateEmployee] File baru berhasil disimpan ke: %s\n", savePathOnDisk)
-
-		savedPathForService := strings.TrimPrefix(filepath.ToSlash(savePathOnDisk), "./")
-		newImagePath = &savedPathForService
-
+		imageFileHeader = fileHeader
 	} else if errFile != nil && errFile != http.ErrMissingFile {
 		log.Printf("[Handler UpdateEmployee] Error mendapatkan file (bukan ErrMissingFile): %v\n", errFile)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Error memproses file gambar: " + errFile.Error()})
 		return
 	}
-	updatedEmployee, serviceErr := h.svc.UpdateEmployee(employeeID, inputDTO, newImagePath)
+	updatedEmployee, serviceErr := h.svc.UpdateEmployee(employeeID, inputDTO, imageFileHeader)
 	if serviceErr != nil {
 		log.Printf("[Handler UpdateEmployee] Error dari service UpdateEmployee untuk ID %s: %v\n", employeeID, serviceErr)
 		if serviceErr.Error() == "employee tidak ditemukan" {
@@ -750,6 +793,11 @@ func (h *handler) AddProduct(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": serviceErr.Error()})
 			return
 		}
+		if strings.HasPrefix(serviceErr.Error(), "spesifikasi tidak valid") || strings.HasPrefix(serviceErr.Error(), "varian tidak valid") ||
+			strings.HasPrefix(serviceErr.Error(), "periode garansi tidak valid") || strings.HasPrefix(serviceErr.Error(), "tarif sewa tidak valid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": serviceErr.Error()})
+			return
+		}
 		// Tangani error duplikasi lain jika ada (misal, jika title produk harus unik)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menambahkan produk", "details": serviceErr.Error()})
 		return
@@ -762,6 +810,34 @@ func (h *handler) AddProduct(c *gin.Context) {
 	})
 }
 
+func (h *handler) UpdateProductCategoryAttributes(c *gin.Context) {
+	categoryID := c.Param("categoryId")
+
+	var input UpdateProductCategoryAttributesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("[Handler UpdateProductCategoryAttributes] Error binding JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data input tidak valid: " + err.Error()})
+		return
+	}
+
+	attributes, err := h.svc.UpdateProductCategoryAttributes(categoryID, input)
+	if err != nil {
+		log.Printf("[Handler UpdateProductCategoryAttributes] Error dari service untuk ID %s: %v\n", categoryID, err)
+		if err.Error() == "kategori produk tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "kode atribut") || strings.Contains(err.Error(), "harus memiliki options") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan atribut kategori produk", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Atribut kategori produk berhasil disimpan", "attributes": attributes})
+}
+
 func (h *handler) ListActiveProductCategories(c *gin.Context) {
 	log.Println("[Handler ListActiveProductCategories] Memulai proses...")
 	categories, err := h.svc.ListActiveProductCategories()
@@ -858,7 +934,8 @@ func (h *handler) UpdateProduct(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": serviceErr.Error()})
 			return
 		}
-		if strings.Contains(serviceErr.Error(), "kategori produk baru tidak valid") {
+		if strings.Contains(serviceErr.Error(), "kategori produk baru tidak valid") || strings.HasPrefix(serviceErr.Error(), "spesifikasi tidak valid") || strings.HasPrefix(serviceErr.Error(), "varian tidak valid") ||
+			strings.HasPrefix(serviceErr.Error(), "periode garansi tidak valid") || strings.HasPrefix(serviceErr.Error(), "tarif sewa tidak valid") {
 			c.JSON(http.StatusBadRequest, gin.H{"error": serviceErr.Error()})
 			return
 		}
@@ -880,12 +957,166 @@ func (h *handler) DeleteProduct(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if strings.HasPrefix(err.Error(), "produk masih memiliki varian") || err.Error() == "produk masih memiliki unit berseri yang terikat pesanan" ||
+			err.Error() == "produk masih memiliki riwayat booking sewa" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Produk berhasil dihapus"})
 }
 
+// parseProductImageID mengambil :imageId dari URL. Mengembalikan false jika format tidak valid (respons sudah dikirim).
+func parseProductImageID(c *gin.Context) (uint, bool) {
+	imageIDUint64, err := strconv.ParseUint(c.Param("imageId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format Image ID tidak valid"})
+		return 0, false
+	}
+	return uint(imageIDUint64), true
+}
+
+func respondProductImageError(c *gin.Context, err error, fallbackMessage string) {
+	if err.Error() == "produk tidak ditemukan" || err.Error() == "gambar produk tidak ditemukan" {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.Contains(err.Error(), "daftar urutan gambar") || err.Error() == "tidak ada gambar yang diupload" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+}
+
+func (h *handler) AddProductImages(c *gin.Context) {
+	productSKU := c.Param("productSKU")
+
+	if err := c.Request.ParseMultipartForm(20 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal memproses form data: " + err.Error()})
+		return
+	}
+
+	// jsonData bersifat opsional, hanya berisi alt text
+	var inputDTO AddProductImagesInput
+	if jsonDataString := c.PostForm("jsonData"); jsonDataString != "" {
+		if err := json.Unmarshal([]byte(jsonDataString), &inputDTO); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format jsonData tidak valid: " + err.Error()})
+			return
+		}
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal memproses file: " + err.Error()})
+		return
+	}
+	imageFiles := form.File["imageFiles"]
+	if len(imageFiles) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Minimal satu file gambar (imageFiles) dibutuhkan"})
+		return
+	}
+
+	uploadDir := "./uploads/images/products/"
+	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyiapkan penyimpanan file."})
+		return
+	}
+
+	var savedImagePaths []string
+	for _, fileHeader := range imageFiles {
+		if fileHeader == nil {
+			continue
+		}
+		savePathOnDisk := filepath.Join(uploadDir, uuid.New().String()+filepath.Ext(fileHeader.Filename))
+		if errSave := c.SaveUploadedFile(fileHeader, savePathOnDisk); errSave != nil {
+			log.Printf("[Handler AddProductImages] Error menyimpan file upload '%s': %v\n", fileHeader.Filename, errSave)
+			for _, savedPath := range savedImagePaths {
+				os.Remove(filepath.Join(".", savedPath))
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan file gambar."})
+			return
+		}
+		savedImagePaths = append(savedImagePaths, strings.TrimPrefix(filepath.ToSlash(savePathOnDisk), "./"))
+	}
+
+	images, serviceErr := h.svc.AddProductImages(productSKU, savedImagePaths, inputDTO.AltTexts)
+	if serviceErr != nil {
+		log.Printf("[Handler AddProductImages] Error dari service untuk SKU %s: %v\n", productSKU, serviceErr)
+		respondProductImageError(c, serviceErr, "Gagal menambahkan gambar produk")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Gambar produk berhasil ditambahkan", "images": images})
+}
+
+func (h *handler) UpdateProductImage(c *gin.Context) {
+	productSKU := c.Param("productSKU")
+	imageID, ok := parseProductImageID(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateProductImageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	image, err := h.svc.UpdateProductImage(productSKU, imageID, input)
+	if err != nil {
+		respondProductImageError(c, err, "Gagal mengupdate gambar produk")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Gambar produk berhasil diupdate", "image": image})
+}
+
+func (h *handler) DeleteProductImage(c *gin.Context) {
+	productSKU := c.Param("productSKU")
+	imageID, ok := parseProductImageID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteProductImage(productSKU, imageID); err != nil {
+		respondProductImageError(c, err, "Gagal menghapus gambar produk")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Gambar produk berhasil dihapus"})
+}
+
+func (h *handler) ReorderProductImages(c *gin.Context) {
+	productSKU := c.Param("productSKU")
+
+	var input ReorderProductImagesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	images, err := h.svc.ReorderProductImages(productSKU, input)
+	if err != nil {
+		respondProductImageError(c, err, "Gagal mengubah urutan gambar produk")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Urutan gambar produk berhasil diupdate", "images": images})
+}
+
+func (h *handler) SetPrimaryProductImage(c *gin.Context) {
+	productSKU := c.Param("productSKU")
+	imageID, ok := parseProductImageID(c)
+	if !ok {
+		return
+	}
+
+	images, err := h.svc.SetPrimaryProductImage(productSKU, imageID)
+	if err != nil {
+		respondProductImageError(c, err, "Gagal mengubah gambar utama produk")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Gambar utama produk berhasil diubah", "images": images})
+}
+
 func (h *handler) ListAllOrders(c *gin.Context) {
 	// Ambil status dari query parameter URL (contoh: /admin/orders?status=Completed)
 	status := c.Query("status")
@@ -896,7 +1127,13 @@ func (h *handler) ListAllOrders(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar pesanan", "details": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
+	// Booking sewa ditampilkan berdampingan dengan pesanan; filter statusnya terpisah karena status sewa berbeda
+	rentals, err := h.svc.ListRentalBookings(AdminRentalBookingQuery{Status: c.Query("rental_status")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar booking sewa", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orders": orders, "rentals": rentals})
 }
 
 func (h *handler) GetOrderDetailForAdmin(c *gin.Context) {
@@ -914,18 +1151,32 @@ func (h *handler) GetOrderDetailForAdmin(c *gin.Context) {
 }
 
 func (h *handler) UpdateOrderStatus(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
 	orderID := c.Param("orderId")
 	var input AdminUpdateOrderStatusInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
 		return
 	}
-	updatedOrder, err := h.svc.UpdateOrderStatus(orderID, input)
+	updatedOrder, err := h.svc.UpdateOrderStatus(orderID, employeeID, input)
 	if err != nil {
 		if err.Error() == "pesanan tidak ditemukan" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if strings.HasPrefix(err.Error(), "nomor seri tidak valid") || strings.HasPrefix(err.Error(), "status pesanan tidak valid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengupdate status pesanan", "details": err.Error()})
 		return
 	}
@@ -946,131 +1197,1035 @@ func (h *handler) DeleteOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Pesanan berhasil dihapus"})
 }
 
-func (h *handler) ListOrderedCustomers(c *gin.Context) {
-	customers, err := h.svc.ListOrderedCustomers()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar customer", "details": err.Error()})
+func (h *handler) SetPaymentSchedule(c *gin.Context) {
+	var input SetPaymentScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"customers": customers})
-}
 
-func (h *handler) GetCustomerDetailForAdmin(c *gin.Context) {
-	customerID := c.Param("customerId") // Ambil dari URL
-	customerDetail, err := h.svc.GetCustomerDetailForAdmin(customerID)
+	milestones, err := h.svc.SetPaymentSchedule(c.Param("orderId"), input)
 	if err != nil {
-		if err.Error() == "customer tidak ditemukan" {
+		switch {
+		case err.Error() == "pesanan tidak ditemukan":
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
+		case strings.HasPrefix(err.Error(), "jadwal pembayaran tidak valid"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "jadwal pembayaran tidak bisa diubah"):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan jadwal pembayaran", "details": err.Error()})
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil detail customer", "details": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"customer_detail": customerDetail})
+	c.JSON(http.StatusOK, gin.H{"message": "Jadwal pembayaran berhasil disimpan", "payment_schedule": milestones})
 }
 
-func (h *handler) DeleteCustomer(c *gin.Context) {
-	customerID := c.Param("customerId")
-	err := h.svc.DeleteCustomer(customerID)
+func (h *handler) VerifyPaymentMilestone(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, _ := employeeIDInterface.(string)
+
+	milestoneID, err := strconv.ParseUint(c.Param("milestoneId"), 10, 32)
 	if err != nil {
-		if err.Error() == "customer tidak ditemukan untuk dihapus" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus customer", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID termin tidak valid"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Customer berhasil dihapus"})
-}
 
-func (h *handler) AddNewsCategory(c *gin.Context) {
-	var input UpsertNewsCategoryInput
+	var input VerifyPaymentMilestoneInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
 		return
 	}
-	category, err := h.svc.AddNewsCategory(input)
+
+	milestone, err := h.svc.VerifyPaymentMilestone(c.Param("orderId"), uint(milestoneID), employeeID, input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan kategori baru", "details": err.Error()})
+		switch err.Error() {
+		case "termin pembayaran tidak ditemukan":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "alasan penolakan wajib diisi":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "termin pembayaran tidak sedang menunggu verifikasi":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memverifikasi termin pembayaran", "details": err.Error()})
+		}
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "Kategori berita berhasil ditambahkan", "category": category})
+	c.JSON(http.StatusOK, gin.H{"message": "Verifikasi termin pembayaran berhasil disimpan", "payment_milestone": milestone})
 }
 
-func (h *handler) ListNewsCategories(c *gin.Context) {
-	categories, err := h.svc.ListNewsCategories()
+func (h *handler) ListOverdueInstallments(c *gin.Context) {
+	report, err := h.svc.ListOverdueInstallments()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar kategori berita", "details": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil laporan termin jatuh tempo", "details": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"categories": categories})
+	c.JSON(http.StatusOK, gin.H{"overdue_installments": report})
 }
 
-func (h *handler) GetNewsCategoryByID(c *gin.Context) {
-	categoryID := c.Param("categoryId")
-	category, err := h.svc.GetNewsCategoryByID(categoryID)
+func (h *handler) ListCompanies(c *gin.Context) {
+	companies, err := h.svc.ListCompanies()
 	if err != nil {
-		if err.Error() == "kategori berita tidak ditemukan" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar perusahaan", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"companies": companies})
+}
+
+func (h *handler) GetCompanyDetail(c *gin.Context) {
+	company, err := h.svc.GetCompanyDetail(c.Param("companyId"))
+	if err != nil {
+		if err.Error() == "perusahaan tidak ditemukan" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil detail kategori berita", "details": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil detail perusahaan", "details": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"category": category})
+	c.JSON(http.StatusOK, company)
 }
 
-func (h *handler) UpdateNewsCategory(c *gin.Context) {
-	categoryID := c.Param("categoryId")
-	var input UpsertNewsCategoryInput
+func respondProductUnitError(c *gin.Context, err error, fallbackMessage string) {
+	switch {
+	case err.Error() == "produk tidak ditemukan", err.Error() == "unit tidak ditemukan", err.Error() == "pesanan tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "unit tidak valid: nomor seri sudah terdaftar"),
+		strings.HasPrefix(err.Error(), "unit tidak valid: hanya unit berstatus"):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "unit tidak valid"), strings.HasPrefix(err.Error(), "nomor seri tidak valid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+	}
+}
+
+func (h *handler) RegisterProductUnits(c *gin.Context) {
+	var input RegisterProductUnitsInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
 		return
 	}
-	category, err := h.svc.UpdateNewsCategory(categoryID, input)
+	units, err := h.svc.RegisterProductUnits(c.Param("productSKU"), input)
 	if err != nil {
-		if err.Error() == "kategori berita tidak ditemukan" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengupdate kategori berita", "details": err.Error()})
+		respondProductUnitError(c, err, "Gagal mendaftarkan unit produk")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Kategori berita berhasil diupdate", "category": category})
+	c.JSON(http.StatusCreated, gin.H{"message": "Unit produk berhasil didaftarkan", "units": units})
 }
 
-func (h *handler) DeleteNewsCategory(c *gin.Context) {
-	categoryID := c.Param("categoryId")
-	err := h.svc.DeleteNewsCategory(categoryID)
+func (h *handler) ListProductUnits(c *gin.Context) {
+	// Filter opsional: /admin/products/:productSKU/units?status=In Stock
+	units, err := h.svc.ListProductUnits(c.Param("productSKU"), c.Query("status"))
 	if err != nil {
-		if err.Error() == "kategori berita tidak ditemukan untuk dihapus" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus kategori berita", "details": err.Error()})
+		respondProductUnitError(c, err, "Gagal mengambil unit produk")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Kategori berita berhasil dihapus"})
+	c.JSON(http.StatusOK, gin.H{"units": units})
 }
 
-func (h *handler) AddNewsPost(c *gin.Context) {
-	// Ambil authorID (employee_id) dari token
-	authorID, exists := c.Get("admin_employee_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+func (h *handler) DeleteProductUnit(c *gin.Context) {
+	unitID, err := strconv.ParseUint(c.Param("unitId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID unit tidak valid"})
+		return
+	}
+	if err := h.svc.DeleteProductUnit(c.Param("productSKU"), uint(unitID)); err != nil {
+		respondProductUnitError(c, err, "Gagal menghapus unit produk")
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Unit produk berhasil dihapus"})
+}
 
-	// Parsing multipart form
-	if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // 10 MB limit
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal memproses form data: " + err.Error()})
+func (h *handler) LookupProductUnit(c *gin.Context) {
+	unit, err := h.svc.LookupProductUnit(c.Param("serialNumber"))
+	if err != nil {
+		respondProductUnitError(c, err, "Gagal mencari unit")
 		return
 	}
+	c.JSON(http.StatusOK, unit)
+}
 
-	// Ambil jsonData
-	jsonDataString := c.PostForm("jsonData")
-	if jsonDataString == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Data berita (jsonData) tidak ditemukan."})
+func (h *handler) AssignOrderUnits(c *gin.Context) {
+	var input AssignOrderUnitsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	units, err := h.svc.AssignOrderUnits(c.Param("orderId"), input)
+	if err != nil {
+		respondProductUnitError(c, err, "Gagal menetapkan nomor seri pesanan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Nomor seri pesanan berhasil ditetapkan", "units": units})
+}
+
+func (h *handler) UpdateWarrantySerialNumber(c *gin.Context) {
+	warrantyID, err := strconv.ParseUint(c.Param("warrantyId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID garansi tidak valid"})
+		return
+	}
+	var input UpdateWarrantySerialNumberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	warranty, err := h.svc.UpdateWarrantySerialNumber(uint(warrantyID), input)
+	if err != nil {
+		if err.Error() == "garansi tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan nomor seri", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Nomor seri berhasil disimpan", "warranty": warranty})
+}
+
+func respondWarrantyClaimError(c *gin.Context, err error, fallbackMessage string) {
+	switch {
+	case err.Error() == "klaim garansi tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "status klaim tidak valid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+	}
+}
+
+func (h *handler) ListWarrantyClaims(c *gin.Context) {
+	// Filter opsional: /admin/warranty-claims?status=Submitted
+	claims, err := h.svc.ListWarrantyClaims(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar klaim garansi", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"warranty_claims": claims})
+}
+
+func (h *handler) GetWarrantyClaim(c *gin.Context) {
+	claim, err := h.svc.GetWarrantyClaim(c.Param("claimId"))
+	if err != nil {
+		respondWarrantyClaimError(c, err, "Gagal mengambil klaim garansi")
+		return
+	}
+	c.JSON(http.StatusOK, claim)
+}
+
+func (h *handler) UpdateWarrantyClaim(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+
+	var input UpdateWarrantyClaimInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data input tidak valid: " + err.Error()})
+		return
+	}
+
+	claim, err := h.svc.UpdateWarrantyClaim(c.Param("claimId"), employeeID, input)
+	if err != nil {
+		respondWarrantyClaimError(c, err, "Gagal memperbarui klaim garansi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Klaim garansi berhasil diperbarui", "warranty_claim": claim})
+}
+
+const maxServiceTicketAttachments = 5
+
+var allowedServiceTicketAttachmentExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".pdf": true}
+
+func respondServiceTicketError(c *gin.Context, err error, fallbackMessage string) {
+	switch {
+	case err.Error() == "tiket layanan tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "tiket layanan sudah ditutup":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "penugasan tidak valid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+	}
+}
+
+func (h *handler) ListServiceTickets(c *gin.Context) {
+	// Filter opsional: ?status=Open&priority=Urgent&department_id=DEP0001&assigned_to=unassigned
+	var query AdminServiceTicketQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter filter tidak valid: " + err.Error()})
+		return
+	}
+	tickets, err := h.svc.ListServiceTickets(query)
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal mengambil tiket layanan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"service_tickets": tickets})
+}
+
+func (h *handler) GetServiceTicket(c *gin.Context) {
+	ticket, err := h.svc.GetServiceTicket(c.Param("ticketId"))
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal mengambil tiket layanan")
+		return
+	}
+	c.JSON(http.StatusOK, ticket)
+}
+
+func (h *handler) AssignServiceTicket(c *gin.Context) {
+	var input AssignServiceTicketInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	ticket, err := h.svc.AssignServiceTicket(c.Param("ticketId"), input)
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal menugaskan tiket layanan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tiket layanan berhasil ditugaskan", "service_ticket": ticket})
+}
+
+func (h *handler) UpdateServiceTicketStatus(c *gin.Context) {
+	var input UpdateServiceTicketStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	ticket, err := h.svc.UpdateServiceTicketStatus(c.Param("ticketId"), input)
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal mengubah status tiket layanan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Status tiket layanan berhasil diubah", "service_ticket": ticket})
+}
+
+func (h *handler) AddServiceTicketMessage(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+
+	var input AdminServiceTicketMessageInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	var attachments []*multipart.FileHeader
+	if c.Request.MultipartForm != nil {
+		attachments = c.Request.MultipartForm.File["attachments"]
+	}
+	if len(attachments) > maxServiceTicketAttachments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Maksimal %d lampiran per pesan", maxServiceTicketAttachments)})
+		return
+	}
+	for _, fileHeader := range attachments {
+		if !allowedServiceTicketAttachmentExts[strings.ToLower(filepath.Ext(fileHeader.Filename))] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format lampiran tidak didukung, gunakan jpg, jpeg, png, webp, atau pdf"})
+			return
+		}
+	}
+
+	ticket, err := h.svc.AddServiceTicketMessage(c.Param("ticketId"), employeeID, input, attachments)
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal mengirim pesan tiket")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Pesan berhasil dikirim", "service_ticket": ticket})
+}
+
+func respondInstallationJobError(c *gin.Context, err error, fallbackMessage string) {
+	msg := err.Error()
+	switch {
+	case msg == "pekerjaan instalasi tidak ditemukan", msg == "pesanan tidak ditemukan", msg == "item checklist tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "jadwal bentrok"), strings.HasPrefix(msg, "checklist instalasi belum selesai"),
+		msg == "pekerjaan instalasi sudah selesai atau dibatalkan", msg == "pekerjaan instalasi belum dijadwalkan",
+		msg == "pesanan sudah memiliki jadwal instalasi aktif":
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "jadwal tidak valid"), strings.HasPrefix(msg, "instalasi tidak valid"), strings.HasPrefix(msg, "nomor seri tidak valid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": msg})
+	}
+}
+
+func (h *handler) CreateInstallationJob(c *gin.Context) {
+	var input CreateInstallationJobInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	job, err := h.svc.CreateInstallationJob(c.Param("orderId"), input)
+	if err != nil {
+		respondInstallationJobError(c, err, "Gagal membuat pekerjaan instalasi")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Pekerjaan instalasi berhasil dibuat", "installation_job": job})
+}
+
+func (h *handler) ListInstallationJobs(c *gin.Context) {
+	// Kalender: ?from=2025-07-01&to=2025-07-31&technician_id=EMP...&status=Scheduled
+	var query AdminInstallationJobQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter filter tidak valid: " + err.Error()})
+		return
+	}
+	jobs, err := h.svc.ListInstallationJobs(query)
+	if err != nil {
+		respondInstallationJobError(c, err, "Gagal mengambil pekerjaan instalasi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"installation_jobs": jobs})
+}
+
+func (h *handler) GetInstallationJob(c *gin.Context) {
+	job, err := h.svc.GetInstallationJob(c.Param("jobId"))
+	if err != nil {
+		respondInstallationJobError(c, err, "Gagal mengambil pekerjaan instalasi")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *handler) ScheduleInstallationJob(c *gin.Context) {
+	var input ScheduleInstallationJobInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	job, err := h.svc.ScheduleInstallationJob(c.Param("jobId"), input)
+	if err != nil {
+		respondInstallationJobError(c, err, "Gagal menjadwalkan instalasi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Jadwal instalasi berhasil disimpan", "installation_job": job})
+}
+
+func (h *handler) SetInstallationChecklist(c *gin.Context) {
+	var input SetInstallationChecklistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	job, err := h.svc.SetInstallationChecklist(c.Param("jobId"), input)
+	if err != nil {
+		respondInstallationJobError(c, err, "Gagal menyimpan checklist instalasi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Checklist instalasi berhasil disimpan", "installation_job": job})
+}
+
+func (h *handler) UpdateInstallationChecklistItem(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID item checklist tidak valid"})
+		return
+	}
+	var input UpdateInstallationChecklistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	job, err := h.svc.UpdateInstallationChecklistItem(c.Param("jobId"), uint(itemID), employeeID, input)
+	if err != nil {
+		respondInstallationJobError(c, err, "Gagal memperbarui checklist instalasi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Checklist instalasi berhasil diperbarui", "installation_job": job})
+}
+
+func (h *handler) UpdateInstallationJobStatus(c *gin.Context) {
+	var input UpdateInstallationJobStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	job, err := h.svc.UpdateInstallationJobStatus(c.Param("jobId"), input)
+	if err != nil {
+		respondInstallationJobError(c, err, "Gagal mengubah status instalasi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Status instalasi berhasil diubah", "installation_job": job})
+}
+
+func (h *handler) CompleteInstallationJob(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	var input CompleteInstallationJobInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	job, err := h.svc.CompleteInstallationJob(c.Param("jobId"), employeeID, input)
+	if err != nil {
+		respondInstallationJobError(c, err, "Gagal menyelesaikan instalasi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Instalasi selesai dan pesanan ditandai Completed", "installation_job": job})
+}
+
+func respondRentalError(c *gin.Context, err error, fallbackMessage string) {
+	msg := err.Error()
+	switch {
+	case msg == "produk tidak ditemukan", msg == "unit sewa tidak ditemukan", msg == "booking sewa tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case msg == "unit sewa masih memiliki booking aktif":
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "unit sewa tidak valid"), strings.HasPrefix(msg, "status booking tidak valid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": msg})
+	}
+}
+
+func (h *handler) AddRentalUnits(c *gin.Context) {
+	var input AddRentalUnitsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	units, err := h.svc.AddRentalUnits(c.Param("productSKU"), input)
+	if err != nil {
+		respondRentalError(c, err, "Gagal menambahkan unit sewa")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Unit sewa berhasil ditambahkan", "rental_units": units})
+}
+
+func (h *handler) ListRentalUnits(c *gin.Context) {
+	units, err := h.svc.ListRentalUnits(c.Param("productSKU"))
+	if err != nil {
+		respondRentalError(c, err, "Gagal mengambil unit sewa")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rental_units": units})
+}
+
+func (h *handler) UpdateRentalUnit(c *gin.Context) {
+	unitID, err := strconv.ParseUint(c.Param("unitId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID unit tidak valid"})
+		return
+	}
+	var input UpdateRentalUnitInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	unit, err := h.svc.UpdateRentalUnit(uint(unitID), input)
+	if err != nil {
+		respondRentalError(c, err, "Gagal memperbarui unit sewa")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Unit sewa berhasil diperbarui", "rental_unit": unit})
+}
+
+func (h *handler) ListRentalBookings(c *gin.Context) {
+	// Contoh: /admin/rentals?status=Confirmed&rental_unit_id=3&from=2025-07-01&to=2025-07-31
+	var query AdminRentalBookingQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter filter tidak valid: " + err.Error()})
+		return
+	}
+	rentals, err := h.svc.ListRentalBookings(query)
+	if err != nil {
+		respondRentalError(c, err, "Gagal mengambil daftar booking sewa")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rentals": rentals})
+}
+
+func (h *handler) GetRentalBooking(c *gin.Context) {
+	rental, err := h.svc.GetRentalBooking(c.Param("bookingId"))
+	if err != nil {
+		respondRentalError(c, err, "Gagal mengambil booking sewa")
+		return
+	}
+	c.JSON(http.StatusOK, rental)
+}
+
+func (h *handler) UpdateRentalBookingStatus(c *gin.Context) {
+	var input UpdateRentalBookingStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	rental, err := h.svc.UpdateRentalBookingStatus(c.Param("bookingId"), input)
+	if err != nil {
+		respondRentalError(c, err, "Gagal mengubah status booking sewa")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Status booking sewa berhasil diubah", "rental": rental})
+}
+
+func (h *handler) InspectRentalReturn(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	var input RentalReturnInspectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	rental, err := h.svc.InspectRentalReturn(c.Param("bookingId"), employeeID, input)
+	if err != nil {
+		respondRentalError(c, err, "Gagal menyimpan inspeksi pengembalian")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Inspeksi pengembalian berhasil disimpan", "rental": rental})
+}
+
+func (h *handler) ListRefunds(c *gin.Context) {
+	// Antrean refund, opsional difilter: /admin/refunds?status=Pending
+	refunds, err := h.svc.ListRefunds(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar refund", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"refunds": refunds})
+}
+
+func (h *handler) GetRefund(c *gin.Context) {
+	refund, err := h.svc.GetRefund(c.Param("refundId"))
+	if err != nil {
+		if err.Error() == "refund tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil refund", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, refund)
+}
+
+func (h *handler) UpdateRefund(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	var input UpdateRefundInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	refund, err := h.svc.UpdateRefund(c.Param("refundId"), employeeID, input)
+	if err != nil {
+		switch {
+		case err.Error() == "refund tidak ditemukan":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "status refund tidak valid"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui refund", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Refund berhasil diperbarui", "refund": refund})
+}
+
+func respondReturnRequestError(c *gin.Context, err error, fallbackMessage string) {
+	msg := err.Error()
+	switch {
+	case msg == "retur tidak ditemukan", msg == "produk tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "stok produk"):
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "status retur tidak valid"), strings.HasPrefix(msg, "nomor seri tidak valid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": msg})
+	}
+}
+
+func (h *handler) ListReturnRequests(c *gin.Context) {
+	// Antrean retur, opsional difilter: /admin/returns?status=Requested&customer_id=...&order_id=...
+	var query AdminReturnRequestQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter query tidak valid: " + err.Error()})
+		return
+	}
+	returnRequests, err := h.svc.ListReturnRequests(query)
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal mengambil daftar retur")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"returns": returnRequests})
+}
+
+func (h *handler) GetReturnRequest(c *gin.Context) {
+	returnRequest, err := h.svc.GetReturnRequest(c.Param("rmaId"))
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal mengambil retur")
+		return
+	}
+	c.JSON(http.StatusOK, returnRequest)
+}
+
+func (h *handler) ReviewReturnRequest(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	var input ReviewReturnRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	returnRequest, err := h.svc.ReviewReturnRequest(c.Param("rmaId"), employeeID, input)
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal meninjau retur")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pengajuan retur berhasil ditinjau", "return": returnRequest})
+}
+
+func (h *handler) ReceiveReturnRequest(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+
+	returnRequest, err := h.svc.ReceiveReturnRequest(c.Param("rmaId"), employeeID)
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal mencatat penerimaan barang retur")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Barang retur berhasil diterima", "return": returnRequest})
+}
+
+func (h *handler) InspectReturnRequest(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	var input InspectReturnRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	returnRequest, err := h.svc.InspectReturnRequest(c.Param("rmaId"), employeeID, input)
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal menyimpan inspeksi retur")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Inspeksi retur berhasil disimpan", "return": returnRequest})
+}
+
+func (h *handler) ResolveReturnRequest(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	var input ResolveReturnRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	returnRequest, err := h.svc.ResolveReturnRequest(c.Param("rmaId"), employeeID, input)
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal menyelesaikan retur")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Retur berhasil diselesaikan", "return": returnRequest})
+}
+
+func (h *handler) ListStockMovements(c *gin.Context) {
+	movements, err := h.svc.ListStockMovements(c.Param("productSKU"))
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal mengambil mutasi stok")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stock_movements": movements})
+}
+
+var allowedDeliveryPhotoExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true}
+
+func respondShipmentError(c *gin.Context, err error, fallbackMessage string) {
+	msg := err.Error()
+	switch {
+	case msg == "pesanan tidak ditemukan", msg == "pengiriman tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case msg == "pengiriman sudah dikonfirmasi diterima", msg == "nomor resi belum diisi untuk pengiriman ini":
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "pengiriman tidak valid"), strings.HasPrefix(msg, "nomor seri tidak valid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": msg})
+	}
+}
+
+func (h *handler) CreateShipment(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	var input CreateShipmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	shipment, err := h.svc.CreateShipment(c.Param("orderId"), employeeID, input)
+	if err != nil {
+		respondShipmentError(c, err, "Gagal mencatat pengiriman")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Pengiriman berhasil dicatat", "shipment": shipment})
+}
+
+func (h *handler) ConfirmShipmentDelivery(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+	var input ConfirmShipmentDeliveryInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	photoFileHeader, err := c.FormFile("photo")
+	if err != nil {
+		photoFileHeader = nil // Foto bukti terima bersifat opsional
+	} else if !allowedDeliveryPhotoExts[strings.ToLower(filepath.Ext(photoFileHeader.Filename))] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format foto tidak didukung, gunakan jpg, jpeg, png, atau webp"})
+		return
+	}
+
+	shipment, err := h.svc.ConfirmShipmentDelivery(c.Param("shipmentId"), employeeID, input, photoFileHeader)
+	if err != nil {
+		respondShipmentError(c, err, "Gagal menyimpan konfirmasi penerimaan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Penerimaan paket berhasil dikonfirmasi", "shipment": shipment})
+}
+
+func (h *handler) GetShipmentTracking(c *gin.Context) {
+	tracking, err := h.svc.GetShipmentTracking(c.Param("shipmentId"))
+	if err != nil {
+		respondShipmentError(c, err, "Gagal melacak pengiriman")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tracking": tracking})
+}
+
+func (h *handler) ListOrderedCustomers(c *gin.Context) {
+	customers, err := h.svc.ListOrderedCustomers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar customer", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"customers": customers})
+}
+
+func (h *handler) GetCustomerDetailForAdmin(c *gin.Context) {
+	customerID := c.Param("customerId") // Ambil dari URL
+	customerDetail, err := h.svc.GetCustomerDetailForAdmin(customerID)
+	if err != nil {
+		if err.Error() == "customer tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil detail customer", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"customer_detail": customerDetail})
+}
+
+func (h *handler) DeleteCustomer(c *gin.Context) {
+	customerID := c.Param("customerId")
+	err := h.svc.DeleteCustomer(customerID)
+	if err != nil {
+		if err.Error() == "customer tidak ditemukan untuk dihapus" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus customer", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Customer berhasil dihapus"})
+}
+
+func (h *handler) AddNewsCategory(c *gin.Context) {
+	var input UpsertNewsCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	category, err := h.svc.AddNewsCategory(input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan kategori baru", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Kategori berita berhasil ditambahkan", "category": category})
+}
+
+func (h *handler) ListNewsCategories(c *gin.Context) {
+	categories, err := h.svc.ListNewsCategories()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar kategori berita", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+func (h *handler) GetNewsCategoryByID(c *gin.Context) {
+	categoryID := c.Param("categoryId")
+	category, err := h.svc.GetNewsCategoryByID(categoryID)
+	if err != nil {
+		if err.Error() == "kategori berita tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil detail kategori berita", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"category": category})
+}
+
+func (h *handler) UpdateNewsCategory(c *gin.Context) {
+	categoryID := c.Param("categoryId")
+	var input UpsertNewsCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	category, err := h.svc.UpdateNewsCategory(categoryID, input)
+	if err != nil {
+		if err.Error() == "kategori berita tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengupdate kategori berita", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Kategori berita berhasil diupdate", "category": category})
+}
+
+func (h *handler) DeleteNewsCategory(c *gin.Context) {
+	categoryID := c.Param("categoryId")
+	err := h.svc.DeleteNewsCategory(categoryID)
+	if err != nil {
+		if err.Error() == "kategori berita tidak ditemukan untuk dihapus" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus kategori berita", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Kategori berita berhasil dihapus"})
+}
+
+func (h *handler) AddNewsPost(c *gin.Context) {
+	// Ambil authorID (employee_id) dari token
+	authorID, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+
+	// Parsing multipart form
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // 10 MB limit
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal memproses form data: " + err.Error()})
+		return
+	}
+
+	// Ambil jsonData
+	jsonDataString := c.PostForm("jsonData")
+	if jsonDataString == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data berita (jsonData) tidak ditemukan."})
 		return
 	}
 	var inputDTO AddNewsPostInput
@@ -1162,11 +2317,21 @@ func (h *handler) UpdateNewsPost(c *gin.Context) {
 	if errFile == nil && file != nil {
 		defer file.Close()
 		imageFileHeader = handlerFile
+	} else if errFile != http.ErrMissingFile {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error memproses file gambar: " + errFile.Error()})
+		return
 	}
 
 	updatedPost, err := h.svc.UpdateNewsPost(newsID, inputDTO, imageFileHeader)
 	if err != nil {
-		// ... (error handling dari service) ...
+		if err.Error() == "postingan berita tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.Contains(err.Error(), "format tanggal publikasi tidak valid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengupdate postingan berita", "details": err.Error()})
 		return
 	}
@@ -1194,3 +2359,97 @@ func (h *handler) GetDashboardStatistics(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, stats)
 }
+
+func respondCompatiblePartsError(c *gin.Context, err error, fallbackMessage string) {
+	if err.Error() == "produk tidak ditemukan" {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.HasPrefix(err.Error(), "suku cadang") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+}
+
+func (h *handler) ListCompatibleParts(c *gin.Context) {
+	compatibilities, err := h.svc.ListCompatibleParts(c.Param("productSKU"))
+	if err != nil {
+		respondCompatiblePartsError(c, err, "Gagal mengambil daftar suku cadang kompatibel")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"compatible_parts": compatibilities})
+}
+
+func (h *handler) SetCompatibleParts(c *gin.Context) {
+	var input SetCompatiblePartsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data input tidak valid: " + err.Error()})
+		return
+	}
+
+	compatibilities, err := h.svc.SetCompatibleParts(c.Param("productSKU"), input)
+	if err != nil {
+		respondCompatiblePartsError(c, err, "Gagal menyimpan daftar suku cadang kompatibel")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Daftar suku cadang kompatibel berhasil disimpan", "compatible_parts": compatibilities})
+}
+
+func respondQuotationRequestError(c *gin.Context, err error, fallbackMessage string) {
+	switch {
+	case err.Error() == "permintaan penawaran tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "permintaan penawaran sudah diproses"):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "quotation tidak valid"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+	}
+}
+
+func (h *handler) ListQuotationRequests(c *gin.Context) {
+	// Filter opsional: /admin/quotation-requests?status=Submitted
+	requests, err := h.svc.ListQuotationRequests(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar permintaan penawaran", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"quotation_requests": requests})
+}
+
+func (h *handler) GetQuotationRequest(c *gin.Context) {
+	request, err := h.svc.GetQuotationRequest(c.Param("requestId"))
+	if err != nil {
+		respondQuotationRequestError(c, err, "Gagal mengambil permintaan penawaran")
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *handler) UpsertQuotation(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("admin_employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Employee ID tidak ditemukan."})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Format Employee ID di token tidak valid"})
+		return
+	}
+
+	var input UpsertQuotationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data input tidak valid: " + err.Error()})
+		return
+	}
+
+	request, err := h.svc.UpsertQuotation(c.Param("requestId"), employeeID, input)
+	if err != nil {
+		respondQuotationRequestError(c, err, "Gagal menyimpan quotation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quotation berhasil disimpan", "quotation_request": request})
+}
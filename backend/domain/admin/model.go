@@ -61,18 +61,110 @@ type UpdateProductCategoryInput struct {
 	Status       string `json:"status" binding:"required"`
 }
 
+type ProductCategoryAttributeInput struct {
+	Code         string   `json:"code" binding:"required"` // Huruf kecil, angka dan underscore, misal "motor_power"
+	Label        string   `json:"label" binding:"required"`
+	DataType     string   `json:"data_type" binding:"required,oneof=number enum text"`
+	Unit         string   `json:"unit"`    // Hanya untuk number, misal "kW" atau "m3/h"
+	Options      []string `json:"options"` // Wajib untuk enum
+	IsRequired   bool     `json:"is_required"`
+	IsFilterable bool     `json:"is_filterable"`
+}
+
+// Skema atribut lengkap sebuah kategori, urutan array menjadi urutan tampil.
+// Atribut yang tidak lagi disertakan akan dihapus beserta nilainya di produk.
+type UpdateProductCategoryAttributesInput struct {
+	Attributes []ProductCategoryAttributeInput `json:"attributes" binding:"dive"`
+}
+
+// Nilai spesifikasi produk, dicocokkan ke atribut kategori lewat Code
+type ProductSpecificationInput struct {
+	Code        string   `json:"code"`
+	NumberValue *float64 `json:"number_value"` // Untuk atribut number
+	Value       string   `json:"value"`        // Untuk atribut enum dan text
+}
+
 type AddProductInput struct {
-	Title             string  `json:"title" binding:"required"`
-	Brand             string  `json:"brand"`
-	ProductCategoryID string  `json:"product_category_id" binding:"required"`
-	PowerSource       string  `json:"power_source"`
-	WarrantyPeriod    string  `json:"warranty_period"`
-	ProductionDate    string  `json:"production_date"` // Diterima sebagai string
-	Descriptions      string  `json:"descriptions"`
-	Stock             int     `json:"stock"`
+	Title             string `json:"title" binding:"required"`
+	Brand             string `json:"brand"`
+	ProductCategoryID string `json:"product_category_id" binding:"required"`
+	PowerSource       string `json:"power_source"`
+	WarrantyPeriod    string `json:"warranty_period"`
+	ProductionDate    string `json:"production_date"` // Diterima sebagai string
+	Descriptions      string `json:"descriptions"`
+	Stock             int    `json:"stock"`
+	IsSerialized      bool   `json:"is_serialized"` // Unit dilacak per nomor seri / nomor mesin
+	// Produk membutuhkan instalasi di lokasi customer setelah dikirim
+	RequiresInstallation bool `json:"requires_installation"`
+	// Produk dapat disewa; tarif mingguan/bulanan opsional (0 = tidak ditawarkan)
+	IsRentable        bool    `json:"is_rentable"`
+	RentalDailyRate   float64 `json:"rental_daily_rate"`
+	RentalWeeklyRate  float64 `json:"rental_weekly_rate"`
+	RentalMonthlyRate float64 `json:"rental_monthly_rate"`
+	RentalDeposit     float64 `json:"rental_deposit"`
 	Status            string  `json:"status" binding:"required"`
 	CapitalPrice      float64 `json:"capital_price" binding:"required,min=0"`
 	RegularPrice      float64 `json:"regular_price" binding:"required,min=0"`
+	// Alt text untuk gambar yang diupload, dipasangkan berdasarkan urutan file di "imageFiles"
+	ImageAltTexts []string `json:"image_alt_texts"`
+	// Divalidasi terhadap skema atribut kategori. Saat update, jika key dikirim (termasuk []) menggantikan
+	// seluruh spesifikasi lama; jika tidak dikirim spesifikasi lama dipertahankan selama kategori tidak berubah
+	Specifications []ProductSpecificationInput `json:"specifications"`
+	// Diisi untuk menjadikan produk ini varian dari produk induk (kategori harus sama)
+	ParentSKU   string `json:"parent_sku"`
+	VariantName string `json:"variant_name"`
+}
+
+// Diterima sebagai jsonData (opsional) saat menambah gambar ke produk yang sudah ada
+type AddProductImagesInput struct {
+	AltTexts []string `json:"alt_texts"`
+}
+
+type UpdateProductImageInput struct {
+	AltText string `json:"alt_text"`
+}
+
+type ReorderProductImagesInput struct {
+	// Seluruh ID gambar milik produk, dalam urutan tampil yang baru
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1"`
+}
+
+type CompatiblePartInput struct {
+	PartSKU string `json:"part_sku" binding:"required"`
+	Notes   string `json:"notes"`
+}
+
+// Daftar lengkap suku cadang yang kompatibel dengan sebuah mesin; menggantikan daftar sebelumnya
+type SetCompatiblePartsInput struct {
+	Parts []CompatiblePartInput `json:"parts" binding:"dive"`
+}
+
+type QuotationItemInput struct {
+	ProductSKU string  `json:"product_sku" binding:"required"`
+	Quantity   int     `json:"quantity" binding:"required,min=1"`
+	UnitPrice  float64 `json:"unit_price" binding:"min=0"`
+}
+
+// Quotation yang disusun sales untuk sebuah RFQ; menggantikan quotation sebelumnya jika belum diterima
+type UpsertQuotationInput struct {
+	Items      []QuotationItemInput `json:"items" binding:"required,min=1,dive"`
+	ValidUntil string               `json:"valid_until" binding:"required,datetime=2006-01-02"`
+	Terms      string               `json:"terms"`
+}
+
+type AdminQuotationRequestView struct {
+	RequestID        string    `json:"request_id"`
+	CustomerID       string    `json:"customer_id"`
+	CustomerFullname string    `json:"customer_fullname"`
+	CustomerEmail    string    `json:"customer_email"`
+	CustomerPhone    string    `json:"customer_phone"`
+	SiteLocation     string    `json:"site_location"`
+	Notes            string    `json:"notes"`
+	Status           string    `json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+
+	Items     []models.QuotationRequestItem `json:"items"`
+	Quotation *models.Quotation             `json:"quotation"`
 }
 
 type AdminOrderListView struct {
@@ -84,11 +176,59 @@ type AdminOrderListView struct {
 	GrandTotal       float64   `json:"grand_total"`
 	// Path gambar dari item pertama di order untuk thumbnail
 	FirstItemImage string `json:"first_item_image"`
+
+	CompanyLegalName string `json:"company_legal_name,omitempty"`
+
+	// order atau rental; untuk rental OrderID berisi booking ID dan GrandTotal berisi biaya sewa
+	EntryType string                  `json:"entry_type"`
+	Rental    *AdminRentalHistoryInfo `json:"rental,omitempty"`
+}
+
+type AdminRentalHistoryInfo struct {
+	BookingID            string    `json:"booking_id"`
+	ProductSKU           string    `json:"product_sku"`
+	ProductTitleSnapshot string    `json:"product_title_snapshot"`
+	UnitCode             string    `json:"unit_code"`
+	StartDate            time.Time `json:"start_date"`
+	EndDate              time.Time `json:"end_date"`
+	RentalDays           int       `json:"rental_days"`
+	Deposit              float64   `json:"deposit"`
 }
 
 type AdminUpdateOrderStatusInput struct {
 	// Menggunakan `oneof` untuk validasi bahwa status yang dikirim adalah salah satu dari nilai yang diizinkan
 	OrderStatus string `json:"order_status" binding:"required,oneof='Pending Confirmation' Processed Shipped Completed Canceled"`
+	// Nomor seri per unit, dikelompokkan per order_item_id. Untuk produk berseri dipakai menetapkan unit
+	// yang dikirim (status Shipped/Completed); untuk produk lain dicatat pada garansi saat Completed.
+	SerialNumbers map[uint][]string `json:"serial_numbers"`
+	// Data kurir untuk shipment yang dibuat otomatis saat order ditandai Shipped tanpa shipment;
+	// kurir paket yang sudah tercatat tidak diubah
+	ShippingCourier string `json:"shipping_courier"`
+	TrackingNumber  string `json:"tracking_number"`
+	Note            string `json:"note"` // Ditampilkan ke customer pada timeline pesanan
+}
+
+type ShipmentItemInput struct {
+	OrderItemID uint `json:"order_item_id" binding:"required"`
+	Quantity    int  `json:"quantity" binding:"required,min=1"`
+}
+
+// Items kosong berarti seluruh sisa item yang belum dikirim ikut dalam shipment ini
+type CreateShipmentInput struct {
+	Courier          string              `json:"courier" binding:"required,max=100"`
+	TrackingNumber   string              `json:"tracking_number" binding:"max=100"`
+	ShippedAt        *time.Time          `json:"shipped_at"` // Default: waktu sekarang
+	EstimatedArrival string              `json:"estimated_arrival" binding:"omitempty,datetime=2006-01-02"`
+	Notes            string              `json:"notes"`
+	Items            []ShipmentItemInput `json:"items" binding:"dive"`
+	// Dipakai saat shipment pertama memindahkan order ke Shipped, sama seperti AdminUpdateOrderStatusInput
+	SerialNumbers map[uint][]string `json:"serial_numbers"`
+}
+
+// Dikirim sebagai multipart form; foto bukti terima (opsional) lewat key "photo"
+type ConfirmShipmentDeliveryInput struct {
+	RecipientName string `form:"recipient_name" binding:"required,max=255"`
+	Notes         string `form:"notes"`
 }
 
 type AdminOrderDetailItemView struct {
@@ -115,6 +255,13 @@ type AdminOrderDetailView struct {
 	CustomerEmail    string `json:"customer_email"`
 	CustomerPhone    string `json:"customer_phone"`
 
+	// Info Perusahaan (jika order dibuat atas nama perusahaan)
+	CompanyID             string `json:"company_id,omitempty"`
+	CompanyLegalName      string `json:"company_legal_name,omitempty"`
+	CompanyNPWP           string `json:"company_npwp,omitempty"`
+	CompanyBillingAddress string `json:"company_billing_address,omitempty"`
+	ApprovedBy            string `json:"approved_by,omitempty"`
+
 	// Info Pengiriman
 	ShippingAddressSnapshot string `json:"shipping_address_snapshot"`
 
@@ -125,6 +272,434 @@ type AdminOrderDetailView struct {
 
 	// Daftar Item yang Dipesan
 	Items []AdminOrderDetailItemView `json:"items"`
+
+	// Jadwal termin pembayaran (kosong jika dibayar sekaligus)
+	OutstandingBalance float64                   `json:"outstanding_balance"`
+	PaymentSchedule    []models.PaymentMilestone `json:"payment_schedule"`
+
+	// Ringkasan pelacakan dari Shipments: tanggal kirim paket pertama, kurir dan resi paket terakhir
+	ShippingCourier string                      `json:"shipping_courier"`
+	TrackingNumber  string                      `json:"tracking_number"`
+	ShippedAt       *time.Time                  `json:"shipped_at"`
+	StatusHistory   []models.OrderStatusHistory `json:"status_history"`
+	Refund          *models.Refund              `json:"refund"` // Refund pembatalan; refund retur ada di Returns
+	Returns         []AdminReturnRequestView    `json:"returns"`
+	Shipments       []models.Shipment           `json:"shipments"`
+}
+
+// Satu termin; isi salah satu dari Percentage (dari grand total) atau Amount
+type PaymentMilestoneInput struct {
+	Label      string   `json:"label" binding:"required,max=100"`
+	Percentage *float64 `json:"percentage" binding:"omitempty,gt=0,lte=100"`
+	Amount     *float64 `json:"amount" binding:"omitempty,gt=0"`
+	DueDate    string   `json:"due_date" binding:"required,datetime=2006-01-02"`
+}
+
+// Menggantikan seluruh jadwal termin; daftar kosong berarti order kembali dibayar sekaligus
+type SetPaymentScheduleInput struct {
+	Milestones []PaymentMilestoneInput `json:"milestones" binding:"dive"`
+}
+
+type VerifyPaymentMilestoneInput struct {
+	Approved        *bool  `json:"approved" binding:"required"`
+	RejectionReason string `json:"rejection_reason"`
+}
+
+type AdminOverdueInstallmentView struct {
+	OrderID          string    `json:"order_id"`
+	CustomerID       string    `json:"customer_id"`
+	CustomerFullname string    `json:"customer_fullname"`
+	CustomerEmail    string    `json:"customer_email"`
+	CustomerPhone    string    `json:"customer_phone"`
+	MilestoneID      uint      `json:"milestone_id"`
+	Label            string    `json:"label"`
+	Amount           float64   `json:"amount"`
+	DueDate          time.Time `json:"due_date"`
+	DaysOverdue      int       `json:"days_overdue"`
+	Status           string    `json:"status"`
+}
+
+type AdminCompanyListView struct {
+	CompanyID   string  `json:"company_id"`
+	LegalName   string  `json:"legal_name"`
+	NPWP        string  `json:"npwp"`
+	MemberCount int64   `json:"member_count"`
+	TotalOrders int64   `json:"total_orders"`
+	TotalSpent  float64 `json:"total_spent"`
+}
+
+type AdminCompanyMemberView struct {
+	CustomerID string    `json:"customer_id"`
+	FullName   string    `json:"full_name"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	JoinedAt   time.Time `json:"joined_at"`
+}
+
+type AdminCompanyDetailView struct {
+	CompanyID         string    `json:"company_id"`
+	LegalName         string    `json:"legal_name"`
+	NPWP              string    `json:"npwp"`
+	BillingAddress    string    `json:"billing_address"`
+	ApprovalThreshold *float64  `json:"approval_threshold"`
+	CreatedAt         time.Time `json:"created_at"`
+
+	Members []AdminCompanyMemberView `json:"members"`
+	Orders  []AdminOrderListView     `json:"orders"`
+}
+
+type RegisterProductUnitsInput struct {
+	SerialNumbers []string `json:"serial_numbers" binding:"required,min=1,dive,required,max=100"`
+	Notes         string   `json:"notes" binding:"max=255"`
+}
+
+type AssignOrderUnitsInput struct {
+	// Nomor seri per order_item_id; menggantikan reservasi sebelumnya untuk item tersebut
+	SerialNumbers map[uint][]string `json:"serial_numbers" binding:"required"`
+}
+
+type AdminUnitWarrantyView struct {
+	WarrantyID uint      `json:"warranty_id"`
+	StartDate  time.Time `json:"start_date"`
+	EndDate    time.Time `json:"end_date"`
+	IsActive   bool      `json:"is_active"`
+}
+
+type AdminProductUnitLookupView struct {
+	UnitID           uint                   `json:"unit_id"`
+	SerialNumber     string                 `json:"serial_number"`
+	Status           string                 `json:"status"`
+	ProductSKU       string                 `json:"product_sku"`
+	ProductTitle     string                 `json:"product_title"`
+	Notes            string                 `json:"notes"`
+	OrderID          *string                `json:"order_id"`
+	OrderItemID      *uint                  `json:"order_item_id"`
+	OrderStatus      string                 `json:"order_status,omitempty"`
+	CustomerID       string                 `json:"customer_id,omitempty"`
+	CustomerFullname string                 `json:"customer_fullname,omitempty"`
+	ShippedAt        *time.Time             `json:"shipped_at"`
+	Warranty         *AdminUnitWarrantyView `json:"warranty"`
+	CreatedAt        time.Time              `json:"created_at"`
+}
+
+type UpdateWarrantySerialNumberInput struct {
+	SerialNumber string `json:"serial_number" binding:"required,max=100"`
+}
+
+type UpdateWarrantyClaimInput struct {
+	Status          string `json:"status" binding:"required,oneof='In Review' Approved Rejected Resolved"`
+	ResolutionNotes string `json:"resolution_notes"`
+}
+
+type AdminWarrantyClaimView struct {
+	ClaimID          string     `json:"claim_id"`
+	WarrantyID       uint       `json:"warranty_id"`
+	OrderID          string     `json:"order_id"`
+	ProductSKU       string     `json:"product_sku"`
+	ProductTitle     string     `json:"product_title"`
+	SerialNumber     string     `json:"serial_number"`
+	WarrantyEndDate  time.Time  `json:"warranty_end_date"`
+	CustomerID       string     `json:"customer_id"`
+	CustomerFullname string     `json:"customer_fullname"`
+	CustomerEmail    string     `json:"customer_email"`
+	Description      string     `json:"description"`
+	Status           string     `json:"status"`
+	ResolutionNotes  string     `json:"resolution_notes"`
+	HandledBy        string     `json:"handled_by"`
+	ResolvedAt       *time.Time `json:"resolved_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	Photos           []string   `json:"photos" gorm:"-"`
+}
+
+type AdminServiceTicketQuery struct {
+	Status       string `form:"status"`
+	Priority     string `form:"priority"`
+	Category     string `form:"category"`
+	DepartmentID string `form:"department_id"`
+	AssignedTo   string `form:"assigned_to"` // EmployeeID, atau "unassigned" untuk tiket yang belum ditugaskan
+}
+
+type AssignServiceTicketInput struct {
+	DepartmentID string `json:"department_id"`
+	AssignedTo   string `json:"assigned_to"` // EmployeeID; kosong berarti hanya ditugaskan ke departemen
+}
+
+type UpdateServiceTicketStatusInput struct {
+	Status   string `json:"status" binding:"required,oneof=Open 'In Progress' 'Waiting Customer' Resolved Closed"`
+	Priority string `json:"priority" binding:"omitempty,oneof=Low Normal High Urgent"`
+}
+
+// Dikirim sebagai multipart form; lampiran diunggah lewat key "attachments"
+type AdminServiceTicketMessageInput struct {
+	Message string `form:"message" binding:"required"`
+}
+
+type AdminServiceTicketAttachmentView struct {
+	FileName string `json:"file_name"`
+	FilePath string `json:"file_path"`
+}
+
+type AdminServiceTicketMessageView struct {
+	ID          uint                               `json:"id"`
+	SenderType  string                             `json:"sender_type"`
+	SenderID    string                             `json:"sender_id"`
+	SenderName  string                             `json:"sender_name"`
+	Message     string                             `json:"message"`
+	Attachments []AdminServiceTicketAttachmentView `json:"attachments"`
+	CreatedAt   time.Time                          `json:"created_at"`
+}
+
+type AdminServiceTicketView struct {
+	TicketID         string     `json:"ticket_id"`
+	CustomerID       string     `json:"customer_id"`
+	CustomerFullname string     `json:"customer_fullname"`
+	CustomerEmail    string     `json:"customer_email"`
+	Subject          string     `json:"subject"`
+	Category         string     `json:"category"`
+	Priority         string     `json:"priority"`
+	Status           string     `json:"status"`
+	OrderItemID      *uint      `json:"order_item_id"`
+	OrderID          *string    `json:"order_id"`
+	ProductSKU       string     `json:"product_sku"`
+	SerialNumber     string     `json:"serial_number"`
+	DepartmentID     *string    `json:"department_id"`
+	DepartmentName   string     `json:"department_name"`
+	AssignedTo       *string    `json:"assigned_to"`
+	AssignedToName   string     `json:"assigned_to_name"`
+	LastMessageAt    time.Time  `json:"last_message_at"`
+	ResolvedAt       *time.Time `json:"resolved_at"`
+	ClosedAt         *time.Time `json:"closed_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	Messages []AdminServiceTicketMessageView `json:"messages,omitempty" gorm:"-"` // Hanya diisi pada detail tiket
+}
+
+type CreateInstallationJobInput struct {
+	SiteAddress        string   `json:"site_address"` // Kosong = alamat pengiriman order
+	RequestedStartDate string   `json:"requested_start_date" binding:"required,datetime=2006-01-02"`
+	RequestedEndDate   string   `json:"requested_end_date" binding:"required,datetime=2006-01-02"`
+	Notes              string   `json:"notes"`
+	ChecklistItems     []string `json:"checklist_items" binding:"dive,required,max=255"` // Kosong = checklist bawaan
+}
+
+type ScheduleInstallationJobInput struct {
+	ScheduledStart time.Time `json:"scheduled_start" binding:"required"`
+	ScheduledEnd   time.Time `json:"scheduled_end" binding:"required"`
+	TechnicianIDs  []string  `json:"technician_ids" binding:"required,min=1,dive,required"`
+}
+
+type SetInstallationChecklistInput struct {
+	Items []string `json:"items" binding:"required,min=1,dive,required,max=255"`
+}
+
+type UpdateInstallationChecklistItemInput struct {
+	IsDone *bool `json:"is_done" binding:"required"`
+}
+
+type UpdateInstallationJobStatusInput struct {
+	Status string `json:"status" binding:"required,oneof='In Progress' Canceled"`
+}
+
+type CompleteInstallationJobInput struct {
+	SignedOffBy  string `json:"signed_off_by" binding:"required,max=255"` // Nama perwakilan customer yang menerima hasil instalasi
+	SignOffNotes string `json:"sign_off_notes"`
+}
+
+type AdminInstallationJobQuery struct {
+	From         string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To           string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	TechnicianID string `form:"technician_id"`
+	Status       string `form:"status"`
+}
+
+type InstallationTechnicianView struct {
+	EmployeeID string `json:"employee_id"`
+	FullName   string `json:"full_name"`
+}
+
+type InstallationChecklistItemView struct {
+	ID          uint       `json:"id"`
+	Position    int        `json:"position"`
+	Description string     `json:"description"`
+	IsDone      bool       `json:"is_done"`
+	DoneBy      string     `json:"done_by"`
+	DoneAt      *time.Time `json:"done_at"`
+}
+
+type AdminInstallationJobView struct {
+	JobID              string                          `json:"job_id"`
+	OrderID            string                          `json:"order_id"`
+	OrderStatus        string                          `json:"order_status"`
+	CustomerID         string                          `json:"customer_id"`
+	CustomerFullname   string                          `json:"customer_fullname"`
+	CustomerPhone      string                          `json:"customer_phone"`
+	SiteAddress        string                          `json:"site_address"`
+	RequestedStartDate time.Time                       `json:"requested_start_date"`
+	RequestedEndDate   time.Time                       `json:"requested_end_date"`
+	ScheduledStart     *time.Time                      `json:"scheduled_start"`
+	ScheduledEnd       *time.Time                      `json:"scheduled_end"`
+	Status             string                          `json:"status"`
+	Notes              string                          `json:"notes"`
+	SignedOffBy        string                          `json:"signed_off_by"`
+	SignOffNotes       string                          `json:"sign_off_notes"`
+	CompletedBy        string                          `json:"completed_by"`
+	CompletedAt        *time.Time                      `json:"completed_at"`
+	Technicians        []InstallationTechnicianView    `json:"technicians"`
+	ChecklistItems     []InstallationChecklistItemView `json:"checklist_items"`
+	CreatedAt          time.Time                       `json:"created_at"`
+	UpdatedAt          time.Time                       `json:"updated_at"`
+}
+
+type AddRentalUnitsInput struct {
+	UnitCodes []string `json:"unit_codes" binding:"required,min=1,dive,required,max=100"`
+	Notes     string   `json:"notes"`
+}
+
+type UpdateRentalUnitInput struct {
+	Status string `json:"status" binding:"required,oneof=Available Maintenance Retired"`
+	Notes  string `json:"notes"`
+}
+
+type AdminRentalBookingQuery struct {
+	Status       string `form:"status"`
+	CustomerID   string `form:"customer_id"`
+	ProductSKU   string `form:"product_sku"`
+	RentalUnitID uint   `form:"rental_unit_id"`
+	From         string `form:"from" binding:"omitempty,datetime=2006-01-02"` // Booking yang periodenya beririsan dengan from..to
+	To           string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type UpdateRentalBookingStatusInput struct {
+	Status string `json:"status" binding:"required,oneof=Confirmed 'On Rent' Canceled"`
+}
+
+type RentalReturnInspectionInput struct {
+	ReturnCondition string  `json:"return_condition" binding:"required,oneof=Good 'Minor Damage' 'Major Damage'"`
+	DamageCharge    float64 `json:"damage_charge" binding:"min=0"` // Dipotong dari deposit
+	InspectionNotes string  `json:"inspection_notes"`
+	// Status unit setelah inspeksi; kosong = Available, atau Maintenance jika kerusakan berat
+	UnitStatus string `json:"unit_status" binding:"omitempty,oneof=Available Maintenance Retired"`
+}
+
+type AdminRentalBookingView struct {
+	BookingID               string     `json:"booking_id"`
+	CustomerID              string     `json:"customer_id"`
+	CustomerFullname        string     `json:"customer_fullname"`
+	CustomerEmail           string     `json:"customer_email"`
+	CustomerPhone           string     `json:"customer_phone"`
+	RentalUnitID            uint       `json:"rental_unit_id"`
+	UnitCode                string     `json:"unit_code"`
+	ProductSKU              string     `json:"product_sku"`
+	ProductTitleSnapshot    string     `json:"product_title_snapshot"`
+	StartDate               time.Time  `json:"start_date"`
+	EndDate                 time.Time  `json:"end_date"`
+	RentalDays              int        `json:"rental_days"`
+	RentalPrice             float64    `json:"rental_price"`
+	Deposit                 float64    `json:"deposit"`
+	Status                  string     `json:"status"`
+	ShippingAddressSnapshot string     `json:"shipping_address_snapshot"`
+	Notes                   string     `json:"notes"`
+	HandedOverAt            *time.Time `json:"handed_over_at"`
+	ReturnedAt              *time.Time `json:"returned_at"`
+	ReturnCondition         string     `json:"return_condition"`
+	InspectionNotes         string     `json:"inspection_notes"`
+	DamageCharge            float64    `json:"damage_charge"`
+	DepositRefund           float64    `json:"deposit_refund"`
+	InspectedBy             string     `json:"inspected_by"`
+	CanceledAt              *time.Time `json:"canceled_at"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+}
+
+type UpdateRefundInput struct {
+	Status           string `json:"status" binding:"required,oneof=Processing Paid"`
+	PaymentReference string `json:"payment_reference" binding:"max=100"` // Wajib saat status Paid
+	AdminNotes       string `json:"admin_notes"`
+}
+
+type AdminRefundView struct {
+	RefundID          string     `json:"refund_id"`
+	OrderID           string     `json:"order_id"`
+	RMAID             *string    `json:"rma_id" gorm:"column:rma_id"` // Terisi jika refund berasal dari retur
+	CustomerID        string     `json:"customer_id"`
+	CustomerFullname  string     `json:"customer_fullname"`
+	CustomerEmail     string     `json:"customer_email"`
+	CustomerPhone     string     `json:"customer_phone"`
+	Amount            float64    `json:"amount"`
+	Reason            string     `json:"reason"`
+	Status            string     `json:"status"`
+	BankName          string     `json:"bank_name"`
+	BankAccountNumber string     `json:"bank_account_number"`
+	BankAccountHolder string     `json:"bank_account_holder"`
+	PaymentReference  string     `json:"payment_reference"`
+	AdminNotes        string     `json:"admin_notes"`
+	ProcessedBy       string     `json:"processed_by"`
+	PaidAt            *time.Time `json:"paid_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type AdminReturnRequestQuery struct {
+	Status     string `form:"status"`
+	CustomerID string `form:"customer_id"`
+	OrderID    string `form:"order_id"`
+}
+
+type ReviewReturnRequestInput struct {
+	Approved   *bool  `json:"approved" binding:"required"`
+	AdminNotes string `json:"admin_notes"` // Instruksi pengiriman barang, atau alasan penolakan (wajib jika ditolak)
+}
+
+type InspectReturnRequestInput struct {
+	IsResellable    *bool    `json:"is_resellable" binding:"required"` // true = barang dikembalikan ke stok
+	InspectionNotes string   `json:"inspection_notes"`
+	SerialNumbers   []string `json:"serial_numbers"` // Wajib untuk produk berseri yang dikembalikan ke stok
+}
+
+type ResolveReturnRequestInput struct {
+	Resolution      string   `json:"resolution" binding:"required,oneof=Refund Replacement Repair"`
+	RefundAmount    *float64 `json:"refund_amount" binding:"omitempty,gt=0"` // Default: quantity x harga saat order
+	ResolutionNotes string   `json:"resolution_notes"`
+	SerialNumbers   []string `json:"serial_numbers"` // Unit pengganti untuk produk berseri
+}
+
+type AdminReturnRequestView struct {
+	RMAID                string         `json:"rma_id"`
+	OrderID              string         `json:"order_id"`
+	OrderItemID          uint           `json:"order_item_id"`
+	CustomerID           string         `json:"customer_id"`
+	CustomerFullname     string         `json:"customer_fullname"`
+	CustomerEmail        string         `json:"customer_email"`
+	CustomerPhone        string         `json:"customer_phone"`
+	ProductSKU           string         `json:"product_sku"`
+	ProductTitleSnapshot string         `json:"product_title_snapshot"`
+	Quantity             int            `json:"quantity"`
+	PriceAtOrder         float64        `json:"price_at_order"`
+	Reason               string         `json:"reason"`
+	ReturnMethod         string         `json:"return_method"`
+	Status               string         `json:"status"`
+	BankName             string         `json:"bank_name"`
+	BankAccountNumber    string         `json:"bank_account_number"`
+	BankAccountHolder    string         `json:"bank_account_holder"`
+	AdminNotes           string         `json:"admin_notes"`
+	ReviewedBy           string         `json:"reviewed_by"`
+	ReceivedAt           *time.Time     `json:"received_at"`
+	ReceivedBy           string         `json:"received_by"`
+	IsResellable         bool           `json:"is_resellable"`
+	RestockedQuantity    int            `json:"restocked_quantity"`
+	InspectionNotes      string         `json:"inspection_notes"`
+	InspectedBy          string         `json:"inspected_by"`
+	InspectedAt          *time.Time     `json:"inspected_at"`
+	Resolution           string         `json:"resolution"`
+	ResolutionNotes      string         `json:"resolution_notes"`
+	ResolvedBy           string         `json:"resolved_by"`
+	ResolvedAt           *time.Time     `json:"resolved_at"`
+	Refund               *models.Refund `json:"refund"`
+	Photos               []string       `json:"photos"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
 }
 
 type AdminCustomerListView struct {
@@ -166,8 +741,9 @@ type AdminCustomerDetailView struct {
 	TotalOrders int     `json:"total_orders"`
 
 	// Info Terkait
-	Addresses    []models.CustomerAddress    `json:"addresses"`
-	OrderHistory []AdminCustomerOrderHistory `json:"order_history"`
+	Addresses     []models.CustomerAddress    `json:"addresses"`
+	OrderHistory  []AdminCustomerOrderHistory `json:"order_history"`
+	RentalHistory []AdminRentalBookingView    `json:"rental_history"`
 }
 
 type UpsertNewsCategoryInput struct {
@@ -216,6 +792,7 @@ type DashboardStats struct {
 	// --- DATA BARU UNTUK SALES OVERVIEW ---
 	TotalIncome      float64 `json:"total_income"`
 	TotalProfit      float64 `json:"total_profit"`
+	TotalRefunded    float64 `json:"total_refunded"` // Refund retur barang yang sudah dikurangkan dari income & profit
 	RevenueChartData struct {
 		Labels []string  `json:"labels"`
 		Series []float64 `json:"series"`
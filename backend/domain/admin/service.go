@@ -5,22 +5,33 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
 	"backend-user/domain/models"
+	"backend-user/domain/notification"
+	"backend-user/domain/shipping"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
 	employeeDateFormat = "2006-01-02"
+
+	profileImageUploadDir  = "./uploads/images/profile/"
+	newsImageUploadDir     = "./uploads/images/news/"
+	serviceTicketUploadDir = "./uploads/tickets/"
+	deliveryPhotoUploadDir = "./uploads/images/deliveries/"
 )
 
 var adminJwtExpirationTime = 1000 * time.Hour
@@ -39,7 +50,7 @@ type Service interface {
 	ListEmployees() ([]models.Employee, error)
 	GetEmployeeByID(employeeID string) (models.Employee, error)
 	DeleteEmployee(employeeID string) error
-	UpdateEmployee(employeeID string, input AddEmployeeInput, newImagePath *string) (models.Employee, error)
+	UpdateEmployee(employeeID string, input AddEmployeeInput, imageFileHeader *multipart.FileHeader) (models.Employee, error)
 	AddDepartment(input AddDepartmentInput) (models.Department, error)
 	ListActiveDepartmentsForDropdown() ([]models.Department, error)
 	ListDepartmentsWithEmployeeCount() ([]models.Department, error)
@@ -52,16 +63,75 @@ type Service interface {
 	UpdateProductCategory(categoryID string, input UpdateProductCategoryInput) (models.ProductCategory, error)
 	DeleteProductCategory(categoryID string) error
 	ListActiveProductCategories() ([]models.ProductCategory, error)
+	UpdateProductCategoryAttributes(categoryID string, input UpdateProductCategoryAttributesInput) ([]models.ProductCategoryAttribute, error)
 	AddProduct(input AddProductInput, imagePaths []string) (models.Product, error)
 	ListProducts() ([]models.Product, error)
 	GetProductBySKU(productSKU string) (models.Product, error)
 	UpdateProduct(productSKU string, input AddProductInput, newImagePaths []string) (models.Product, error)
 	DeleteProduct(productSKU string) error
+	AddProductImages(productSKU string, imagePaths []string, altTexts []string) ([]models.ProductImage, error)
+	UpdateProductImage(productSKU string, imageID uint, input UpdateProductImageInput) (models.ProductImage, error)
+	DeleteProductImage(productSKU string, imageID uint) error
+	ReorderProductImages(productSKU string, input ReorderProductImagesInput) ([]models.ProductImage, error)
+	SetPrimaryProductImage(productSKU string, imageID uint) ([]models.ProductImage, error)
+	ListCompatibleParts(machineSKU string) ([]models.ProductCompatibility, error)
+	SetCompatibleParts(machineSKU string, input SetCompatiblePartsInput) ([]models.ProductCompatibility, error)
+	RegisterProductUnits(productSKU string, input RegisterProductUnitsInput) ([]models.ProductUnit, error)
+	ListProductUnits(productSKU, statusFilter string) ([]models.ProductUnit, error)
+	DeleteProductUnit(productSKU string, unitID uint) error
+	LookupProductUnit(serialNumber string) (AdminProductUnitLookupView, error)
+	ListQuotationRequests(statusFilter string) ([]AdminQuotationRequestView, error)
+	GetQuotationRequest(requestID string) (AdminQuotationRequestView, error)
+	UpsertQuotation(requestID, employeeID string, input UpsertQuotationInput) (AdminQuotationRequestView, error)
+
 	ListAllOrders(statusFilter string) ([]AdminOrderListView, error)
 	GetOrderDetailForAdmin(orderID string) (AdminOrderDetailView, error)
-	UpdateOrderStatus(orderID string, input AdminUpdateOrderStatusInput) (models.Order, error)
+	UpdateOrderStatus(orderID, employeeID string, input AdminUpdateOrderStatusInput) (models.Order, error)
 	DeleteOrder(orderID string) error
+	AssignOrderUnits(orderID string, input AssignOrderUnitsInput) ([]models.ProductUnit, error)
+	CreateInstallationJob(orderID string, input CreateInstallationJobInput) (AdminInstallationJobView, error)
+	ListInstallationJobs(query AdminInstallationJobQuery) ([]AdminInstallationJobView, error)
+	GetInstallationJob(jobID string) (AdminInstallationJobView, error)
+	ScheduleInstallationJob(jobID string, input ScheduleInstallationJobInput) (AdminInstallationJobView, error)
+	SetInstallationChecklist(jobID string, input SetInstallationChecklistInput) (AdminInstallationJobView, error)
+	UpdateInstallationChecklistItem(jobID string, itemID uint, employeeID string, input UpdateInstallationChecklistItemInput) (AdminInstallationJobView, error)
+	UpdateInstallationJobStatus(jobID string, input UpdateInstallationJobStatusInput) (AdminInstallationJobView, error)
+	CompleteInstallationJob(jobID, employeeID string, input CompleteInstallationJobInput) (AdminInstallationJobView, error)
+	SetPaymentSchedule(orderID string, input SetPaymentScheduleInput) ([]models.PaymentMilestone, error)
+	VerifyPaymentMilestone(orderID string, milestoneID uint, employeeID string, input VerifyPaymentMilestoneInput) (models.PaymentMilestone, error)
+	ListOverdueInstallments() ([]AdminOverdueInstallmentView, error)
 	ListOrderedCustomers() ([]AdminCustomerListView, error)
+	ListCompanies() ([]AdminCompanyListView, error)
+	GetCompanyDetail(companyID string) (AdminCompanyDetailView, error)
+	UpdateWarrantySerialNumber(warrantyID uint, input UpdateWarrantySerialNumberInput) (models.Warranty, error)
+	ListWarrantyClaims(statusFilter string) ([]AdminWarrantyClaimView, error)
+	GetWarrantyClaim(claimID string) (AdminWarrantyClaimView, error)
+	UpdateWarrantyClaim(claimID, employeeID string, input UpdateWarrantyClaimInput) (AdminWarrantyClaimView, error)
+	ListServiceTickets(query AdminServiceTicketQuery) ([]AdminServiceTicketView, error)
+	GetServiceTicket(ticketID string) (AdminServiceTicketView, error)
+	AssignServiceTicket(ticketID string, input AssignServiceTicketInput) (AdminServiceTicketView, error)
+	UpdateServiceTicketStatus(ticketID string, input UpdateServiceTicketStatusInput) (AdminServiceTicketView, error)
+	AddServiceTicketMessage(ticketID, employeeID string, input AdminServiceTicketMessageInput, attachments []*multipart.FileHeader) (AdminServiceTicketView, error)
+	AddRentalUnits(productSKU string, input AddRentalUnitsInput) ([]models.RentalUnit, error)
+	ListRentalUnits(productSKU string) ([]models.RentalUnit, error)
+	UpdateRentalUnit(unitID uint, input UpdateRentalUnitInput) (models.RentalUnit, error)
+	ListRentalBookings(query AdminRentalBookingQuery) ([]AdminRentalBookingView, error)
+	GetRentalBooking(bookingID string) (AdminRentalBookingView, error)
+	UpdateRentalBookingStatus(bookingID string, input UpdateRentalBookingStatusInput) (AdminRentalBookingView, error)
+	InspectRentalReturn(bookingID, employeeID string, input RentalReturnInspectionInput) (AdminRentalBookingView, error)
+	ListRefunds(statusFilter string) ([]AdminRefundView, error)
+	GetRefund(refundID string) (AdminRefundView, error)
+	UpdateRefund(refundID, employeeID string, input UpdateRefundInput) (AdminRefundView, error)
+	ListReturnRequests(query AdminReturnRequestQuery) ([]AdminReturnRequestView, error)
+	GetReturnRequest(rmaID string) (AdminReturnRequestView, error)
+	ReviewReturnRequest(rmaID, employeeID string, input ReviewReturnRequestInput) (AdminReturnRequestView, error)
+	ReceiveReturnRequest(rmaID, employeeID string) (AdminReturnRequestView, error)
+	InspectReturnRequest(rmaID, employeeID string, input InspectReturnRequestInput) (AdminReturnRequestView, error)
+	ResolveReturnRequest(rmaID, employeeID string, input ResolveReturnRequestInput) (AdminReturnRequestView, error)
+	ListStockMovements(productSKU string) ([]models.StockMovement, error)
+	CreateShipment(orderID, employeeID string, input CreateShipmentInput) (models.Shipment, error)
+	ConfirmShipmentDelivery(shipmentID, employeeID string, input ConfirmShipmentDeliveryInput, photoFileHeader *multipart.FileHeader) (models.Shipment, error)
+	GetShipmentTracking(shipmentID string) (shipping.TrackingResult, error)
 	GetCustomerDetailForAdmin(customerID string) (AdminCustomerDetailView, error)
 	DeleteCustomer(customerID string) error
 
@@ -83,12 +153,14 @@ type Service interface {
 type service struct {
 	db           *gorm.DB
 	jwtSecretKey []byte
+	tracker      shipping.Provider
 }
 
-func NewService(db *gorm.DB, adminJwtSecret []byte) Service {
+func NewService(db *gorm.DB, adminJwtSecret []byte, tracker shipping.Provider) Service {
 	return &service{
 		db:           db,
 		jwtSecretKey: adminJwtSecret,
+		tracker:      tracker,
 	}
 }
 
@@ -107,6 +179,46 @@ func getDepartmentName(db *gorm.DB, departmentID string) (string, error) {
 	return dept.DepartmentName, nil
 }
 
+// saveUploadedFile menyimpan file upload ke uploadDir dan mengembalikan path relatif untuk disimpan di DB.
+func saveUploadedFile(fileHeader *multipart.FileHeader, uploadDir, filename string) (string, error) {
+	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("gagal membuat direktori upload: %w", err)
+	}
+	savePathOnDisk := filepath.Join(uploadDir, filename)
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("gagal membuka file upload: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(savePathOnDisk)
+	if err != nil {
+		return "", fmt.Errorf("gagal membuat file tujuan: %w", err)
+	}
+	_, errCopy := io.Copy(dst, src)
+	errClose := dst.Close()
+	if errCopy != nil || errClose != nil {
+		os.Remove(savePathOnDisk)
+		if errCopy == nil {
+			errCopy = errClose
+		}
+		return "", fmt.Errorf("gagal menyimpan file upload: %w", errCopy)
+	}
+	return strings.TrimPrefix(filepath.ToSlash(savePathOnDisk), "./"), nil
+}
+
+// removeUploadedFile menghapus file berdasarkan path relatif yang tersimpan di DB. Kegagalan hanya di-log.
+func removeUploadedFile(relativePath string) {
+	if relativePath == "" {
+		return
+	}
+	fullPath := filepath.Join(".", relativePath)
+	if err := os.Remove(fullPath); err != nil {
+		log.Printf("Peringatan: Gagal menghapus file %s: %v\n", fullPath, err)
+	}
+}
+
 func (s *service) AddEmployee(input AddEmployeeInput, imageFileHeader *multipart.FileHeader) (models.Employee, error) {
 	log.Printf("[Service AddEmployee] Menambah employee baru dengan email: %s\n", input.Email)
 
@@ -399,7 +511,7 @@ func (s *service) DeleteEmployee(employeeID string) error {
 	return nil
 }
 
-func (s *service) UpdateEmployee(employeeID string, input AddEmployeeInput, newImagePath *string) (models.Employee, error) {
+func (s *service) UpdateEmployee(employeeID string, input AddEmployeeInput, imageFileHeader *multipart.FileHeader) (models.Employee, error) {
 	var birthday, joinDate time.Time
 	var err error
 	if input.Birthday != "" {
@@ -415,6 +527,17 @@ func (s *service) UpdateEmployee(employeeID string, input AddEmployeeInput, newI
 		}
 	}
 
+	// Simpan file gambar baru terlebih dahulu (jika ada), path-nya baru dipakai di dalam transaksi
+	var newImagePath string
+	if imageFileHeader != nil {
+		uniqueFilename := "emp_" + uuid.New().String() + filepath.Ext(imageFileHeader.Filename)
+		newImagePath, err = saveUploadedFile(imageFileHeader, profileImageUploadDir, uniqueFilename)
+		if err != nil {
+			return models.Employee{}, err
+		}
+	}
+
+	var oldImagePath string
 	var finalUpdatedEmployee models.Employee
 	err = s.db.Transaction(func(tx *gorm.DB) error {
 		var empToUpdate models.Employee
@@ -424,7 +547,7 @@ func (s *service) UpdateEmployee(employeeID string, input AddEmployeeInput, newI
 			}
 			return fmt.Errorf("gagal mengambil employee: %w", err)
 		}
-		oldImagePath := empToUpdate.Image
+		oldImagePath = empToUpdate.Image
 		empToUpdate.FullName = input.FullName
 		empToUpdate.Email = input.Email
 		empToUpdate.Phone = input.Phone
@@ -437,22 +560,11 @@ func (s *service) UpdateEmployee(employeeID string, input AddEmployeeInput, newI
 		empToUpdate.Department = input.DepartmentID // Simpan DepartmentID
 		empToUpdate.Role = input.Role
 		empToUpdate.Status = input.Status
-
-		if newImagePath != nil {
-			if oldImagePath != "" && (newImagePath == nil || *newImagePath != oldImagePath) {
-				fullOldPath := filepath.Join(".", oldImagePath)
-				if errRem := os.Remove(fullOldPath); errRem != nil {
-					log.Printf("Peringatan: Gagal menghapus file lama %s: %v", fullOldPath, errRem)
-				}
-			}
-			if *newImagePath == "" {
-				empToUpdate.Image = ""
-			} else {
-				empToUpdate.Image = *newImagePath
-			}
+		if newImagePath != "" {
+			empToUpdate.Image = newImagePath
 		}
 
-		if err := tx.Save(&empToUpdate).Error; err != nil {
+		if err := tx.Omit("Address", "Account").Save(&empToUpdate).Error; err != nil {
 			return fmt.Errorf("gagal update employee: %w", err)
 		}
 
@@ -475,9 +587,16 @@ func (s *service) UpdateEmployee(employeeID string, input AddEmployeeInput, newI
 		return nil
 	})
 	if err != nil {
+		// Transaksi gagal, file baru tidak jadi dipakai
+		removeUploadedFile(newImagePath)
 		return models.Employee{}, err
 	}
 
+	// File lama baru dihapus setelah commit berhasil
+	if newImagePath != "" && oldImagePath != newImagePath {
+		removeUploadedFile(oldImagePath)
+	}
+
 	deptName, _ := getDepartmentName(s.db, finalUpdatedEmployee.Department)
 	finalUpdatedEmployee.DepartmentName = deptName
 
@@ -732,7 +851,7 @@ func (s *service) ListProductCategories() ([]models.ProductCategory, error) {
 
 func (s *service) GetProductCategoryByID(categoryID string) (models.ProductCategory, error) {
 	var category models.ProductCategory
-	if err := s.db.Where("category_id = ?", categoryID).First(&category).Error; err != nil {
+	if err := s.db.Preload("Attributes", orderedCategoryAttributes).Where("category_id = ?", categoryID).First(&category).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("[Service GetProductCategoryByID] Kategori produk dengan ID %s tidak ditemukan.\n", categoryID)
 			return models.ProductCategory{}, errors.New("kategori produk tidak ditemukan")
@@ -813,6 +932,15 @@ func (s *service) DeleteProductCategory(categoryID string) error {
 			return errors.New("kategori produk tidak ditemukan") // atau gorm.ErrRecordNotFound jika Anda cek dulu
 		}
 
+		// Skema atribut ikut dihapus beserta nilai spesifikasi yang memakainya
+		if err := tx.Where("attribute_id IN (?)", tx.Model(&models.ProductCategoryAttribute{}).Select("id").Where("category_id = ?", categoryID)).
+			Delete(&models.ProductSpecification{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus spesifikasi produk terkait: %w", err)
+		}
+		if err := tx.Where("category_id = ?", categoryID).Delete(&models.ProductCategoryAttribute{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus atribut kategori produk: %w", err)
+		}
+
 		return nil // Commit transaksi
 	})
 
@@ -825,9 +953,28 @@ func (s *service) DeleteProductCategory(categoryID string) error {
 	return nil
 }
 
+// validateRentalRates memastikan produk sewa memiliki tarif harian dan tidak ada tarif negatif.
+func validateRentalRates(input AddProductInput) error {
+	if input.RentalDailyRate < 0 || input.RentalWeeklyRate < 0 || input.RentalMonthlyRate < 0 || input.RentalDeposit < 0 {
+		return errors.New("tarif sewa tidak valid: tarif dan deposit tidak boleh negatif")
+	}
+	if input.IsRentable && input.RentalDailyRate <= 0 {
+		return errors.New("tarif sewa tidak valid: produk sewa wajib memiliki tarif harian")
+	}
+	return nil
+}
+
 func (s *service) AddProduct(input AddProductInput, imagePaths []string) (models.Product, error) {
 	log.Printf("[Service AddProduct] Input diterima: %+v, Jumlah Gambar: %d\n", input, len(imagePaths))
 
+	warrantyMonths, ok := models.ParseWarrantyPeriod(input.WarrantyPeriod)
+	if !ok {
+		return models.Product{}, errors.New("periode garansi tidak valid: gunakan format seperti '12 Months' atau '2 Years'")
+	}
+	if err := validateRentalRates(input); err != nil {
+		return models.Product{}, err
+	}
+
 	var parsedProductionDate *time.Time
 	if input.ProductionDate != "" {
 		t, err := time.Parse(employeeDateFormat, input.ProductionDate)
@@ -846,6 +993,16 @@ func (s *service) AddProduct(input AddProductInput, imagePaths []string) (models
 		return models.Product{}, fmt.Errorf("gagal memvalidasi kategori produk: %w", err)
 	}
 
+	// Validasi spesifikasi terhadap skema atribut kategori sebelum SKU dibuat
+	specifications, err := buildProductSpecifications(s.db, input.ProductCategoryID, input.Specifications)
+	if err != nil {
+		return models.Product{}, err
+	}
+	parentSKU, err := validateProductParent(s.db, "", input)
+	if err != nil {
+		return models.Product{}, err
+	}
+
 	// 2. Generate Product SKU unik
 	var nextVal int
 	if err := s.db.Raw("SELECT nextval('product_sku_seq')").Scan(&nextVal).Error; err != nil {
@@ -857,24 +1014,34 @@ func (s *service) AddProduct(input AddProductInput, imagePaths []string) (models
 
 	// 3. Buat instance Product dengan CapitalPrice
 	product := models.Product{
-		ProductSKU:        newProductSKU,
-		Title:             input.Title,
-		Brand:             input.Brand,
-		ProductCategoryID: input.ProductCategoryID,
-		PowerSource:       input.PowerSource,
-		WarrantyPeriod:    input.WarrantyPeriod,
-		ProductionDate:    parsedProductionDate,
-		Descriptions:      input.Descriptions,
-		Stock:             input.Stock,
-		Status:            input.Status,
-		CapitalPrice:      input.CapitalPrice, // <<< TAMBAHKAN CapitalPrice DARI INPUT
-		RegularPrice:      input.RegularPrice,
+		ProductSKU:           newProductSKU,
+		Title:                input.Title,
+		Brand:                input.Brand,
+		ProductCategoryID:    input.ProductCategoryID,
+		PowerSource:          input.PowerSource,
+		WarrantyPeriod:       input.WarrantyPeriod,
+		WarrantyMonths:       warrantyMonths,
+		ProductionDate:       parsedProductionDate,
+		Descriptions:         input.Descriptions,
+		Stock:                input.Stock,
+		IsSerialized:         input.IsSerialized,
+		RequiresInstallation: input.RequiresInstallation,
+		IsRentable:           input.IsRentable,
+		RentalDailyRate:      input.RentalDailyRate,
+		RentalWeeklyRate:     input.RentalWeeklyRate,
+		RentalMonthlyRate:    input.RentalMonthlyRate,
+		RentalDeposit:        input.RentalDeposit,
+		Status:               input.Status,
+		CapitalPrice:         input.CapitalPrice, // <<< TAMBAHKAN CapitalPrice DARI INPUT
+		RegularPrice:         input.RegularPrice,
+		ParentSKU:            parentSKU,
+		VariantName:          input.VariantName,
 		// Images akan di-create terpisah dan direlasikan
 	}
 
 	var finalCreatedProduct models.Product
 
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
 		// 4. Simpan data produk utama
 		if err := tx.Create(&product).Error; err != nil {
 			return fmt.Errorf("gagal menyimpan data produk: %w", err)
@@ -883,10 +1050,13 @@ func (s *service) AddProduct(input AddProductInput, imagePaths []string) (models
 		// 5. Simpan gambar produk (jika ada)
 		if len(imagePaths) > 0 {
 			var productImages []models.ProductImage
-			for _, imgPath := range imagePaths {
+			for i, imgPath := range imagePaths {
 				productImages = append(productImages, models.ProductImage{
 					ProductSKU: newProductSKU, // Relasikan dengan SKU produk yang baru dibuat
 					Image:      imgPath,       // Path gambar yang sudah disimpan oleh handler
+					AltText:    altTextAt(input.ImageAltTexts, i),
+					Position:   i,
+					IsPrimary:  i == 0, // Gambar pertama otomatis menjadi gambar utama
 				})
 			}
 			if err := tx.Create(&productImages).Error; err != nil {
@@ -894,8 +1064,12 @@ func (s *service) AddProduct(input AddProductInput, imagePaths []string) (models
 			}
 		}
 
+		if err := replaceProductSpecifications(tx, newProductSKU, specifications); err != nil {
+			return err
+		}
+
 		// 6. Ambil kembali produk yang baru dibuat dengan preloading Images dan ProductCategory
-		if err := tx.Preload("Images").Preload("ProductCategory").First(&finalCreatedProduct, "product_sku = ?", newProductSKU).Error; err != nil {
+		if err := tx.Scopes(preloadProductDetails).First(&finalCreatedProduct, "product_sku = ?", newProductSKU).Error; err != nil {
 			return fmt.Errorf("gagal mengambil data produk lengkap setelah create: %w", err)
 		}
 		return nil // Commit transaksi
@@ -923,7 +1097,7 @@ func (s *service) ListActiveProductCategories() ([]models.ProductCategory, error
 
 func (s *service) ListProducts() ([]models.Product, error) {
 	var products []models.Product
-	if err := s.db.Preload("Images").Preload("ProductCategory").Order("created_at desc").Find(&products).Error; err != nil {
+	if err := s.db.Scopes(preloadProductDetails).Order("created_at desc").Find(&products).Error; err != nil {
 		log.Printf("[Service ListProducts] Error: %v\n", err)
 		return nil, fmt.Errorf("gagal mengambil daftar produk: %w", err)
 	}
@@ -932,7 +1106,7 @@ func (s *service) ListProducts() ([]models.Product, error) {
 
 func (s *service) GetProductBySKU(productSKU string) (models.Product, error) {
 	var product models.Product
-	if err := s.db.Preload("Images").Preload("ProductCategory").Where("product_sku = ?", productSKU).First(&product).Error; err != nil {
+	if err := s.db.Scopes(preloadProductDetails).Where("product_sku = ?", productSKU).First(&product).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return models.Product{}, errors.New("produk tidak ditemukan")
 		}
@@ -944,6 +1118,14 @@ func (s *service) GetProductBySKU(productSKU string) (models.Product, error) {
 func (s *service) UpdateProduct(productSKU string, input AddProductInput, newImagePaths []string) (models.Product, error) {
 	log.Printf("[Service UpdateProduct] Input diterima untuk SKU %s: %+v, Jumlah Gambar Baru: %d\n", productSKU, input, len(newImagePaths))
 
+	warrantyMonths, ok := models.ParseWarrantyPeriod(input.WarrantyPeriod)
+	if !ok {
+		return models.Product{}, errors.New("periode garansi tidak valid: gunakan format seperti '12 Months' atau '2 Years'")
+	}
+	if err := validateRentalRates(input); err != nil {
+		return models.Product{}, err
+	}
+
 	var parsedProductionDate *time.Time
 	if input.ProductionDate != "" {
 		t, err := time.Parse(employeeDateFormat, input.ProductionDate)
@@ -964,8 +1146,27 @@ func (s *service) UpdateProduct(productSKU string, input AddProductInput, newIma
 		}
 	}
 
+	// Key "specifications" yang tidak dikirim (nil) berarti spesifikasi lama dipertahankan,
+	// kecuali kategori berubah karena spesifikasi lama mengikuti skema kategori sebelumnya
+	var currentCategoryID string
+	if err := s.db.Model(&models.Product{}).Where("product_sku = ?", productSKU).Pluck("product_category", &currentCategoryID).Error; err != nil {
+		return models.Product{}, fmt.Errorf("gagal mengambil kategori produk: %w", err)
+	}
+	replaceSpecifications := input.Specifications != nil || currentCategoryID != input.ProductCategoryID
+	var specifications []models.ProductSpecification
+	if replaceSpecifications {
+		var err error
+		if specifications, err = buildProductSpecifications(s.db, input.ProductCategoryID, input.Specifications); err != nil {
+			return models.Product{}, err
+		}
+	}
+	parentSKU, err := validateProductParent(s.db, productSKU, input)
+	if err != nil {
+		return models.Product{}, err
+	}
+
 	var finalUpdatedProduct models.Product
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
 		var productToUpdate models.Product
 		// Ambil produk yang ada, termasuk preload Images untuk mengetahui gambar lama
 		if err := tx.Preload("Images").Where("product_sku = ?", productSKU).First(&productToUpdate).Error; err != nil {
@@ -975,13 +1176,8 @@ func (s *service) UpdateProduct(productSKU string, input AddProductInput, newIma
 			return fmt.Errorf("gagal mengambil produk untuk diupdate: %w", err)
 		}
 
-		// Simpan path gambar lama untuk potensi penghapusan file fisik
-		var oldImageFilePathsOnDisk []string
-		for _, oldImg := range productToUpdate.Images {
-			if oldImg.Image != "" { // Pastikan path tidak kosong
-				oldImageFilePathsOnDisk = append(oldImageFilePathsOnDisk, filepath.Join(".", oldImg.Image))
-			}
-		}
+		// Simpan nilai lama untuk mendeteksi penurunan harga / stok kembali tersedia
+		productBefore := productToUpdate
 
 		// Update fields pada productToUpdate
 		productToUpdate.Title = input.Title
@@ -989,45 +1185,43 @@ func (s *service) UpdateProduct(productSKU string, input AddProductInput, newIma
 		productToUpdate.ProductCategoryID = input.ProductCategoryID
 		productToUpdate.PowerSource = input.PowerSource
 		productToUpdate.WarrantyPeriod = input.WarrantyPeriod
+		productToUpdate.WarrantyMonths = warrantyMonths
 		productToUpdate.ProductionDate = parsedProductionDate
 		productToUpdate.Descriptions = input.Descriptions
 		productToUpdate.Stock = input.Stock
+		productToUpdate.IsSerialized = input.IsSerialized
+		productToUpdate.RequiresInstallation = input.RequiresInstallation
+		productToUpdate.IsRentable = input.IsRentable
+		productToUpdate.RentalDailyRate = input.RentalDailyRate
+		productToUpdate.RentalWeeklyRate = input.RentalWeeklyRate
+		productToUpdate.RentalMonthlyRate = input.RentalMonthlyRate
+		productToUpdate.RentalDeposit = input.RentalDeposit
 		productToUpdate.Status = input.Status
 		productToUpdate.CapitalPrice = input.CapitalPrice
 		productToUpdate.RegularPrice = input.RegularPrice
+		productToUpdate.ParentSKU = parentSKU
+		productToUpdate.VariantName = input.VariantName
 
+		// Gambar baru ditambahkan di belakang gambar yang sudah ada. Penghapusan dan pengurutan
+		// gambar dilakukan lewat endpoint gambar produk masing-masing.
 		if len(newImagePaths) > 0 {
-			// 1. Hapus file gambar lama dari server
-			for _, fullOldPath := range oldImageFilePathsOnDisk {
-				if err := os.Remove(fullOldPath); err != nil {
-					// Jangan gagalkan transaksi utama, cukup log error
-					log.Printf("[Service UpdateProduct] Peringatan: Gagal menghapus file gambar lama %s: %v\n", fullOldPath, err)
-				} else {
-					log.Printf("[Service UpdateProduct] Berhasil menghapus file gambar lama: %s\n", fullOldPath)
-				}
-			}
-			// 2. Hapus record gambar lama dari DB untuk produk ini
-			if err := tx.Where("product_sku = ?", productSKU).Delete(&models.ProductImage{}).Error; err != nil {
-				// Jika tidak ada gambar lama, ini tidak error, jadi tidak perlu cek gorm.ErrRecordNotFound
-				log.Printf("[Service UpdateProduct] Info/Error saat menghapus record gambar lama dari DB: %v\n", err)
-				// return fmt.Errorf("gagal menghapus gambar lama dari db: %w", err) // Mungkin tidak perlu menggagalkan jika hanya tidak ada record
-			}
-			// 3. Tambahkan record gambar baru
-			var newProductImagesToCreate []models.ProductImage
-			for _, imgPath := range newImagePaths {
-				newProductImagesToCreate = append(newProductImagesToCreate, models.ProductImage{ProductSKU: productSKU, Image: imgPath})
-			}
-			if len(newProductImagesToCreate) > 0 {
-				if err := tx.Create(&newProductImagesToCreate).Error; err != nil {
-					return fmt.Errorf("gagal menyimpan gambar produk baru: %w", err)
-				}
+			if _, err := appendProductImages(tx, productSKU, newImagePaths, input.ImageAltTexts); err != nil {
+				return err
 			}
 		}
-		if err := tx.Omit("ProductCategory", "Images").Save(&productToUpdate).Error; err != nil {
+		if err := tx.Omit("ProductCategory", "Images", "Specifications", "Parent", "Variants").Save(&productToUpdate).Error; err != nil {
 			return fmt.Errorf("gagal menyimpan update produk: %w", err)
 		}
+		if replaceSpecifications {
+			if err := replaceProductSpecifications(tx, productSKU, specifications); err != nil {
+				return err
+			}
+		}
+		if _, err := notification.QueueProductChange(tx, productBefore, productToUpdate); err != nil {
+			return err
+		}
 
-		if err := tx.Preload("Images").Preload("ProductCategory").First(&finalUpdatedProduct, "product_sku = ?", productSKU).Error; err != nil {
+		if err := tx.Scopes(preloadProductDetails).First(&finalUpdatedProduct, "product_sku = ?", productSKU).Error; err != nil {
 			return fmt.Errorf("gagal mengambil data produk lengkap setelah update: %w", err)
 		}
 		return nil // Commit transaksi
@@ -1050,6 +1244,14 @@ func (s *service) DeleteProduct(productSKU string) error {
 			return fmt.Errorf("gagal mengambil produk untuk dihapus: %w", err)
 		}
 
+		var variantCount int64
+		if err := tx.Model(&models.Product{}).Where("parent_sku = ?", productSKU).Count(&variantCount).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa varian produk: %w", err)
+		}
+		if variantCount > 0 {
+			return errors.New("produk masih memiliki varian, hapus atau pindahkan varian terlebih dahulu")
+		}
+
 		// Hapus file gambar dari server
 		for _, img := range product.Images {
 			fullPath := filepath.Join(".", img.Image)
@@ -1065,6 +1267,46 @@ func (s *service) DeleteProduct(productSKU string) error {
 			}
 		}
 
+		if err := tx.Where("product_sku = ?", productSKU).Delete(&models.ProductSpecification{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus spesifikasi produk: %w", err)
+		}
+		if err := tx.Where("part_sku = ? OR machine_sku = ?", productSKU, productSKU).Delete(&models.ProductCompatibility{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus relasi kompatibilitas produk: %w", err)
+		}
+
+		// Unit yang sudah terikat pesanan adalah riwayat pengiriman, sehingga produknya tidak boleh dihapus
+		var boundUnits int64
+		if err := tx.Model(&models.ProductUnit{}).Where("product_sku = ? AND status <> ?", productSKU, models.ProductUnitInStock).Count(&boundUnits).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa unit produk: %w", err)
+		}
+		if boundUnits > 0 {
+			return errors.New("produk masih memiliki unit berseri yang terikat pesanan")
+		}
+		if err := tx.Where("product_sku = ?", productSKU).Delete(&models.ProductUnit{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus unit produk: %w", err)
+		}
+		// Booking sewa adalah riwayat transaksi customer, sama seperti unit yang terikat pesanan
+		var rentalBookings int64
+		if err := tx.Model(&models.RentalBooking{}).Where("product_sku = ?", productSKU).Count(&rentalBookings).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa booking sewa produk: %w", err)
+		}
+		if rentalBookings > 0 {
+			return errors.New("produk masih memiliki riwayat booking sewa")
+		}
+		if err := tx.Where("product_sku = ?", productSKU).Delete(&models.RentalUnit{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus unit sewa produk: %w", err)
+		}
+		if err := tx.Where("product_sku = ?", productSKU).Delete(&models.StockMovement{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus mutasi stok produk: %w", err)
+		}
+		// Feed in-app customer tetap disimpan sebagai riwayat
+		if err := tx.Where("product_sku = ?", productSKU).Delete(&models.WishlistItem{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus wishlist produk: %w", err)
+		}
+		if err := tx.Where("product_sku = ?", productSKU).Delete(&models.ProductNotification{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus antrean notifikasi produk: %w", err)
+		}
+
 		// Hapus produk utama
 		if err := tx.Where("product_sku = ?", productSKU).Delete(&models.Product{}).Error; err != nil {
 			return fmt.Errorf("gagal menghapus produk: %w", err)
@@ -1073,174 +1315,3499 @@ func (s *service) DeleteProduct(productSKU string) error {
 	})
 }
 
-func (s *service) ListAllOrders(statusFilter string) ([]AdminOrderListView, error) {
-	var ordersFromDB []models.Order
-	log.Printf("[Service ListAllOrders] Mengambil data pesanan dengan filter status: '%s'\n", statusFilter)
-
-	// Mulai query, belum dieksekusi
-	query := s.db.Preload("OrderItems").Order("order_date_time DESC")
-
-	// Terapkan filter status JIKA parameter statusFilter tidak kosong
-	if statusFilter != "" {
-		query = query.Where("order_status = ?", statusFilter)
+func altTextAt(altTexts []string, i int) string {
+	if i < len(altTexts) {
+		return strings.TrimSpace(altTexts[i])
 	}
+	return ""
+}
 
-	// Jalankan query yang sudah difilter (atau tidak)
-	if err := query.Find(&ordersFromDB).Error; err != nil {
-		log.Printf("[Service ListAllOrders] Error saat mengambil pesanan dari DB: %v\n", err)
-		return nil, fmt.Errorf("gagal mengambil daftar pesanan: %w", err)
+// appendProductImages menambahkan gambar di belakang urutan yang ada. Jika produk belum punya
+// gambar utama, gambar baru pertama dijadikan gambar utama.
+func appendProductImages(tx *gorm.DB, productSKU string, imagePaths []string, altTexts []string) ([]models.ProductImage, error) {
+	var existing []models.ProductImage
+	if err := tx.Where("product_sku = ?", productSKU).Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil gambar produk: %w", err)
 	}
-
-	// Logika mapping ke DTO tetap sama persis
-	orderListView := make([]AdminOrderListView, 0, len(ordersFromDB))
-	for _, order := range ordersFromDB {
-		var firstImage string
-		if len(order.OrderItems) > 0 && order.OrderItems[0].ProductImageSnapshot != "" {
-			firstImage = order.OrderItems[0].ProductImageSnapshot
+	nextPosition := 0
+	hasPrimary := false
+	for _, img := range existing {
+		if img.Position >= nextPosition {
+			nextPosition = img.Position + 1
 		}
-
-		orderView := AdminOrderListView{
-			OrderID:          order.OrderID,
-			CustomerFullname: order.CustomerFullname,
-			OrderDateTime:    order.OrderDateTime,
-			PaymentMethod:    order.PaymentMethod,
-			OrderStatus:      order.OrderStatus,
-			GrandTotal:       order.GrandTotal,
-			FirstItemImage:   firstImage,
+		if img.IsPrimary {
+			hasPrimary = true
 		}
-		orderListView = append(orderListView, orderView)
 	}
 
-	log.Printf("[Service ListAllOrders] Berhasil mengambil %d pesanan.\n", len(orderListView))
-	return orderListView, nil
+	newImages := make([]models.ProductImage, 0, len(imagePaths))
+	for i, imgPath := range imagePaths {
+		newImages = append(newImages, models.ProductImage{
+			ProductSKU: productSKU,
+			Image:      imgPath,
+			AltText:    altTextAt(altTexts, i),
+			Position:   nextPosition + i,
+			IsPrimary:  !hasPrimary && i == 0,
+		})
+	}
+	if len(newImages) > 0 {
+		if err := tx.Create(&newImages).Error; err != nil {
+			return nil, fmt.Errorf("gagal menyimpan gambar produk baru: %w", err)
+		}
+	}
+	return newImages, nil
 }
 
-func (s *service) GetOrderDetailForAdmin(orderID string) (AdminOrderDetailView, error) {
-	var orderFromDB models.Order
-	var orderDetailView AdminOrderDetailView
-
-	// Preload semua relasi yang dibutuhkan untuk halaman detail
-	if err := s.db.
-		Preload("OrderItems").
-		Where("order_id = ?", orderID).
-		First(&orderFromDB).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return orderDetailView, errors.New("pesanan tidak ditemukan")
+// normalizeProductImages merapikan position menjadi 0..n-1 dan memastikan tepat satu gambar utama.
+func normalizeProductImages(tx *gorm.DB, productSKU string) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	if err := models.OrderedProductImages(tx.Where("product_sku = ?", productSKU)).Find(&images).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil gambar produk: %w", err)
+	}
+	primaryIndex := -1
+	for i := range images {
+		if images[i].IsPrimary && primaryIndex == -1 {
+			primaryIndex = i
 		}
-		return orderDetailView, fmt.Errorf("gagal mengambil detail pesanan: %w", err)
 	}
-
-	// Mapping ke DTO utama
-	orderDetailView = AdminOrderDetailView{
-		OrderID:                 orderFromDB.OrderID,
-		OrderDateTime:           orderFromDB.OrderDateTime,
-		OrderStatus:             orderFromDB.OrderStatus,
-		PaymentMethod:           orderFromDB.PaymentMethod,
-		ProofOfPayment:          orderFromDB.ProofOfPayment,
-		Notes:                   orderFromDB.Notes,
-		CustomerID:              orderFromDB.CustomerID,
-		CustomerFullname:        orderFromDB.CustomerFullname,
-		CustomerEmail:           orderFromDB.CustomerEmail,
-		CustomerPhone:           orderFromDB.CustomerPhone,
-		ShippingAddressSnapshot: orderFromDB.ShippingAddressSnapshot,
-		Subtotal:                orderFromDB.GrandTotal, // Asumsi subtotal = grandtotal jika ongkir 0
-		ShippingCost:            0,
-		GrandTotal:              orderFromDB.GrandTotal,
-		Items:                   []AdminOrderDetailItemView{},
+	if primaryIndex == -1 && len(images) > 0 {
+		primaryIndex = 0
 	}
-
-	// Mapping item-itemnya
-	for _, item := range orderFromDB.OrderItems {
-		orderDetailView.Items = append(orderDetailView.Items, AdminOrderDetailItemView{
-			ProductSKU:           item.ProductSKU,
-			ProductImageSnapshot: item.ProductImageSnapshot,
-			ProductTitleSnapshot: item.ProductTitleSnapshot,
-			PriceAtOrder:         item.PriceAtOrder,
-			Quantity:             item.Quantity,
-			SubTotal:             item.SubTotal,
-		})
+	for i := range images {
+		isPrimary := i == primaryIndex
+		if images[i].Position == i && images[i].IsPrimary == isPrimary {
+			continue
+		}
+		images[i].Position = i
+		images[i].IsPrimary = isPrimary
+		if err := tx.Model(&models.ProductImage{}).Where("id = ?", images[i].ID).
+			Updates(map[string]interface{}{"position": i, "is_primary": isPrimary}).Error; err != nil {
+			return nil, fmt.Errorf("gagal merapikan urutan gambar produk: %w", err)
+		}
 	}
-
-	return orderDetailView, nil
+	return images, nil
 }
 
-func (s *service) UpdateOrderStatus(orderID string, input AdminUpdateOrderStatusInput) (models.Order, error) {
-	var order models.Order
+func ensureProductExists(tx *gorm.DB, productSKU string) error {
+	var count int64
+	if err := tx.Model(&models.Product{}).Where("product_sku = ?", productSKU).Count(&count).Error; err != nil {
+		return fmt.Errorf("gagal memeriksa produk: %w", err)
+	}
+	if count == 0 {
+		return errors.New("produk tidak ditemukan")
+	}
+	return nil
+}
 
-	// Cari order berdasarkan ID
-	if err := s.db.Where("order_id = ?", orderID).First(&order).Error; err != nil {
+func findProductImage(tx *gorm.DB, productSKU string, imageID uint) (models.ProductImage, error) {
+	var image models.ProductImage
+	if err := tx.Where("id = ? AND product_sku = ?", imageID, productSKU).First(&image).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return models.Order{}, errors.New("pesanan tidak ditemukan")
+			return models.ProductImage{}, errors.New("gambar produk tidak ditemukan")
 		}
-		return models.Order{}, fmt.Errorf("gagal mencari pesanan: %w", err)
+		return models.ProductImage{}, fmt.Errorf("gagal mengambil gambar produk: %w", err)
 	}
+	return image, nil
+}
 
-	// Update statusnya
-	order.OrderStatus = input.OrderStatus
-
-	// Simpan perubahan
-	if err := s.db.Save(&order).Error; err != nil {
-		return models.Order{}, fmt.Errorf("gagal mengupdate status pesanan: %w", err)
+func (s *service) AddProductImages(productSKU string, imagePaths []string, altTexts []string) ([]models.ProductImage, error) {
+	log.Printf("[Service AddProductImages] SKU: %s, Jumlah Gambar: %d\n", productSKU, len(imagePaths))
+	if len(imagePaths) == 0 {
+		return nil, errors.New("tidak ada gambar yang diupload")
 	}
 
-	return order, nil
+	var images []models.ProductImage
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureProductExists(tx, productSKU); err != nil {
+			return err
+		}
+		if _, err := appendProductImages(tx, productSKU, imagePaths, altTexts); err != nil {
+			return err
+		}
+		var err error
+		images, err = normalizeProductImages(tx, productSKU)
+		return err
+	})
+	if err != nil {
+		// File sudah disimpan handler, hapus lagi karena tidak jadi dipakai
+		for _, imgPath := range imagePaths {
+			removeUploadedFile(imgPath)
+		}
+		return nil, err
+	}
+	return images, nil
 }
 
-func (s *service) DeleteOrder(orderID string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var order models.Order
-		// Ambil data order untuk mendapatkan path bukti pembayaran sebelum menghapus
-		if err := tx.Where("order_id = ?", orderID).First(&order).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("pesanan tidak ditemukan")
-			}
-			return fmt.Errorf("gagal mencari pesanan untuk dihapus: %w", err)
-		}
+func (s *service) UpdateProductImage(productSKU string, imageID uint, input UpdateProductImageInput) (models.ProductImage, error) {
+	image, err := findProductImage(s.db, productSKU, imageID)
+	if err != nil {
+		return models.ProductImage{}, err
+	}
+	image.AltText = strings.TrimSpace(input.AltText)
+	if err := s.db.Model(&image).Update("alt_text", image.AltText).Error; err != nil {
+		return models.ProductImage{}, fmt.Errorf("gagal mengupdate alt text gambar: %w", err)
+	}
+	return image, nil
+}
 
-		// Hapus file bukti pembayaran dari server jika ada
-		if order.ProofOfPayment != "" {
-			fullPath := filepath.Join(".", order.ProofOfPayment)
-			if err := os.Remove(fullPath); err != nil {
-				// Jangan gagalkan transaksi jika file tidak ada, cukup log
-				log.Printf("[Service DeleteOrder] Peringatan: Gagal menghapus file bukti pembayaran %s: %v\n", fullPath, err)
-			} else {
-				log.Printf("[Service DeleteOrder] Berhasil menghapus file bukti pembayaran: %s\n", fullPath)
-			}
+func (s *service) DeleteProductImage(productSKU string, imageID uint) error {
+	var deletedImage models.ProductImage
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		image, err := findProductImage(tx, productSKU, imageID)
+		if err != nil {
+			return err
 		}
-
-		result := tx.Where("order_id = ?", orderID).Delete(&models.Order{})
-		if result.Error != nil {
-			return fmt.Errorf("gagal menghapus pesanan: %w", result.Error)
+		if err := tx.Delete(&image).Error; err != nil {
+			return fmt.Errorf("gagal menghapus gambar produk: %w", err)
 		}
-
-		if result.RowsAffected == 0 {
-			return errors.New("pesanan tidak ditemukan saat mencoba menghapus")
+		// Rapikan urutan; jika yang dihapus gambar utama, gambar pertama yang tersisa menggantikannya
+		if _, err := normalizeProductImages(tx, productSKU); err != nil {
+			return err
 		}
+		deletedImage = image
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	removeUploadedFile(deletedImage.Image)
+	log.Printf("[Service DeleteProductImage] Gambar ID %d dari SKU %s berhasil dihapus.\n", imageID, productSKU)
+	return nil
 }
 
-func (s *service) ListOrderedCustomers() ([]AdminCustomerListView, error) {
-	var results []AdminCustomerListView
+func (s *service) ReorderProductImages(productSKU string, input ReorderProductImagesInput) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureProductExists(tx, productSKU); err != nil {
+			return err
+		}
+		var existing []models.ProductImage
+		if err := tx.Where("product_sku = ?", productSKU).Find(&existing).Error; err != nil {
+			return fmt.Errorf("gagal mengambil gambar produk: %w", err)
+		}
 
-	err := s.db.Table("customers c").
-		Select("c.customer_id, d.first_name || ' ' || d.last_name as full_name, c.email, c.phone, SUM(o.grand_total) as total_spent, MAX(o.order_date_time) as last_purchase").
-		Joins("JOIN customer_details d ON c.customer_id = d.customer_id").
-		Joins("JOIN orders o ON c.customer_id = o.customer_id").
-		Group("c.customer_id, d.first_name, d.last_name, c.email, c.phone").
-		Order("last_purchase DESC").
-		Scan(&results).Error
+		// Daftar ID harus berisi tepat seluruh gambar milik produk, masing-masing sekali
+		existingIDs := make(map[uint]bool, len(existing))
+		for _, img := range existing {
+			existingIDs[img.ID] = true
+		}
+		if len(input.ImageIDs) != len(existing) {
+			return errors.New("daftar urutan gambar harus berisi semua gambar produk")
+		}
+		seen := make(map[uint]bool, len(input.ImageIDs))
+		for _, id := range input.ImageIDs {
+			if !existingIDs[id] || seen[id] {
+				return errors.New("daftar urutan gambar harus berisi semua gambar produk")
+			}
+			seen[id] = true
+		}
 
+		for position, id := range input.ImageIDs {
+			if err := tx.Model(&models.ProductImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return fmt.Errorf("gagal menyimpan urutan gambar: %w", err)
+			}
+		}
+		var err error
+		images, err = normalizeProductImages(tx, productSKU)
+		return err
+	})
 	if err != nil {
-		log.Printf("[Service ListOrderedCustomers] Error: %v\n", err)
-		return nil, fmt.Errorf("gagal mengambil daftar customer: %w", err)
+		return nil, err
 	}
-	return results, nil
+	return images, nil
 }
 
-func (s *service) GetCustomerDetailForAdmin(customerID string) (AdminCustomerDetailView, error) {
-	var customer models.Customer
-	var customerDetailView AdminCustomerDetailView
+func (s *service) SetPrimaryProductImage(productSKU string, imageID uint) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := findProductImage(tx, productSKU, imageID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ProductImage{}).Where("product_sku = ? AND id <> ?", productSKU, imageID).Update("is_primary", false).Error; err != nil {
+			return fmt.Errorf("gagal mengubah gambar utama: %w", err)
+		}
+		if err := tx.Model(&models.ProductImage{}).Where("id = ?", imageID).Update("is_primary", true).Error; err != nil {
+			return fmt.Errorf("gagal mengubah gambar utama: %w", err)
+		}
+		var err error
+		images, err = normalizeProductImages(tx, productSKU)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// orderedCompatibleParts memuat data suku cadang untuk daftar kompatibilitas sebuah mesin.
+func orderedCompatibleParts(db *gorm.DB, machineSKU string) *gorm.DB {
+	return db.Preload("Part").Where("machine_sku = ?", machineSKU).Order("id ASC")
+}
+
+func (s *service) ListCompatibleParts(machineSKU string) ([]models.ProductCompatibility, error) {
+	if err := ensureProductExists(s.db, machineSKU); err != nil {
+		return nil, err
+	}
+	var compatibilities []models.ProductCompatibility
+	if err := orderedCompatibleParts(s.db, machineSKU).Find(&compatibilities).Error; err != nil {
+		log.Printf("[Service ListCompatibleParts] Error untuk SKU %s: %v\n", machineSKU, err)
+		return nil, fmt.Errorf("gagal mengambil daftar suku cadang kompatibel: %w", err)
+	}
+	return compatibilities, nil
+}
+
+func (s *service) SetCompatibleParts(machineSKU string, input SetCompatiblePartsInput) ([]models.ProductCompatibility, error) {
+	log.Printf("[Service SetCompatibleParts] Mesin %s, jumlah suku cadang: %d\n", machineSKU, len(input.Parts))
+
+	var compatibilities []models.ProductCompatibility
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureProductExists(tx, machineSKU); err != nil {
+			return err
+		}
+
+		seenParts := make(map[string]bool, len(input.Parts))
+		newCompatibilities := make([]models.ProductCompatibility, 0, len(input.Parts))
+		for _, part := range input.Parts {
+			if part.PartSKU == machineSKU {
+				return errors.New("suku cadang tidak boleh sama dengan produk mesin")
+			}
+			if seenParts[part.PartSKU] {
+				return fmt.Errorf("suku cadang %s disebutkan lebih dari sekali", part.PartSKU)
+			}
+			seenParts[part.PartSKU] = true
+
+			var partCount int64
+			if err := tx.Model(&models.Product{}).Where("product_sku = ?", part.PartSKU).Count(&partCount).Error; err != nil {
+				return fmt.Errorf("gagal memvalidasi suku cadang: %w", err)
+			}
+			if partCount == 0 {
+				return fmt.Errorf("suku cadang %s tidak ditemukan", part.PartSKU)
+			}
+			newCompatibilities = append(newCompatibilities, models.ProductCompatibility{
+				PartSKU:    part.PartSKU,
+				MachineSKU: machineSKU,
+				Notes:      strings.TrimSpace(part.Notes),
+			})
+		}
+
+		if err := tx.Where("machine_sku = ?", machineSKU).Delete(&models.ProductCompatibility{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus daftar kompatibilitas lama: %w", err)
+		}
+		if len(newCompatibilities) > 0 {
+			if err := tx.Omit("Part").Create(&newCompatibilities).Error; err != nil {
+				return fmt.Errorf("gagal menyimpan daftar kompatibilitas: %w", err)
+			}
+		}
+		return orderedCompatibleParts(tx, machineSKU).Find(&compatibilities).Error
+	})
+	if err != nil {
+		log.Printf("[Service SetCompatibleParts] Gagal untuk SKU %s: %v\n", machineSKU, err)
+		return nil, err
+	}
+	return compatibilities, nil
+}
+
+var attributeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// orderedCategoryAttributes dipakai saat Preload("Attributes") agar atribut terurut sesuai position.
+func orderedCategoryAttributes(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
+
+// preloadProductDetails memuat gambar, kategori dan spesifikasi produk untuk respons admin.
+func preloadProductDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("Images", models.OrderedProductImages).
+		Preload("ProductCategory").
+		Preload("Specifications", models.OrderedProductSpecifications).
+		Preload("Specifications.Attribute").
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("product_sku ASC") })
+}
+
+// validateProductParent memeriksa input varian. productSKU kosong berarti produk baru.
+// Varian hanya satu tingkat: induk tidak boleh varian, dan produk yang punya varian tidak boleh jadi varian.
+func validateProductParent(db *gorm.DB, productSKU string, input AddProductInput) (*string, error) {
+	if input.ParentSKU == "" {
+		return nil, nil
+	}
+	if input.ParentSKU == productSKU {
+		return nil, errors.New("varian tidak valid: produk tidak dapat menjadi varian dirinya sendiri")
+	}
+	if strings.TrimSpace(input.VariantName) == "" {
+		return nil, errors.New("varian tidak valid: nama varian wajib diisi")
+	}
+
+	var parent models.Product
+	if err := db.Where("product_sku = ?", input.ParentSKU).First(&parent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("varian tidak valid: produk induk tidak ditemukan")
+		}
+		return nil, fmt.Errorf("gagal memvalidasi produk induk: %w", err)
+	}
+	if parent.ParentSKU != nil {
+		return nil, errors.New("varian tidak valid: produk induk tidak boleh berupa varian")
+	}
+	if parent.ProductCategoryID != input.ProductCategoryID {
+		return nil, errors.New("varian tidak valid: kategori varian harus sama dengan kategori produk induk")
+	}
+
+	if productSKU != "" {
+		var variantCount int64
+		if err := db.Model(&models.Product{}).Where("parent_sku = ?", productSKU).Count(&variantCount).Error; err != nil {
+			return nil, fmt.Errorf("gagal memeriksa varian produk: %w", err)
+		}
+		if variantCount > 0 {
+			return nil, errors.New("varian tidak valid: produk yang memiliki varian tidak dapat dijadikan varian")
+		}
+	}
+
+	parentSKU := parent.ProductSKU
+	return &parentSKU, nil
+}
+
+func (s *service) UpdateProductCategoryAttributes(categoryID string, input UpdateProductCategoryAttributesInput) ([]models.ProductCategoryAttribute, error) {
+	log.Printf("[Service UpdateProductCategoryAttributes] Category ID: %s, Jumlah atribut: %d\n", categoryID, len(input.Attributes))
+
+	// 1. Validasi skema sebelum menyentuh database
+	seenCodes := make(map[string]bool)
+	for _, attr := range input.Attributes {
+		if !attributeCodePattern.MatchString(attr.Code) {
+			return nil, fmt.Errorf("kode atribut '%s' tidak valid (gunakan huruf kecil, angka dan underscore)", attr.Code)
+		}
+		if seenCodes[attr.Code] {
+			return nil, fmt.Errorf("kode atribut '%s' duplikat", attr.Code)
+		}
+		seenCodes[attr.Code] = true
+		if attr.DataType == models.AttributeTypeEnum && len(attr.Options) == 0 {
+			return nil, fmt.Errorf("atribut '%s' bertipe enum harus memiliki options", attr.Code)
+		}
+	}
+
+	var savedAttributes []models.ProductCategoryAttribute
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var category models.ProductCategory
+		if err := tx.Where("category_id = ?", categoryID).First(&category).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("kategori produk tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil kategori produk: %w", err)
+		}
+
+		var existingAttributes []models.ProductCategoryAttribute
+		if err := tx.Where("category_id = ?", categoryID).Find(&existingAttributes).Error; err != nil {
+			return fmt.Errorf("gagal mengambil atribut kategori: %w", err)
+		}
+		existingByCode := make(map[string]models.ProductCategoryAttribute, len(existingAttributes))
+		for _, attr := range existingAttributes {
+			existingByCode[attr.Code] = attr
+		}
+
+		// 2. Hapus atribut yang tidak lagi ada di skema beserta nilainya
+		var removedIDs []uint
+		for _, attr := range existingAttributes {
+			if !seenCodes[attr.Code] {
+				removedIDs = append(removedIDs, attr.ID)
+			}
+		}
+		if len(removedIDs) > 0 {
+			if err := tx.Where("attribute_id IN ?", removedIDs).Delete(&models.ProductSpecification{}).Error; err != nil {
+				return fmt.Errorf("gagal menghapus nilai spesifikasi lama: %w", err)
+			}
+			if err := tx.Where("id IN ?", removedIDs).Delete(&models.ProductCategoryAttribute{}).Error; err != nil {
+				return fmt.Errorf("gagal menghapus atribut lama: %w", err)
+			}
+		}
+
+		// 3. Upsert atribut berdasarkan code
+		for i, attrInput := range input.Attributes {
+			attr, exists := existingByCode[attrInput.Code]
+			if exists {
+				// Nilai lama tidak lagi cocok jika tipe berubah atau pilihan enum dihapus
+				if attr.DataType != attrInput.DataType {
+					if err := tx.Where("attribute_id = ?", attr.ID).Delete(&models.ProductSpecification{}).Error; err != nil {
+						return fmt.Errorf("gagal menghapus nilai spesifikasi atribut '%s': %w", attr.Code, err)
+					}
+				} else if attrInput.DataType == models.AttributeTypeEnum {
+					if err := tx.Where("attribute_id = ? AND text_value NOT IN ?", attr.ID, attrInput.Options).Delete(&models.ProductSpecification{}).Error; err != nil {
+						return fmt.Errorf("gagal menghapus nilai spesifikasi atribut '%s': %w", attr.Code, err)
+					}
+				}
+			} else {
+				attr = models.ProductCategoryAttribute{CategoryID: categoryID, Code: attrInput.Code}
+			}
+
+			attr.Label = attrInput.Label
+			attr.DataType = attrInput.DataType
+			attr.Unit = ""
+			if attrInput.DataType == models.AttributeTypeNumber {
+				attr.Unit = attrInput.Unit
+			}
+			attr.Options = nil
+			if attrInput.DataType == models.AttributeTypeEnum {
+				attr.Options = attrInput.Options
+			}
+			attr.IsRequired = attrInput.IsRequired
+			attr.IsFilterable = attrInput.IsFilterable
+			attr.Position = i
+
+			if err := tx.Save(&attr).Error; err != nil {
+				return fmt.Errorf("gagal menyimpan atribut '%s': %w", attr.Code, err)
+			}
+		}
+
+		return orderedCategoryAttributes(tx.Where("category_id = ?", categoryID)).Find(&savedAttributes).Error
+	})
+	if err != nil {
+		log.Printf("[Service UpdateProductCategoryAttributes] Transaksi gagal untuk Category ID %s: %v\n", categoryID, err)
+		return nil, err
+	}
+
+	log.Printf("[Service UpdateProductCategoryAttributes] Skema %d atribut tersimpan untuk Category ID %s\n", len(savedAttributes), categoryID)
+	return savedAttributes, nil
+}
+
+// buildProductSpecifications memvalidasi input spesifikasi terhadap skema atribut kategori
+// dan mengembalikan record yang siap disimpan (tanpa ProductSKU).
+func buildProductSpecifications(db *gorm.DB, categoryID string, inputs []ProductSpecificationInput) ([]models.ProductSpecification, error) {
+	var attributes []models.ProductCategoryAttribute
+	if err := db.Where("category_id = ?", categoryID).Find(&attributes).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil skema atribut kategori: %w", err)
+	}
+	attributesByCode := make(map[string]models.ProductCategoryAttribute, len(attributes))
+	for _, attr := range attributes {
+		attributesByCode[attr.Code] = attr
+	}
+
+	specifications := make([]models.ProductSpecification, 0, len(inputs))
+	filledCodes := make(map[string]bool, len(inputs))
+	for _, in := range inputs {
+		attr, ok := attributesByCode[in.Code]
+		if !ok {
+			return nil, fmt.Errorf("spesifikasi tidak valid: atribut '%s' tidak dikenal untuk kategori ini", in.Code)
+		}
+		if filledCodes[in.Code] {
+			return nil, fmt.Errorf("spesifikasi tidak valid: atribut '%s' diisi lebih dari sekali", in.Code)
+		}
+
+		spec := models.ProductSpecification{AttributeID: attr.ID}
+		value := strings.TrimSpace(in.Value)
+		switch attr.DataType {
+		case models.AttributeTypeNumber:
+			if in.NumberValue == nil {
+				// Nilai kosong diperlakukan sebagai tidak diisi
+				continue
+			}
+			spec.NumberValue = in.NumberValue
+		case models.AttributeTypeEnum:
+			if value == "" {
+				continue
+			}
+			validOption := false
+			for _, option := range attr.Options {
+				if option == value {
+					validOption = true
+					break
+				}
+			}
+			if !validOption {
+				return nil, fmt.Errorf("spesifikasi tidak valid: '%s' bukan pilihan yang tersedia untuk %s", value, attr.Label)
+			}
+			spec.TextValue = value
+		default:
+			if value == "" {
+				continue
+			}
+			spec.TextValue = value
+		}
+		filledCodes[in.Code] = true
+		specifications = append(specifications, spec)
+	}
+
+	for _, attr := range attributes {
+		if attr.IsRequired && !filledCodes[attr.Code] {
+			return nil, fmt.Errorf("spesifikasi tidak valid: %s wajib diisi", attr.Label)
+		}
+	}
+	return specifications, nil
+}
+
+// replaceProductSpecifications mengganti seluruh spesifikasi produk dengan hasil buildProductSpecifications.
+func replaceProductSpecifications(tx *gorm.DB, productSKU string, specifications []models.ProductSpecification) error {
+	if err := tx.Where("product_sku = ?", productSKU).Delete(&models.ProductSpecification{}).Error; err != nil {
+		return fmt.Errorf("gagal menghapus spesifikasi lama: %w", err)
+	}
+	if len(specifications) == 0 {
+		return nil
+	}
+	for i := range specifications {
+		specifications[i].ProductSKU = productSKU
+	}
+	if err := tx.Omit("Attribute").Create(&specifications).Error; err != nil {
+		return fmt.Errorf("gagal menyimpan spesifikasi produk: %w", err)
+	}
+	return nil
+}
+
+func toAdminQuotationRequestView(request models.QuotationRequest, detail models.CustomerDetail) AdminQuotationRequestView {
+	return AdminQuotationRequestView{
+		RequestID:        request.RequestID,
+		CustomerID:       request.CustomerID,
+		CustomerFullname: strings.TrimSpace(detail.FirstName + " " + detail.LastName),
+		CustomerEmail:    detail.Email,
+		CustomerPhone:    detail.Phone,
+		SiteLocation:     request.SiteLocation,
+		Notes:            request.Notes,
+		Status:           request.Status,
+		CreatedAt:        request.CreatedAt,
+		Items:            request.Items,
+		Quotation:        request.Quotation,
+	}
+}
+
+func (s *service) ListQuotationRequests(statusFilter string) ([]AdminQuotationRequestView, error) {
+	query := s.db.Preload("Items").Preload("Quotation.Items").Order("created_at DESC")
+	if statusFilter != "" {
+		query = query.Where("status = ?", statusFilter)
+	}
+
+	var requests []models.QuotationRequest
+	if err := query.Find(&requests).Error; err != nil {
+		log.Printf("[Service ListQuotationRequests] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil daftar permintaan penawaran: %w", err)
+	}
+
+	customerIDs := make([]string, 0, len(requests))
+	for _, request := range requests {
+		customerIDs = append(customerIDs, request.CustomerID)
+	}
+	var details []models.CustomerDetail
+	if len(customerIDs) > 0 {
+		if err := s.db.Where("customer_id IN ?", customerIDs).Find(&details).Error; err != nil {
+			return nil, fmt.Errorf("gagal mengambil data customer: %w", err)
+		}
+	}
+	detailByCustomer := make(map[string]models.CustomerDetail, len(details))
+	for _, detail := range details {
+		detailByCustomer[detail.CustomerID] = detail
+	}
+
+	views := make([]AdminQuotationRequestView, 0, len(requests))
+	for _, request := range requests {
+		views = append(views, toAdminQuotationRequestView(request, detailByCustomer[request.CustomerID]))
+	}
+	return views, nil
+}
+
+func (s *service) GetQuotationRequest(requestID string) (AdminQuotationRequestView, error) {
+	var request models.QuotationRequest
+	if err := s.db.Preload("Items").Preload("Quotation.Items").
+		Where("request_id = ?", requestID).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return AdminQuotationRequestView{}, errors.New("permintaan penawaran tidak ditemukan")
+		}
+		return AdminQuotationRequestView{}, fmt.Errorf("gagal mengambil permintaan penawaran: %w", err)
+	}
+
+	var detail models.CustomerDetail
+	if err := s.db.Where("customer_id = ?", request.CustomerID).First(&detail).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return AdminQuotationRequestView{}, fmt.Errorf("gagal mengambil data customer: %w", err)
+	}
+	return toAdminQuotationRequestView(request, detail), nil
+}
+
+// UpsertQuotation menyusun (atau merevisi) quotation untuk RFQ yang belum diterima/ditolak customer.
+func (s *service) UpsertQuotation(requestID, employeeID string, input UpsertQuotationInput) (AdminQuotationRequestView, error) {
+	log.Printf("[Service UpsertQuotation] RequestID: %s, oleh: %s\n", requestID, employeeID)
+
+	validUntil, err := time.ParseInLocation("2006-01-02", input.ValidUntil, time.Local)
+	if err != nil {
+		return AdminQuotationRequestView{}, errors.New("quotation tidak valid: format tanggal berlaku harus YYYY-MM-DD")
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if validUntil.Before(today) {
+		return AdminQuotationRequestView{}, errors.New("quotation tidak valid: tanggal berlaku tidak boleh di masa lalu")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var request models.QuotationRequest
+		if err := tx.Preload("Quotation").Where("request_id = ?", requestID).First(&request).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("permintaan penawaran tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil permintaan penawaran: %w", err)
+		}
+		if request.Status != models.QuotationRequestSubmitted && request.Status != models.QuotationRequestQuoted {
+			return errors.New("permintaan penawaran sudah diproses customer dan tidak bisa diubah")
+		}
+
+		items := make([]models.QuotationItem, 0, len(input.Items))
+		seenSKUs := make(map[string]bool, len(input.Items))
+		var total float64
+		for _, itemInput := range input.Items {
+			if seenSKUs[itemInput.ProductSKU] {
+				return fmt.Errorf("quotation tidak valid: produk %s disebutkan lebih dari sekali", itemInput.ProductSKU)
+			}
+			seenSKUs[itemInput.ProductSKU] = true
+
+			var product models.Product
+			if err := tx.Where("product_sku = ?", itemInput.ProductSKU).First(&product).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("quotation tidak valid: produk %s tidak ditemukan", itemInput.ProductSKU)
+				}
+				return fmt.Errorf("gagal memverifikasi produk: %w", err)
+			}
+			title := product.Title
+			if product.VariantName != "" {
+				title = fmt.Sprintf("%s - %s", product.Title, product.VariantName)
+			}
+			items = append(items, models.QuotationItem{
+				ProductSKU:           product.ProductSKU,
+				ProductTitleSnapshot: title,
+				Quantity:             itemInput.Quantity,
+				UnitPrice:            itemInput.UnitPrice,
+			})
+			total += itemInput.UnitPrice * float64(itemInput.Quantity)
+		}
+
+		quotation := request.Quotation
+		if quotation == nil {
+			var nextVal int
+			if err := tx.Raw("SELECT nextval('quotation_id_seq')").Scan(&nextVal).Error; err != nil {
+				return fmt.Errorf("gagal mendapatkan ID quotation: %w", err)
+			}
+			quotation = &models.Quotation{
+				QuotationID: fmt.Sprintf("QUO%05d", nextVal),
+				RequestID:   request.RequestID,
+			}
+		} else if err := tx.Where("quotation_id = ?", quotation.QuotationID).Delete(&models.QuotationItem{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus item quotation lama: %w", err)
+		}
+
+		quotation.PreparedBy = employeeID
+		quotation.ValidUntil = validUntil
+		quotation.Terms = input.Terms
+		quotation.TotalAmount = total
+		if err := tx.Omit("Items").Save(quotation).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan quotation: %w", err)
+		}
+		for i := range items {
+			items[i].QuotationID = quotation.QuotationID
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan item quotation: %w", err)
+		}
+
+		return tx.Model(&models.QuotationRequest{}).Where("request_id = ?", request.RequestID).
+			Update("status", models.QuotationRequestQuoted).Error
+	})
+	if err != nil {
+		log.Printf("[Service UpsertQuotation] Gagal untuk RequestID %s: %v\n", requestID, err)
+		return AdminQuotationRequestView{}, err
+	}
+
+	return s.GetQuotationRequest(requestID)
+}
+
+// adminRentalListViews memetakan booking sewa ke entri daftar pesanan admin.
+func adminRentalListViews(db *gorm.DB, bookings []models.RentalBooking) ([]AdminOrderListView, error) {
+	productSKUs := make([]string, 0, len(bookings))
+	for _, booking := range bookings {
+		if !slices.Contains(productSKUs, booking.ProductSKU) {
+			productSKUs = append(productSKUs, booking.ProductSKU)
+		}
+	}
+	images, err := models.PrimaryProductImagesBySKU(db, productSKUs)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]AdminOrderListView, 0, len(bookings))
+	for _, booking := range bookings {
+		views = append(views, AdminOrderListView{
+			OrderID:          booking.BookingID,
+			CustomerFullname: booking.CustomerFullname,
+			OrderDateTime:    booking.CreatedAt,
+			OrderStatus:      booking.Status,
+			GrandTotal:       booking.RentalPrice,
+			FirstItemImage:   images[booking.ProductSKU],
+			EntryType:        models.OrderHistoryEntryRental,
+			Rental: &AdminRentalHistoryInfo{
+				BookingID:            booking.BookingID,
+				ProductSKU:           booking.ProductSKU,
+				ProductTitleSnapshot: booking.ProductTitleSnapshot,
+				UnitCode:             booking.RentalUnit.UnitCode,
+				StartDate:            booking.StartDate,
+				EndDate:              booking.EndDate,
+				RentalDays:           booking.RentalDays,
+				Deposit:              booking.Deposit,
+			},
+		})
+	}
+	return views, nil
+}
+
+func (s *service) ListAllOrders(statusFilter string) ([]AdminOrderListView, error) {
+	var ordersFromDB []models.Order
+	log.Printf("[Service ListAllOrders] Mengambil data pesanan dengan filter status: '%s'\n", statusFilter)
+
+	// Mulai query, belum dieksekusi
+	query := s.db.Preload("OrderItems").Order("order_date_time DESC")
+
+	// Terapkan filter status JIKA parameter statusFilter tidak kosong
+	if statusFilter != "" {
+		query = query.Where("order_status = ?", statusFilter)
+	}
+
+	// Jalankan query yang sudah difilter (atau tidak)
+	if err := query.Find(&ordersFromDB).Error; err != nil {
+		log.Printf("[Service ListAllOrders] Error saat mengambil pesanan dari DB: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil daftar pesanan: %w", err)
+	}
+
+	// Logika mapping ke DTO tetap sama persis
+	orderListView := make([]AdminOrderListView, 0, len(ordersFromDB))
+	for _, order := range ordersFromDB {
+		var firstImage string
+		if len(order.OrderItems) > 0 && order.OrderItems[0].ProductImageSnapshot != "" {
+			firstImage = order.OrderItems[0].ProductImageSnapshot
+		}
+
+		orderView := AdminOrderListView{
+			OrderID:          order.OrderID,
+			CustomerFullname: order.CustomerFullname,
+			OrderDateTime:    order.OrderDateTime,
+			PaymentMethod:    order.PaymentMethod,
+			OrderStatus:      order.OrderStatus,
+			GrandTotal:       order.GrandTotal,
+			FirstItemImage:   firstImage,
+			CompanyLegalName: order.CompanyLegalName,
+			EntryType:        models.OrderHistoryEntryOrder,
+		}
+		orderListView = append(orderListView, orderView)
+	}
+
+	// Booking sewa ditampilkan bersama pesanan; filter status berlaku untuk keduanya
+	bookingQuery := s.db.Preload("RentalUnit")
+	if statusFilter != "" {
+		bookingQuery = bookingQuery.Where("status = ?", statusFilter)
+	}
+	var bookings []models.RentalBooking
+	if err := bookingQuery.Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil daftar booking sewa: %w", err)
+	}
+	rentalViews, err := adminRentalListViews(s.db, bookings)
+	if err != nil {
+		return nil, err
+	}
+	orderListView = append(orderListView, rentalViews...)
+	slices.SortStableFunc(orderListView, func(a, b AdminOrderListView) int {
+		return b.OrderDateTime.Compare(a.OrderDateTime)
+	})
+
+	log.Printf("[Service ListAllOrders] Berhasil mengambil %d pesanan.\n", len(orderListView))
+	return orderListView, nil
+}
+
+func (s *service) GetOrderDetailForAdmin(orderID string) (AdminOrderDetailView, error) {
+	var orderFromDB models.Order
+	var orderDetailView AdminOrderDetailView
+
+	// Preload semua relasi yang dibutuhkan untuk halaman detail
+	if err := s.db.
+		Preload("OrderItems").
+		Preload("PaymentMilestones", orderedPaymentMilestones).
+		Where("order_id = ?", orderID).
+		First(&orderFromDB).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return orderDetailView, errors.New("pesanan tidak ditemukan")
+		}
+		return orderDetailView, fmt.Errorf("gagal mengambil detail pesanan: %w", err)
+	}
+
+	// Mapping ke DTO utama
+	orderDetailView = AdminOrderDetailView{
+		OrderID:                 orderFromDB.OrderID,
+		OrderDateTime:           orderFromDB.OrderDateTime,
+		OrderStatus:             orderFromDB.OrderStatus,
+		PaymentMethod:           orderFromDB.PaymentMethod,
+		ProofOfPayment:          orderFromDB.ProofOfPayment,
+		Notes:                   orderFromDB.Notes,
+		CustomerID:              orderFromDB.CustomerID,
+		CustomerFullname:        orderFromDB.CustomerFullname,
+		CustomerEmail:           orderFromDB.CustomerEmail,
+		CustomerPhone:           orderFromDB.CustomerPhone,
+		ShippingAddressSnapshot: orderFromDB.ShippingAddressSnapshot,
+		CompanyLegalName:        orderFromDB.CompanyLegalName,
+		CompanyNPWP:             orderFromDB.CompanyNPWP,
+		CompanyBillingAddress:   orderFromDB.CompanyBillingAddress,
+		ApprovedBy:              orderFromDB.ApprovedBy,
+		Subtotal:                orderFromDB.GrandTotal, // Asumsi subtotal = grandtotal jika ongkir 0
+		ShippingCost:            0,
+		GrandTotal:              orderFromDB.GrandTotal,
+		Items:                   []AdminOrderDetailItemView{},
+		OutstandingBalance:      orderFromDB.OutstandingBalance(),
+		PaymentSchedule:         orderFromDB.PaymentMilestones,
+	}
+	if err := s.db.Where("order_id = ?", orderID).Order("created_at ASC, id ASC").Find(&orderDetailView.StatusHistory).Error; err != nil {
+		return orderDetailView, fmt.Errorf("gagal mengambil riwayat status pesanan: %w", err)
+	}
+	var refund models.Refund
+	if err := s.db.Where("order_id = ? AND rma_id IS NULL", orderID).Limit(1).Find(&refund).Error; err != nil {
+		return orderDetailView, fmt.Errorf("gagal mengambil data refund: %w", err)
+	}
+	if refund.RefundID != "" {
+		orderDetailView.Refund = &refund
+	}
+	returns, err := s.ListReturnRequests(AdminReturnRequestQuery{OrderID: orderID})
+	if err != nil {
+		return orderDetailView, err
+	}
+	orderDetailView.Returns = returns
+	if err := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("order_id = ?", orderID).Order("shipped_at ASC").Find(&orderDetailView.Shipments).Error; err != nil {
+		return orderDetailView, fmt.Errorf("gagal mengambil data pengiriman: %w", err)
+	}
+	if n := len(orderDetailView.Shipments); n > 0 {
+		latest := orderDetailView.Shipments[n-1]
+		orderDetailView.ShippingCourier = latest.Courier
+		orderDetailView.TrackingNumber = latest.TrackingNumber
+		orderDetailView.ShippedAt = &orderDetailView.Shipments[0].ShippedAt
+	}
+
+	if orderFromDB.CompanyID != nil {
+		orderDetailView.CompanyID = *orderFromDB.CompanyID
+	}
+
+	// Mapping item-itemnya
+	for _, item := range orderFromDB.OrderItems {
+		orderDetailView.Items = append(orderDetailView.Items, AdminOrderDetailItemView{
+			ProductSKU:           item.ProductSKU,
+			ProductImageSnapshot: item.ProductImageSnapshot,
+			ProductTitleSnapshot: item.ProductTitleSnapshot,
+			PriceAtOrder:         item.PriceAtOrder,
+			Quantity:             item.Quantity,
+			SubTotal:             item.SubTotal,
+		})
+	}
+
+	return orderDetailView, nil
+}
+
+// Perubahan status order yang boleh dilakukan admin. Canceled dan Completed bersifat final; order yang
+// menunggu approval perusahaan hanya dapat dibatalkan admin, persetujuannya lewat approver.
+var orderStatusTransitions = map[string][]string{
+	"Pending":                          {"Pending Confirmation", "Processed", "Canceled"},
+	"Pending Confirmation":             {"Processed", "Canceled"},
+	models.OrderStatusAwaitingApproval: {"Canceled"},
+	"Processed":                        {"Shipped", "Completed", "Canceled"},
+	"Shipped":                          {"Completed", "Canceled"},
+}
+
+func (s *service) UpdateOrderStatus(orderID, employeeID string, input AdminUpdateOrderStatusInput) (models.Order, error) {
+	var order models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Cari order berdasarkan ID
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mencari pesanan: %w", err)
+		}
+		if !slices.Contains(orderStatusTransitions[order.OrderStatus], input.OrderStatus) {
+			return fmt.Errorf("status pesanan tidak valid: tidak dapat mengubah %s menjadi %s", order.OrderStatus, input.OrderStatus)
+		}
+
+		if err := applyOrderStatus(tx, &order, input.OrderStatus, input.SerialNumbers, employeeID, input.Note); err != nil {
+			return err
+		}
+		if input.OrderStatus != "Shipped" {
+			return nil
+		}
+		// Order yang langsung ditandai Shipped tanpa shipment dicatat sebagai satu pengiriman utuh
+		shipped, err := orderHasShipments(tx, orderID)
+		if err != nil || shipped {
+			return err
+		}
+		_, err = createShipment(tx, orderID, nil, models.Shipment{
+			Courier:        strings.TrimSpace(input.ShippingCourier),
+			TrackingNumber: strings.TrimSpace(input.TrackingNumber),
+			ShippedAt:      time.Now(),
+			Notes:          strings.TrimSpace(input.Note),
+			CreatedBy:      employeeID,
+		})
+		return err
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+	return order, nil
+}
+
+// applyOrderStatus menyimpan status baru order beserta efek sampingnya: unit berseri dikirim,
+// garansi dibuat saat Completed, serta reservasi unit dan jadwal instalasi dibatalkan saat Canceled.
+// Perubahan status dicatat ke timeline pesanan atas nama employeeID.
+func applyOrderStatus(tx *gorm.DB, order *models.Order, status string, serialNumbers map[uint][]string, employeeID, note string) error {
+	statusChanged := order.OrderStatus != status
+	order.OrderStatus = status
+	if err := tx.Save(order).Error; err != nil {
+		return fmt.Errorf("gagal mengupdate status pesanan: %w", err)
+	}
+	if statusChanged {
+		if err := recordOrderStatus(tx, order.OrderID, status, employeeID, note); err != nil {
+			return err
+		}
+	}
+
+	switch status {
+	case "Shipped":
+		return shipOrderUnits(tx, *order, serialNumbers)
+	case "Completed":
+		if err := shipOrderUnits(tx, *order, serialNumbers); err != nil {
+			return err
+		}
+		return createOrderWarranties(tx, *order, serialNumbers)
+	case "Canceled":
+		// Stok dikembalikan hanya jika barang belum dikirim; barang yang sudah keluar kembali lewat retur.
+		// Canceled bersifat final sehingga stok tidak pernah dikembalikan dua kali.
+		shipped, err := orderHasShipments(tx, order.OrderID)
+		if err != nil {
+			return err
+		}
+		return models.ApplyOrderCancellation(tx, order.OrderID, statusChanged && !shipped)
+	}
+	return nil
+}
+
+// orderHasShipments melaporkan apakah sudah ada paket order yang dikirim.
+func orderHasShipments(tx *gorm.DB, orderID string) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.Shipment{}).Where("order_id = ?", orderID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("gagal memeriksa pengiriman pesanan: %w", err)
+	}
+	return count > 0, nil
+}
+
+// orderShipmentRemaining mengembalikan item order beserta quantity yang belum masuk shipment mana pun.
+func orderShipmentRemaining(tx *gorm.DB, orderID string) ([]models.OrderItem, map[uint]int, error) {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", orderID).Order("order_item_id ASC").Find(&items).Error; err != nil {
+		return nil, nil, fmt.Errorf("gagal mengambil item pesanan: %w", err)
+	}
+	var shipped []struct {
+		OrderItemID uint
+		Quantity    int
+	}
+	if err := tx.Table("shipment_items si").
+		Select("si.order_item_id, SUM(si.quantity) AS quantity").
+		Joins("JOIN shipments s ON s.shipment_id = si.shipment_id").
+		Where("s.order_id = ?", orderID).
+		Group("si.order_item_id").
+		Scan(&shipped).Error; err != nil {
+		return nil, nil, fmt.Errorf("gagal menghitung item yang sudah dikirim: %w", err)
+	}
+	remaining := make(map[uint]int, len(items))
+	for _, item := range items {
+		remaining[item.OrderItemID] = item.Quantity
+	}
+	for _, row := range shipped {
+		remaining[row.OrderItemID] -= row.Quantity
+	}
+	return items, remaining, nil
+}
+
+// createShipment menyimpan shipment baru beserta itemnya. requested kosong berarti seluruh sisa item
+// order yang belum dikirim.
+func createShipment(tx *gorm.DB, orderID string, requested []ShipmentItemInput, shipment models.Shipment) (models.Shipment, error) {
+	items, remaining, err := orderShipmentRemaining(tx, orderID)
+	if err != nil {
+		return shipment, err
+	}
+	itemsByID := make(map[uint]models.OrderItem, len(items))
+	for _, item := range items {
+		itemsByID[item.OrderItemID] = item
+	}
+
+	shipment.Items = nil
+	if len(requested) == 0 {
+		for _, item := range items {
+			if remaining[item.OrderItemID] > 0 {
+				requested = append(requested, ShipmentItemInput{OrderItemID: item.OrderItemID, Quantity: remaining[item.OrderItemID]})
+			}
+		}
+	}
+	seen := make(map[uint]bool)
+	for _, line := range requested {
+		item, ok := itemsByID[line.OrderItemID]
+		if !ok {
+			return shipment, fmt.Errorf("pengiriman tidak valid: item %d bukan bagian dari pesanan ini", line.OrderItemID)
+		}
+		if seen[line.OrderItemID] {
+			return shipment, fmt.Errorf("pengiriman tidak valid: item %d disebut lebih dari sekali", line.OrderItemID)
+		}
+		seen[line.OrderItemID] = true
+		if line.Quantity > remaining[line.OrderItemID] {
+			return shipment, fmt.Errorf("pengiriman tidak valid: item %d (%s) hanya tersisa %d yang belum dikirim",
+				line.OrderItemID, item.ProductSKU, remaining[line.OrderItemID])
+		}
+		shipment.Items = append(shipment.Items, models.ShipmentItem{
+			OrderItemID:          item.OrderItemID,
+			ProductSKU:           item.ProductSKU,
+			ProductTitleSnapshot: item.ProductTitleSnapshot,
+			Quantity:             line.Quantity,
+		})
+	}
+	if len(shipment.Items) == 0 {
+		return shipment, errors.New("pengiriman tidak valid: semua item pesanan sudah dikirim")
+	}
+
+	var nextVal int
+	if err := tx.Raw("SELECT nextval('shipment_id_seq')").Scan(&nextVal).Error; err != nil {
+		return shipment, fmt.Errorf("gagal mendapatkan ID pengiriman: %w", err)
+	}
+	shipment.ShipmentID = fmt.Sprintf("SHP%05d", nextVal)
+	shipment.OrderID = orderID
+	shipment.Status = models.ShipmentInTransit
+	if err := tx.Create(&shipment).Error; err != nil {
+		return shipment, fmt.Errorf("gagal menyimpan pengiriman: %w", err)
+	}
+	return shipment, nil
+}
+
+// CreateShipment mencatat satu paket pengiriman order. Shipment pertama memindahkan order Processed
+// menjadi Shipped; shipment berikutnya (pengiriman bertahap) hanya dicatat di timeline.
+func (s *service) CreateShipment(orderID, employeeID string, input CreateShipmentInput) (models.Shipment, error) {
+	var shipment models.Shipment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mencari pesanan: %w", err)
+		}
+		if order.OrderStatus != "Processed" && order.OrderStatus != "Shipped" {
+			return fmt.Errorf("pengiriman tidak valid: pesanan berstatus %s tidak dapat dikirim", order.OrderStatus)
+		}
+
+		shippedAt := time.Now()
+		if input.ShippedAt != nil {
+			shippedAt = *input.ShippedAt
+		}
+		var estimatedArrival *time.Time
+		if input.EstimatedArrival != "" {
+			eta, err := time.ParseInLocation("2006-01-02", input.EstimatedArrival, time.Local)
+			if err != nil {
+				return fmt.Errorf("pengiriman tidak valid: format estimasi tiba salah: %w", err)
+			}
+			estimatedArrival = &eta
+		}
+
+		var err error
+		shipment, err = createShipment(tx, orderID, input.Items, models.Shipment{
+			Courier:          strings.TrimSpace(input.Courier),
+			TrackingNumber:   strings.TrimSpace(input.TrackingNumber),
+			ShippedAt:        shippedAt,
+			EstimatedArrival: estimatedArrival,
+			Notes:            strings.TrimSpace(input.Notes),
+			CreatedBy:        employeeID,
+		})
+		if err != nil {
+			return err
+		}
+
+		note := fmt.Sprintf("Paket %s dikirim via %s", shipment.ShipmentID, shipment.Courier)
+		if shipment.TrackingNumber != "" {
+			note += ", resi " + shipment.TrackingNumber
+		}
+		if order.OrderStatus == "Processed" {
+			return applyOrderStatus(tx, &order, "Shipped", input.SerialNumbers, employeeID, note)
+		}
+		return recordOrderStatus(tx, orderID, order.OrderStatus, employeeID, note)
+	})
+	if err != nil {
+		log.Printf("[Service CreateShipment] Gagal untuk order %s: %v\n", orderID, err)
+		return models.Shipment{}, err
+	}
+	log.Printf("[Service CreateShipment] Shipment %s dibuat untuk order %s.\n", shipment.ShipmentID, orderID)
+	return shipment, nil
+}
+
+// ConfirmShipmentDelivery mencatat bahwa paket sudah diterima, beserta nama penerima dan foto bukti terima.
+func (s *service) ConfirmShipmentDelivery(shipmentID, employeeID string, input ConfirmShipmentDeliveryInput, photoFileHeader *multipart.FileHeader) (models.Shipment, error) {
+	var shipment models.Shipment
+	var savedPhoto string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("shipment_id = ?", shipmentID).First(&shipment).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pengiriman tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil pengiriman: %w", err)
+		}
+		if shipment.Status == models.ShipmentDelivered {
+			return errors.New("pengiriman sudah dikonfirmasi diterima")
+		}
+		if photoFileHeader != nil {
+			uniqueFilename := fmt.Sprintf("delivery_%s_%d_%s%s", shipmentID, time.Now().UnixNano(), uuid.New().String()[:8], filepath.Ext(photoFileHeader.Filename))
+			var err error
+			savedPhoto, err = saveUploadedFile(photoFileHeader, deliveryPhotoUploadDir, uniqueFilename)
+			if err != nil {
+				return err
+			}
+			shipment.DeliveryPhoto = savedPhoto
+		}
+
+		now := time.Now()
+		recipientName := strings.TrimSpace(input.RecipientName)
+		shipment.Status = models.ShipmentDelivered
+		shipment.DeliveredAt = &now
+		shipment.RecipientName = recipientName
+		shipment.DeliveryNotes = strings.TrimSpace(input.Notes)
+		shipment.ConfirmedBy = employeeID
+		if err := tx.Omit(clause.Associations).Save(&shipment).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan konfirmasi penerimaan: %w", err)
+		}
+
+		var order models.Order
+		if err := tx.Select("order_id", "order_status").Where("order_id = ?", shipment.OrderID).First(&order).Error; err != nil {
+			return fmt.Errorf("gagal mengambil pesanan: %w", err)
+		}
+		return recordOrderStatus(tx, order.OrderID, order.OrderStatus, employeeID,
+			fmt.Sprintf("Paket %s diterima oleh %s", shipmentID, recipientName))
+	})
+	if err != nil {
+		log.Printf("[Service ConfirmShipmentDelivery] Gagal untuk %s: %v\n", shipmentID, err)
+		removeUploadedFile(savedPhoto)
+		return models.Shipment{}, err
+	}
+	if err := s.db.Where("shipment_id = ?", shipmentID).Order("id ASC").Find(&shipment.Items).Error; err != nil {
+		return models.Shipment{}, fmt.Errorf("gagal mengambil item pengiriman: %w", err)
+	}
+	return shipment, nil
+}
+
+func (s *service) GetShipmentTracking(shipmentID string) (shipping.TrackingResult, error) {
+	var shipment models.Shipment
+	if err := s.db.Where("shipment_id = ?", shipmentID).First(&shipment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return shipping.TrackingResult{}, errors.New("pengiriman tidak ditemukan")
+		}
+		return shipping.TrackingResult{}, fmt.Errorf("gagal mengambil pengiriman: %w", err)
+	}
+	return shipping.Track(s.tracker, shipment)
+}
+
+// recordOrderStatus mencatat perubahan status order oleh karyawan ke timeline pesanan.
+func recordOrderStatus(tx *gorm.DB, orderID, status, employeeID, note string) error {
+	history := models.OrderStatusHistory{
+		OrderID:       orderID,
+		Status:        status,
+		Note:          strings.TrimSpace(note),
+		ChangedByType: models.OrderStatusChangedByEmployee,
+		ChangedBy:     employeeID,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return fmt.Errorf("gagal mencatat riwayat status pesanan: %w", err)
+	}
+	return nil
+}
+
+// createOrderWarranties membuat satu garansi per unit barang bergaransi pada order yang selesai.
+// Aman dipanggil berulang: unit yang sudah punya garansi hanya diperbarui nomor serinya bila dikirim.
+func createOrderWarranties(tx *gorm.DB, order models.Order, serialNumbers map[uint][]string) error {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.OrderID).Order("order_item_id ASC").Find(&items).Error; err != nil {
+		return fmt.Errorf("gagal mengambil item pesanan: %w", err)
+	}
+	itemsByID := make(map[uint]models.OrderItem, len(items))
+	productSKUs := make([]string, 0, len(items))
+	for _, item := range items {
+		itemsByID[item.OrderItemID] = item
+		productSKUs = append(productSKUs, item.ProductSKU)
+	}
+	for orderItemID, serials := range serialNumbers {
+		item, ok := itemsByID[orderItemID]
+		if !ok {
+			return fmt.Errorf("nomor seri tidak valid: item %d bukan bagian dari pesanan ini", orderItemID)
+		}
+		if len(serials) > item.Quantity {
+			return fmt.Errorf("nomor seri tidak valid: item %d hanya memiliki %d unit", orderItemID, item.Quantity)
+		}
+	}
+
+	var products []models.Product
+	if err := tx.Select("product_sku", "warranty_months").Where("product_sku IN ?", productSKUs).Find(&products).Error; err != nil {
+		return fmt.Errorf("gagal mengambil data garansi produk: %w", err)
+	}
+	warrantyMonths := make(map[string]int, len(products))
+	for _, product := range products {
+		if product.WarrantyMonths != nil {
+			warrantyMonths[product.ProductSKU] = *product.WarrantyMonths
+		}
+	}
+
+	// Produk berseri memakai nomor seri unit yang benar-benar dikirim
+	var units []models.ProductUnit
+	if err := tx.Where("order_id = ? AND status = ?", order.OrderID, models.ProductUnitShipped).Order("id ASC").Find(&units).Error; err != nil {
+		return fmt.Errorf("gagal mengambil unit pesanan: %w", err)
+	}
+	shippedUnits := make(map[uint][]string)
+	for _, unit := range units {
+		if unit.OrderItemID != nil {
+			shippedUnits[*unit.OrderItemID] = append(shippedUnits[*unit.OrderItemID], unit.SerialNumber)
+		}
+	}
+
+	now := time.Now()
+	startDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var warranties []models.Warranty
+	for _, item := range items {
+		months := warrantyMonths[item.ProductSKU]
+		if months <= 0 {
+			continue
+		}
+		serials := serialNumbers[item.OrderItemID]
+		for unit := 1; unit <= item.Quantity; unit++ {
+			warranty := models.Warranty{
+				OrderID:              order.OrderID,
+				OrderItemID:          item.OrderItemID,
+				UnitIndex:            unit,
+				CustomerID:           order.CustomerID,
+				ProductSKU:           item.ProductSKU,
+				ProductTitleSnapshot: item.ProductTitleSnapshot,
+				StartDate:            startDate,
+				EndDate:              startDate.AddDate(0, months, 0),
+			}
+			if units := shippedUnits[item.OrderItemID]; unit <= len(units) {
+				warranty.SerialNumber = units[unit-1]
+			} else if unit <= len(serials) {
+				warranty.SerialNumber = strings.TrimSpace(serials[unit-1])
+			}
+			warranties = append(warranties, warranty)
+		}
+	}
+	if len(warranties) == 0 {
+		return nil
+	}
+
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "order_item_id"}, {Name: "unit_index"}},
+		DoNothing: true,
+	}).Create(&warranties).Error; err != nil {
+		return fmt.Errorf("gagal membuat garansi: %w", err)
+	}
+	// Unit yang garansinya sudah ada sebelumnya tetap menerima nomor seri baru
+	for _, warranty := range warranties {
+		if warranty.SerialNumber == "" {
+			continue
+		}
+		if err := tx.Model(&models.Warranty{}).
+			Where("order_item_id = ? AND unit_index = ?", warranty.OrderItemID, warranty.UnitIndex).
+			Update("serial_number", warranty.SerialNumber).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan nomor seri: %w", err)
+		}
+	}
+	log.Printf("[Service UpdateOrderStatus] Garansi untuk %d unit pada order %s disiapkan.\n", len(warranties), order.OrderID)
+	return nil
+}
+
+// loadSerializedOrderItems mengambil item order yang produknya dilacak per nomor seri, diindeks per order_item_id.
+func loadSerializedOrderItems(tx *gorm.DB, orderID string) (map[uint]models.OrderItem, error) {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ? AND product_sku IN (?)", orderID,
+		tx.Model(&models.Product{}).Select("product_sku").Where("is_serialized = ?", true)).
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil item pesanan: %w", err)
+	}
+	itemsByID := make(map[uint]models.OrderItem, len(items))
+	for _, item := range items {
+		itemsByID[item.OrderItemID] = item
+	}
+	return itemsByID, nil
+}
+
+// reserveOrderUnits menetapkan unit (berdasarkan nomor seri) untuk item order berseri.
+// Reservasi lama item yang sama dikembalikan ke stok; item yang unitnya sudah dikirim tidak bisa diubah.
+func reserveOrderUnits(tx *gorm.DB, orderID string, serializedItems map[uint]models.OrderItem, serialNumbers map[uint][]string) error {
+	seen := make(map[string]bool)
+	for orderItemID, serials := range serialNumbers {
+		item, ok := serializedItems[orderItemID]
+		if !ok {
+			// Item non-berseri: nomor serinya hanya dicatat pada garansi
+			continue
+		}
+		if len(serials) > item.Quantity {
+			return fmt.Errorf("nomor seri tidak valid: item %d hanya memiliki %d unit", orderItemID, item.Quantity)
+		}
+		var shipped int64
+		if err := tx.Model(&models.ProductUnit{}).Where("order_item_id = ? AND status = ?", orderItemID, models.ProductUnitShipped).Count(&shipped).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa unit pesanan: %w", err)
+		}
+		if shipped > 0 {
+			return fmt.Errorf("nomor seri tidak valid: unit item %d sudah dikirim", orderItemID)
+		}
+		if err := tx.Model(&models.ProductUnit{}).
+			Where("order_item_id = ? AND status = ?", orderItemID, models.ProductUnitReserved).
+			Updates(map[string]interface{}{"status": models.ProductUnitInStock, "order_id": nil, "order_item_id": nil}).Error; err != nil {
+			return fmt.Errorf("gagal melepas reservasi unit: %w", err)
+		}
+
+		for _, rawSerial := range serials {
+			serial := strings.TrimSpace(rawSerial)
+			if seen[serial] {
+				return fmt.Errorf("nomor seri tidak valid: %s dikirim lebih dari sekali", serial)
+			}
+			seen[serial] = true
+
+			var unit models.ProductUnit
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("serial_number = ?", serial).First(&unit).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("nomor seri tidak valid: %s belum terdaftar", serial)
+				}
+				return fmt.Errorf("gagal mengambil unit %s: %w", serial, err)
+			}
+			if unit.ProductSKU != item.ProductSKU {
+				return fmt.Errorf("nomor seri tidak valid: %s bukan unit produk %s", serial, item.ProductSKU)
+			}
+			if unit.Status != models.ProductUnitInStock {
+				return fmt.Errorf("nomor seri tidak valid: %s berstatus %s", serial, unit.Status)
+			}
+			unit.Status = models.ProductUnitReserved
+			unit.OrderID = &orderID
+			unit.OrderItemID = &item.OrderItemID
+			if err := tx.Save(&unit).Error; err != nil {
+				return fmt.Errorf("gagal mereservasi unit %s: %w", serial, err)
+			}
+		}
+	}
+	return nil
+}
+
+// shipOrderUnits menandai unit item berseri sebagai Shipped. Setiap item berseri harus sudah
+// punya unit sebanyak quantity-nya, baik dari reservasi sebelumnya maupun dari serialNumbers.
+func shipOrderUnits(tx *gorm.DB, order models.Order, serialNumbers map[uint][]string) error {
+	serializedItems, err := loadSerializedOrderItems(tx, order.OrderID)
+	if err != nil {
+		return err
+	}
+	if len(serializedItems) == 0 {
+		return nil
+	}
+	if err := reserveOrderUnits(tx, order.OrderID, serializedItems, serialNumbers); err != nil {
+		return err
+	}
+
+	for _, item := range serializedItems {
+		var assigned int64
+		if err := tx.Model(&models.ProductUnit{}).
+			Where("order_item_id = ? AND status IN ?", item.OrderItemID, []string{models.ProductUnitReserved, models.ProductUnitShipped}).
+			Count(&assigned).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa unit pesanan: %w", err)
+		}
+		if int(assigned) != item.Quantity {
+			return fmt.Errorf("nomor seri tidak valid: item %d (%s) membutuhkan %d nomor seri, baru %d yang ditetapkan",
+				item.OrderItemID, item.ProductSKU, item.Quantity, assigned)
+		}
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.ProductUnit{}).
+		Where("order_id = ? AND status = ?", order.OrderID, models.ProductUnitReserved).
+		Updates(map[string]interface{}{"status": models.ProductUnitShipped, "shipped_at": now}).Error; err != nil {
+		return fmt.Errorf("gagal menandai unit terkirim: %w", err)
+	}
+	return nil
+}
+
+// releaseOrderUnits mengembalikan unit yang masih direservasi sebuah order ke stok.
+func releaseOrderUnits(tx *gorm.DB, orderID string) error {
+	if err := tx.Model(&models.ProductUnit{}).
+		Where("order_id = ? AND status = ?", orderID, models.ProductUnitReserved).
+		Updates(map[string]interface{}{"status": models.ProductUnitInStock, "order_id": nil, "order_item_id": nil}).Error; err != nil {
+		return fmt.Errorf("gagal melepas reservasi unit: %w", err)
+	}
+	return nil
+}
+
+func (s *service) AssignOrderUnits(orderID string, input AssignOrderUnitsInput) ([]models.ProductUnit, error) {
+	var units []models.ProductUnit
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mencari pesanan: %w", err)
+		}
+		if order.OrderStatus == "Canceled" {
+			return errors.New("nomor seri tidak valid: pesanan sudah dibatalkan")
+		}
+
+		serializedItems, err := loadSerializedOrderItems(tx, orderID)
+		if err != nil {
+			return err
+		}
+		for orderItemID := range input.SerialNumbers {
+			if _, ok := serializedItems[orderItemID]; !ok {
+				return fmt.Errorf("nomor seri tidak valid: item %d bukan item berseri pada pesanan ini", orderItemID)
+			}
+		}
+		if err := reserveOrderUnits(tx, orderID, serializedItems, input.SerialNumbers); err != nil {
+			return err
+		}
+		return tx.Where("order_id = ?", orderID).Order("order_item_id ASC, id ASC").Find(&units).Error
+	})
+	if err != nil {
+		log.Printf("[Service AssignOrderUnits] Gagal untuk order %s: %v\n", orderID, err)
+		return nil, err
+	}
+	return units, nil
+}
+
+func (s *service) RegisterProductUnits(productSKU string, input RegisterProductUnitsInput) ([]models.ProductUnit, error) {
+	var units []models.ProductUnit
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := tx.Select("product_sku", "is_serialized").Where("product_sku = ?", productSKU).First(&product).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("produk tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil produk: %w", err)
+		}
+		if !product.IsSerialized {
+			return errors.New("unit tidak valid: produk ini tidak dilacak per nomor seri")
+		}
+
+		serials := make([]string, 0, len(input.SerialNumbers))
+		seen := make(map[string]bool)
+		for _, rawSerial := range input.SerialNumbers {
+			serial := strings.TrimSpace(rawSerial)
+			if serial == "" || seen[serial] {
+				return fmt.Errorf("unit tidak valid: nomor seri %q kosong atau duplikat", rawSerial)
+			}
+			seen[serial] = true
+			serials = append(serials, serial)
+		}
+		var existing []string
+		if err := tx.Model(&models.ProductUnit{}).Where("serial_number IN ?", serials).Pluck("serial_number", &existing).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa nomor seri: %w", err)
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("unit tidak valid: nomor seri sudah terdaftar: %s", strings.Join(existing, ", "))
+		}
+
+		for _, serial := range serials {
+			units = append(units, models.ProductUnit{
+				ProductSKU:   productSKU,
+				SerialNumber: serial,
+				Status:       models.ProductUnitInStock,
+				Notes:        strings.TrimSpace(input.Notes),
+			})
+		}
+		return tx.Create(&units).Error
+	})
+	if err != nil {
+		log.Printf("[Service RegisterProductUnits] Gagal untuk SKU %s: %v\n", productSKU, err)
+		return nil, err
+	}
+	log.Printf("[Service RegisterProductUnits] %d unit didaftarkan untuk SKU %s.\n", len(units), productSKU)
+	return units, nil
+}
+
+func (s *service) ListProductUnits(productSKU, statusFilter string) ([]models.ProductUnit, error) {
+	var count int64
+	if err := s.db.Model(&models.Product{}).Where("product_sku = ?", productSKU).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("gagal memverifikasi produk: %w", err)
+	}
+	if count == 0 {
+		return nil, errors.New("produk tidak ditemukan")
+	}
+
+	units := []models.ProductUnit{}
+	query := s.db.Where("product_sku = ?", productSKU)
+	if statusFilter != "" {
+		query = query.Where("status = ?", statusFilter)
+	}
+	if err := query.Order("created_at ASC, id ASC").Find(&units).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil unit produk: %w", err)
+	}
+	return units, nil
+}
+
+func (s *service) DeleteProductUnit(productSKU string, unitID uint) error {
+	var unit models.ProductUnit
+	if err := s.db.Where("id = ? AND product_sku = ?", unitID, productSKU).First(&unit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("unit tidak ditemukan")
+		}
+		return fmt.Errorf("gagal mengambil unit: %w", err)
+	}
+	if unit.Status != models.ProductUnitInStock {
+		return errors.New("unit tidak valid: hanya unit berstatus In Stock yang dapat dihapus")
+	}
+	if err := s.db.Delete(&unit).Error; err != nil {
+		return fmt.Errorf("gagal menghapus unit: %w", err)
+	}
+	return nil
+}
+
+// LookupProductUnit menelusuri riwayat satu unit: produk, pesanan, customer penerima dan garansinya.
+func (s *service) LookupProductUnit(serialNumber string) (AdminProductUnitLookupView, error) {
+	var unit models.ProductUnit
+	if err := s.db.Where("serial_number = ?", strings.TrimSpace(serialNumber)).First(&unit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return AdminProductUnitLookupView{}, errors.New("unit tidak ditemukan")
+		}
+		return AdminProductUnitLookupView{}, fmt.Errorf("gagal mengambil unit: %w", err)
+	}
+
+	view := AdminProductUnitLookupView{
+		UnitID:       unit.ID,
+		SerialNumber: unit.SerialNumber,
+		Status:       unit.Status,
+		ProductSKU:   unit.ProductSKU,
+		Notes:        unit.Notes,
+		OrderID:      unit.OrderID,
+		OrderItemID:  unit.OrderItemID,
+		ShippedAt:    unit.ShippedAt,
+		CreatedAt:    unit.CreatedAt,
+	}
+	var product models.Product
+	if err := s.db.Select("product_sku", "title").Where("product_sku = ?", unit.ProductSKU).First(&product).Error; err == nil {
+		view.ProductTitle = product.Title
+	}
+	if unit.OrderID != nil {
+		var order models.Order
+		if err := s.db.Where("order_id = ?", *unit.OrderID).First(&order).Error; err == nil {
+			view.OrderStatus = order.OrderStatus
+			view.CustomerID = order.CustomerID
+			view.CustomerFullname = order.CustomerFullname
+		}
+	}
+	if unit.OrderItemID != nil {
+		var warranty models.Warranty
+		err := s.db.Where("order_item_id = ? AND serial_number = ?", *unit.OrderItemID, unit.SerialNumber).First(&warranty).Error
+		if err == nil {
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			view.Warranty = &AdminUnitWarrantyView{
+				WarrantyID: warranty.ID,
+				StartDate:  warranty.StartDate,
+				EndDate:    warranty.EndDate,
+				IsActive:   !warranty.EndDate.Before(today),
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return AdminProductUnitLookupView{}, fmt.Errorf("gagal mengambil garansi unit: %w", err)
+		}
+	}
+	return view, nil
+}
+
+func (s *service) UpdateWarrantySerialNumber(warrantyID uint, input UpdateWarrantySerialNumberInput) (models.Warranty, error) {
+	var warranty models.Warranty
+	if err := s.db.First(&warranty, warrantyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Warranty{}, errors.New("garansi tidak ditemukan")
+		}
+		return models.Warranty{}, fmt.Errorf("gagal mengambil garansi: %w", err)
+	}
+	warranty.SerialNumber = strings.TrimSpace(input.SerialNumber)
+	if err := s.db.Save(&warranty).Error; err != nil {
+		return models.Warranty{}, fmt.Errorf("gagal menyimpan nomor seri: %w", err)
+	}
+	return warranty, nil
+}
+
+// Transisi status klaim garansi yang diizinkan
+var warrantyClaimTransitions = map[string][]string{
+	models.WarrantyClaimSubmitted: {models.WarrantyClaimInReview, models.WarrantyClaimApproved, models.WarrantyClaimRejected},
+	models.WarrantyClaimInReview:  {models.WarrantyClaimApproved, models.WarrantyClaimRejected},
+	models.WarrantyClaimApproved:  {models.WarrantyClaimResolved},
+}
+
+func (s *service) warrantyClaimQuery() *gorm.DB {
+	return s.db.Table("warranty_claims wc").
+		Select(`wc.claim_id, wc.warranty_id, w.order_id, w.product_sku, w.product_title_snapshot AS product_title,
+			w.serial_number, w.end_date AS warranty_end_date, wc.customer_id,
+			TRIM(COALESCE(d.first_name, '') || ' ' || COALESCE(d.last_name, '')) AS customer_fullname, c.email AS customer_email,
+			wc.description, wc.status, wc.resolution_notes, wc.handled_by, wc.resolved_at, wc.created_at, wc.updated_at`).
+		Joins("JOIN warranties w ON w.id = wc.warranty_id").
+		Joins("LEFT JOIN customers c ON c.customer_id = wc.customer_id").
+		Joins("LEFT JOIN customer_details d ON d.customer_id = wc.customer_id")
+}
+
+func (s *service) attachWarrantyClaimPhotos(claims []AdminWarrantyClaimView) error {
+	if len(claims) == 0 {
+		return nil
+	}
+	claimIDs := make([]string, 0, len(claims))
+	for _, claim := range claims {
+		claimIDs = append(claimIDs, claim.ClaimID)
+	}
+	var photos []models.WarrantyClaimPhoto
+	if err := s.db.Where("claim_id IN ?", claimIDs).Order("id ASC").Find(&photos).Error; err != nil {
+		return fmt.Errorf("gagal mengambil foto klaim garansi: %w", err)
+	}
+	photosByClaim := make(map[string][]string)
+	for _, photo := range photos {
+		photosByClaim[photo.ClaimID] = append(photosByClaim[photo.ClaimID], photo.Image)
+	}
+	for i := range claims {
+		claims[i].Photos = photosByClaim[claims[i].ClaimID]
+		if claims[i].Photos == nil {
+			claims[i].Photos = []string{}
+		}
+	}
+	return nil
+}
+
+func (s *service) ListWarrantyClaims(statusFilter string) ([]AdminWarrantyClaimView, error) {
+	claims := []AdminWarrantyClaimView{}
+	query := s.warrantyClaimQuery()
+	if statusFilter != "" {
+		query = query.Where("wc.status = ?", statusFilter)
+	}
+	if err := query.Order("wc.created_at DESC").Scan(&claims).Error; err != nil {
+		log.Printf("[Service ListWarrantyClaims] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil klaim garansi: %w", err)
+	}
+	if err := s.attachWarrantyClaimPhotos(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (s *service) GetWarrantyClaim(claimID string) (AdminWarrantyClaimView, error) {
+	var claims []AdminWarrantyClaimView
+	if err := s.warrantyClaimQuery().Where("wc.claim_id = ?", claimID).Scan(&claims).Error; err != nil {
+		return AdminWarrantyClaimView{}, fmt.Errorf("gagal mengambil klaim garansi: %w", err)
+	}
+	if len(claims) == 0 {
+		return AdminWarrantyClaimView{}, errors.New("klaim garansi tidak ditemukan")
+	}
+	if err := s.attachWarrantyClaimPhotos(claims); err != nil {
+		return AdminWarrantyClaimView{}, err
+	}
+	return claims[0], nil
+}
+
+func (s *service) UpdateWarrantyClaim(claimID, employeeID string, input UpdateWarrantyClaimInput) (AdminWarrantyClaimView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var claim models.WarrantyClaim
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("claim_id = ?", claimID).First(&claim).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("klaim garansi tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil klaim garansi: %w", err)
+		}
+		if !slices.Contains(warrantyClaimTransitions[claim.Status], input.Status) {
+			return fmt.Errorf("status klaim tidak valid: tidak dapat mengubah dari %s ke %s", claim.Status, input.Status)
+		}
+		notes := strings.TrimSpace(input.ResolutionNotes)
+		closing := input.Status == models.WarrantyClaimRejected || input.Status == models.WarrantyClaimResolved
+		if closing && notes == "" {
+			return fmt.Errorf("status klaim tidak valid: catatan penyelesaian wajib diisi untuk status %s", input.Status)
+		}
+
+		claim.Status = input.Status
+		if notes != "" {
+			claim.ResolutionNotes = notes
+		}
+		claim.HandledBy = employeeID
+		if closing {
+			now := time.Now()
+			claim.ResolvedAt = &now
+		}
+		return tx.Omit(clause.Associations).Save(&claim).Error
+	})
+	if err != nil {
+		log.Printf("[Service UpdateWarrantyClaim] Gagal untuk klaim %s: %v\n", claimID, err)
+		return AdminWarrantyClaimView{}, err
+	}
+	return s.GetWarrantyClaim(claimID)
+}
+
+func (s *service) DeleteOrder(orderID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		// Ambil data order untuk mendapatkan path bukti pembayaran sebelum menghapus
+		if err := tx.Where("order_id = ?", orderID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mencari pesanan untuk dihapus: %w", err)
+		}
+
+		// Hapus file bukti pembayaran dari server jika ada
+		if order.ProofOfPayment != "" {
+			fullPath := filepath.Join(".", order.ProofOfPayment)
+			if err := os.Remove(fullPath); err != nil {
+				// Jangan gagalkan transaksi jika file tidak ada, cukup log
+				log.Printf("[Service DeleteOrder] Peringatan: Gagal menghapus file bukti pembayaran %s: %v\n", fullPath, err)
+			} else {
+				log.Printf("[Service DeleteOrder] Berhasil menghapus file bukti pembayaran: %s\n", fullPath)
+			}
+		}
+
+		var milestones []models.PaymentMilestone
+		if err := tx.Where("order_id = ?", orderID).Find(&milestones).Error; err != nil {
+			return fmt.Errorf("gagal mengambil termin pembayaran: %w", err)
+		}
+		for _, milestone := range milestones {
+			if milestone.ProofOfPayment == "" {
+				continue
+			}
+			if err := os.Remove(filepath.Join(".", milestone.ProofOfPayment)); err != nil {
+				log.Printf("[Service DeleteOrder] Peringatan: Gagal menghapus bukti pembayaran termin %s: %v\n", milestone.ProofOfPayment, err)
+			}
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.PaymentMilestone{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus termin pembayaran: %w", err)
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.OrderStatusHistory{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus riwayat status pesanan: %w", err)
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.Refund{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus refund pesanan: %w", err)
+		}
+		var deliveryPhotos []string
+		if err := tx.Model(&models.Shipment{}).Where("order_id = ? AND delivery_photo <> ''", orderID).Pluck("delivery_photo", &deliveryPhotos).Error; err != nil {
+			return fmt.Errorf("gagal mengambil foto bukti terima: %w", err)
+		}
+		for _, photo := range deliveryPhotos {
+			removeUploadedFile(photo)
+		}
+		shipmentIDs := tx.Model(&models.Shipment{}).Select("shipment_id").Where("order_id = ?", orderID)
+		if err := tx.Where("shipment_id IN (?)", shipmentIDs).Delete(&models.ShipmentItem{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus item pengiriman: %w", err)
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.Shipment{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus pengiriman pesanan: %w", err)
+		}
+		rmaIDs := tx.Model(&models.ReturnRequest{}).Select("rma_id").Where("order_id = ?", orderID)
+		var returnPhotos []models.ReturnRequestPhoto
+		if err := tx.Where("rma_id IN (?)", rmaIDs).Find(&returnPhotos).Error; err != nil {
+			return fmt.Errorf("gagal mengambil foto retur: %w", err)
+		}
+		for _, photo := range returnPhotos {
+			if err := os.Remove(filepath.Join(".", photo.Image)); err != nil {
+				log.Printf("[Service DeleteOrder] Peringatan: Gagal menghapus foto retur %s: %v\n", photo.Image, err)
+			}
+		}
+		if err := tx.Where("rma_id IN (?)", rmaIDs).Delete(&models.ReturnRequestPhoto{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus foto retur: %w", err)
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.ReturnRequest{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus retur pesanan: %w", err)
+		}
+
+		if err := releaseOrderUnits(tx, orderID); err != nil {
+			return err
+		}
+		// Unit yang sudah dikirim tetap berstatus Shipped, hanya tautannya ke order yang dilepas
+		if err := tx.Model(&models.ProductUnit{}).Where("order_id = ?", orderID).
+			Updates(map[string]interface{}{"order_id": nil, "order_item_id": nil}).Error; err != nil {
+			return fmt.Errorf("gagal melepas unit pesanan: %w", err)
+		}
+
+		jobIDs := tx.Model(&models.InstallationJob{}).Select("job_id").Where("order_id = ?", orderID)
+		if err := tx.Where("job_id IN (?)", jobIDs).Delete(&models.InstallationChecklistItem{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus checklist instalasi: %w", err)
+		}
+		if err := tx.Where("job_id IN (?)", jobIDs).Delete(&models.InstallationJobTechnician{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus teknisi instalasi: %w", err)
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.InstallationJob{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus jadwal instalasi: %w", err)
+		}
+
+		// Garansi unit order ini beserta klaim dan fotonya ikut dihapus
+		warrantyIDs := tx.Model(&models.Warranty{}).Select("id").Where("order_id = ?", orderID)
+		claimIDs := tx.Model(&models.WarrantyClaim{}).Select("claim_id").Where("warranty_id IN (?)", warrantyIDs)
+		var claimPhotos []models.WarrantyClaimPhoto
+		if err := tx.Where("claim_id IN (?)", claimIDs).Find(&claimPhotos).Error; err != nil {
+			return fmt.Errorf("gagal mengambil foto klaim garansi: %w", err)
+		}
+		for _, photo := range claimPhotos {
+			if err := os.Remove(filepath.Join(".", photo.Image)); err != nil {
+				log.Printf("[Service DeleteOrder] Peringatan: Gagal menghapus foto klaim garansi %s: %v\n", photo.Image, err)
+			}
+		}
+		if err := tx.Where("claim_id IN (?)", claimIDs).Delete(&models.WarrantyClaimPhoto{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus foto klaim garansi: %w", err)
+		}
+		if err := tx.Where("warranty_id IN (?)", warrantyIDs).Delete(&models.WarrantyClaim{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus klaim garansi: %w", err)
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.Warranty{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus garansi: %w", err)
+		}
+
+		result := tx.Where("order_id = ?", orderID).Delete(&models.Order{})
+		if result.Error != nil {
+			return fmt.Errorf("gagal menghapus pesanan: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("pesanan tidak ditemukan saat mencoba menghapus")
+		}
+		return nil
+	})
+}
+
+func orderedPaymentMilestones(db *gorm.DB) *gorm.DB {
+	return db.Order("sequence ASC")
+}
+
+// SetPaymentSchedule mengganti jadwal termin order. Total termin harus sama dengan grand total;
+// selisih pembulatan dari persentase (maks. Rp 1) dibebankan ke termin terakhir.
+func (s *service) SetPaymentSchedule(orderID string, input SetPaymentScheduleInput) ([]models.PaymentMilestone, error) {
+	log.Printf("[Service SetPaymentSchedule] OrderID: %s, jumlah termin: %d\n", orderID, len(input.Milestones))
+
+	var milestones []models.PaymentMilestone
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Preload("PaymentMilestones").Where("order_id = ?", orderID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mencari pesanan: %w", err)
+		}
+		if order.OrderStatus == "Canceled" {
+			return errors.New("jadwal pembayaran tidak valid: pesanan sudah dibatalkan")
+		}
+		for _, existing := range order.PaymentMilestones {
+			if existing.Status == models.PaymentMilestonePaid || existing.Status == models.PaymentMilestoneAwaitingVerification {
+				return errors.New("jadwal pembayaran tidak bisa diubah karena sudah ada termin yang dibayar atau menunggu verifikasi")
+			}
+		}
+
+		milestones = make([]models.PaymentMilestone, 0, len(input.Milestones))
+		var total float64
+		for i, milestoneInput := range input.Milestones {
+			if (milestoneInput.Percentage == nil) == (milestoneInput.Amount == nil) {
+				return fmt.Errorf("jadwal pembayaran tidak valid: termin '%s' harus diisi persentase atau nominal (salah satu)", milestoneInput.Label)
+			}
+			dueDate, err := time.ParseInLocation("2006-01-02", milestoneInput.DueDate, time.Local)
+			if err != nil {
+				return fmt.Errorf("jadwal pembayaran tidak valid: tanggal jatuh tempo termin '%s' harus YYYY-MM-DD", milestoneInput.Label)
+			}
+
+			amount := 0.0
+			if milestoneInput.Percentage != nil {
+				amount = math.Round(order.GrandTotal*(*milestoneInput.Percentage)) / 100
+			} else {
+				amount = math.Round(*milestoneInput.Amount*100) / 100
+			}
+			total += amount
+			milestones = append(milestones, models.PaymentMilestone{
+				OrderID:    orderID,
+				Sequence:   i + 1,
+				Label:      milestoneInput.Label,
+				Percentage: milestoneInput.Percentage,
+				Amount:     amount,
+				DueDate:    dueDate,
+				Status:     models.PaymentMilestoneUnpaid,
+			})
+		}
+		if len(milestones) > 0 {
+			difference := math.Round((order.GrandTotal-total)*100) / 100
+			if math.Abs(difference) > 1 {
+				return fmt.Errorf("jadwal pembayaran tidak valid: total termin %.2f harus sama dengan total pesanan %.2f", total, order.GrandTotal)
+			}
+			milestones[len(milestones)-1].Amount += difference
+		}
+
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.PaymentMilestone{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus jadwal pembayaran lama: %w", err)
+		}
+		if len(milestones) > 0 {
+			if err := tx.Create(&milestones).Error; err != nil {
+				return fmt.Errorf("gagal menyimpan jadwal pembayaran: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service SetPaymentSchedule] Gagal untuk OrderID %s: %v\n", orderID, err)
+		return nil, err
+	}
+	return milestones, nil
+}
+
+// VerifyPaymentMilestone menyetujui atau menolak bukti bayar sebuah termin.
+// Termin pertama yang disetujui pada order yang masih menunggu akan memproses order tersebut.
+func (s *service) VerifyPaymentMilestone(orderID string, milestoneID uint, employeeID string, input VerifyPaymentMilestoneInput) (models.PaymentMilestone, error) {
+	var milestone models.PaymentMilestone
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND order_id = ?", milestoneID, orderID).First(&milestone).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("termin pembayaran tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil termin pembayaran: %w", err)
+		}
+		if milestone.Status != models.PaymentMilestoneAwaitingVerification {
+			return errors.New("termin pembayaran tidak sedang menunggu verifikasi")
+		}
+
+		now := time.Now()
+		milestone.VerifiedBy = employeeID
+		milestone.VerifiedAt = &now
+		if *input.Approved {
+			milestone.Status = models.PaymentMilestonePaid
+			milestone.RejectionReason = ""
+		} else {
+			if strings.TrimSpace(input.RejectionReason) == "" {
+				return errors.New("alasan penolakan wajib diisi")
+			}
+			milestone.Status = models.PaymentMilestoneRejected
+			milestone.RejectionReason = input.RejectionReason
+		}
+		if err := tx.Save(&milestone).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan verifikasi termin: %w", err)
+		}
+
+		if milestone.Status == models.PaymentMilestonePaid {
+			result := tx.Model(&models.Order{}).
+				Where("order_id = ? AND order_status IN ?", orderID, []string{"Pending", "Pending Confirmation"}).
+				Update("order_status", "Processed")
+			if result.Error != nil {
+				return fmt.Errorf("gagal memperbarui status pesanan: %w", result.Error)
+			}
+			if result.RowsAffected > 0 {
+				if err := recordOrderStatus(tx, orderID, "Processed", employeeID, "Pembayaran "+milestone.Label+" terverifikasi"); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service VerifyPaymentMilestone] Gagal untuk termin %d order %s: %v\n", milestoneID, orderID, err)
+		return models.PaymentMilestone{}, err
+	}
+	return milestone, nil
+}
+
+func (s *service) ListOverdueInstallments() ([]AdminOverdueInstallmentView, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	var rows []struct {
+		models.PaymentMilestone
+		CustomerID       string
+		CustomerFullname string
+		CustomerEmail    string
+		CustomerPhone    string
+	}
+	if err := s.db.Model(&models.PaymentMilestone{}).
+		Select("payment_milestones.*, orders.customer_id, orders.customer_fullname, orders.customer_email, orders.customer_phone").
+		Joins("JOIN orders ON orders.order_id = payment_milestones.order_id").
+		Where("payment_milestones.status <> ? AND payment_milestones.due_date < ?", models.PaymentMilestonePaid, today).
+		Where("orders.order_status <> ?", "Canceled").
+		Order("payment_milestones.due_date ASC, payment_milestones.order_id ASC").
+		Scan(&rows).Error; err != nil {
+		log.Printf("[Service ListOverdueInstallments] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil laporan termin jatuh tempo: %w", err)
+	}
+
+	report := make([]AdminOverdueInstallmentView, 0, len(rows))
+	for _, row := range rows {
+		dueDate := time.Date(row.DueDate.Year(), row.DueDate.Month(), row.DueDate.Day(), 0, 0, 0, 0, time.Local)
+		report = append(report, AdminOverdueInstallmentView{
+			OrderID:          row.OrderID,
+			CustomerID:       row.CustomerID,
+			CustomerFullname: row.CustomerFullname,
+			CustomerEmail:    row.CustomerEmail,
+			CustomerPhone:    row.CustomerPhone,
+			MilestoneID:      row.ID,
+			Label:            row.Label,
+			Amount:           row.Amount,
+			DueDate:          row.DueDate,
+			DaysOverdue:      int(today.Sub(dueDate).Hours() / 24),
+			Status:           row.Status,
+		})
+	}
+	return report, nil
+}
+
+func (s *service) ListCompanies() ([]AdminCompanyListView, error) {
+	var companies []AdminCompanyListView
+	if err := s.db.Table("companies co").
+		Select(`co.company_id, co.legal_name, co.npwp,
+			(SELECT COUNT(*) FROM company_members m WHERE m.company_id = co.company_id) AS member_count,
+			(SELECT COUNT(*) FROM orders o WHERE o.company_id = co.company_id AND o.order_status <> 'Canceled') AS total_orders,
+			(SELECT COALESCE(SUM(o.grand_total), 0) FROM orders o WHERE o.company_id = co.company_id AND o.order_status <> 'Canceled') AS total_spent`).
+		Order("co.legal_name ASC").
+		Scan(&companies).Error; err != nil {
+		log.Printf("[Service ListCompanies] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil daftar perusahaan: %w", err)
+	}
+	return companies, nil
+}
+
+func (s *service) GetCompanyDetail(companyID string) (AdminCompanyDetailView, error) {
+	var company models.Company
+	if err := s.db.Where("company_id = ?", companyID).First(&company).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return AdminCompanyDetailView{}, errors.New("perusahaan tidak ditemukan")
+		}
+		return AdminCompanyDetailView{}, fmt.Errorf("gagal mengambil perusahaan: %w", err)
+	}
+
+	detail := AdminCompanyDetailView{
+		CompanyID:         company.CompanyID,
+		LegalName:         company.LegalName,
+		NPWP:              company.NPWP,
+		BillingAddress:    company.BillingAddress,
+		ApprovalThreshold: company.ApprovalThreshold,
+		CreatedAt:         company.CreatedAt,
+		Members:           []AdminCompanyMemberView{},
+		Orders:            []AdminOrderListView{},
+	}
+	if err := s.db.Table("company_members m").
+		Select("m.customer_id, TRIM(COALESCE(d.first_name, '') || ' ' || COALESCE(d.last_name, '')) AS full_name, c.email, m.role, m.created_at AS joined_at").
+		Joins("JOIN customers c ON c.customer_id = m.customer_id").
+		Joins("LEFT JOIN customer_details d ON d.customer_id = m.customer_id").
+		Where("m.company_id = ?", companyID).
+		Order("m.created_at ASC").
+		Scan(&detail.Members).Error; err != nil {
+		return AdminCompanyDetailView{}, fmt.Errorf("gagal mengambil anggota perusahaan: %w", err)
+	}
+
+	var orders []models.Order
+	if err := s.db.Preload("OrderItems").Where("company_id = ?", companyID).Order("order_date_time DESC").Find(&orders).Error; err != nil {
+		return AdminCompanyDetailView{}, fmt.Errorf("gagal mengambil pesanan perusahaan: %w", err)
+	}
+	for _, order := range orders {
+		var firstImage string
+		if len(order.OrderItems) > 0 {
+			firstImage = order.OrderItems[0].ProductImageSnapshot
+		}
+		detail.Orders = append(detail.Orders, AdminOrderListView{
+			OrderID:          order.OrderID,
+			CustomerFullname: order.CustomerFullname,
+			OrderDateTime:    order.OrderDateTime,
+			PaymentMethod:    order.PaymentMethod,
+			OrderStatus:      order.OrderStatus,
+			GrandTotal:       order.GrandTotal,
+			FirstItemImage:   firstImage,
+			CompanyLegalName: order.CompanyLegalName,
+			EntryType:        models.OrderHistoryEntryOrder,
+		})
+	}
+	return detail, nil
+}
+
+func (s *service) serviceTicketQuery() *gorm.DB {
+	return s.db.Table("service_tickets t").
+		Select(`t.ticket_id, t.customer_id,
+			TRIM(COALESCE(d.first_name, '') || ' ' || COALESCE(d.last_name, '')) AS customer_fullname, c.email AS customer_email,
+			t.subject, t.category, t.priority, t.status, t.order_item_id, oi.order_id, t.product_sku, t.serial_number,
+			t.department_id, dep.department_name, t.assigned_to, e.full_name AS assigned_to_name,
+			t.last_message_at, t.resolved_at, t.closed_at, t.created_at, t.updated_at`).
+		Joins("LEFT JOIN customers c ON c.customer_id = t.customer_id").
+		Joins("LEFT JOIN customer_details d ON d.customer_id = t.customer_id").
+		Joins("LEFT JOIN order_items oi ON oi.order_item_id = t.order_item_id").
+		Joins("LEFT JOIN departments dep ON dep.department_id = t.department_id").
+		Joins("LEFT JOIN employees e ON e.employee_id = t.assigned_to")
+}
+
+// ListServiceTickets mengembalikan antrian tiket: prioritas tertinggi dulu, lalu yang paling lama menunggu.
+func (s *service) ListServiceTickets(query AdminServiceTicketQuery) ([]AdminServiceTicketView, error) {
+	tickets := []AdminServiceTicketView{}
+	db := s.serviceTicketQuery()
+	if query.Status != "" {
+		db = db.Where("t.status = ?", query.Status)
+	}
+	if query.Priority != "" {
+		db = db.Where("t.priority = ?", query.Priority)
+	}
+	if query.Category != "" {
+		db = db.Where("t.category = ?", query.Category)
+	}
+	if query.DepartmentID != "" {
+		db = db.Where("t.department_id = ?", query.DepartmentID)
+	}
+	if query.AssignedTo == "unassigned" {
+		db = db.Where("t.assigned_to IS NULL")
+	} else if query.AssignedTo != "" {
+		db = db.Where("t.assigned_to = ?", query.AssignedTo)
+	}
+	if err := db.Order(clause.OrderBy{Expression: clause.Expr{SQL: "CASE t.priority WHEN ? THEN 0 WHEN ? THEN 1 WHEN ? THEN 2 ELSE 3 END, t.last_message_at ASC",
+		Vars: []interface{}{models.ServiceTicketPriorityUrgent, models.ServiceTicketPriorityHigh, models.ServiceTicketPriorityNormal}}}).
+		Scan(&tickets).Error; err != nil {
+		log.Printf("[Service ListServiceTickets] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil tiket layanan: %w", err)
+	}
+	return tickets, nil
+}
+
+func (s *service) GetServiceTicket(ticketID string) (AdminServiceTicketView, error) {
+	var tickets []AdminServiceTicketView
+	if err := s.serviceTicketQuery().Where("t.ticket_id = ?", ticketID).Scan(&tickets).Error; err != nil {
+		return AdminServiceTicketView{}, fmt.Errorf("gagal mengambil tiket layanan: %w", err)
+	}
+	if len(tickets) == 0 {
+		return AdminServiceTicketView{}, errors.New("tiket layanan tidak ditemukan")
+	}
+	ticket := tickets[0]
+
+	var messages []models.ServiceTicketMessage
+	if err := s.db.Preload("Attachments").Where("ticket_id = ?", ticketID).Order("created_at ASC, id ASC").Find(&messages).Error; err != nil {
+		return AdminServiceTicketView{}, fmt.Errorf("gagal mengambil pesan tiket: %w", err)
+	}
+	var employees []models.Employee
+	if err := s.db.Select("employee_id", "full_name").
+		Where("employee_id IN (?)", s.db.Model(&models.ServiceTicketMessage{}).Select("sender_id").
+			Where("ticket_id = ? AND sender_type = ?", ticketID, models.ServiceTicketSenderEmployee)).
+		Find(&employees).Error; err != nil {
+		return AdminServiceTicketView{}, fmt.Errorf("gagal mengambil data karyawan: %w", err)
+	}
+	employeeNames := make(map[string]string, len(employees))
+	for _, employee := range employees {
+		employeeNames[employee.EmployeeID] = employee.FullName
+	}
+
+	ticket.Messages = make([]AdminServiceTicketMessageView, 0, len(messages))
+	for _, message := range messages {
+		messageView := AdminServiceTicketMessageView{
+			ID:          message.ID,
+			SenderType:  message.SenderType,
+			SenderID:    message.SenderID,
+			SenderName:  ticket.CustomerFullname,
+			Message:     message.Message,
+			Attachments: make([]AdminServiceTicketAttachmentView, 0, len(message.Attachments)),
+			CreatedAt:   message.CreatedAt,
+		}
+		if message.SenderType == models.ServiceTicketSenderEmployee {
+			messageView.SenderName = employeeNames[message.SenderID]
+		}
+		for _, attachment := range message.Attachments {
+			messageView.Attachments = append(messageView.Attachments, AdminServiceTicketAttachmentView{FileName: attachment.FileName, FilePath: attachment.FilePath})
+		}
+		ticket.Messages = append(ticket.Messages, messageView)
+	}
+	return ticket, nil
+}
+
+// findOpenServiceTicketForUpdate mengunci tiket untuk diubah; tiket yang sudah Closed tidak bisa diubah lagi.
+func findOpenServiceTicketForUpdate(tx *gorm.DB, ticketID string) (models.ServiceTicket, error) {
+	var ticket models.ServiceTicket
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("ticket_id = ?", ticketID).First(&ticket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ServiceTicket{}, errors.New("tiket layanan tidak ditemukan")
+		}
+		return models.ServiceTicket{}, fmt.Errorf("gagal mengambil tiket layanan: %w", err)
+	}
+	if ticket.Status == models.ServiceTicketClosed {
+		return models.ServiceTicket{}, errors.New("tiket layanan sudah ditutup")
+	}
+	return ticket, nil
+}
+
+func (s *service) AssignServiceTicket(ticketID string, input AssignServiceTicketInput) (AdminServiceTicketView, error) {
+	departmentID := strings.TrimSpace(input.DepartmentID)
+	employeeID := strings.TrimSpace(input.AssignedTo)
+	if departmentID == "" && employeeID == "" {
+		return AdminServiceTicketView{}, errors.New("penugasan tidak valid: departemen atau karyawan wajib diisi")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ticket, err := findOpenServiceTicketForUpdate(tx, ticketID)
+		if err != nil {
+			return err
+		}
+
+		if employeeID != "" {
+			var employee models.Employee
+			if err := tx.Select("employee_id", "department").Where("employee_id = ?", employeeID).First(&employee).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("penugasan tidak valid: karyawan tidak ditemukan")
+				}
+				return fmt.Errorf("gagal mengambil karyawan: %w", err)
+			}
+			if departmentID == "" {
+				departmentID = employee.Department
+			} else if employee.Department != departmentID {
+				return errors.New("penugasan tidak valid: karyawan bukan anggota departemen tersebut")
+			}
+		}
+		var departmentCount int64
+		if err := tx.Model(&models.Department{}).Where("department_id = ?", departmentID).Count(&departmentCount).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa departemen: %w", err)
+		}
+		if departmentCount == 0 {
+			return errors.New("penugasan tidak valid: departemen tidak ditemukan")
+		}
+
+		ticket.DepartmentID = &departmentID
+		ticket.AssignedTo = nil
+		if employeeID != "" {
+			ticket.AssignedTo = &employeeID
+			if ticket.Status == models.ServiceTicketOpen {
+				ticket.Status = models.ServiceTicketInProgress
+			}
+		}
+		return tx.Omit(clause.Associations).Save(&ticket).Error
+	})
+	if err != nil {
+		log.Printf("[Service AssignServiceTicket] Gagal untuk tiket %s: %v\n", ticketID, err)
+		return AdminServiceTicketView{}, err
+	}
+	return s.GetServiceTicket(ticketID)
+}
+
+func (s *service) UpdateServiceTicketStatus(ticketID string, input UpdateServiceTicketStatusInput) (AdminServiceTicketView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ticket, err := findOpenServiceTicketForUpdate(tx, ticketID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		ticket.Status = input.Status
+		switch input.Status {
+		case models.ServiceTicketResolved:
+			ticket.ResolvedAt = &now
+		case models.ServiceTicketClosed:
+			ticket.ClosedAt = &now
+		default:
+			ticket.ResolvedAt = nil
+		}
+		if input.Priority != "" {
+			ticket.Priority = input.Priority
+		}
+		return tx.Omit(clause.Associations).Save(&ticket).Error
+	})
+	if err != nil {
+		log.Printf("[Service UpdateServiceTicketStatus] Gagal untuk tiket %s: %v\n", ticketID, err)
+		return AdminServiceTicketView{}, err
+	}
+	return s.GetServiceTicket(ticketID)
+}
+
+func (s *service) AddServiceTicketMessage(ticketID, employeeID string, input AdminServiceTicketMessageInput, attachments []*multipart.FileHeader) (AdminServiceTicketView, error) {
+	var savedPaths []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ticket, err := findOpenServiceTicketForUpdate(tx, ticketID)
+		if err != nil {
+			return err
+		}
+
+		message := models.ServiceTicketMessage{
+			TicketID:   ticketID,
+			SenderType: models.ServiceTicketSenderEmployee,
+			SenderID:   employeeID,
+			Message:    strings.TrimSpace(input.Message),
+		}
+		for _, fileHeader := range attachments {
+			uniqueFilename := fmt.Sprintf("ticket_%s_%d_%s%s", ticketID, time.Now().UnixNano(), uuid.New().String()[:8], filepath.Ext(fileHeader.Filename))
+			savedPath, err := saveUploadedFile(fileHeader, serviceTicketUploadDir, uniqueFilename)
+			if err != nil {
+				return err
+			}
+			savedPaths = append(savedPaths, savedPath)
+			message.Attachments = append(message.Attachments, models.ServiceTicketAttachment{FileName: filepath.Base(fileHeader.Filename), FilePath: savedPath})
+		}
+		if err := tx.Create(&message).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan pesan tiket: %w", err)
+		}
+
+		if ticket.Status == models.ServiceTicketOpen {
+			ticket.Status = models.ServiceTicketInProgress
+		}
+		ticket.LastMessageAt = message.CreatedAt
+		return tx.Omit(clause.Associations).Save(&ticket).Error
+	})
+	if err != nil {
+		log.Printf("[Service AddServiceTicketMessage] Gagal untuk tiket %s: %v\n", ticketID, err)
+		for _, savedPath := range savedPaths {
+			removeUploadedFile(savedPath)
+		}
+		return AdminServiceTicketView{}, err
+	}
+	return s.GetServiceTicket(ticketID)
+}
+
+// toAdminInstallationJobViews melengkapi pekerjaan instalasi dengan data order dan nama teknisi.
+func (s *service) toAdminInstallationJobViews(jobs []models.InstallationJob) ([]AdminInstallationJobView, error) {
+	views := make([]AdminInstallationJobView, 0, len(jobs))
+	if len(jobs) == 0 {
+		return views, nil
+	}
+	orderIDs := make([]string, 0, len(jobs))
+	var employeeIDs []string
+	for _, job := range jobs {
+		orderIDs = append(orderIDs, job.OrderID)
+		for _, technician := range job.Technicians {
+			employeeIDs = append(employeeIDs, technician.EmployeeID)
+		}
+	}
+	var orders []models.Order
+	if err := s.db.Select("order_id", "order_status", "customer_fullname", "customer_phone").Where("order_id IN ?", orderIDs).Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil pesanan instalasi: %w", err)
+	}
+	ordersByID := make(map[string]models.Order, len(orders))
+	for _, order := range orders {
+		ordersByID[order.OrderID] = order
+	}
+	employeeNames := make(map[string]string)
+	if len(employeeIDs) > 0 {
+		var employees []models.Employee
+		if err := s.db.Select("employee_id", "full_name").Where("employee_id IN ?", employeeIDs).Find(&employees).Error; err != nil {
+			return nil, fmt.Errorf("gagal mengambil data teknisi: %w", err)
+		}
+		for _, employee := range employees {
+			employeeNames[employee.EmployeeID] = employee.FullName
+		}
+	}
+
+	for _, job := range jobs {
+		order := ordersByID[job.OrderID]
+		view := AdminInstallationJobView{
+			JobID:              job.JobID,
+			OrderID:            job.OrderID,
+			OrderStatus:        order.OrderStatus,
+			CustomerID:         job.CustomerID,
+			CustomerFullname:   order.CustomerFullname,
+			CustomerPhone:      order.CustomerPhone,
+			SiteAddress:        job.SiteAddress,
+			RequestedStartDate: job.RequestedStartDate,
+			RequestedEndDate:   job.RequestedEndDate,
+			ScheduledStart:     job.ScheduledStart,
+			ScheduledEnd:       job.ScheduledEnd,
+			Status:             job.Status,
+			Notes:              job.Notes,
+			SignedOffBy:        job.SignedOffBy,
+			SignOffNotes:       job.SignOffNotes,
+			CompletedBy:        job.CompletedBy,
+			CompletedAt:        job.CompletedAt,
+			Technicians:        make([]InstallationTechnicianView, 0, len(job.Technicians)),
+			ChecklistItems:     make([]InstallationChecklistItemView, 0, len(job.ChecklistItems)),
+			CreatedAt:          job.CreatedAt,
+			UpdatedAt:          job.UpdatedAt,
+		}
+		for _, technician := range job.Technicians {
+			view.Technicians = append(view.Technicians, InstallationTechnicianView{EmployeeID: technician.EmployeeID, FullName: employeeNames[technician.EmployeeID]})
+		}
+		for _, item := range job.ChecklistItems {
+			view.ChecklistItems = append(view.ChecklistItems, InstallationChecklistItemView{
+				ID:          item.ID,
+				Position:    item.Position,
+				Description: item.Description,
+				IsDone:      item.IsDone,
+				DoneBy:      item.DoneBy,
+				DoneAt:      item.DoneAt,
+			})
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+func preloadInstallationJobDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("Technicians", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("ChecklistItems", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, id ASC") })
+}
+
+func (s *service) GetInstallationJob(jobID string) (AdminInstallationJobView, error) {
+	var job models.InstallationJob
+	if err := preloadInstallationJobDetails(s.db).Where("job_id = ?", jobID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return AdminInstallationJobView{}, errors.New("pekerjaan instalasi tidak ditemukan")
+		}
+		return AdminInstallationJobView{}, fmt.Errorf("gagal mengambil pekerjaan instalasi: %w", err)
+	}
+	views, err := s.toAdminInstallationJobViews([]models.InstallationJob{job})
+	if err != nil {
+		return AdminInstallationJobView{}, err
+	}
+	return views[0], nil
+}
+
+// ListInstallationJobs untuk tampilan kalender: jika from/to diisi hanya pekerjaan terjadwal
+// yang jadwalnya beririsan dengan rentang tanggal tersebut yang dikembalikan.
+func (s *service) ListInstallationJobs(query AdminInstallationJobQuery) ([]AdminInstallationJobView, error) {
+	db := preloadInstallationJobDetails(s.db)
+	if query.From != "" {
+		from, _ := time.ParseInLocation("2006-01-02", query.From, time.Local)
+		db = db.Where("scheduled_end > ?", from)
+	}
+	if query.To != "" {
+		to, _ := time.ParseInLocation("2006-01-02", query.To, time.Local)
+		db = db.Where("scheduled_start < ?", to.AddDate(0, 0, 1))
+	}
+	if query.TechnicianID != "" {
+		db = db.Where("job_id IN (?)", s.db.Model(&models.InstallationJobTechnician{}).Select("job_id").Where("employee_id = ?", query.TechnicianID))
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	var jobs []models.InstallationJob
+	if err := db.Order("COALESCE(scheduled_start, requested_start_date) ASC").Find(&jobs).Error; err != nil {
+		log.Printf("[Service ListInstallationJobs] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil pekerjaan instalasi: %w", err)
+	}
+	return s.toAdminInstallationJobViews(jobs)
+}
+
+func (s *service) CreateInstallationJob(orderID string, input CreateInstallationJobInput) (AdminInstallationJobView, error) {
+	var jobID string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mencari pesanan: %w", err)
+		}
+		if !slices.Contains(models.InstallableOrderStatuses, order.OrderStatus) {
+			return fmt.Errorf("instalasi tidak valid: pesanan berstatus %s belum dapat dijadwalkan instalasi", order.OrderStatus)
+		}
+
+		var installableItems int64
+		if err := tx.Model(&models.OrderItem{}).
+			Joins("JOIN products ON products.product_sku = order_items.product_sku").
+			Where("order_items.order_id = ? AND products.requires_installation = ?", orderID, true).
+			Count(&installableItems).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa produk pesanan: %w", err)
+		}
+		if installableItems == 0 {
+			return errors.New("instalasi tidak valid: pesanan tidak memiliki produk yang memerlukan instalasi")
+		}
+		var activeJobs int64
+		if err := tx.Model(&models.InstallationJob{}).
+			Where("order_id = ? AND status IN ?", orderID, models.ActiveInstallationJobStatuses).
+			Count(&activeJobs).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa jadwal instalasi: %w", err)
+		}
+		if activeJobs > 0 {
+			return errors.New("pesanan sudah memiliki jadwal instalasi aktif")
+		}
+
+		startDate, _ := time.ParseInLocation("2006-01-02", input.RequestedStartDate, time.Local)
+		endDate, _ := time.ParseInLocation("2006-01-02", input.RequestedEndDate, time.Local)
+		if endDate.Before(startDate) {
+			return errors.New("instalasi tidak valid: tanggal akhir tidak boleh sebelum tanggal mulai")
+		}
+		siteAddress := strings.TrimSpace(input.SiteAddress)
+		if siteAddress == "" {
+			siteAddress = order.ShippingAddressSnapshot
+		}
+		if siteAddress == "" {
+			return errors.New("instalasi tidak valid: alamat lokasi wajib diisi")
+		}
+
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('installation_job_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID pekerjaan instalasi: %w", err)
+		}
+		job := models.InstallationJob{
+			JobID:              fmt.Sprintf("INS%05d", nextVal),
+			OrderID:            orderID,
+			CustomerID:         order.CustomerID,
+			SiteAddress:        siteAddress,
+			RequestedStartDate: startDate,
+			RequestedEndDate:   endDate,
+			Status:             models.InstallationJobRequested,
+			Notes:              strings.TrimSpace(input.Notes),
+		}
+		checklist := input.ChecklistItems
+		if len(checklist) == 0 {
+			checklist = models.DefaultInstallationChecklist
+		}
+		for i, description := range checklist {
+			job.ChecklistItems = append(job.ChecklistItems, models.InstallationChecklistItem{Position: i + 1, Description: strings.TrimSpace(description)})
+		}
+		if err := tx.Create(&job).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan pekerjaan instalasi: %w", err)
+		}
+		jobID = job.JobID
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service CreateInstallationJob] Gagal untuk order %s: %v\n", orderID, err)
+		return AdminInstallationJobView{}, err
+	}
+	return s.GetInstallationJob(jobID)
+}
+
+// findActiveInstallationJobForUpdate mengunci pekerjaan instalasi yang belum selesai / dibatalkan.
+func findActiveInstallationJobForUpdate(tx *gorm.DB, jobID string) (models.InstallationJob, error) {
+	var job models.InstallationJob
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("job_id = ?", jobID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.InstallationJob{}, errors.New("pekerjaan instalasi tidak ditemukan")
+		}
+		return models.InstallationJob{}, fmt.Errorf("gagal mengambil pekerjaan instalasi: %w", err)
+	}
+	if !slices.Contains(models.ActiveInstallationJobStatuses, job.Status) {
+		return models.InstallationJob{}, errors.New("pekerjaan instalasi sudah selesai atau dibatalkan")
+	}
+	return job, nil
+}
+
+// ScheduleInstallationJob menetapkan jadwal dan teknisi. Teknisi yang jadwalnya beririsan dengan
+// pekerjaan aktif lain ditolak; baris karyawan dikunci agar dua penjadwalan bersamaan tidak lolos.
+func (s *service) ScheduleInstallationJob(jobID string, input ScheduleInstallationJobInput) (AdminInstallationJobView, error) {
+	if !input.ScheduledEnd.After(input.ScheduledStart) {
+		return AdminInstallationJobView{}, errors.New("jadwal tidak valid: waktu selesai harus setelah waktu mulai")
+	}
+	technicianIDs := make([]string, 0, len(input.TechnicianIDs))
+	for _, technicianID := range input.TechnicianIDs {
+		technicianID = strings.TrimSpace(technicianID)
+		if !slices.Contains(technicianIDs, technicianID) {
+			technicianIDs = append(technicianIDs, technicianID)
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		job, err := findActiveInstallationJobForUpdate(tx, jobID)
+		if err != nil {
+			return err
+		}
+
+		var employees []models.Employee
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("employee_id").
+			Where("employee_id IN ?", technicianIDs).Order("employee_id ASC").Find(&employees).Error; err != nil {
+			return fmt.Errorf("gagal mengambil data teknisi: %w", err)
+		}
+		if len(employees) != len(technicianIDs) {
+			return errors.New("jadwal tidak valid: satu atau lebih teknisi tidak ditemukan")
+		}
+
+		var conflicts []struct {
+			EmployeeID     string
+			JobID          string
+			ScheduledStart time.Time
+			ScheduledEnd   time.Time
+		}
+		if err := tx.Table("installation_job_technicians t").
+			Select("t.employee_id, j.job_id, j.scheduled_start, j.scheduled_end").
+			Joins("JOIN installation_jobs j ON j.job_id = t.job_id").
+			Where("t.employee_id IN ? AND j.job_id <> ?", technicianIDs, jobID).
+			Where("j.status IN ?", []string{models.InstallationJobScheduled, models.InstallationJobInProgress}).
+			Where("j.scheduled_start < ? AND j.scheduled_end > ?", input.ScheduledEnd, input.ScheduledStart).
+			Order("j.scheduled_start ASC").
+			Scan(&conflicts).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa jadwal teknisi: %w", err)
+		}
+		if len(conflicts) > 0 {
+			conflict := conflicts[0]
+			return fmt.Errorf("jadwal bentrok: teknisi %s sudah dijadwalkan pada %s (%s - %s)", conflict.EmployeeID, conflict.JobID,
+				conflict.ScheduledStart.Format("02 Jan 2006 15:04"), conflict.ScheduledEnd.Format("02 Jan 2006 15:04"))
+		}
+
+		if err := tx.Where("job_id = ?", jobID).Delete(&models.InstallationJobTechnician{}).Error; err != nil {
+			return fmt.Errorf("gagal mengganti teknisi: %w", err)
+		}
+		technicians := make([]models.InstallationJobTechnician, 0, len(technicianIDs))
+		for _, technicianID := range technicianIDs {
+			technicians = append(technicians, models.InstallationJobTechnician{JobID: jobID, EmployeeID: technicianID})
+		}
+		if err := tx.Create(&technicians).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan teknisi: %w", err)
+		}
+
+		job.ScheduledStart = &input.ScheduledStart
+		job.ScheduledEnd = &input.ScheduledEnd
+		if job.Status == models.InstallationJobRequested {
+			job.Status = models.InstallationJobScheduled
+		}
+		return tx.Save(&job).Error
+	})
+	if err != nil {
+		log.Printf("[Service ScheduleInstallationJob] Gagal untuk %s: %v\n", jobID, err)
+		return AdminInstallationJobView{}, err
+	}
+	return s.GetInstallationJob(jobID)
+}
+
+func (s *service) SetInstallationChecklist(jobID string, input SetInstallationChecklistInput) (AdminInstallationJobView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := findActiveInstallationJobForUpdate(tx, jobID); err != nil {
+			return err
+		}
+		if err := tx.Where("job_id = ?", jobID).Delete(&models.InstallationChecklistItem{}).Error; err != nil {
+			return fmt.Errorf("gagal mengganti checklist: %w", err)
+		}
+		items := make([]models.InstallationChecklistItem, 0, len(input.Items))
+		for i, description := range input.Items {
+			items = append(items, models.InstallationChecklistItem{JobID: jobID, Position: i + 1, Description: strings.TrimSpace(description)})
+		}
+		return tx.Create(&items).Error
+	})
+	if err != nil {
+		return AdminInstallationJobView{}, err
+	}
+	return s.GetInstallationJob(jobID)
+}
+
+func (s *service) UpdateInstallationChecklistItem(jobID string, itemID uint, employeeID string, input UpdateInstallationChecklistItemInput) (AdminInstallationJobView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := findActiveInstallationJobForUpdate(tx, jobID); err != nil {
+			return err
+		}
+		var item models.InstallationChecklistItem
+		if err := tx.Where("id = ? AND job_id = ?", itemID, jobID).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("item checklist tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil item checklist: %w", err)
+		}
+		item.IsDone = *input.IsDone
+		item.DoneBy = ""
+		item.DoneAt = nil
+		if item.IsDone {
+			now := time.Now()
+			item.DoneBy = employeeID
+			item.DoneAt = &now
+		}
+		return tx.Save(&item).Error
+	})
+	if err != nil {
+		return AdminInstallationJobView{}, err
+	}
+	return s.GetInstallationJob(jobID)
+}
+
+func (s *service) UpdateInstallationJobStatus(jobID string, input UpdateInstallationJobStatusInput) (AdminInstallationJobView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		job, err := findActiveInstallationJobForUpdate(tx, jobID)
+		if err != nil {
+			return err
+		}
+		if input.Status == models.InstallationJobInProgress && job.Status != models.InstallationJobScheduled {
+			return errors.New("pekerjaan instalasi belum dijadwalkan")
+		}
+		job.Status = input.Status
+		return tx.Save(&job).Error
+	})
+	if err != nil {
+		return AdminInstallationJobView{}, err
+	}
+	return s.GetInstallationJob(jobID)
+}
+
+// CompleteInstallationJob mencatat serah terima hasil instalasi dan menyelesaikan order-nya jika sudah Shipped.
+func (s *service) CompleteInstallationJob(jobID, employeeID string, input CompleteInstallationJobInput) (AdminInstallationJobView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		job, err := findActiveInstallationJobForUpdate(tx, jobID)
+		if err != nil {
+			return err
+		}
+		if job.Status == models.InstallationJobRequested {
+			return errors.New("pekerjaan instalasi belum dijadwalkan")
+		}
+		var pendingItems int64
+		if err := tx.Model(&models.InstallationChecklistItem{}).Where("job_id = ? AND is_done = ?", jobID, false).Count(&pendingItems).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa checklist: %w", err)
+		}
+		if pendingItems > 0 {
+			return fmt.Errorf("checklist instalasi belum selesai: %d item tersisa", pendingItems)
+		}
+
+		now := time.Now()
+		job.Status = models.InstallationJobCompleted
+		job.SignedOffBy = strings.TrimSpace(input.SignedOffBy)
+		job.SignOffNotes = strings.TrimSpace(input.SignOffNotes)
+		job.CompletedBy = employeeID
+		job.CompletedAt = &now
+		if err := tx.Save(&job).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan serah terima instalasi: %w", err)
+		}
+
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", job.OrderID).First(&order).Error; err != nil {
+			return fmt.Errorf("gagal mengambil pesanan instalasi: %w", err)
+		}
+		// Order yang belum dikirim tidak diselesaikan lewat instalasi agar verifikasi pembayaran,
+		// pengiriman unit dan pembuatan garansi tidak terlewati
+		if order.OrderStatus != "Shipped" {
+			return nil
+		}
+		return applyOrderStatus(tx, &order, "Completed", nil, employeeID, "Instalasi selesai, diterima oleh "+strings.TrimSpace(input.SignedOffBy))
+	})
+	if err != nil {
+		log.Printf("[Service CompleteInstallationJob] Gagal untuk %s: %v\n", jobID, err)
+		return AdminInstallationJobView{}, err
+	}
+	log.Printf("[Service CompleteInstallationJob] Instalasi %s selesai, ditandatangani oleh %s.\n", jobID, input.SignedOffBy)
+	return s.GetInstallationJob(jobID)
+}
+
+func (s *service) AddRentalUnits(productSKU string, input AddRentalUnitsInput) ([]models.RentalUnit, error) {
+	var units []models.RentalUnit
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := tx.Where("product_sku = ?", productSKU).First(&product).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("produk tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil produk: %w", err)
+		}
+		if !product.IsRentable {
+			return errors.New("unit sewa tidak valid: produk tidak ditandai dapat disewa")
+		}
+
+		unitCodes := make([]string, 0, len(input.UnitCodes))
+		for _, unitCode := range input.UnitCodes {
+			unitCode = strings.TrimSpace(unitCode)
+			if slices.Contains(unitCodes, unitCode) {
+				return fmt.Errorf("unit sewa tidak valid: kode unit %s dikirim lebih dari sekali", unitCode)
+			}
+			unitCodes = append(unitCodes, unitCode)
+		}
+		var existing []string
+		if err := tx.Model(&models.RentalUnit{}).Where("unit_code IN ?", unitCodes).Pluck("unit_code", &existing).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa kode unit: %w", err)
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("unit sewa tidak valid: kode unit %s sudah terdaftar", strings.Join(existing, ", "))
+		}
+
+		for _, unitCode := range unitCodes {
+			units = append(units, models.RentalUnit{
+				ProductSKU: productSKU,
+				UnitCode:   unitCode,
+				Status:     models.RentalUnitAvailable,
+				Notes:      strings.TrimSpace(input.Notes),
+			})
+		}
+		if err := tx.Create(&units).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan unit sewa: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service AddRentalUnits] Gagal untuk produk %s: %v\n", productSKU, err)
+		return nil, err
+	}
+	return units, nil
+}
+
+func (s *service) ListRentalUnits(productSKU string) ([]models.RentalUnit, error) {
+	var units []models.RentalUnit
+	if err := s.db.Where("product_sku = ?", productSKU).Order("unit_code ASC").Find(&units).Error; err != nil {
+		log.Printf("[Service ListRentalUnits] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil unit sewa: %w", err)
+	}
+	return units, nil
+}
+
+// UpdateRentalUnit mengubah status unit. Unit yang masih memiliki booking aktif tidak dapat dipensiunkan;
+// status Maintenance tetap diizinkan agar unit tidak menerima booking baru.
+func (s *service) UpdateRentalUnit(unitID uint, input UpdateRentalUnitInput) (models.RentalUnit, error) {
+	var unit models.RentalUnit
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", unitID).First(&unit).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("unit sewa tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil unit sewa: %w", err)
+		}
+		if input.Status == models.RentalUnitRetired {
+			var activeBookings int64
+			if err := tx.Model(&models.RentalBooking{}).
+				Where("rental_unit_id = ? AND status IN ?", unitID, models.ActiveRentalBookingStatuses).
+				Count(&activeBookings).Error; err != nil {
+				return fmt.Errorf("gagal memeriksa booking unit sewa: %w", err)
+			}
+			if activeBookings > 0 {
+				return errors.New("unit sewa masih memiliki booking aktif")
+			}
+		}
+		unit.Status = input.Status
+		unit.Notes = strings.TrimSpace(input.Notes)
+		return tx.Save(&unit).Error
+	})
+	if err != nil {
+		log.Printf("[Service UpdateRentalUnit] Gagal untuk unit %d: %v\n", unitID, err)
+		return models.RentalUnit{}, err
+	}
+	return unit, nil
+}
+
+func toAdminRentalBookingView(booking models.RentalBooking) AdminRentalBookingView {
+	return AdminRentalBookingView{
+		BookingID:               booking.BookingID,
+		CustomerID:              booking.CustomerID,
+		CustomerFullname:        booking.CustomerFullname,
+		CustomerEmail:           booking.CustomerEmail,
+		CustomerPhone:           booking.CustomerPhone,
+		RentalUnitID:            booking.RentalUnitID,
+		UnitCode:                booking.RentalUnit.UnitCode,
+		ProductSKU:              booking.ProductSKU,
+		ProductTitleSnapshot:    booking.ProductTitleSnapshot,
+		StartDate:               booking.StartDate,
+		EndDate:                 booking.EndDate,
+		RentalDays:              booking.RentalDays,
+		RentalPrice:             booking.RentalPrice,
+		Deposit:                 booking.Deposit,
+		Status:                  booking.Status,
+		ShippingAddressSnapshot: booking.ShippingAddressSnapshot,
+		Notes:                   booking.Notes,
+		HandedOverAt:            booking.HandedOverAt,
+		ReturnedAt:              booking.ReturnedAt,
+		ReturnCondition:         booking.ReturnCondition,
+		InspectionNotes:         booking.InspectionNotes,
+		DamageCharge:            booking.DamageCharge,
+		DepositRefund:           booking.DepositRefund,
+		InspectedBy:             booking.InspectedBy,
+		CanceledAt:              booking.CanceledAt,
+		CreatedAt:               booking.CreatedAt,
+		UpdatedAt:               booking.UpdatedAt,
+	}
+}
+
+func (s *service) ListRentalBookings(query AdminRentalBookingQuery) ([]AdminRentalBookingView, error) {
+	db := s.db.Preload("RentalUnit")
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.CustomerID != "" {
+		db = db.Where("customer_id = ?", query.CustomerID)
+	}
+	if query.ProductSKU != "" {
+		db = db.Where("product_sku = ?", query.ProductSKU)
+	}
+	if query.RentalUnitID != 0 {
+		db = db.Where("rental_unit_id = ?", query.RentalUnitID)
+	}
+	if query.From != "" {
+		db = db.Where("end_date >= ?", query.From)
+	}
+	if query.To != "" {
+		db = db.Where("start_date <= ?", query.To)
+	}
+
+	var bookings []models.RentalBooking
+	if err := db.Order("start_date DESC, booking_id DESC").Find(&bookings).Error; err != nil {
+		log.Printf("[Service ListRentalBookings] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil booking sewa: %w", err)
+	}
+	views := make([]AdminRentalBookingView, 0, len(bookings))
+	for _, booking := range bookings {
+		views = append(views, toAdminRentalBookingView(booking))
+	}
+	return views, nil
+}
+
+func (s *service) GetRentalBooking(bookingID string) (AdminRentalBookingView, error) {
+	var booking models.RentalBooking
+	if err := s.db.Preload("RentalUnit").Where("booking_id = ?", bookingID).First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return AdminRentalBookingView{}, errors.New("booking sewa tidak ditemukan")
+		}
+		return AdminRentalBookingView{}, fmt.Errorf("gagal mengambil booking sewa: %w", err)
+	}
+	return toAdminRentalBookingView(booking), nil
+}
+
+func findRentalBookingForUpdate(tx *gorm.DB, bookingID string) (models.RentalBooking, error) {
+	var booking models.RentalBooking
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("booking_id = ?", bookingID).First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.RentalBooking{}, errors.New("booking sewa tidak ditemukan")
+		}
+		return models.RentalBooking{}, fmt.Errorf("gagal mengambil booking sewa: %w", err)
+	}
+	return booking, nil
+}
+
+// Perubahan status booking yang boleh dilakukan admin; Returned hanya lewat inspeksi pengembalian
+var rentalBookingTransitions = map[string][]string{
+	models.RentalBookingPending:   {models.RentalBookingConfirmed, models.RentalBookingCanceled},
+	models.RentalBookingConfirmed: {models.RentalBookingOnRent, models.RentalBookingCanceled},
+}
+
+func (s *service) UpdateRentalBookingStatus(bookingID string, input UpdateRentalBookingStatusInput) (AdminRentalBookingView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := findRentalBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(rentalBookingTransitions[booking.Status], input.Status) {
+			return fmt.Errorf("status booking tidak valid: tidak dapat mengubah %s menjadi %s", booking.Status, input.Status)
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"status": input.Status}
+		switch input.Status {
+		case models.RentalBookingOnRent:
+			var unit models.RentalUnit
+			if err := tx.Where("id = ?", booking.RentalUnitID).First(&unit).Error; err != nil {
+				return fmt.Errorf("gagal mengambil unit sewa: %w", err)
+			}
+			if unit.Status != models.RentalUnitAvailable {
+				return fmt.Errorf("status booking tidak valid: unit %s sedang %s", unit.UnitCode, unit.Status)
+			}
+			updates["handed_over_at"] = now
+		case models.RentalBookingCanceled:
+			updates["canceled_at"] = now
+		}
+		return tx.Model(&booking).Updates(updates).Error
+	})
+	if err != nil {
+		log.Printf("[Service UpdateRentalBookingStatus] Gagal untuk %s: %v\n", bookingID, err)
+		return AdminRentalBookingView{}, err
+	}
+	return s.GetRentalBooking(bookingID)
+}
+
+// InspectRentalReturn mencatat hasil inspeksi unit yang dikembalikan. Biaya kerusakan dipotong dari
+// deposit; sisa deposit yang dikembalikan tidak pernah negatif.
+func (s *service) InspectRentalReturn(bookingID, employeeID string, input RentalReturnInspectionInput) (AdminRentalBookingView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		booking, err := findRentalBookingForUpdate(tx, bookingID)
+		if err != nil {
+			return err
+		}
+		if booking.Status != models.RentalBookingOnRent {
+			return errors.New("status booking tidak valid: hanya booking yang sedang disewa yang dapat diinspeksi")
+		}
+		inspectionNotes := strings.TrimSpace(input.InspectionNotes)
+		if input.ReturnCondition != "Good" && inspectionNotes == "" {
+			return errors.New("status booking tidak valid: catatan inspeksi wajib diisi jika unit rusak")
+		}
+
+		unitStatus := input.UnitStatus
+		if unitStatus == "" {
+			unitStatus = models.RentalUnitAvailable
+			if input.ReturnCondition == "Major Damage" {
+				unitStatus = models.RentalUnitMaintenance
+			}
+		}
+		if err := tx.Model(&models.RentalUnit{}).Where("id = ?", booking.RentalUnitID).Update("status", unitStatus).Error; err != nil {
+			return fmt.Errorf("gagal memperbarui status unit sewa: %w", err)
+		}
+
+		now := time.Now()
+		booking.Status = models.RentalBookingReturned
+		booking.ReturnedAt = &now
+		booking.ReturnCondition = input.ReturnCondition
+		booking.InspectionNotes = inspectionNotes
+		booking.DamageCharge = input.DamageCharge
+		booking.DepositRefund = max(booking.Deposit-input.DamageCharge, 0)
+		booking.InspectedBy = employeeID
+		return tx.Omit(clause.Associations).Save(&booking).Error
+	})
+	if err != nil {
+		log.Printf("[Service InspectRentalReturn] Gagal untuk %s: %v\n", bookingID, err)
+		return AdminRentalBookingView{}, err
+	}
+	return s.GetRentalBooking(bookingID)
+}
+
+func (s *service) refundQuery() *gorm.DB {
+	return s.db.Table("refunds r").
+		Select("r.*, o.customer_fullname, o.customer_email, o.customer_phone").
+		Joins("JOIN orders o ON o.order_id = r.order_id")
+}
+
+// ListRefunds mengembalikan antrean refund: yang belum diproses paling atas, lalu yang terlama.
+func (s *service) ListRefunds(statusFilter string) ([]AdminRefundView, error) {
+	query := s.refundQuery()
+	if statusFilter != "" {
+		query = query.Where("r.status = ?", statusFilter)
+	}
+	refunds := []AdminRefundView{}
+	if err := query.
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "CASE r.status WHEN ? THEN 0 WHEN ? THEN 1 ELSE 2 END, r.created_at ASC",
+			Vars: []interface{}{models.RefundPending, models.RefundProcessing}}}).
+		Scan(&refunds).Error; err != nil {
+		log.Printf("[Service ListRefunds] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil daftar refund: %w", err)
+	}
+	return refunds, nil
+}
+
+func (s *service) GetRefund(refundID string) (AdminRefundView, error) {
+	var refunds []AdminRefundView
+	if err := s.refundQuery().Where("r.refund_id = ?", refundID).Scan(&refunds).Error; err != nil {
+		return AdminRefundView{}, fmt.Errorf("gagal mengambil refund: %w", err)
+	}
+	if len(refunds) == 0 {
+		return AdminRefundView{}, errors.New("refund tidak ditemukan")
+	}
+	return refunds[0], nil
+}
+
+// Perubahan status refund yang diizinkan; Paid bersifat final
+var refundTransitions = map[string][]string{
+	models.RefundPending:    {models.RefundProcessing, models.RefundPaid},
+	models.RefundProcessing: {models.RefundPaid},
+}
+
+func (s *service) UpdateRefund(refundID, employeeID string, input UpdateRefundInput) (AdminRefundView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var refund models.Refund
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("refund_id = ?", refundID).First(&refund).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("refund tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil refund: %w", err)
+		}
+		if !slices.Contains(refundTransitions[refund.Status], input.Status) {
+			return fmt.Errorf("status refund tidak valid: tidak dapat mengubah %s menjadi %s", refund.Status, input.Status)
+		}
+		paymentReference := strings.TrimSpace(input.PaymentReference)
+		if input.Status == models.RefundPaid {
+			if paymentReference == "" {
+				return errors.New("status refund tidak valid: nomor referensi pembayaran wajib diisi")
+			}
+			now := time.Now()
+			refund.PaymentReference = paymentReference
+			refund.PaidAt = &now
+		}
+		refund.Status = input.Status
+		if notes := strings.TrimSpace(input.AdminNotes); notes != "" {
+			refund.AdminNotes = notes
+		}
+		refund.ProcessedBy = employeeID
+		return tx.Save(&refund).Error
+	})
+	if err != nil {
+		log.Printf("[Service UpdateRefund] Gagal untuk %s: %v\n", refundID, err)
+		return AdminRefundView{}, err
+	}
+	return s.GetRefund(refundID)
+}
+
+// loadAdminReturnRequestViews melengkapi retur dengan data customer dari order, harga item dan refund-nya.
+func (s *service) loadAdminReturnRequestViews(returnRequests []models.ReturnRequest) ([]AdminReturnRequestView, error) {
+	views := make([]AdminReturnRequestView, 0, len(returnRequests))
+	if len(returnRequests) == 0 {
+		return views, nil
+	}
+	orderIDs := make([]string, 0, len(returnRequests))
+	orderItemIDs := make([]uint, 0, len(returnRequests))
+	rmaIDs := make([]string, 0, len(returnRequests))
+	for _, returnRequest := range returnRequests {
+		orderIDs = append(orderIDs, returnRequest.OrderID)
+		orderItemIDs = append(orderItemIDs, returnRequest.OrderItemID)
+		rmaIDs = append(rmaIDs, returnRequest.RMAID)
+	}
+
+	var orders []models.Order
+	if err := s.db.Select("order_id", "customer_fullname", "customer_email", "customer_phone").
+		Where("order_id IN ?", orderIDs).Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil pesanan retur: %w", err)
+	}
+	ordersByID := make(map[string]models.Order, len(orders))
+	for _, order := range orders {
+		ordersByID[order.OrderID] = order
+	}
+	var items []models.OrderItem
+	if err := s.db.Select("order_item_id", "price_at_order").Where("order_item_id IN ?", orderItemIDs).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil item retur: %w", err)
+	}
+	pricesByItem := make(map[uint]float64, len(items))
+	for _, item := range items {
+		pricesByItem[item.OrderItemID] = item.PriceAtOrder
+	}
+	var refunds []models.Refund
+	if err := s.db.Where("rma_id IN ?", rmaIDs).Find(&refunds).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil refund retur: %w", err)
+	}
+	refundsByRMA := make(map[string]models.Refund, len(refunds))
+	for _, refund := range refunds {
+		refundsByRMA[*refund.RMAID] = refund
+	}
+
+	for _, returnRequest := range returnRequests {
+		order := ordersByID[returnRequest.OrderID]
+		view := AdminReturnRequestView{
+			RMAID:                returnRequest.RMAID,
+			OrderID:              returnRequest.OrderID,
+			OrderItemID:          returnRequest.OrderItemID,
+			CustomerID:           returnRequest.CustomerID,
+			CustomerFullname:     order.CustomerFullname,
+			CustomerEmail:        order.CustomerEmail,
+			CustomerPhone:        order.CustomerPhone,
+			ProductSKU:           returnRequest.ProductSKU,
+			ProductTitleSnapshot: returnRequest.ProductTitleSnapshot,
+			Quantity:             returnRequest.Quantity,
+			PriceAtOrder:         pricesByItem[returnRequest.OrderItemID],
+			Reason:               returnRequest.Reason,
+			ReturnMethod:         returnRequest.ReturnMethod,
+			Status:               returnRequest.Status,
+			BankName:             returnRequest.BankName,
+			BankAccountNumber:    returnRequest.BankAccountNumber,
+			BankAccountHolder:    returnRequest.BankAccountHolder,
+			AdminNotes:           returnRequest.AdminNotes,
+			ReviewedBy:           returnRequest.ReviewedBy,
+			ReceivedAt:           returnRequest.ReceivedAt,
+			ReceivedBy:           returnRequest.ReceivedBy,
+			IsResellable:         returnRequest.IsResellable,
+			RestockedQuantity:    returnRequest.RestockedQuantity,
+			InspectionNotes:      returnRequest.InspectionNotes,
+			InspectedBy:          returnRequest.InspectedBy,
+			InspectedAt:          returnRequest.InspectedAt,
+			Resolution:           returnRequest.Resolution,
+			ResolutionNotes:      returnRequest.ResolutionNotes,
+			ResolvedBy:           returnRequest.ResolvedBy,
+			ResolvedAt:           returnRequest.ResolvedAt,
+			Photos:               make([]string, 0, len(returnRequest.Photos)),
+			CreatedAt:            returnRequest.CreatedAt,
+			UpdatedAt:            returnRequest.UpdatedAt,
+		}
+		if refund, ok := refundsByRMA[returnRequest.RMAID]; ok {
+			view.Refund = &refund
+		}
+		for _, photo := range returnRequest.Photos {
+			view.Photos = append(view.Photos, photo.Image)
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// ListReturnRequests mengembalikan antrean retur: yang menunggu tindakan admin paling atas, lalu yang terlama.
+func (s *service) ListReturnRequests(query AdminReturnRequestQuery) ([]AdminReturnRequestView, error) {
+	dbQuery := s.db.Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") })
+	if query.Status != "" {
+		dbQuery = dbQuery.Where("status = ?", query.Status)
+	}
+	if query.CustomerID != "" {
+		dbQuery = dbQuery.Where("customer_id = ?", query.CustomerID)
+	}
+	if query.OrderID != "" {
+		dbQuery = dbQuery.Where("order_id = ?", query.OrderID)
+	}
+	var returnRequests []models.ReturnRequest
+	if err := dbQuery.
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "CASE WHEN status IN ? THEN 1 ELSE 0 END, created_at ASC",
+			Vars: []interface{}{[]string{models.ReturnRejected, models.ReturnResolved}}}}).
+		Find(&returnRequests).Error; err != nil {
+		log.Printf("[Service ListReturnRequests] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil daftar retur: %w", err)
+	}
+	return s.loadAdminReturnRequestViews(returnRequests)
+}
+
+func (s *service) GetReturnRequest(rmaID string) (AdminReturnRequestView, error) {
+	var returnRequest models.ReturnRequest
+	if err := s.db.Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("rma_id = ?", rmaID).First(&returnRequest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return AdminReturnRequestView{}, errors.New("retur tidak ditemukan")
+		}
+		return AdminReturnRequestView{}, fmt.Errorf("gagal mengambil retur: %w", err)
+	}
+	views, err := s.loadAdminReturnRequestViews([]models.ReturnRequest{returnRequest})
+	if err != nil {
+		return AdminReturnRequestView{}, err
+	}
+	return views[0], nil
+}
+
+func findReturnRequestForUpdate(tx *gorm.DB, rmaID string) (models.ReturnRequest, error) {
+	var returnRequest models.ReturnRequest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("rma_id = ?", rmaID).First(&returnRequest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return returnRequest, errors.New("retur tidak ditemukan")
+		}
+		return returnRequest, fmt.Errorf("gagal mengambil retur: %w", err)
+	}
+	return returnRequest, nil
+}
+
+// ReviewReturnRequest menyetujui atau menolak pengajuan retur; penolakan wajib disertai alasan.
+func (s *service) ReviewReturnRequest(rmaID, employeeID string, input ReviewReturnRequestInput) (AdminReturnRequestView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		returnRequest, err := findReturnRequestForUpdate(tx, rmaID)
+		if err != nil {
+			return err
+		}
+		if returnRequest.Status != models.ReturnRequested {
+			return fmt.Errorf("status retur tidak valid: retur sudah berstatus %s", returnRequest.Status)
+		}
+		adminNotes := strings.TrimSpace(input.AdminNotes)
+		returnRequest.Status = models.ReturnApproved
+		if !*input.Approved {
+			if adminNotes == "" {
+				return errors.New("status retur tidak valid: alasan penolakan wajib diisi")
+			}
+			returnRequest.Status = models.ReturnRejected
+		}
+		returnRequest.AdminNotes = adminNotes
+		returnRequest.ReviewedBy = employeeID
+		return tx.Omit(clause.Associations).Save(&returnRequest).Error
+	})
+	if err != nil {
+		log.Printf("[Service ReviewReturnRequest] Gagal untuk %s: %v\n", rmaID, err)
+		return AdminReturnRequestView{}, err
+	}
+	return s.GetReturnRequest(rmaID)
+}
+
+// ReceiveReturnRequest mencatat bahwa barang retur yang sudah disetujui telah diterima gudang.
+func (s *service) ReceiveReturnRequest(rmaID, employeeID string) (AdminReturnRequestView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		returnRequest, err := findReturnRequestForUpdate(tx, rmaID)
+		if err != nil {
+			return err
+		}
+		if returnRequest.Status != models.ReturnApproved {
+			return fmt.Errorf("status retur tidak valid: hanya retur Approved yang dapat diterima, status saat ini %s", returnRequest.Status)
+		}
+		now := time.Now()
+		returnRequest.Status = models.ReturnReceived
+		returnRequest.ReceivedAt = &now
+		returnRequest.ReceivedBy = employeeID
+		return tx.Omit(clause.Associations).Save(&returnRequest).Error
+	})
+	if err != nil {
+		log.Printf("[Service ReceiveReturnRequest] Gagal untuk %s: %v\n", rmaID, err)
+		return AdminReturnRequestView{}, err
+	}
+	return s.GetReturnRequest(rmaID)
+}
+
+// recordStockMovement mengubah stok produk sebesar quantity dan mencatat mutasinya.
+func recordStockMovement(tx *gorm.DB, productSKU string, quantity int, reason, referenceID, notes, employeeID string) error {
+	if err := tx.Model(&models.Product{}).Where("product_sku = ?", productSKU).
+		Update("stock", gorm.Expr("stock + ?", quantity)).Error; err != nil {
+		return fmt.Errorf("gagal memperbarui stok produk %s: %w", productSKU, err)
+	}
+	movement := models.StockMovement{
+		ProductSKU:  productSKU,
+		Quantity:    quantity,
+		Reason:      reason,
+		ReferenceID: referenceID,
+		Notes:       notes,
+		CreatedBy:   employeeID,
+	}
+	if err := tx.Create(&movement).Error; err != nil {
+		return fmt.Errorf("gagal mencatat mutasi stok: %w", err)
+	}
+	return nil
+}
+
+// loadReturnSerialUnits mengunci unit berseri yang disebut admin dan memastikan jumlah serta statusnya sesuai.
+func loadReturnSerialUnits(tx *gorm.DB, rawSerials []string, quantity int, scope func(*gorm.DB) *gorm.DB) ([]models.ProductUnit, error) {
+	serials := make([]string, 0, len(rawSerials))
+	seen := make(map[string]bool)
+	for _, rawSerial := range rawSerials {
+		serial := strings.TrimSpace(rawSerial)
+		if serial == "" || seen[serial] {
+			return nil, fmt.Errorf("nomor seri tidak valid: nomor seri %q kosong atau duplikat", rawSerial)
+		}
+		seen[serial] = true
+		serials = append(serials, serial)
+	}
+	if len(serials) != quantity {
+		return nil, fmt.Errorf("nomor seri tidak valid: dibutuhkan %d nomor seri, diterima %d", quantity, len(serials))
+	}
+	var units []models.ProductUnit
+	if err := scope(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("serial_number IN ?", serials)).Find(&units).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil unit berseri: %w", err)
+	}
+	if len(units) != len(serials) {
+		return nil, errors.New("nomor seri tidak valid: sebagian nomor seri tidak ditemukan atau tidak sesuai dengan retur ini")
+	}
+	return units, nil
+}
+
+// InspectReturnRequest mencatat hasil inspeksi barang retur. Barang yang masih layak jual dikembalikan
+// ke stok dan dicatat sebagai mutasi stok.
+func (s *service) InspectReturnRequest(rmaID, employeeID string, input InspectReturnRequestInput) (AdminReturnRequestView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		returnRequest, err := findReturnRequestForUpdate(tx, rmaID)
+		if err != nil {
+			return err
+		}
+		if returnRequest.Status != models.ReturnReceived {
+			return fmt.Errorf("status retur tidak valid: hanya retur Received yang dapat diinspeksi, status saat ini %s", returnRequest.Status)
+		}
+		inspectionNotes := strings.TrimSpace(input.InspectionNotes)
+
+		if *input.IsResellable {
+			var product models.Product
+			if err := tx.Select("product_sku", "is_serialized").Where("product_sku = ?", returnRequest.ProductSKU).First(&product).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("status retur tidak valid: produk sudah dihapus sehingga barang tidak dapat dikembalikan ke stok")
+				}
+				return fmt.Errorf("gagal mengambil produk: %w", err)
+			}
+			if product.IsSerialized {
+				units, err := loadReturnSerialUnits(tx, input.SerialNumbers, returnRequest.Quantity, func(db *gorm.DB) *gorm.DB {
+					return db.Where("order_item_id = ? AND status = ?", returnRequest.OrderItemID, models.ProductUnitShipped)
+				})
+				if err != nil {
+					return err
+				}
+				unitIDs := make([]uint, 0, len(units))
+				for _, unit := range units {
+					unitIDs = append(unitIDs, unit.ID)
+				}
+				if err := tx.Model(&models.ProductUnit{}).Where("id IN ?", unitIDs).
+					Updates(map[string]interface{}{"status": models.ProductUnitInStock, "order_id": nil, "order_item_id": nil, "shipped_at": nil}).Error; err != nil {
+					return fmt.Errorf("gagal mengembalikan unit berseri ke stok: %w", err)
+				}
+			}
+			if err := recordStockMovement(tx, returnRequest.ProductSKU, returnRequest.Quantity, models.StockMovementReturn,
+				returnRequest.RMAID, inspectionNotes, employeeID); err != nil {
+				return err
+			}
+			returnRequest.RestockedQuantity = returnRequest.Quantity
+		}
+
+		now := time.Now()
+		returnRequest.Status = models.ReturnInspected
+		returnRequest.IsResellable = *input.IsResellable
+		returnRequest.InspectionNotes = inspectionNotes
+		returnRequest.InspectedBy = employeeID
+		returnRequest.InspectedAt = &now
+		return tx.Omit(clause.Associations).Save(&returnRequest).Error
+	})
+	if err != nil {
+		log.Printf("[Service InspectReturnRequest] Gagal untuk %s: %v\n", rmaID, err)
+		return AdminReturnRequestView{}, err
+	}
+	return s.GetReturnRequest(rmaID)
+}
+
+// ResolveReturnRequest menutup retur yang sudah diinspeksi. Refund masuk ke antrean refund, penggantian
+// mengurangi stok untuk barang pengganti, dan perbaikan hanya dicatat.
+func (s *service) ResolveReturnRequest(rmaID, employeeID string, input ResolveReturnRequestInput) (AdminReturnRequestView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		returnRequest, err := findReturnRequestForUpdate(tx, rmaID)
+		if err != nil {
+			return err
+		}
+		if returnRequest.Status != models.ReturnInspected {
+			return fmt.Errorf("status retur tidak valid: hanya retur Inspected yang dapat diselesaikan, status saat ini %s", returnRequest.Status)
+		}
+		resolutionNotes := strings.TrimSpace(input.ResolutionNotes)
+
+		switch input.Resolution {
+		case models.ReturnResolutionRefund:
+			var item models.OrderItem
+			if err := tx.Where("order_item_id = ?", returnRequest.OrderItemID).First(&item).Error; err != nil {
+				return fmt.Errorf("gagal mengambil item pesanan: %w", err)
+			}
+			maxAmount := float64(returnRequest.Quantity) * item.PriceAtOrder
+			amount := maxAmount
+			if input.RefundAmount != nil {
+				amount = *input.RefundAmount
+			}
+			if amount > maxAmount {
+				return fmt.Errorf("status retur tidak valid: nominal refund melebihi nilai barang yang diretur (%.2f)", maxAmount)
+			}
+			var nextVal int
+			if err := tx.Raw("SELECT nextval('refund_id_seq')").Scan(&nextVal).Error; err != nil {
+				return fmt.Errorf("gagal mendapatkan ID refund: %w", err)
+			}
+			refund := models.Refund{
+				RefundID:          fmt.Sprintf("RFD%05d", nextVal),
+				OrderID:           returnRequest.OrderID,
+				RMAID:             &returnRequest.RMAID,
+				CustomerID:        returnRequest.CustomerID,
+				Amount:            amount,
+				Reason:            fmt.Sprintf("Retur %s: %s", returnRequest.RMAID, returnRequest.Reason),
+				Status:            models.RefundPending,
+				BankName:          returnRequest.BankName,
+				BankAccountNumber: returnRequest.BankAccountNumber,
+				BankAccountHolder: returnRequest.BankAccountHolder,
+			}
+			if err := tx.Create(&refund).Error; err != nil {
+				return fmt.Errorf("gagal membuat refund retur: %w", err)
+			}
+		case models.ReturnResolutionReplacement:
+			var product models.Product
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("product_sku", "title", "stock", "is_serialized").
+				Where("product_sku = ?", returnRequest.ProductSKU).First(&product).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("status retur tidak valid: produk sudah dihapus sehingga tidak dapat diganti")
+				}
+				return fmt.Errorf("gagal mengambil produk: %w", err)
+			}
+			if product.Stock < returnRequest.Quantity {
+				return fmt.Errorf("stok produk '%s' tidak mencukupi untuk penggantian (tersedia: %d, dibutuhkan: %d)", product.Title, product.Stock, returnRequest.Quantity)
+			}
+			if product.IsSerialized {
+				units, err := loadReturnSerialUnits(tx, input.SerialNumbers, returnRequest.Quantity, func(db *gorm.DB) *gorm.DB {
+					return db.Where("product_sku = ? AND status = ?", returnRequest.ProductSKU, models.ProductUnitInStock)
+				})
+				if err != nil {
+					return err
+				}
+				unitIDs := make([]uint, 0, len(units))
+				for _, unit := range units {
+					unitIDs = append(unitIDs, unit.ID)
+				}
+				if err := tx.Model(&models.ProductUnit{}).Where("id IN ?", unitIDs).
+					Updates(map[string]interface{}{"status": models.ProductUnitShipped, "order_id": returnRequest.OrderID,
+						"order_item_id": returnRequest.OrderItemID, "shipped_at": time.Now()}).Error; err != nil {
+					return fmt.Errorf("gagal menandai unit pengganti: %w", err)
+				}
+			}
+			if err := recordStockMovement(tx, returnRequest.ProductSKU, -returnRequest.Quantity, models.StockMovementReplacement,
+				returnRequest.RMAID, resolutionNotes, employeeID); err != nil {
+				return err
+			}
+		case models.ReturnResolutionRepair:
+			if returnRequest.RestockedQuantity > 0 {
+				return errors.New("status retur tidak valid: barang sudah dikembalikan ke stok sehingga tidak dapat diperbaiki untuk customer")
+			}
+		}
+
+		now := time.Now()
+		returnRequest.Status = models.ReturnResolved
+		returnRequest.Resolution = input.Resolution
+		returnRequest.ResolutionNotes = resolutionNotes
+		returnRequest.ResolvedBy = employeeID
+		returnRequest.ResolvedAt = &now
+		return tx.Omit(clause.Associations).Save(&returnRequest).Error
+	})
+	if err != nil {
+		log.Printf("[Service ResolveReturnRequest] Gagal untuk %s: %v\n", rmaID, err)
+		return AdminReturnRequestView{}, err
+	}
+	log.Printf("[Service ResolveReturnRequest] Retur %s diselesaikan dengan %s.\n", rmaID, input.Resolution)
+	return s.GetReturnRequest(rmaID)
+}
+
+func (s *service) ListStockMovements(productSKU string) ([]models.StockMovement, error) {
+	var count int64
+	if err := s.db.Model(&models.Product{}).Where("product_sku = ?", productSKU).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("gagal memverifikasi produk: %w", err)
+	}
+	if count == 0 {
+		return nil, errors.New("produk tidak ditemukan")
+	}
+	movements := []models.StockMovement{}
+	if err := s.db.Where("product_sku = ?", productSKU).Order("created_at DESC, id DESC").Find(&movements).Error; err != nil {
+		log.Printf("[Service ListStockMovements] Error untuk SKU %s: %v\n", productSKU, err)
+		return nil, fmt.Errorf("gagal mengambil mutasi stok: %w", err)
+	}
+	return movements, nil
+}
+
+func (s *service) ListOrderedCustomers() ([]AdminCustomerListView, error) {
+	var results []AdminCustomerListView
+
+	err := s.db.Table("customers c").
+		Select("c.customer_id, d.first_name || ' ' || d.last_name as full_name, c.email, c.phone, SUM(o.grand_total) as total_spent, MAX(o.order_date_time) as last_purchase").
+		Joins("JOIN customer_details d ON c.customer_id = d.customer_id").
+		Joins("JOIN orders o ON c.customer_id = o.customer_id").
+		Group("c.customer_id, d.first_name, d.last_name, c.email, c.phone").
+		Order("last_purchase DESC").
+		Scan(&results).Error
+
+	if err != nil {
+		log.Printf("[Service ListOrderedCustomers] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil daftar customer: %w", err)
+	}
+	return results, nil
+}
+
+func (s *service) GetCustomerDetailForAdmin(customerID string) (AdminCustomerDetailView, error) {
+	var customer models.Customer
+	var customerDetailView AdminCustomerDetailView
 
 	// Preload semua relasi yang dibutuhkan
 	if err := s.db.
@@ -1293,12 +4860,21 @@ func (s *service) GetCustomerDetailForAdmin(customerID string) (AdminCustomerDet
 	customerDetailView.TotalSpent = totalSpent
 	customerDetailView.OrderHistory = orderHistory
 
+	rentalHistory, err := s.ListRentalBookings(AdminRentalBookingQuery{CustomerID: customerID})
+	if err != nil {
+		return customerDetailView, err
+	}
+	customerDetailView.RentalHistory = rentalHistory
+
 	return customerDetailView, nil
 }
 
 func (s *service) DeleteCustomer(customerID string) error {
 
 	log.Printf("[Service DeleteCustomer] Menghapus customer dengan ID: %s\n", customerID)
+	if err := s.db.Where("customer_id = ?", customerID).Delete(&models.CompanyMember{}).Error; err != nil {
+		return fmt.Errorf("gagal menghapus keanggotaan perusahaan customer: %w", err)
+	}
 	result := s.db.Where("customer_id = ?", customerID).Delete(&models.Customer{})
 
 	if result.Error != nil {
@@ -1548,46 +5124,65 @@ func (s *service) GetNewsPostByID(newsID string) (models.NewsPost, error) {
 }
 
 func (s *service) UpdateNewsPost(newsID string, input UpdateNewsPostInput, imageFileHeader *multipart.FileHeader) (models.NewsPost, error) {
-	// Ambil post yang ada
-	post, err := s.GetNewsPostByID(newsID)
-	if err != nil {
-		return models.NewsPost{}, err // Mengembalikan error "tidak ditemukan"
+	// Parsing tanggal sebelum menyentuh file apa pun
+	publicationDate, errParseDate := time.Parse("2006-01-02", input.PublicationDate)
+	if errParseDate != nil {
+		return models.NewsPost{}, fmt.Errorf("format tanggal publikasi tidak valid: %w", errParseDate)
 	}
 
-	oldImagePath := post.Image
-	var newImagePath string = oldImagePath
-
-	// Handle upload file baru jika ada
+	// Simpan file gambar baru jika ada (penamaan sama seperti di AddNewsPost)
+	var newImagePath string
 	if imageFileHeader != nil {
-		// Logika penyimpanan file (sama seperti di AddNewsPost)
-		// ... (buat nama unik, simpan file, dapatkan path baru)
-		// newImagePath = "path/ke/gambar/baru.jpg"
-
-		// Setelah file baru berhasil disimpan, hapus file lama jika ada
-		if oldImagePath != "" {
-			os.Remove(filepath.Join(".", oldImagePath))
+		uniqueFilename := fmt.Sprintf("news_%s_%d%s", newsID, time.Now().UnixNano(), filepath.Ext(imageFileHeader.Filename))
+		savedPath, err := saveUploadedFile(imageFileHeader, newsImageUploadDir, uniqueFilename)
+		if err != nil {
+			return models.NewsPost{}, err
 		}
+		newImagePath = savedPath
 	}
 
-	// Parsing tanggal
-	publicationDate, errParseDate := time.Parse("2006-01-02", input.PublicationDate)
-	if errParseDate != nil {
-		return models.NewsPost{}, fmt.Errorf("format tanggal publikasi tidak valid: %w", errParseDate)
-	}
+	var oldImagePath string
+	var updatedPost models.NewsPost
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var post models.NewsPost
+		if err := tx.Where("news_id = ?", newsID).First(&post).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("postingan berita tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil detail berita: %w", err)
+		}
+		oldImagePath = post.Image
+
+		// Update field
+		post.Title = input.Title
+		post.Content = input.Content
+		post.CategoryID = input.CategoryID
+		post.PublicationDate = publicationDate
+		post.Status = input.Status
+		if newImagePath != "" {
+			post.Image = newImagePath
+		}
+
+		if err := tx.Save(&post).Error; err != nil {
+			return fmt.Errorf("gagal mengupdate postingan berita: %w", err)
+		}
 
-	// Update field
-	post.Title = input.Title
-	post.Content = input.Content
-	post.CategoryID = input.CategoryID
-	post.PublicationDate = publicationDate
-	post.Status = input.Status
-	post.Image = newImagePath // Update dengan path gambar baru atau tetap yang lama
+		if err := tx.Preload("Author").Preload("NewsCategory").First(&updatedPost, "news_id = ?", newsID).Error; err != nil {
+			return fmt.Errorf("gagal mengambil data berita setelah update: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// Transaksi gagal, hapus file baru yang sudah terlanjur disimpan
+		removeUploadedFile(newImagePath)
+		return models.NewsPost{}, err
+	}
 
-	// Simpan perubahan
-	if err := s.db.Save(&post).Error; err != nil {
-		return models.NewsPost{}, fmt.Errorf("gagal mengupdate postingan berita: %w", err)
+	// Hapus file lama hanya setelah commit berhasil
+	if newImagePath != "" && oldImagePath != newImagePath {
+		removeUploadedFile(oldImagePath)
 	}
-	return post, nil
+	return updatedPost, nil
 }
 
 func (s *service) DeleteNewsPost(newsID string) error {
@@ -1702,6 +5297,42 @@ func (s *service) GetDashboardStatistics() (DashboardStats, error) {
 		return stats, fmt.Errorf("gagal menghitung total profit: %w", err)
 	}
 
+	// 6b. Koreksi retur barang: refund retur mengurangi income & profit, barang yang kembali ke stok
+	// mengembalikan modalnya, sedangkan barang pengganti menambah modal yang keluar.
+	err = s.db.Table("refunds r").
+		Select("COALESCE(SUM(r.amount), 0)").
+		Joins("JOIN orders o ON o.order_id = r.order_id").
+		Where("r.rma_id IS NOT NULL AND o.order_status IN ?", successStatuses).
+		Row().Scan(&stats.TotalRefunded)
+	if err != nil {
+		log.Printf("[Service GetDashboardStatistics] Error menghitung refund retur: %v\n", err)
+		return stats, fmt.Errorf("gagal menghitung refund retur: %w", err)
+	}
+	var currentMonthRefunded, returnCostAdjustment float64
+	err = s.db.Table("refunds r").
+		Select("COALESCE(SUM(r.amount), 0)").
+		Joins("JOIN orders o ON o.order_id = r.order_id").
+		Where("r.rma_id IS NOT NULL AND r.created_at >= ? AND o.order_status IN ?", startOfMonth, successStatuses).
+		Row().Scan(&currentMonthRefunded)
+	if err != nil {
+		log.Printf("[Service GetDashboardStatistics] Error menghitung refund retur bulan ini: %v\n", err)
+		return stats, fmt.Errorf("gagal menghitung refund retur bulan ini: %w", err)
+	}
+	err = s.db.Table("return_requests rr").
+		Select("COALESCE(SUM(rr.restocked_quantity * p.capital_price) - SUM(CASE WHEN rr.resolution = ? THEN rr.quantity * p.capital_price ELSE 0 END), 0)",
+			models.ReturnResolutionReplacement).
+		Joins("JOIN orders o ON o.order_id = rr.order_id").
+		Joins("JOIN products p ON p.product_sku = rr.product_sku").
+		Where("o.order_status IN ?", successStatuses).
+		Row().Scan(&returnCostAdjustment)
+	if err != nil {
+		log.Printf("[Service GetDashboardStatistics] Error menghitung modal retur: %v\n", err)
+		return stats, fmt.Errorf("gagal menghitung modal retur: %w", err)
+	}
+	stats.CurrentMonthEarnings -= currentMonthRefunded
+	stats.TotalIncome -= stats.TotalRefunded
+	stats.TotalProfit += returnCostAdjustment - stats.TotalRefunded
+
 	// 7. Get Order Status Chart (menjumlahkan nilai uang per status)
 	var statusValues []struct {
 		OrderStatus string
@@ -0,0 +1,62 @@
+package document
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	DownloadOrderDocumentForAdmin(c *gin.Context)
+	DownloadOrderDocumentForCustomer(c *gin.Context)
+}
+
+func NewHandler(svc Service) Handler {
+	return &handler{
+		svc: svc,
+	}
+}
+
+type handler struct {
+	svc Service
+}
+
+func respondDocumentError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "pesanan tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "jenis dokumen tidak dikenal":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Jenis dokumen harus invoice atau proforma"})
+	case "dokumen tidak tersedia untuk pesanan yang dibatalkan":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat dokumen", "details": err.Error()})
+	}
+}
+
+func (h *handler) DownloadOrderDocumentForAdmin(c *gin.Context) {
+	document, err := h.svc.GetOrderDocument(c.Param("orderId"), c.Param("documentType"))
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+	c.FileAttachment(document.FilePath, document.DocumentNumber+".pdf")
+}
+
+func (h *handler) DownloadOrderDocumentForCustomer(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	document, err := h.svc.GetCustomerOrderDocument(customerID, c.Param("orderId"), c.Param("documentType"))
+	if err != nil {
+		log.Printf("[Handler DownloadOrderDocumentForCustomer] Error untuk CustomerID %s: %v\n", customerID, err)
+		respondDocumentError(c, err)
+		return
+	}
+	c.FileAttachment(document.FilePath, document.DocumentNumber+".pdf")
+}
@@ -0,0 +1,58 @@
+package document
+
+// Identitas perusahaan yang dicetak di kop dan instruksi pembayaran dokumen
+type CompanyProfile struct {
+	Name              string
+	Address           string
+	Email             string
+	Website           string
+	BankName          string
+	BankAccountNumber string
+	BankAccountHolder string
+	TaxRatePercent    float64 // PPN, harga produk sudah termasuk pajak
+}
+
+func DefaultCompanyProfile() CompanyProfile {
+	return CompanyProfile{
+		Name:              "PumaCon, CV. Putra Manunggal",
+		Address:           "Jl. Raya Kebonagung No.157, Sono Tengah, Kebonagung, Kec. Pakisaji, Kabupaten Malang, Jawa Timur 65162",
+		Email:             "cs@pumacon.com",
+		Website:           "pumacon.com",
+		BankName:          "Bank BCA",
+		BankAccountNumber: "0391886481",
+		BankAccountHolder: "CV Putra Manunggal",
+		TaxRatePercent:    11,
+	}
+}
+
+type documentLine struct {
+	ProductSKU string  `json:"product_sku"`
+	Title      string  `json:"title"`
+	Quantity   int     `json:"quantity"`
+	UnitPrice  float64 `json:"unit_price"`
+	SubTotal   float64 `json:"sub_total"`
+}
+
+// Data yang dicetak ke PDF; hash JSON-nya menjadi fingerprint untuk mendeteksi perubahan order
+type orderDocumentData struct {
+	DocumentType     string         `json:"document_type"`
+	DocumentNumber   string         `json:"document_number"`
+	OrderID          string         `json:"order_id"`
+	OrderDateTime    string         `json:"order_date_time"`
+	OrderStatus      string         `json:"order_status"`
+	PaymentMethod    string         `json:"payment_method"`
+	CustomerID       string         `json:"customer_id"`
+	CustomerFullname string         `json:"customer_fullname"`
+	CustomerEmail    string         `json:"customer_email"`
+	CustomerPhone    string         `json:"customer_phone"`
+	ShippingAddress  string         `json:"shipping_address"`
+	CompanyName      string         `json:"company_name"`
+	CompanyNPWP      string         `json:"company_npwp"`
+	CompanyBilling   string         `json:"company_billing"`
+	Notes            string         `json:"notes"`
+	Lines            []documentLine `json:"lines"`
+	Subtotal         float64        `json:"subtotal"`
+	ShippingCost     float64        `json:"shipping_cost"` // Order belum menyimpan ongkos kirim; selalu 0
+	GrandTotal       float64        `json:"grand_total"`
+	Company          CompanyProfile `json:"company"`
+}
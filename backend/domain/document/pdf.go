@@ -0,0 +1,183 @@
+package document
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"backend-user/domain/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+var documentTitles = map[string]string{
+	models.OrderDocumentInvoice:  "INVOICE",
+	models.OrderDocumentProforma: "PENAWARAN PRO-FORMA",
+}
+
+// formatNPWP menampilkan NPWP 15 digit dengan format 00.000.000.0-000.000; NPWP 16 digit tetap apa adanya.
+func formatNPWP(npwp string) string {
+	if len(npwp) != 15 {
+		return npwp
+	}
+	return fmt.Sprintf("%s.%s.%s.%s-%s.%s", npwp[0:2], npwp[2:5], npwp[5:8], npwp[8:9], npwp[9:12], npwp[12:15])
+}
+
+// writeOrderDocumentPDF merender invoice / penawaran pro-forma ke filePath.
+// File ditulis ke file sementara lalu di-rename agar unduhan tidak pernah membaca PDF setengah jadi.
+func writeOrderDocumentPDF(filePath string, data orderDocumentData) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return fmt.Errorf("gagal membuat direktori dokumen: %w", err)
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 8, tr(fmt.Sprintf("%s - %s | Halaman %d/{nb}", data.DocumentNumber, data.Company.Name, pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	// Kop perusahaan
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(110, 8, tr(data.Company.Name), "", 0, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 8, tr(documentTitles[data.DocumentType]), "", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.MultiCell(110, 4.5, tr(data.Company.Address), "", "L", false)
+	pdf.CellFormat(110, 4.5, tr(fmt.Sprintf("%s | %s", data.Company.Email, data.Company.Website)), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.Line(15, pdf.GetY(), 195, pdf.GetY())
+	pdf.Ln(4)
+
+	// Info dokumen & customer
+	top := pdf.GetY()
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(90, 5, tr("Ditagihkan kepada"), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	if data.CompanyName != "" {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(90, 4.5, tr(data.CompanyName), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(90, 4.5, tr("NPWP: "+formatNPWP(data.CompanyNPWP)), "", 1, "L", false, 0, "")
+		pdf.MultiCell(90, 4.5, tr(data.CompanyBilling), "", "L", false)
+		pdf.CellFormat(90, 4.5, tr("Pembeli:"), "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(90, 4.5, tr(data.CustomerFullname), "", 1, "L", false, 0, "")
+	pdf.CellFormat(90, 4.5, tr(data.CustomerEmail), "", 1, "L", false, 0, "")
+	if data.CustomerPhone != "" {
+		pdf.CellFormat(90, 4.5, tr(data.CustomerPhone), "", 1, "L", false, 0, "")
+	}
+	pdf.MultiCell(90, 4.5, tr(data.ShippingAddress), "", "L", false)
+	bottomLeft := pdf.GetY()
+
+	pdf.SetXY(115, top)
+	details := [][2]string{
+		{"Nomor", data.DocumentNumber},
+		{"No. Pesanan", data.OrderID},
+		{"Tanggal Pesanan", data.OrderDateTime},
+		{"Status", data.OrderStatus},
+		{"Metode Pembayaran", data.PaymentMethod},
+	}
+	for _, detail := range details {
+		pdf.SetX(115)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(35, 5, tr(detail[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(45, 5, tr(detail[1]), "", 1, "L", false, 0, "")
+	}
+	if pdf.GetY() < bottomLeft {
+		pdf.SetY(bottomLeft)
+	}
+	pdf.Ln(6)
+
+	// Tabel item
+	widths := []float64{10, 28, 72, 15, 27.5, 27.5}
+	headers := []string{"No", "SKU", "Produk", "Qty", "Harga", "Subtotal"}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(235, 235, 235)
+	for i, header := range headers {
+		align := "L"
+		if i >= 3 {
+			align = "R"
+		}
+		pdf.CellFormat(widths[i], 7, tr(header), "1", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("Helvetica", "", 9)
+	for i, line := range data.Lines {
+		pdf.CellFormat(widths[0], 6, fmt.Sprintf("%d", i+1), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, tr(line.ProductSKU), "1", 0, "L", false, 0, "")
+		title := []rune(line.Title)
+		for pdf.GetStringWidth(tr(string(title))) > widths[2]-2 && len(title) > 4 {
+			title = append([]rune(strings.TrimSpace(string(title[:len(title)-4]))), '.', '.', '.')
+		}
+		pdf.CellFormat(widths[2], 6, tr(string(title)), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 6, fmt.Sprintf("%d", line.Quantity), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, models.FormatRupiah(line.UnitPrice), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[5], 6, models.FormatRupiah(line.SubTotal), "1", 1, "R", false, 0, "")
+	}
+	pdf.Ln(3)
+
+	// Ringkasan harga; harga sudah termasuk PPN sehingga pajak ditampilkan sebagai rincian
+	taxBase := data.Subtotal
+	if data.Company.TaxRatePercent > 0 {
+		taxBase = data.Subtotal * 100 / (100 + data.Company.TaxRatePercent)
+	}
+	summary := [][2]string{
+		{"Subtotal", models.FormatRupiah(data.Subtotal)},
+		{"DPP", models.FormatRupiah(taxBase)},
+		{fmt.Sprintf("PPN %g%% (termasuk)", data.Company.TaxRatePercent), models.FormatRupiah(data.Subtotal - taxBase)},
+		{"Ongkos Kirim", models.FormatRupiah(data.ShippingCost)},
+	}
+	for _, row := range summary {
+		pdf.SetX(115)
+		pdf.CellFormat(45, 6, tr(row[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(35, 6, tr(row[1]), "", 1, "R", false, 0, "")
+	}
+	pdf.SetX(115)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(45, 7, tr("Total"), "T", 0, "L", false, 0, "")
+	pdf.CellFormat(35, 7, models.FormatRupiah(data.GrandTotal), "T", 1, "R", false, 0, "")
+	pdf.Ln(6)
+
+	// Instruksi pembayaran
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(0, 6, tr("Instruksi Pembayaran"), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.MultiCell(0, 4.5, tr(fmt.Sprintf(
+		"Transfer sebesar %s ke %s nomor rekening %s a.n. %s. Cantumkan nomor pesanan %s pada berita transfer.",
+		models.FormatRupiah(data.GrandTotal), data.Company.BankName, data.Company.BankAccountNumber, data.Company.BankAccountHolder, data.OrderID,
+	)), "", "L", false)
+	if data.DocumentType == models.OrderDocumentProforma {
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "I", 9)
+		pdf.MultiCell(0, 4.5, tr("Dokumen ini adalah penawaran pro-forma dan bukan bukti pembayaran. Invoice resmi diterbitkan terpisah."), "", "L", false)
+	}
+	if data.Notes != "" {
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(0, 5, tr("Catatan"), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 4.5, tr(data.Notes), "", "L", false)
+	}
+
+	tmpPath := filePath + ".tmp"
+	if err := pdf.OutputFileAndClose(tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("gagal membuat file PDF: %w", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("gagal menyimpan file PDF: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package document
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"backend-user/domain/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Disimpan di luar ./uploads karena folder itu disajikan publik tanpa autentikasi
+const documentStorageDir = "./storage/documents/"
+
+type Service interface {
+	GetOrderDocument(orderID, documentType string) (models.OrderDocument, error)
+	GetCustomerOrderDocument(customerID, orderID, documentType string) (models.OrderDocument, error)
+}
+
+type service struct {
+	db      *gorm.DB
+	company CompanyProfile
+}
+
+func NewService(db *gorm.DB, company CompanyProfile) Service {
+	return &service{
+		db:      db,
+		company: company,
+	}
+}
+
+var documentNumberFormats = map[string]struct {
+	sequence string
+	prefix   string
+}{
+	models.OrderDocumentInvoice:  {sequence: "invoice_number_seq", prefix: "INV"},
+	models.OrderDocumentProforma: {sequence: "proforma_number_seq", prefix: "PRO"},
+}
+
+func (s *service) GetCustomerOrderDocument(customerID, orderID, documentType string) (models.OrderDocument, error) {
+	var count int64
+	// Anggota perusahaan juga boleh mengunduh dokumen order perusahaannya
+	if err := s.db.Model(&models.Order{}).
+		Where("order_id = ?", orderID).
+		Where("customer_id = ? OR company_id IN (?)", customerID,
+			s.db.Model(&models.CompanyMember{}).Select("company_id").Where("customer_id = ?", customerID)).
+		Count(&count).Error; err != nil {
+		return models.OrderDocument{}, fmt.Errorf("gagal memverifikasi pesanan: %w", err)
+	}
+	if count == 0 {
+		return models.OrderDocument{}, errors.New("pesanan tidak ditemukan")
+	}
+	return s.GetOrderDocument(orderID, documentType)
+}
+
+// GetOrderDocument mengembalikan dokumen tersimpan, membuatnya jika belum ada
+// dan membuat ulang file PDF jika data order sudah berubah sejak terakhir dibuat.
+func (s *service) GetOrderDocument(orderID, documentType string) (models.OrderDocument, error) {
+	numberFormat, ok := documentNumberFormats[documentType]
+	if !ok {
+		return models.OrderDocument{}, errors.New("jenis dokumen tidak dikenal")
+	}
+
+	var document models.OrderDocument
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Kunci baris order agar dua permintaan bersamaan tidak memakai dua nomor dokumen
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil pesanan: %w", err)
+		}
+		if order.OrderStatus == "Canceled" {
+			return errors.New("dokumen tidak tersedia untuk pesanan yang dibatalkan")
+		}
+		if err := tx.Order("order_item_id ASC").Where("order_id = ?", orderID).Find(&order.OrderItems).Error; err != nil {
+			return fmt.Errorf("gagal mengambil item pesanan: %w", err)
+		}
+
+		err := tx.Where("order_id = ? AND document_type = ?", orderID, documentType).First(&document).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("gagal mengambil dokumen: %w", err)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			var nextVal int
+			if err := tx.Raw("SELECT nextval(?)", numberFormat.sequence).Scan(&nextVal).Error; err != nil {
+				return fmt.Errorf("gagal mendapatkan nomor dokumen: %w", err)
+			}
+			document = models.OrderDocument{
+				OrderID:        orderID,
+				DocumentType:   documentType,
+				DocumentNumber: fmt.Sprintf("%s%05d", numberFormat.prefix, nextVal),
+			}
+		}
+
+		data := s.buildOrderDocumentData(order, documentType, document.DocumentNumber)
+		fingerprint, err := fingerprintOf(data)
+		if err != nil {
+			return err
+		}
+		if document.ID != 0 && document.Fingerprint == fingerprint {
+			if _, statErr := os.Stat(document.FilePath); statErr == nil {
+				return nil
+			}
+		}
+
+		filePath := filepath.Join(documentStorageDir, documentType, document.DocumentNumber+".pdf")
+		if err := writeOrderDocumentPDF(filePath, data); err != nil {
+			return err
+		}
+		log.Printf("[Service GetOrderDocument] Dokumen %s untuk order %s dibuat.\n", document.DocumentNumber, orderID)
+
+		document.FilePath = filePath
+		document.Fingerprint = fingerprint
+		document.GeneratedAt = time.Now()
+		return tx.Save(&document).Error
+	})
+	if err != nil {
+		log.Printf("[Service GetOrderDocument] Gagal untuk order %s (%s): %v\n", orderID, documentType, err)
+		return models.OrderDocument{}, err
+	}
+	return document, nil
+}
+
+func (s *service) buildOrderDocumentData(order models.Order, documentType, documentNumber string) orderDocumentData {
+	data := orderDocumentData{
+		DocumentType:     documentType,
+		DocumentNumber:   documentNumber,
+		OrderID:          order.OrderID,
+		OrderDateTime:    order.OrderDateTime.Format("02 Jan 2006 15:04"),
+		OrderStatus:      order.OrderStatus,
+		PaymentMethod:    order.PaymentMethod,
+		CustomerID:       order.CustomerID,
+		CustomerFullname: order.CustomerFullname,
+		CustomerEmail:    order.CustomerEmail,
+		CustomerPhone:    order.CustomerPhone,
+		ShippingAddress:  order.ShippingAddressSnapshot,
+		CompanyName:      order.CompanyLegalName,
+		CompanyNPWP:      order.CompanyNPWP,
+		CompanyBilling:   order.CompanyBillingAddress,
+		Notes:            order.Notes,
+		Lines:            make([]documentLine, 0, len(order.OrderItems)),
+		GrandTotal:       order.GrandTotal,
+		Company:          s.company,
+	}
+	for _, item := range order.OrderItems {
+		subTotal := item.PriceAtOrder * float64(item.Quantity)
+		data.Lines = append(data.Lines, documentLine{
+			ProductSKU: item.ProductSKU,
+			Title:      item.ProductTitleSnapshot,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.PriceAtOrder,
+			SubTotal:   subTotal,
+		})
+		data.Subtotal += subTotal
+	}
+	return data
+}
+
+func fingerprintOf(data orderDocumentData) (string, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("gagal menghitung fingerprint dokumen: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
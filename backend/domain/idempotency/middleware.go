@@ -0,0 +1,293 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-user/domain/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// responseRecorder meneruskan respons ke client sekaligus menyalinnya untuk disimpan.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// store menyimpan record idempotency; dipisah dari middleware agar alurnya dapat diuji tanpa database.
+type store interface {
+	// acquire mendaftarkan key baru atau mengembalikan record yang sudah ada dengan acquired=false
+	acquire(record models.IdempotencyRecord, now time.Time) (models.IdempotencyRecord, bool, error)
+	extendLock(id uint, until time.Time) error
+	complete(id uint, statusCode int, contentType string, body []byte, completedAt time.Time) error
+	release(id uint) error
+}
+
+// Middleware membuat request POST aman untuk di-retry. Jika client mengirim header Idempotency-Key,
+// respons pertama disimpan selama retention dan dikembalikan apa adanya untuk retry dengan key dan isi
+// request yang sama. Harus dipasang setelah middleware autentikasi karena key berlaku per pemilik akun.
+//
+// Selama request pertama berjalan, key dikunci selama lockTimeout dan kuncinya diperpanjang berkala.
+// Request yang lambat tetap memegang key, sedangkan key milik proses yang mati di tengah request
+// dapat dipakai lagi setelah lockTimeout, tanpa menunggu retention habis.
+func Middleware(db *gorm.DB, retention, lockTimeout time.Duration) gin.HandlerFunc {
+	return newMiddleware(&gormStore{db: db}, retention, lockTimeout)
+}
+
+func newMiddleware(records store, retention, lockTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(HeaderKey))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key maksimal 255 karakter"})
+			return
+		}
+		scope := requestScope(c)
+		if scope == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Gagal membaca body request"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := fingerprint(c.Request.Method, c.Request.URL.Path, c.ContentType(), c.GetHeader("Content-Type"), body)
+
+		now := time.Now()
+		lockedUntil := now.Add(lockTimeout)
+		record, acquired, err := records.acquire(models.IdempotencyRecord{
+			Scope:          scope,
+			IdempotencyKey: key,
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			RequestHash:    requestHash,
+			LockedUntil:    &lockedUntil,
+			ExpiresAt:      now.Add(retention),
+		}, now)
+		if err != nil {
+			log.Printf("[Middleware Idempotency] Gagal memeriksa key %s: %v\n", key, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Gagal memeriksa Idempotency-Key", "details": err.Error()})
+			return
+		}
+		if !acquired {
+			replay(c, record, requestHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		completed := false
+		stopHeartbeat := make(chan struct{})
+		go heartbeat(records, record.ID, lockTimeout, stopHeartbeat)
+		defer func() {
+			close(stopHeartbeat)
+			// Request gagal (error server / panic) tidak disimpan agar client dapat mencoba lagi
+			if !completed {
+				if err := records.release(record.ID); err != nil {
+					log.Printf("[Middleware Idempotency] Peringatan: gagal melepas key %s: %v\n", key, err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		if err := records.complete(record.ID, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes(), time.Now()); err != nil {
+			log.Printf("[Middleware Idempotency] Peringatan: gagal menyimpan respons untuk key %s: %v\n", key, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// heartbeat memperpanjang kunci key setiap sepertiga lockTimeout sampai stop ditutup.
+func heartbeat(records store, id uint, lockTimeout time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(lockTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if err := records.extendLock(id, now.Add(lockTimeout)); err != nil {
+				log.Printf("[Middleware Idempotency] Peringatan: gagal memperpanjang kunci record %d: %v\n", id, err)
+			}
+		}
+	}
+}
+
+// requestScope memisahkan key antar akun agar key yang sama dari pengguna berbeda tidak saling bertabrakan.
+func requestScope(c *gin.Context) string {
+	if customerID, ok := c.Get("customer_id_from_token"); ok {
+		if id, isString := customerID.(string); isString && id != "" {
+			return "customer:" + id
+		}
+	}
+	if employeeID, ok := c.Get("admin_employee_id"); ok {
+		if id, isString := employeeID.(string); isString && id != "" {
+			return "employee:" + id
+		}
+	}
+	return ""
+}
+
+// fingerprint menghitung hash isi request. Body multipart di-hash per bagian (nama field, nama file dan
+// isinya) karena client bisa membuat boundary baru setiap kali retry.
+func fingerprint(method, path, mediaType, contentTypeHeader string, body []byte) string {
+	hasher := sha256.New()
+	hasher.Write([]byte(method + " " + path + "\n"))
+
+	if mediaType == "multipart/form-data" {
+		if _, params, err := mime.ParseMediaType(contentTypeHeader); err == nil && params["boundary"] != "" {
+			reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+			partsHasher := sha256.New()
+			valid := true
+			for {
+				part, err := reader.NextPart()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					valid = false
+					break
+				}
+				partsHasher.Write([]byte(part.FormName() + "\x00" + part.FileName() + "\x00"))
+				if _, err := io.Copy(partsHasher, part); err != nil {
+					valid = false
+					break
+				}
+				partsHasher.Write([]byte{0})
+			}
+			if valid {
+				hasher.Write(partsHasher.Sum(nil))
+				return hex.EncodeToString(hasher.Sum(nil))
+			}
+		}
+	}
+	hasher.Write(body)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// reclaimable menentukan apakah record lama boleh diganti request baru: masa retensinya habis, atau
+// request pertama belum selesai dan kuncinya tidak lagi diperpanjang (misal server mati di tengah request).
+func reclaimable(existing models.IdempotencyRecord, now time.Time) bool {
+	if existing.ExpiresAt.Before(now) {
+		return true
+	}
+	return existing.CompletedAt == nil && (existing.LockedUntil == nil || existing.LockedUntil.Before(now))
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func (s *gormStore) acquire(record models.IdempotencyRecord, now time.Time) (models.IdempotencyRecord, bool, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		candidate := record
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&candidate)
+		if result.Error != nil {
+			return record, false, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return candidate, true, nil
+		}
+
+		var existing models.IdempotencyRecord
+		if err := s.db.Where("scope = ? AND idempotency_key = ?", record.Scope, record.IdempotencyKey).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue // Baru saja dilepas oleh request lain
+			}
+			return record, false, err
+		}
+		if !reclaimable(existing, now) {
+			return existing, false, nil
+		}
+		// Syarat diulang di DELETE agar kunci yang baru saja diperpanjang tidak ikut terhapus
+		if err := s.db.Where("id = ? AND (expires_at < ? OR (completed_at IS NULL AND (locked_until IS NULL OR locked_until < ?)))", existing.ID, now, now).
+			Delete(&models.IdempotencyRecord{}).Error; err != nil {
+			return record, false, err
+		}
+	}
+	return record, false, errors.New("key sedang diperebutkan request lain, silakan coba lagi")
+}
+
+func (s *gormStore) extendLock(id uint, until time.Time) error {
+	return s.db.Model(&models.IdempotencyRecord{}).Where("id = ? AND completed_at IS NULL", id).Update("locked_until", until).Error
+}
+
+func (s *gormStore) complete(id uint, statusCode int, contentType string, body []byte, completedAt time.Time) error {
+	return s.db.Model(&models.IdempotencyRecord{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+		"completed_at":  completedAt,
+		"locked_until":  nil,
+	}).Error
+}
+
+func (s *gormStore) release(id uint) error {
+	return s.db.Delete(&models.IdempotencyRecord{}, id).Error
+}
+
+// replay mengembalikan respons tersimpan untuk retry dengan key yang sama.
+func replay(c *gin.Context, record models.IdempotencyRecord, requestHash string) {
+	if record.RequestHash != requestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key sudah dipakai untuk request yang berbeda"})
+		return
+	}
+	if record.CompletedAt == nil {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Request dengan Idempotency-Key ini masih diproses, coba lagi sebentar"})
+		return
+	}
+	c.Header(HeaderReplayed, "true")
+	contentType := record.ContentType
+	if contentType == "" {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Data(record.StatusCode, contentType, record.ResponseBody)
+	c.Abort()
+}
+
+// PurgeExpired menghapus record yang sudah melewati masa retensi.
+func PurgeExpired(db *gorm.DB) (int64, error) {
+	result := db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,372 @@
+package idempotency
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend-user/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryStore meniru gormStore tanpa database, dengan aturan penggantian record yang sama (reclaimable).
+type memoryStore struct {
+	mu      sync.Mutex
+	nextID  uint
+	records map[string]models.IdempotencyRecord
+	extends int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: map[string]models.IdempotencyRecord{}}
+}
+
+func (s *memoryStore) seed(record models.IdempotencyRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	record.ID = s.nextID
+	s.records[record.Scope+"\x00"+record.IdempotencyKey] = record
+}
+
+func (s *memoryStore) acquire(record models.IdempotencyRecord, now time.Time) (models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mapKey := record.Scope + "\x00" + record.IdempotencyKey
+	if existing, ok := s.records[mapKey]; ok && !reclaimable(existing, now) {
+		return existing, false, nil
+	}
+	s.nextID++
+	record.ID = s.nextID
+	s.records[mapKey] = record
+	return record, true, nil
+}
+
+func (s *memoryStore) update(id uint, apply func(*models.IdempotencyRecord)) {
+	for mapKey, record := range s.records {
+		if record.ID == id {
+			apply(&record)
+			s.records[mapKey] = record
+		}
+	}
+}
+
+func (s *memoryStore) extendLock(id uint, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.extends++
+	s.update(id, func(record *models.IdempotencyRecord) { record.LockedUntil = &until })
+	return nil
+}
+
+func (s *memoryStore) complete(id uint, statusCode int, contentType string, body []byte, completedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(id, func(record *models.IdempotencyRecord) {
+		record.StatusCode = statusCode
+		record.ContentType = contentType
+		record.ResponseBody = append([]byte(nil), body...)
+		record.CompletedAt = &completedAt
+		record.LockedUntil = nil
+	})
+	return nil
+}
+
+func (s *memoryStore) release(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for mapKey, record := range s.records {
+		if record.ID == id {
+			delete(s.records, mapKey)
+		}
+	}
+	return nil
+}
+
+type testRequest struct {
+	key  string
+	body string
+}
+
+func newTestRouter(records store, lockTimeout time.Duration, customerID string, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/orders", func(c *gin.Context) {
+		if customerID != "" {
+			c.Set("customer_id_from_token", customerID)
+		}
+		c.Next()
+	}, newMiddleware(records, time.Hour, lockTimeout), handler)
+	return router
+}
+
+func serve(router *gin.Engine, request testRequest) *httptest.ResponseRecorder {
+	httpRequest := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(request.body))
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if request.key != "" {
+		httpRequest.Header.Set(HeaderKey, request.key)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httpRequest)
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	orderBody := `{"product_sku":"SKU001","quantity":1}`
+	orderHash := fingerprint(http.MethodPost, "/orders", "application/json", "application/json", []byte(orderBody))
+
+	tests := []struct {
+		name         string
+		customerID   string
+		seed         *models.IdempotencyRecord
+		handlerCodes []int // Status yang dikembalikan handler pada panggilan ke-n
+		requests     []testRequest
+		wantCodes    []int
+		wantCalls    int
+		wantReplayed bool // Header Idempotent-Replayed pada respons terakhir
+	}{
+		{
+			name:         "tanpa key request selalu dijalankan",
+			customerID:   "CUS0001",
+			handlerCodes: []int{http.StatusCreated, http.StatusCreated},
+			requests:     []testRequest{{body: orderBody}, {body: orderBody}},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantCalls:    2,
+		},
+		{
+			name:         "tanpa akun key diabaikan",
+			handlerCodes: []int{http.StatusCreated, http.StatusCreated},
+			requests:     []testRequest{{key: "k1", body: orderBody}, {key: "k1", body: orderBody}},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantCalls:    2,
+		},
+		{
+			name:       "key terlalu panjang ditolak",
+			customerID: "CUS0001",
+			requests:   []testRequest{{key: strings.Repeat("k", maxKeyLength+1), body: orderBody}},
+			wantCodes:  []int{http.StatusBadRequest},
+		},
+		{
+			name:         "retry dengan isi sama mendapat respons tersimpan",
+			customerID:   "CUS0001",
+			handlerCodes: []int{http.StatusCreated},
+			requests:     []testRequest{{key: "k1", body: orderBody}, {key: "k1", body: orderBody}},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+		{
+			name:         "key sama dengan isi berbeda ditolak",
+			customerID:   "CUS0001",
+			handlerCodes: []int{http.StatusCreated},
+			requests:     []testRequest{{key: "k1", body: orderBody}, {key: "k1", body: `{"product_sku":"SKU002","quantity":1}`}},
+			wantCodes:    []int{http.StatusCreated, http.StatusUnprocessableEntity},
+			wantCalls:    1,
+		},
+		{
+			name:         "key sama dari akun lain tidak bertabrakan",
+			customerID:   "CUS0002",
+			seed:         &models.IdempotencyRecord{Scope: "customer:CUS0001", IdempotencyKey: "k1", RequestHash: orderHash, StatusCode: http.StatusCreated, CompletedAt: &past, ExpiresAt: future},
+			handlerCodes: []int{http.StatusCreated},
+			requests:     []testRequest{{key: "k1", body: orderBody}},
+			wantCodes:    []int{http.StatusCreated},
+			wantCalls:    1,
+		},
+		{
+			name:       "request pertama masih diproses",
+			customerID: "CUS0001",
+			seed:       &models.IdempotencyRecord{Scope: "customer:CUS0001", IdempotencyKey: "k1", RequestHash: orderHash, LockedUntil: &future, ExpiresAt: future},
+			requests:   []testRequest{{key: "k1", body: orderBody}},
+			wantCodes:  []int{http.StatusConflict},
+		},
+		{
+			name:         "kunci request terputus sudah lewat",
+			customerID:   "CUS0001",
+			seed:         &models.IdempotencyRecord{Scope: "customer:CUS0001", IdempotencyKey: "k1", RequestHash: orderHash, LockedUntil: &past, ExpiresAt: future},
+			handlerCodes: []int{http.StatusCreated},
+			requests:     []testRequest{{key: "k1", body: orderBody}},
+			wantCodes:    []int{http.StatusCreated},
+			wantCalls:    1,
+		},
+		{
+			name:         "record kedaluwarsa diganti",
+			customerID:   "CUS0001",
+			seed:         &models.IdempotencyRecord{Scope: "customer:CUS0001", IdempotencyKey: "k1", RequestHash: "lama", StatusCode: http.StatusCreated, CompletedAt: &past, ExpiresAt: past},
+			handlerCodes: []int{http.StatusCreated},
+			requests:     []testRequest{{key: "k1", body: orderBody}},
+			wantCodes:    []int{http.StatusCreated},
+			wantCalls:    1,
+		},
+		{
+			name:         "error server tidak disimpan sehingga retry dijalankan ulang",
+			customerID:   "CUS0001",
+			handlerCodes: []int{http.StatusInternalServerError, http.StatusCreated},
+			requests:     []testRequest{{key: "k1", body: orderBody}, {key: "k1", body: orderBody}},
+			wantCodes:    []int{http.StatusInternalServerError, http.StatusCreated},
+			wantCalls:    2,
+		},
+		{
+			name:         "error validasi disimpan dan di-replay",
+			customerID:   "CUS0001",
+			handlerCodes: []int{http.StatusBadRequest},
+			requests:     []testRequest{{key: "k1", body: orderBody}, {key: "k1", body: orderBody}},
+			wantCodes:    []int{http.StatusBadRequest, http.StatusBadRequest},
+			wantCalls:    1,
+			wantReplayed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := newMemoryStore()
+			if tt.seed != nil {
+				records.seed(*tt.seed)
+			}
+			calls := 0
+			router := newTestRouter(records, time.Minute, tt.customerID, func(c *gin.Context) {
+				code := tt.handlerCodes[calls]
+				calls++
+				c.JSON(code, gin.H{"call": calls})
+			})
+
+			var first, last *httptest.ResponseRecorder
+			for i, request := range tt.requests {
+				last = serve(router, request)
+				if i == 0 {
+					first = last
+				}
+				if last.Code != tt.wantCodes[i] {
+					t.Fatalf("request %d: status = %d, want %d (body %s)", i, last.Code, tt.wantCodes[i], last.Body.String())
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler dipanggil %d kali, want %d", calls, tt.wantCalls)
+			}
+			if replayed := last.Header().Get(HeaderReplayed) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %t, want %t", replayed, tt.wantReplayed)
+			}
+			if tt.wantReplayed && last.Body.String() != first.Body.String() {
+				t.Errorf("body replay = %s, want %s", last.Body.String(), first.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddlewareExtendsLockWhileRequestRuns(t *testing.T) {
+	records := newMemoryStore()
+	router := newTestRouter(records, 30*time.Millisecond, "CUS0001", func(c *gin.Context) {
+		time.Sleep(100 * time.Millisecond)
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	if code := serve(router, testRequest{key: "k1", body: `{}`}).Code; code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", code, http.StatusCreated)
+	}
+	records.mu.Lock()
+	defer records.mu.Unlock()
+	if records.extends == 0 {
+		t.Error("kunci tidak diperpanjang selama request berjalan")
+	}
+	record := records.records["customer:CUS0001\x00k1"]
+	if record.CompletedAt == nil || record.LockedUntil != nil {
+		t.Errorf("record = %+v, want selesai tanpa kunci", record)
+	}
+}
+
+func TestReclaimable(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Second), now.Add(time.Second)
+	tests := []struct {
+		name   string
+		record models.IdempotencyRecord
+		want   bool
+	}{
+		{"selesai dan masih disimpan", models.IdempotencyRecord{CompletedAt: &past, ExpiresAt: future}, false},
+		{"selesai dan kedaluwarsa", models.IdempotencyRecord{CompletedAt: &past, ExpiresAt: past}, true},
+		{"diproses dengan kunci aktif", models.IdempotencyRecord{LockedUntil: &future, ExpiresAt: future}, false},
+		{"diproses dengan kunci lewat", models.IdempotencyRecord{LockedUntil: &past, ExpiresAt: future}, true},
+		{"diproses tanpa kunci", models.IdempotencyRecord{ExpiresAt: future}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reclaimable(tt.record, now); got != tt.want {
+				t.Errorf("reclaimable() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+type multipartPart struct {
+	field, filename, content string
+}
+
+func multipartBody(t *testing.T, boundary string, parts []multipartPart) (string, []byte) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.SetBoundary(boundary); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range parts {
+		if part.filename == "" {
+			if err := writer.WriteField(part.field, part.content); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		fileWriter, err := writer.CreateFormFile(part.field, part.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fileWriter.Write([]byte(part.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writer.FormDataContentType(), body.Bytes()
+}
+
+func TestFingerprintMultipart(t *testing.T) {
+	proof := []multipartPart{{"payment_method", "", "Transfer"}, {"proof_of_payment", "bukti.jpg", "gambar-1"}}
+	multipartHash := func(path, boundary string, parts []multipartPart) string {
+		contentType, body := multipartBody(t, boundary, parts)
+		return fingerprint(http.MethodPost, path, "multipart/form-data", contentType, body)
+	}
+	base := multipartHash("/orders", "boundary-a", proof)
+
+	tests := []struct {
+		name     string
+		hash     string
+		wantSame bool
+	}{
+		{"boundary berbeda", multipartHash("/orders", "boundary-b", proof), true},
+		{"isi file berbeda", multipartHash("/orders", "boundary-a", []multipartPart{proof[0], {"proof_of_payment", "bukti.jpg", "gambar-2"}}), false},
+		{"nama file berbeda", multipartHash("/orders", "boundary-a", []multipartPart{proof[0], {"proof_of_payment", "lain.jpg", "gambar-1"}}), false},
+		{"nilai field berbeda", multipartHash("/orders", "boundary-a", []multipartPart{{"payment_method", "", "COD"}, proof[1]}), false},
+		{"path berbeda", multipartHash("/orders/quotation", "boundary-a", proof), false},
+		{"multipart rusak di-hash mentah", fingerprint(http.MethodPost, "/orders", "multipart/form-data", "multipart/form-data; boundary=x", []byte("bukan multipart")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := tt.hash == base; same != tt.wantSame {
+				t.Errorf("hash sama = %t, want %t", same, tt.wantSame)
+			}
+		})
+	}
+
+	broken := []byte("bukan multipart")
+	if fingerprint(http.MethodPost, "/orders", "multipart/form-data", "multipart/form-data; boundary=x", broken) !=
+		fingerprint(http.MethodPost, "/orders", "multipart/form-data", "multipart/form-data; boundary=x", broken) {
+		t.Error("hash multipart rusak tidak stabil")
+	}
+}
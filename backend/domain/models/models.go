@@ -1,7 +1,14 @@
 package models
 
 import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // --- CUSTOMER DOMAIN MODELS ---
@@ -59,31 +66,133 @@ type Cart struct {
 	RegularPrice float64 `gorm:"column:regular_price;type:numeric(12,2);not null"`
 	Quantity     int     `gorm:"not null;default:1"`
 	Total        float64 `gorm:"->;-:migration"`
+	// Item yang disimpan untuk nanti tetap di tabel carts tetapi tidak ikut checkout maupun total keranjang
+	SavedForLater bool `gorm:"column:saved_for_later;not null;default:false;index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (Cart) TableName() string { return "carts" }
+
+// GuestCart adalah keranjang pengunjung yang belum login, diakses lewat cart token bertanda tangan
+// dan digabung ke tabel carts saat pengunjung login atau mendaftar.
+type GuestCart struct {
+	GuestCartID string    `gorm:"column:guest_cart_id;primaryKey;size:36"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	Items []GuestCartItem `gorm:"foreignKey:GuestCartID;references:GuestCartID"`
+}
+
+func (GuestCart) TableName() string { return "guest_carts" }
+
+type GuestCartItem struct {
+	ID           uint    `gorm:"primaryKey"`
+	GuestCartID  string  `gorm:"column:guest_cart_id;size:36;not null;uniqueIndex:idx_guest_cart_product"`
+	ProductSKU   string  `gorm:"column:product_sku;size:13;not null;uniqueIndex:idx_guest_cart_product"`
+	Image        string  `gorm:"type:text"`
+	Title        string  `gorm:"size:255;not null"`
+	RegularPrice float64 `gorm:"column:regular_price;type:numeric(12,2);not null"`
+	Quantity     int     `gorm:"not null;default:1"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
 
-func (Cart) TableName() string { return "carts" }
+func (GuestCartItem) TableName() string { return "guest_cart_items" }
+
+// Produk yang dipantau customer; perubahan harga/stok dikabarkan sesuai preferensi notifikasinya
+type WishlistItem struct {
+	ID                uint    `gorm:"primaryKey"`
+	CustomerID        string  `gorm:"column:customer_id;size:13;not null;uniqueIndex:idx_wishlist_customer_product"`
+	ProductSKU        string  `gorm:"column:product_sku;size:13;not null;uniqueIndex:idx_wishlist_customer_product;index"`
+	PriceWhenAdded    float64 `gorm:"column:price_when_added;type:numeric(12,2);not null"`
+	NotifyPriceDrop   bool    `gorm:"column:notify_price_drop;not null;default:true"`
+	NotifyBackInStock bool    `gorm:"column:notify_back_in_stock;not null;default:true"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+
+	Product Product `gorm:"foreignKey:ProductSKU;references:ProductSKU"`
+}
+
+func (WishlistItem) TableName() string { return "wishlist_items" }
+
+const (
+	ProductNotificationPriceDrop   = "Price Drop"
+	ProductNotificationBackInStock = "Back In Stock"
+
+	ProductNotificationPending   = "Pending"
+	ProductNotificationSent      = "Sent"
+	ProductNotificationFailed    = "Failed"
+	ProductNotificationCancelled = "Cancelled" // Harga/stok sudah kembali sebelum notifikasi terkirim
+)
+
+// Antrean notifikasi wishlist. Baris dibuat saat produk berubah lalu dikirim oleh dispatcher
+// ke setiap channel (email, feed in-app) dengan batas jumlah per customer.
+type ProductNotification struct {
+	ID                uint       `gorm:"primaryKey"`
+	CustomerID        string     `gorm:"column:customer_id;size:13;not null;index"`
+	ProductSKU        string     `gorm:"column:product_sku;size:13;not null;index"`
+	Type              string     `gorm:"size:20;not null"`
+	Status            string     `gorm:"size:20;not null;default:'Pending';index"`
+	Title             string     `gorm:"size:255;not null"`
+	Message           string     `gorm:"type:text;not null"`
+	OldPrice          *float64   `gorm:"column:old_price;type:numeric(12,2)"`
+	NewPrice          *float64   `gorm:"column:new_price;type:numeric(12,2)"`
+	DeliveredChannels string     `gorm:"column:delivered_channels;size:100"` // Dipisah koma, agar retry tidak mengirim ulang
+	Attempts          int        `gorm:"not null;default:0"`
+	LastError         string     `gorm:"column:last_error;type:text"`
+	SentAt            *time.Time `gorm:"column:sent_at;index"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (ProductNotification) TableName() string { return "product_notifications" }
+
+// Feed notifikasi in-app customer
+type CustomerNotification struct {
+	ID                    uint       `gorm:"primaryKey"`
+	CustomerID            string     `gorm:"column:customer_id;size:13;not null;index"`
+	ProductNotificationID *uint      `gorm:"column:product_notification_id;uniqueIndex"`
+	Type                  string     `gorm:"size:20;not null"`
+	Title                 string     `gorm:"size:255;not null"`
+	Message               string     `gorm:"type:text;not null"`
+	ProductSKU            string     `gorm:"column:product_sku;size:13"`
+	ReadAt                *time.Time `gorm:"column:read_at"`
+	CreatedAt             time.Time
+}
+
+func (CustomerNotification) TableName() string { return "customer_notifications" }
 
 type Order struct {
-	OrderID                 string          `gorm:"primaryKey;size:10"`
-	CustomerID              string          `gorm:"column:customer_id;size:13;not null;index"`
-	CustomerFullname        string          `gorm:"column:customer_fullname;size:200;not null"`
-	CustomerEmail           string          `gorm:"column:customer_email;size:255;not null"`
-	CustomerPhone           string          `gorm:"column:customer_phone;size:20"`
-	ShippingAddressID       uint            `gorm:"column:shipping_address_id;not null"`
-	ShippingAddressSnapshot string          `gorm:"column:shipping_address_snapshot;type:text;not null"`
-	OrderDateTime           time.Time       `gorm:"column:order_date_time;not null;autoCreateTime"`
-	PaymentMethod           string          `gorm:"column:payment_method;size:50;not null"`
-	OrderStatus             string          `gorm:"column:order_status;size:50;not null"`
-	GrandTotal              float64         `gorm:"column:grand_total;type:numeric(12,2);not null"`
-	Notes                   string          `gorm:"type:text"`
-	ProofOfPayment          string          `gorm:"column:proof_of_payment;type:text"`
-	CreatedAt               time.Time       `gorm:"autoCreateTime"`
-	UpdatedAt               time.Time       `gorm:"autoUpdateTime"`
-	OrderItems              []OrderItem     `gorm:"foreignKey:OrderID;references:OrderID"`
-	Customer                Customer        `gorm:"foreignKey:CustomerID;references:CustomerID"`
-	ShippingAddress         CustomerAddress `gorm:"foreignKey:ShippingAddressID;references:AddressID"`
+	OrderID                 string    `gorm:"primaryKey;size:10"`
+	CustomerID              string    `gorm:"column:customer_id;size:13;not null;index"`
+	CustomerFullname        string    `gorm:"column:customer_fullname;size:200;not null"`
+	CustomerEmail           string    `gorm:"column:customer_email;size:255;not null"`
+	CustomerPhone           string    `gorm:"column:customer_phone;size:20"`
+	ShippingAddressID       uint      `gorm:"column:shipping_address_id;not null"`
+	ShippingAddressSnapshot string    `gorm:"column:shipping_address_snapshot;type:text;not null"`
+	OrderDateTime           time.Time `gorm:"column:order_date_time;not null;autoCreateTime"`
+	PaymentMethod           string    `gorm:"column:payment_method;size:50;not null"`
+	OrderStatus             string    `gorm:"column:order_status;size:50;not null"`
+	GrandTotal              float64   `gorm:"column:grand_total;type:numeric(12,2);not null"`
+	Notes                   string    `gorm:"type:text"`
+	ProofOfPayment          string    `gorm:"column:proof_of_payment;type:text"`
+	// Diisi jika order dibuat atas nama perusahaan; data perusahaan di-snapshot untuk invoice
+	CompanyID             *string            `gorm:"column:company_id;size:10;index"`
+	CompanyLegalName      string             `gorm:"column:company_legal_name;size:255"`
+	CompanyNPWP           string             `gorm:"column:company_npwp;size:16"`
+	CompanyBillingAddress string             `gorm:"column:company_billing_address;type:text"`
+	ApprovedBy            string             `gorm:"column:approved_by;size:13"`       // CustomerID approver perusahaan
+	ShippingCourier       string             `gorm:"column:shipping_courier;size:100"` // Deprecated: pengiriman dicatat di Shipment; hanya dibaca backfill order lama
+	TrackingNumber        string             `gorm:"column:tracking_number;size:100"`  // Deprecated: lihat ShippingCourier
+	ShippedAt             *time.Time         `gorm:"column:shipped_at"`                // Deprecated: lihat ShippingCourier
+	CreatedAt             time.Time          `gorm:"autoCreateTime"`
+	UpdatedAt             time.Time          `gorm:"autoUpdateTime"`
+	OrderItems            []OrderItem        `gorm:"foreignKey:OrderID;references:OrderID"`
+	PaymentMilestones     []PaymentMilestone `gorm:"foreignKey:OrderID;references:OrderID"`
+	Customer              Customer           `gorm:"foreignKey:CustomerID;references:CustomerID"`
+	ShippingAddress       CustomerAddress    `gorm:"foreignKey:ShippingAddressID;references:AddressID"`
 }
 
 func (Order) TableName() string { return "orders" }
@@ -103,6 +212,630 @@ type OrderItem struct {
 
 func (OrderItem) TableName() string { return "order_items" }
 
+// Status pengiriman
+const (
+	ShipmentInTransit = "In Transit"
+	ShipmentDelivered = "Delivered"
+)
+
+// Satu paket pengiriman order; order besar dapat dikirim bertahap dalam beberapa shipment
+type Shipment struct {
+	ShipmentID       string     `gorm:"primaryKey;size:10"` // Format SHP00001
+	OrderID          string     `gorm:"column:order_id;size:10;not null;index"`
+	Courier          string     `gorm:"size:100;not null"`
+	TrackingNumber   string     `gorm:"column:tracking_number;size:100;index"`
+	ShippedAt        time.Time  `gorm:"column:shipped_at;not null"`
+	EstimatedArrival *time.Time `gorm:"column:estimated_arrival;type:date"`
+	Status           string     `gorm:"size:20;not null;index"`
+	Notes            string     `gorm:"type:text"`
+	CreatedBy        string     `gorm:"column:created_by;size:13"` // EmployeeID
+	// Konfirmasi penerimaan
+	DeliveredAt   *time.Time     `gorm:"column:delivered_at"`
+	RecipientName string         `gorm:"column:recipient_name;size:255"`
+	DeliveryPhoto string         `gorm:"column:delivery_photo;type:text"`
+	DeliveryNotes string         `gorm:"column:delivery_notes;type:text"`
+	ConfirmedBy   string         `gorm:"column:confirmed_by;size:13"` // EmployeeID
+	Items         []ShipmentItem `gorm:"foreignKey:ShipmentID;references:ShipmentID"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (Shipment) TableName() string { return "shipments" }
+
+type ShipmentItem struct {
+	ID                   uint   `gorm:"primaryKey"`
+	ShipmentID           string `gorm:"column:shipment_id;size:10;not null;index"`
+	OrderItemID          uint   `gorm:"column:order_item_id;not null;index"`
+	ProductSKU           string `gorm:"column:product_sku;size:13;not null"`
+	ProductTitleSnapshot string `gorm:"column:product_title_snapshot;size:255;not null"`
+	Quantity             int    `gorm:"not null"`
+}
+
+func (ShipmentItem) TableName() string { return "shipment_items" }
+
+// Pihak yang mengubah status order
+const (
+	OrderStatusChangedByCustomer = "customer"
+	OrderStatusChangedByEmployee = "employee"
+)
+
+// Riwayat perubahan status order, ditampilkan sebagai timeline pelacakan pesanan
+type OrderStatusHistory struct {
+	ID            uint   `gorm:"primaryKey"`
+	OrderID       string `gorm:"column:order_id;size:10;not null;index"`
+	Status        string `gorm:"size:50;not null"`
+	Note          string `gorm:"type:text"`
+	ChangedByType string `gorm:"column:changed_by_type;size:20;not null"`
+	ChangedBy     string `gorm:"column:changed_by;size:13"` // CustomerID atau EmployeeID
+	CreatedAt     time.Time
+}
+
+func (OrderStatusHistory) TableName() string { return "order_status_histories" }
+
+// Status order yang masih boleh dibatalkan sendiri oleh customer
+var CustomerCancelableOrderStatuses = []string{"Pending", "Pending Confirmation", OrderStatusAwaitingApproval}
+
+// ApplyOrderCancellation menjalankan efek samping pembatalan order yang sama untuk semua jalur
+// (customer, approver perusahaan, admin): unit berseri yang direservasi dilepas, jadwal instalasi
+// aktif dibatalkan, dan jika restoreStock stok item dikembalikan. Status order diubah oleh pemanggil.
+func ApplyOrderCancellation(tx *gorm.DB, orderID string, restoreStock bool) error {
+	if restoreStock {
+		var items []OrderItem
+		if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+			return fmt.Errorf("gagal mengambil item pesanan: %w", err)
+		}
+		for _, item := range items {
+			if err := tx.Model(&Product{}).Where("product_sku = ?", item.ProductSKU).
+				Update("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+				return fmt.Errorf("gagal mengembalikan stok produk %s: %w", item.ProductSKU, err)
+			}
+		}
+	}
+	if err := tx.Model(&ProductUnit{}).
+		Where("order_id = ? AND status = ?", orderID, ProductUnitReserved).
+		Updates(map[string]interface{}{"status": ProductUnitInStock, "order_id": nil, "order_item_id": nil}).Error; err != nil {
+		return fmt.Errorf("gagal melepas reservasi unit: %w", err)
+	}
+	if err := tx.Model(&InstallationJob{}).
+		Where("order_id = ? AND status IN ?", orderID, ActiveInstallationJobStatuses).
+		Update("status", InstallationJobCanceled).Error; err != nil {
+		return fmt.Errorf("gagal membatalkan jadwal instalasi: %w", err)
+	}
+	return nil
+}
+
+// Status pengembalian dana
+const (
+	RefundPending    = "Pending"
+	RefundProcessing = "Processing"
+	RefundPaid       = "Paid"
+)
+
+// Pengembalian dana untuk order yang dibatalkan setelah sebagian / seluruh pembayarannya terverifikasi
+type Refund struct {
+	RefundID   string  `gorm:"primaryKey;size:10"` // Format RFD00001
+	OrderID    string  `gorm:"column:order_id;size:10;not null;index"`
+	RMAID      *string `gorm:"column:rma_id;size:10;uniqueIndex"` // Terisi jika refund berasal dari retur barang
+	CustomerID string  `gorm:"column:customer_id;size:13;not null;index"`
+	Amount     float64 `gorm:"type:numeric(12,2);not null"`
+	Reason     string  `gorm:"type:text"`
+	Status     string  `gorm:"size:20;not null;index"`
+	// Rekening tujuan yang diisi customer saat membatalkan / mengajukan retur
+	BankName          string     `gorm:"column:bank_name;size:100"`
+	BankAccountNumber string     `gorm:"column:bank_account_number;size:50"`
+	BankAccountHolder string     `gorm:"column:bank_account_holder;size:255"`
+	PaymentReference  string     `gorm:"column:payment_reference;size:100"` // Nomor referensi transfer dari admin
+	AdminNotes        string     `gorm:"column:admin_notes;type:text"`
+	ProcessedBy       string     `gorm:"column:processed_by;size:13"` // EmployeeID
+	PaidAt            *time.Time `gorm:"column:paid_at"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (Refund) TableName() string { return "refunds" }
+
+// Status, metode pengiriman dan resolusi retur barang (RMA)
+const (
+	ReturnRequested = "Requested"
+	ReturnApproved  = "Approved"
+	ReturnRejected  = "Rejected"
+	ReturnReceived  = "Received"
+	ReturnInspected = "Inspected"
+	ReturnResolved  = "Resolved"
+
+	ReturnMethodPickup  = "Pickup"
+	ReturnMethodDropOff = "Drop Off"
+	ReturnMethodCourier = "Courier"
+
+	ReturnResolutionRefund      = "Refund"
+	ReturnResolutionReplacement = "Replacement"
+	ReturnResolutionRepair      = "Repair"
+)
+
+// Retur sebagian / seluruh quantity satu OrderItem dari order yang sudah Completed
+type ReturnRequest struct {
+	RMAID                string `gorm:"column:rma_id;primaryKey;size:10"` // Format RMA00001
+	OrderID              string `gorm:"column:order_id;size:10;not null;index"`
+	OrderItemID          uint   `gorm:"column:order_item_id;not null;index"`
+	CustomerID           string `gorm:"column:customer_id;size:13;not null;index"`
+	ProductSKU           string `gorm:"column:product_sku;size:13;not null;index"`
+	ProductTitleSnapshot string `gorm:"column:product_title_snapshot;size:255;not null"`
+	Quantity             int    `gorm:"not null"`
+	Reason               string `gorm:"type:text;not null"`
+	ReturnMethod         string `gorm:"column:return_method;size:20;not null"`
+	Status               string `gorm:"size:20;not null;index"`
+	// Rekening tujuan jika retur diselesaikan dengan refund
+	BankName          string `gorm:"column:bank_name;size:100"`
+	BankAccountNumber string `gorm:"column:bank_account_number;size:50"`
+	BankAccountHolder string `gorm:"column:bank_account_holder;size:255"`
+
+	AdminNotes        string     `gorm:"column:admin_notes;type:text"` // Instruksi pengiriman / alasan penolakan
+	ReviewedBy        string     `gorm:"column:reviewed_by;size:13"`
+	ReceivedAt        *time.Time `gorm:"column:received_at"`
+	ReceivedBy        string     `gorm:"column:received_by;size:13"`
+	IsResellable      bool       `gorm:"column:is_resellable;default:false"`
+	RestockedQuantity int        `gorm:"column:restocked_quantity;default:0"`
+	InspectionNotes   string     `gorm:"column:inspection_notes;type:text"`
+	InspectedBy       string     `gorm:"column:inspected_by;size:13"`
+	InspectedAt       *time.Time `gorm:"column:inspected_at"`
+	Resolution        string     `gorm:"size:20"`
+	ResolutionNotes   string     `gorm:"column:resolution_notes;type:text"`
+	ResolvedBy        string     `gorm:"column:resolved_by;size:13"`
+	ResolvedAt        *time.Time `gorm:"column:resolved_at"`
+
+	Photos    []ReturnRequestPhoto `gorm:"foreignKey:RMAID;references:RMAID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (ReturnRequest) TableName() string { return "return_requests" }
+
+type ReturnRequestPhoto struct {
+	ID        uint   `gorm:"primaryKey"`
+	RMAID     string `gorm:"column:rma_id;size:10;not null;index"`
+	Image     string `gorm:"type:text;not null"`
+	CreatedAt time.Time
+}
+
+func (ReturnRequestPhoto) TableName() string { return "return_request_photos" }
+
+// Alasan mutasi stok
+const (
+	StockMovementReturn      = "Return"
+	StockMovementReplacement = "Replacement"
+)
+
+// Mutasi stok produk di luar penjualan biasa; Quantity positif berarti barang masuk gudang
+type StockMovement struct {
+	ID          uint   `gorm:"primaryKey"`
+	ProductSKU  string `gorm:"column:product_sku;size:13;not null;index"`
+	Quantity    int    `gorm:"not null"`
+	Reason      string `gorm:"size:30;not null"`
+	ReferenceID string `gorm:"column:reference_id;size:20;index"` // Misal RMAID
+	Notes       string `gorm:"type:text"`
+	CreatedBy   string `gorm:"column:created_by;size:13"` // EmployeeID
+	CreatedAt   time.Time
+}
+
+func (StockMovement) TableName() string { return "stock_movements" }
+
+// Status awal order perusahaan yang nilainya melewati batas persetujuan
+const OrderStatusAwaitingApproval = "Awaiting Approval"
+
+// Peran anggota perusahaan
+const (
+	CompanyRoleBuyer    = "buyer"
+	CompanyRoleApprover = "approver"
+)
+
+// Akun perusahaan (B2B) dengan beberapa customer sebagai pembeli/penyetuju
+type Company struct {
+	CompanyID      string `gorm:"primaryKey;size:10"` // Format CMP00001
+	LegalName      string `gorm:"column:legal_name;size:255;not null"`
+	NPWP           string `gorm:"column:npwp;size:16;not null;unique"` // Hanya digit (15 atau 16)
+	BillingAddress string `gorm:"column:billing_address;type:text;not null"`
+	// Order buyer di atas nilai ini harus disetujui approver; nil berarti tanpa persetujuan
+	ApprovalThreshold *float64        `gorm:"column:approval_threshold;type:numeric(14,2)"`
+	Members           []CompanyMember `gorm:"foreignKey:CompanyID;references:CompanyID"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (Company) TableName() string { return "companies" }
+
+// Keanggotaan customer di perusahaan; satu customer hanya bisa menjadi anggota satu perusahaan
+type CompanyMember struct {
+	ID         uint   `gorm:"primaryKey"`
+	CompanyID  string `gorm:"column:company_id;size:10;not null;index"`
+	CustomerID string `gorm:"column:customer_id;size:13;not null;uniqueIndex"`
+	Role       string `gorm:"size:20;not null"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (CompanyMember) TableName() string { return "company_members" }
+
+// Status termin pembayaran
+const (
+	PaymentMilestoneUnpaid               = "Unpaid"
+	PaymentMilestoneAwaitingVerification = "Awaiting Verification"
+	PaymentMilestonePaid                 = "Paid"
+	PaymentMilestoneRejected             = "Rejected"
+)
+
+// Termin pembayaran (DP, pelunasan saat kirim, dst.) untuk Order yang tidak dibayar sekaligus
+type PaymentMilestone struct {
+	ID              uint       `gorm:"primaryKey"`
+	OrderID         string     `gorm:"column:order_id;size:10;not null;index"`
+	Sequence        int        `gorm:"not null"`
+	Label           string     `gorm:"size:100;not null"`
+	Percentage      *float64   `gorm:"type:numeric(5,2)"` // Diisi jika termin ditentukan dalam persen
+	Amount          float64    `gorm:"type:numeric(12,2);not null"`
+	DueDate         time.Time  `gorm:"column:due_date;type:date;not null;index"`
+	Status          string     `gorm:"size:30;not null;index"`
+	ProofOfPayment  string     `gorm:"column:proof_of_payment;type:text"`
+	SubmittedAt     *time.Time `gorm:"column:submitted_at"`
+	VerifiedBy      string     `gorm:"column:verified_by;size:13"`
+	VerifiedAt      *time.Time `gorm:"column:verified_at"`
+	RejectionReason string     `gorm:"column:rejection_reason;type:text"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (PaymentMilestone) TableName() string { return "payment_milestones" }
+
+// OutstandingBalance menghitung sisa tagihan order; PaymentMilestones harus sudah di-preload.
+// Order tanpa jadwal termin dianggap lunas sejak diproses admin (Processed/Shipped/Completed).
+func (o Order) OutstandingBalance() float64 {
+	if o.OrderStatus == "Canceled" {
+		return 0
+	}
+	if len(o.PaymentMilestones) == 0 {
+		switch o.OrderStatus {
+		case "Processed", "Shipped", "Completed":
+			return 0
+		}
+		return o.GrandTotal
+	}
+	outstanding := o.GrandTotal
+	for _, milestone := range o.PaymentMilestones {
+		if milestone.Status == PaymentMilestonePaid {
+			outstanding -= milestone.Amount
+		}
+	}
+	if outstanding < 0 {
+		return 0
+	}
+	return outstanding
+}
+
+var warrantyPeriodPattern = regexp.MustCompile(`^(\d+)\s*(months?|bulan|years?|tahun)$`)
+
+// FormatRupiah memformat angka menjadi "Rp 1.234.567"; angka negatif menjadi "-Rp 1.234.567".
+func FormatRupiah(amount float64) string {
+	rounded := int64(math.Round(amount))
+	negative := rounded < 0
+	if negative {
+		rounded = -rounded
+	}
+	digits := fmt.Sprintf("%d", rounded)
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	if negative {
+		return "-Rp " + grouped.String()
+	}
+	return "Rp " + grouped.String()
+}
+
+// ParseWarrantyPeriod mengubah teks seperti "12 Months" atau "2 Tahun" menjadi jumlah bulan.
+// Teks kosong berarti tanpa garansi (nil, true); format lain tidak dikenali (nil, false).
+func ParseWarrantyPeriod(period string) (*int, bool) {
+	normalized := strings.ToLower(strings.TrimSpace(period))
+	if normalized == "" {
+		return nil, true
+	}
+	match := warrantyPeriodPattern.FindStringSubmatch(normalized)
+	if match == nil {
+		return nil, false
+	}
+	value, err := strconv.Atoi(match[1])
+	if err != nil {
+		return nil, false
+	}
+	if strings.HasPrefix(match[2], "year") || match[2] == "tahun" {
+		value *= 12
+	}
+	if value == 0 {
+		return nil, true
+	}
+	return &value, true
+}
+
+// Status klaim garansi
+const (
+	WarrantyClaimSubmitted = "Submitted"
+	WarrantyClaimInReview  = "In Review"
+	WarrantyClaimApproved  = "Approved"
+	WarrantyClaimRejected  = "Rejected"
+	WarrantyClaimResolved  = "Resolved"
+)
+
+// Garansi satu unit barang dari OrderItem; dibuat saat order Completed
+type Warranty struct {
+	ID                   uint      `gorm:"primaryKey"`
+	OrderID              string    `gorm:"column:order_id;size:10;not null;index"`
+	OrderItemID          uint      `gorm:"column:order_item_id;not null;uniqueIndex:idx_warranty_unit"`
+	UnitIndex            int       `gorm:"column:unit_index;not null;uniqueIndex:idx_warranty_unit"` // Unit ke-n dari quantity OrderItem
+	CustomerID           string    `gorm:"column:customer_id;size:13;not null;index"`
+	ProductSKU           string    `gorm:"column:product_sku;size:13;not null;index"`
+	ProductTitleSnapshot string    `gorm:"column:product_title_snapshot;size:255;not null"`
+	SerialNumber         string    `gorm:"column:serial_number;size:100;index"`
+	StartDate            time.Time `gorm:"column:start_date;type:date;not null"`
+	EndDate              time.Time `gorm:"column:end_date;type:date;not null"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+func (Warranty) TableName() string { return "warranties" }
+
+type WarrantyClaim struct {
+	ClaimID         string               `gorm:"primaryKey;size:10"` // Format WCL00001
+	WarrantyID      uint                 `gorm:"column:warranty_id;not null;index"`
+	CustomerID      string               `gorm:"column:customer_id;size:13;not null;index"`
+	Description     string               `gorm:"type:text;not null"`
+	Status          string               `gorm:"size:20;not null;index"`
+	ResolutionNotes string               `gorm:"column:resolution_notes;type:text"`
+	HandledBy       string               `gorm:"column:handled_by;size:13"` // EmployeeID
+	ResolvedAt      *time.Time           `gorm:"column:resolved_at"`
+	Photos          []WarrantyClaimPhoto `gorm:"foreignKey:ClaimID;references:ClaimID"`
+	Warranty        Warranty             `gorm:"foreignKey:WarrantyID;references:ID"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (WarrantyClaim) TableName() string { return "warranty_claims" }
+
+type WarrantyClaimPhoto struct {
+	ID        uint   `gorm:"primaryKey"`
+	ClaimID   string `gorm:"column:claim_id;size:10;not null;index"`
+	Image     string `gorm:"type:text;not null"`
+	CreatedAt time.Time
+}
+
+func (WarrantyClaimPhoto) TableName() string { return "warranty_claim_photos" }
+
+// Kategori, prioritas dan status tiket layanan purna jual
+const (
+	ServiceTicketCategoryMaintenance  = "Maintenance"
+	ServiceTicketCategoryInstallation = "Installation"
+	ServiceTicketCategoryRepair       = "Repair"
+	ServiceTicketCategoryOther        = "Other"
+
+	ServiceTicketPriorityLow    = "Low"
+	ServiceTicketPriorityNormal = "Normal"
+	ServiceTicketPriorityHigh   = "High"
+	ServiceTicketPriorityUrgent = "Urgent"
+
+	ServiceTicketOpen            = "Open"
+	ServiceTicketInProgress      = "In Progress"
+	ServiceTicketWaitingCustomer = "Waiting Customer"
+	ServiceTicketResolved        = "Resolved"
+	ServiceTicketClosed          = "Closed"
+)
+
+// Pengirim pesan pada thread tiket
+const (
+	ServiceTicketSenderCustomer = "customer"
+	ServiceTicketSenderEmployee = "employee"
+)
+
+type ServiceTicket struct {
+	TicketID      string                 `gorm:"primaryKey;size:10"` // Format TCK00001
+	CustomerID    string                 `gorm:"column:customer_id;size:13;not null;index"`
+	Subject       string                 `gorm:"size:255;not null"`
+	Category      string                 `gorm:"size:20;not null"`
+	Priority      string                 `gorm:"size:20;not null"`
+	Status        string                 `gorm:"size:20;not null;index"`
+	OrderItemID   *uint                  `gorm:"column:order_item_id;index"`
+	ProductSKU    string                 `gorm:"column:product_sku;size:13"`
+	SerialNumber  string                 `gorm:"column:serial_number;size:100;index"`
+	DepartmentID  *string                `gorm:"column:department_id;size:7;index"`
+	AssignedTo    *string                `gorm:"column:assigned_to;size:13;index"` // EmployeeID
+	LastMessageAt time.Time              `gorm:"column:last_message_at"`
+	ResolvedAt    *time.Time             `gorm:"column:resolved_at"`
+	ClosedAt      *time.Time             `gorm:"column:closed_at"`
+	Messages      []ServiceTicketMessage `gorm:"foreignKey:TicketID;references:TicketID"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (ServiceTicket) TableName() string { return "service_tickets" }
+
+type ServiceTicketMessage struct {
+	ID          uint                      `gorm:"primaryKey"`
+	TicketID    string                    `gorm:"column:ticket_id;size:10;not null;index"`
+	SenderType  string                    `gorm:"column:sender_type;size:10;not null"`
+	SenderID    string                    `gorm:"column:sender_id;size:13;not null"` // CustomerID atau EmployeeID
+	Message     string                    `gorm:"type:text;not null"`
+	Attachments []ServiceTicketAttachment `gorm:"foreignKey:MessageID;references:ID"`
+	CreatedAt   time.Time
+}
+
+func (ServiceTicketMessage) TableName() string { return "service_ticket_messages" }
+
+type ServiceTicketAttachment struct {
+	ID        uint   `gorm:"primaryKey"`
+	MessageID uint   `gorm:"column:message_id;not null;index"`
+	FileName  string `gorm:"column:file_name;size:255;not null"` // Nama file asli dari pengunggah
+	FilePath  string `gorm:"column:file_path;type:text;not null"`
+	CreatedAt time.Time
+}
+
+func (ServiceTicketAttachment) TableName() string { return "service_ticket_attachments" }
+
+// Status pekerjaan instalasi
+const (
+	InstallationJobRequested  = "Requested"
+	InstallationJobScheduled  = "Scheduled"
+	InstallationJobInProgress = "In Progress"
+	InstallationJobCompleted  = "Completed"
+	InstallationJobCanceled   = "Canceled"
+)
+
+// Pekerjaan yang masih berjalan; satu order hanya boleh punya satu pekerjaan aktif
+var ActiveInstallationJobStatuses = []string{InstallationJobRequested, InstallationJobScheduled, InstallationJobInProgress}
+
+// Status order yang boleh diajukan instalasi: pembayaran sudah terverifikasi. Serah terima instalasi
+// hanya menyelesaikan order yang sudah Shipped.
+var InstallableOrderStatuses = []string{"Processed", "Shipped"}
+
+// Pekerjaan instalasi & commissioning di lokasi customer untuk sebuah order
+type InstallationJob struct {
+	JobID              string                      `gorm:"primaryKey;size:10"` // Format INS00001
+	OrderID            string                      `gorm:"column:order_id;size:10;not null;index"`
+	CustomerID         string                      `gorm:"column:customer_id;size:13;not null;index"`
+	SiteAddress        string                      `gorm:"column:site_address;type:text;not null"`
+	RequestedStartDate time.Time                   `gorm:"column:requested_start_date;type:date;not null"`
+	RequestedEndDate   time.Time                   `gorm:"column:requested_end_date;type:date;not null"`
+	ScheduledStart     *time.Time                  `gorm:"column:scheduled_start;index"`
+	ScheduledEnd       *time.Time                  `gorm:"column:scheduled_end;index"`
+	Status             string                      `gorm:"size:20;not null;index"`
+	Notes              string                      `gorm:"type:text"`
+	SignedOffBy        string                      `gorm:"column:signed_off_by;size:255"` // Nama perwakilan customer saat serah terima
+	SignOffNotes       string                      `gorm:"column:sign_off_notes;type:text"`
+	CompletedBy        string                      `gorm:"column:completed_by;size:13"` // EmployeeID
+	CompletedAt        *time.Time                  `gorm:"column:completed_at"`
+	Technicians        []InstallationJobTechnician `gorm:"foreignKey:JobID;references:JobID"`
+	ChecklistItems     []InstallationChecklistItem `gorm:"foreignKey:JobID;references:JobID"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+func (InstallationJob) TableName() string { return "installation_jobs" }
+
+type InstallationJobTechnician struct {
+	ID         uint   `gorm:"primaryKey"`
+	JobID      string `gorm:"column:job_id;size:10;not null;uniqueIndex:idx_job_technician"`
+	EmployeeID string `gorm:"column:employee_id;size:13;not null;uniqueIndex:idx_job_technician;index"`
+	CreatedAt  time.Time
+}
+
+func (InstallationJobTechnician) TableName() string { return "installation_job_technicians" }
+
+type InstallationChecklistItem struct {
+	ID          uint       `gorm:"primaryKey"`
+	JobID       string     `gorm:"column:job_id;size:10;not null;index"`
+	Position    int        `gorm:"not null"`
+	Description string     `gorm:"size:255;not null"`
+	IsDone      bool       `gorm:"column:is_done;default:false"`
+	DoneBy      string     `gorm:"column:done_by;size:13"` // EmployeeID
+	DoneAt      *time.Time `gorm:"column:done_at"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (InstallationChecklistItem) TableName() string { return "installation_checklist_items" }
+
+// Checklist bawaan jika admin tidak menentukan checklist sendiri
+var DefaultInstallationChecklist = []string{
+	"Pemeriksaan kesiapan lokasi & pondasi",
+	"Pemasangan dan penyetelan unit",
+	"Pengujian tanpa beban",
+	"Commissioning / uji beban",
+	"Pelatihan operator",
+}
+
+// Jenis dokumen PDF yang dihasilkan untuk sebuah Order
+const (
+	OrderDocumentInvoice  = "invoice"
+	OrderDocumentProforma = "proforma"
+)
+
+// Dokumen PDF yang tersimpan untuk sebuah Order; dibuat ulang jika Fingerprint data order berubah
+type OrderDocument struct {
+	ID             uint      `gorm:"primaryKey"`
+	OrderID        string    `gorm:"column:order_id;size:10;not null;uniqueIndex:idx_order_document_type"`
+	DocumentType   string    `gorm:"column:document_type;size:20;not null;uniqueIndex:idx_order_document_type"`
+	DocumentNumber string    `gorm:"column:document_number;size:10;not null;unique"` // Format INV00001 / PRO00001
+	FilePath       string    `gorm:"column:file_path;type:text;not null"`
+	Fingerprint    string    `gorm:"size:64;not null"`
+	GeneratedAt    time.Time `gorm:"column:generated_at;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (OrderDocument) TableName() string { return "order_documents" }
+
+// Status permintaan penawaran (RFQ)
+const (
+	QuotationRequestSubmitted = "Submitted" // Menunggu penawaran dari sales
+	QuotationRequestQuoted    = "Quoted"    // Quotation sudah dikirim, menunggu keputusan customer
+	QuotationRequestAccepted  = "Accepted"  // Diterima customer dan sudah menjadi Order
+	QuotationRequestDeclined  = "Declined"  // Ditolak atau dibatalkan customer
+)
+
+// Permintaan penawaran harga dari customer untuk satu atau lebih SKU
+type QuotationRequest struct {
+	RequestID    string                 `gorm:"primaryKey;size:10"` // Format RFQ00001
+	CustomerID   string                 `gorm:"column:customer_id;size:13;not null;index"`
+	SiteLocation string                 `gorm:"column:site_location;type:text;not null"`
+	Notes        string                 `gorm:"type:text"`
+	Status       string                 `gorm:"size:20;not null;index"`
+	Items        []QuotationRequestItem `gorm:"foreignKey:RequestID;references:RequestID"`
+	Quotation    *Quotation             `gorm:"foreignKey:RequestID;references:RequestID"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (QuotationRequest) TableName() string { return "quotation_requests" }
+
+type QuotationRequestItem struct {
+	ID                   uint   `gorm:"primaryKey"`
+	RequestID            string `gorm:"column:request_id;size:10;not null;index"`
+	ProductSKU           string `gorm:"column:product_sku;size:13;not null"`
+	ProductTitleSnapshot string `gorm:"column:product_title_snapshot;size:255;not null"`
+	Quantity             int    `gorm:"not null"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+func (QuotationRequestItem) TableName() string { return "quotation_request_items" }
+
+// Penawaran harga dari sales untuk sebuah QuotationRequest
+type Quotation struct {
+	QuotationID string          `gorm:"primaryKey;size:10"` // Format QUO00001
+	RequestID   string          `gorm:"column:request_id;size:10;not null;uniqueIndex"`
+	PreparedBy  string          `gorm:"column:prepared_by;size:13;not null"` // EmployeeID sales
+	ValidUntil  time.Time       `gorm:"column:valid_until;type:date;not null"`
+	Terms       string          `gorm:"type:text"`
+	TotalAmount float64         `gorm:"column:total_amount;type:numeric(14,2);not null"`
+	OrderID     *string         `gorm:"column:order_id;size:10"` // Diisi setelah diterima customer
+	AcceptedAt  *time.Time      `gorm:"column:accepted_at"`
+	Items       []QuotationItem `gorm:"foreignKey:QuotationID;references:QuotationID"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (Quotation) TableName() string { return "quotations" }
+
+type QuotationItem struct {
+	ID                   uint    `gorm:"primaryKey"`
+	QuotationID          string  `gorm:"column:quotation_id;size:10;not null;index"`
+	ProductSKU           string  `gorm:"column:product_sku;size:13;not null"`
+	ProductTitleSnapshot string  `gorm:"column:product_title_snapshot;size:255;not null"`
+	Quantity             int     `gorm:"not null"`
+	UnitPrice            float64 `gorm:"column:unit_price;type:numeric(12,2);not null"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+func (QuotationItem) TableName() string { return "quotation_items" }
+
 // === MODEL-MODEL DARI DOMAIN ADMIN ===
 
 type Employee struct {
@@ -165,12 +898,60 @@ type ProductCategory struct {
 	Description   string `gorm:"type:text"`
 	Status        string `gorm:"not null;size:20"`
 	ProductsCount int64  `gorm:"-"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// Skema spesifikasi teknis yang berlaku untuk produk di kategori ini
+	Attributes []ProductCategoryAttribute `gorm:"foreignKey:CategoryID;references:CategoryID"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 func (ProductCategory) TableName() string { return "product_categories" }
 
+// Tipe data atribut spesifikasi
+const (
+	AttributeTypeNumber = "number" // Angka dengan satuan (Unit), misal 7.5 kW
+	AttributeTypeEnum   = "enum"   // Salah satu dari Options
+	AttributeTypeText   = "text"   // Teks bebas
+)
+
+type ProductCategoryAttribute struct {
+	ID           uint     `gorm:"primaryKey"`
+	CategoryID   string   `gorm:"size:7;not null;uniqueIndex:idx_category_attribute_code"`
+	Code         string   `gorm:"size:50;not null;uniqueIndex:idx_category_attribute_code"` // Kunci stabil untuk API & filter, misal "motor_power"
+	Label        string   `gorm:"size:100;not null"`
+	DataType     string   `gorm:"column:data_type;size:10;not null"`
+	Unit         string   `gorm:"size:20"`
+	Options      []string `gorm:"type:jsonb;serializer:json"` // Pilihan nilai untuk tipe enum
+	IsRequired   bool     `gorm:"column:is_required;not null;default:false"`
+	IsFilterable bool     `gorm:"column:is_filterable;not null;default:false"`
+	Position     int      `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (ProductCategoryAttribute) TableName() string { return "product_category_attributes" }
+
+// Nilai spesifikasi satu produk untuk satu atribut kategori.
+// NumberValue dipakai untuk tipe number, TextValue untuk enum dan text.
+type ProductSpecification struct {
+	ID          uint                     `gorm:"primaryKey"`
+	ProductSKU  string                   `gorm:"size:14;not null;uniqueIndex:idx_product_specification_attribute"`
+	AttributeID uint                     `gorm:"not null;uniqueIndex:idx_product_specification_attribute;index"`
+	Attribute   ProductCategoryAttribute `gorm:"foreignKey:AttributeID"`
+	NumberValue *float64                 `gorm:"type:numeric(14,4)"`
+	TextValue   string                   `gorm:"type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (ProductSpecification) TableName() string { return "product_specifications" }
+
+// OrderedProductSpecifications mengurutkan spesifikasi produk mengikuti urutan atribut di kategori.
+func OrderedProductSpecifications(db *gorm.DB) *gorm.DB {
+	return db.Select("product_specifications.*").
+		Joins("JOIN product_category_attributes ON product_category_attributes.id = product_specifications.attribute_id").
+		Order("product_category_attributes.position ASC, product_specifications.id ASC")
+}
+
 type Product struct {
 	ProductSKU        string          `gorm:"primaryKey;size:13"`
 	Title             string          `gorm:"not null;size:255"`
@@ -179,29 +960,218 @@ type Product struct {
 	ProductCategory   ProductCategory `gorm:"foreignKey:ProductCategoryID;references:CategoryID"`
 	PowerSource       string          `gorm:"size:100"`
 	WarrantyPeriod    string          `gorm:"size:50"`
+	WarrantyMonths    *int            `gorm:"column:warranty_months"` // Hasil parsing WarrantyPeriod; nil jika tanpa garansi
 	ProductionDate    *time.Time      `gorm:"type:date"`
 	Descriptions      string          `gorm:"type:text"`
 	Stock             int             `gorm:"default:0"`
-	Status            string          `gorm:"not null;size:20"`
-	CapitalPrice      float64         `gorm:"type:numeric(12,2)"`
-	RegularPrice      float64         `gorm:"type:numeric(12,2)"`
-	Images            []ProductImage  `gorm:"foreignKey:ProductSKU;references:ProductSKU"`
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
+	IsSerialized      bool            `gorm:"column:is_serialized;default:false"` // Setiap unit dilacak dengan nomor seri / nomor mesin
+	// Mesin yang perlu instalasi & commissioning di lokasi (misal batching plant, crusher)
+	RequiresInstallation bool `gorm:"column:requires_installation;default:false"`
+	// Mode sewa: tarif 0 berarti periode tersebut tidak ditawarkan, tarif harian wajib diisi
+	IsRentable        bool                   `gorm:"column:is_rentable;default:false"`
+	RentalDailyRate   float64                `gorm:"column:rental_daily_rate;type:numeric(12,2);default:0"`
+	RentalWeeklyRate  float64                `gorm:"column:rental_weekly_rate;type:numeric(12,2);default:0"`
+	RentalMonthlyRate float64                `gorm:"column:rental_monthly_rate;type:numeric(12,2);default:0"`
+	RentalDeposit     float64                `gorm:"column:rental_deposit;type:numeric(12,2);default:0"`
+	Status            string                 `gorm:"not null;size:20"`
+	CapitalPrice      float64                `gorm:"type:numeric(12,2)"`
+	RegularPrice      float64                `gorm:"type:numeric(12,2)"`
+	Images            []ProductImage         `gorm:"foreignKey:ProductSKU;references:ProductSKU"`
+	Specifications    []ProductSpecification `gorm:"foreignKey:ProductSKU;references:ProductSKU"`
+	// Diisi jika produk ini adalah varian. Induk menyimpan konten bersama (deskripsi, gambar),
+	// varian menyimpan SKU, harga, stok dan spesifikasinya sendiri.
+	ParentSKU   *string   `gorm:"column:parent_sku;size:13;index"`
+	VariantName string    `gorm:"column:variant_name;size:100"` // Misal "Standard" atau "Diesel"
+	Parent      *Product  `gorm:"foreignKey:ParentSKU;references:ProductSKU"`
+	Variants    []Product `gorm:"foreignKey:ParentSKU;references:ProductSKU"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
 func (Product) TableName() string { return "products" }
 
+// DisplayTitle menambahkan nama varian ke judul agar snapshot keranjang, pesanan, dan notifikasi jelas.
+func (p Product) DisplayTitle() string {
+	if p.VariantName == "" || strings.Contains(p.Title, p.VariantName) {
+		return p.Title
+	}
+	return fmt.Sprintf("%s (%s)", p.Title, p.VariantName)
+}
+
+// RentalPrice menghitung biaya sewa untuk sejumlah hari: bulan (30 hari) dan minggu (7 hari) dipakai
+// jika tarifnya tersedia, sisa hari dihitung harian tapi tidak pernah melebihi tarif periode di atasnya.
+func (p Product) RentalPrice(days int) float64 {
+	if days <= 0 {
+		return 0
+	}
+	// Biaya untuk kurang dari satu minggu
+	shortTerm := func(d int) float64 {
+		cost := float64(d) * p.RentalDailyRate
+		if p.RentalWeeklyRate > 0 && d > 0 && cost > p.RentalWeeklyRate {
+			cost = p.RentalWeeklyRate
+		}
+		return cost
+	}
+	// Biaya untuk kurang dari satu bulan
+	midTerm := func(d int) float64 {
+		cost := shortTerm(d)
+		if p.RentalWeeklyRate > 0 {
+			cost = float64(d/7)*p.RentalWeeklyRate + shortTerm(d%7)
+		}
+		if p.RentalMonthlyRate > 0 && d > 0 && cost > p.RentalMonthlyRate {
+			cost = p.RentalMonthlyRate
+		}
+		return cost
+	}
+	if p.RentalMonthlyRate > 0 {
+		return float64(days/30)*p.RentalMonthlyRate + midTerm(days%30)
+	}
+	return midTerm(days)
+}
+
+// Status unit armada sewa
+const (
+	RentalUnitAvailable   = "Available"
+	RentalUnitMaintenance = "Maintenance"
+	RentalUnitRetired     = "Retired"
+)
+
+// Unit fisik milik armada sewa; terpisah dari ProductUnit yang dijual
+type RentalUnit struct {
+	ID         uint   `gorm:"primaryKey"`
+	ProductSKU string `gorm:"column:product_sku;size:13;not null;index"`
+	UnitCode   string `gorm:"column:unit_code;size:100;not null;uniqueIndex"` // Nomor armada / nomor seri
+	Status     string `gorm:"size:20;not null;index"`
+	Notes      string `gorm:"size:255"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (RentalUnit) TableName() string { return "rental_units" }
+
+// Status booking sewa
+const (
+	RentalBookingPending   = "Pending"
+	RentalBookingConfirmed = "Confirmed"
+	RentalBookingOnRent    = "On Rent"
+	RentalBookingReturned  = "Returned"
+	RentalBookingCanceled  = "Canceled"
+)
+
+// Booking yang masih menempati kalender unit
+var ActiveRentalBookingStatuses = []string{RentalBookingPending, RentalBookingConfirmed, RentalBookingOnRent}
+
+// Jenis entri pada riwayat pesanan customer dan daftar pesanan admin
+const (
+	OrderHistoryEntryOrder  = "order"
+	OrderHistoryEntryRental = "rental"
+)
+
+type RentalBooking struct {
+	BookingID               string     `gorm:"primaryKey;size:10"` // Format RNT00001
+	CustomerID              string     `gorm:"column:customer_id;size:13;not null;index"`
+	CustomerFullname        string     `gorm:"column:customer_fullname;size:255"`
+	CustomerEmail           string     `gorm:"column:customer_email;size:255"`
+	CustomerPhone           string     `gorm:"column:customer_phone;size:20"`
+	RentalUnitID            uint       `gorm:"column:rental_unit_id;not null;index"`
+	ProductSKU              string     `gorm:"column:product_sku;size:13;not null;index"`
+	ProductTitleSnapshot    string     `gorm:"column:product_title_snapshot;size:255;not null"`
+	StartDate               time.Time  `gorm:"column:start_date;type:date;not null;index"`
+	EndDate                 time.Time  `gorm:"column:end_date;type:date;not null;index"` // Inklusif
+	RentalDays              int        `gorm:"column:rental_days;not null"`
+	RentalPrice             float64    `gorm:"column:rental_price;type:numeric(12,2);not null"`
+	Deposit                 float64    `gorm:"type:numeric(12,2);not null;default:0"`
+	Status                  string     `gorm:"size:20;not null;index"`
+	ShippingAddressSnapshot string     `gorm:"column:shipping_address_snapshot;type:text"`
+	Notes                   string     `gorm:"type:text"`
+	HandedOverAt            *time.Time `gorm:"column:handed_over_at"`
+	ReturnedAt              *time.Time `gorm:"column:returned_at"`
+	// Hasil inspeksi saat unit kembali
+	ReturnCondition string     `gorm:"column:return_condition;size:20"` // Good, Minor Damage, Major Damage
+	InspectionNotes string     `gorm:"column:inspection_notes;type:text"`
+	DamageCharge    float64    `gorm:"column:damage_charge;type:numeric(12,2);default:0"`
+	DepositRefund   float64    `gorm:"column:deposit_refund;type:numeric(12,2);default:0"`
+	InspectedBy     string     `gorm:"column:inspected_by;size:13"` // EmployeeID
+	CanceledAt      *time.Time `gorm:"column:canceled_at"`
+	RentalUnit      RentalUnit `gorm:"foreignKey:RentalUnitID;references:ID"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (RentalBooking) TableName() string { return "rental_bookings" }
+
+// Status unit produk berseri
+const (
+	ProductUnitInStock  = "In Stock"
+	ProductUnitReserved = "Reserved"
+	ProductUnitShipped  = "Shipped"
+)
+
+// Satu unit fisik produk berseri beserta order item tempat unit tersebut dikirim
+type ProductUnit struct {
+	ID           uint       `gorm:"primaryKey"`
+	ProductSKU   string     `gorm:"column:product_sku;size:13;not null;index"`
+	SerialNumber string     `gorm:"column:serial_number;size:100;not null;uniqueIndex"`
+	Status       string     `gorm:"size:20;not null;index"`
+	OrderID      *string    `gorm:"column:order_id;size:10;index"`
+	OrderItemID  *uint      `gorm:"column:order_item_id;index"`
+	ShippedAt    *time.Time `gorm:"column:shipped_at"`
+	Notes        string     `gorm:"size:255"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (ProductUnit) TableName() string { return "product_units" }
+
+// Relasi kompatibilitas suku cadang (PartSKU) dengan mesin (MachineSKU)
+type ProductCompatibility struct {
+	ID         uint    `gorm:"primaryKey"`
+	PartSKU    string  `gorm:"column:part_sku;size:13;not null;uniqueIndex:idx_part_machine"`
+	MachineSKU string  `gorm:"column:machine_sku;size:13;not null;uniqueIndex:idx_part_machine;index"`
+	Notes      string  `gorm:"size:255"` // Misal "Untuk unit produksi 2020 ke atas"
+	Part       Product `gorm:"foreignKey:PartSKU;references:ProductSKU"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (ProductCompatibility) TableName() string { return "product_compatibilities" }
+
 type ProductImage struct {
 	ID         uint   `gorm:"primaryKey"` // Menambahkan ID sebagai PK yang lebih standar untuk GORM
 	ProductSKU string `gorm:"size:14;index;not null"`
 	Image      string `gorm:"type:text;not null"`
+	AltText    string `gorm:"column:alt_text;size:255"`
+	Position   int    `gorm:"not null;default:0"`                       // Urutan tampil, 0 = paling awal
+	IsPrimary  bool   `gorm:"column:is_primary;not null;default:false"` // Gambar utama untuk thumbnail listing
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
 
 func (ProductImage) TableName() string { return "product_images" }
 
+// OrderedProductImages dipakai saat Preload("Images") agar gambar selalu terurut sesuai kolom position.
+func OrderedProductImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
+
+// PrimaryProductImagesBySKU mengembalikan path gambar utama tiap SKU (gambar pertama jika belum ada yang ditandai).
+func PrimaryProductImagesBySKU(db *gorm.DB, productSKUs []string) (map[string]string, error) {
+	images := make(map[string]string, len(productSKUs))
+	if len(productSKUs) == 0 {
+		return images, nil
+	}
+	var rows []ProductImage
+	if err := db.Where("product_sku IN ?", productSKUs).Order("is_primary DESC, position ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil gambar produk: %w", err)
+	}
+	for _, row := range rows {
+		if _, ok := images[row.ProductSKU]; !ok {
+			images[row.ProductSKU] = row.Image
+		}
+	}
+	return images, nil
+}
+
 type NewsCategory struct {
 	CategoryID    string `gorm:"primaryKey;size:8"`
 	CategoryName  string `gorm:"not null;unique;size:100"`
@@ -235,3 +1205,23 @@ type NewsPost struct {
 func (NewsPost) TableName() string {
 	return "news"
 }
+
+// Hasil request POST yang dikirim dengan header Idempotency-Key, disimpan agar retry dari client
+// mendapat respons yang sama tanpa menjalankan ulang request
+type IdempotencyRecord struct {
+	ID             uint       `gorm:"primaryKey"`
+	Scope          string     `gorm:"size:30;not null;uniqueIndex:idx_idempotency_scope_key"` // customer:<id> atau employee:<id>
+	IdempotencyKey string     `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_scope_key"`
+	Method         string     `gorm:"size:10;not null"`
+	Path           string     `gorm:"size:255;not null"`
+	RequestHash    string     `gorm:"column:request_hash;size:64;not null"`
+	StatusCode     int        `gorm:"column:status_code"`
+	ContentType    string     `gorm:"column:content_type;size:100"`
+	ResponseBody   []byte     `gorm:"column:response_body"`
+	CompletedAt    *time.Time `gorm:"column:completed_at"` // null = request pertama masih diproses
+	LockedUntil    *time.Time `gorm:"column:locked_until"` // Diperpanjang selama request pertama berjalan
+	ExpiresAt      time.Time  `gorm:"column:expires_at;not null;index"`
+	CreatedAt      time.Time
+}
+
+func (IdempotencyRecord) TableName() string { return "idempotency_records" }
@@ -0,0 +1,99 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"backend-user/domain/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Penerima notifikasi, diambil dari data customer saat dispatch
+type Recipient struct {
+	CustomerID string
+	Email      string
+	Name       string
+}
+
+// Channel mengirim satu notifikasi ke customer lewat satu media. Nama channel disimpan di
+// DeliveredChannels agar channel yang sudah berhasil tidak dikirim ulang saat retry.
+type Channel interface {
+	Name() string
+	Deliver(recipient Recipient, notification models.ProductNotification) error
+}
+
+// ErrChannelUnavailable dikembalikan channel yang belum terhubung ke layanan pengirim. Dispatcher
+// melewati channel tersebut tanpa mencatatnya sebagai terkirim maupun gagal.
+var ErrChannelUnavailable = errors.New("channel notifikasi belum dikonfigurasi")
+
+// EmailSender adalah integrasi pengirim email (SMTP, layanan email transaksional, dll).
+type EmailSender interface {
+	Send(to, subject, body string) error
+}
+
+// LogEmailSender adalah stub selama server email belum dikonfigurasi: email hanya dicatat ke log,
+// tidak dikirim, sehingga Send selalu mengembalikan ErrChannelUnavailable.
+type LogEmailSender struct{}
+
+func NewLogEmailSender() *LogEmailSender {
+	return &LogEmailSender{}
+}
+
+func (LogEmailSender) Send(to, subject, body string) error {
+	log.Printf("[Notification Email] Belum dikirim (server email belum dikonfigurasi). Kepada: %s, Subjek: %s, Isi: %s\n", to, subject, body)
+	return ErrChannelUnavailable
+}
+
+type EmailChannel struct {
+	sender EmailSender
+}
+
+func NewEmailChannel(sender EmailSender) *EmailChannel {
+	return &EmailChannel{sender: sender}
+}
+
+func (c *EmailChannel) Name() string { return "email" }
+
+func (c *EmailChannel) Deliver(recipient Recipient, notification models.ProductNotification) error {
+	if strings.TrimSpace(recipient.Email) == "" {
+		return fmt.Errorf("customer %s tidak memiliki email", recipient.CustomerID)
+	}
+	greeting := "Halo"
+	if recipient.Name != "" {
+		greeting = "Halo " + recipient.Name
+	}
+	body := fmt.Sprintf("%s,\n\n%s\n\nProduk ini ada di wishlist Anda. Atur notifikasi wishlist dari halaman akun Anda.", greeting, notification.Message)
+	return c.sender.Send(recipient.Email, notification.Title, body)
+}
+
+// InAppChannel menulis notifikasi ke feed in-app customer.
+type InAppChannel struct {
+	db *gorm.DB
+}
+
+func NewInAppChannel(db *gorm.DB) *InAppChannel {
+	return &InAppChannel{db: db}
+}
+
+func (c *InAppChannel) Name() string { return "in_app" }
+
+func (c *InAppChannel) Deliver(recipient Recipient, notification models.ProductNotification) error {
+	notificationID := notification.ID
+	entry := models.CustomerNotification{
+		CustomerID:            recipient.CustomerID,
+		ProductNotificationID: &notificationID,
+		Type:                  notification.Type,
+		Title:                 notification.Title,
+		Message:               notification.Message,
+		ProductSKU:            notification.ProductSKU,
+	}
+	// Unique pada product_notification_id mencegah entri ganda jika status antrean gagal disimpan
+	if err := c.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error; err != nil {
+		return fmt.Errorf("gagal menyimpan notifikasi in-app: %w", err)
+	}
+	return nil
+}
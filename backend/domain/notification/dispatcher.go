@@ -0,0 +1,279 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"backend-user/domain/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxDeliveryAttempts = 5
+	dispatchBatchSize   = 200
+)
+
+// Throttle membatasi jumlah notifikasi yang dikirim ke satu customer dalam satu jendela waktu.
+// Notifikasi di atas batas tetap Pending dan dikirim setelah jendela bergeser.
+type Throttle struct {
+	MaxPerWindow int
+	Window       time.Duration
+}
+
+// QueueProductChange mengantrekan notifikasi untuk customer yang memantau produk jika harga turun
+// atau stok kembali tersedia. Dipanggil di dalam transaksi update produk.
+func QueueProductChange(tx *gorm.DB, before, after models.Product) (int, error) {
+	if after.Status != "Published" {
+		return 0, nil
+	}
+
+	queued := 0
+	if after.RegularPrice < before.RegularPrice {
+		oldPrice, newPrice := before.RegularPrice, after.RegularPrice
+		count, err := queueForWatchers(tx, after, "notify_price_drop", models.ProductNotification{
+			Type:     models.ProductNotificationPriceDrop,
+			Title:    "Harga turun: " + after.DisplayTitle(),
+			Message:  fmt.Sprintf("Harga %s turun dari %s menjadi %s.", after.DisplayTitle(), models.FormatRupiah(oldPrice), models.FormatRupiah(newPrice)),
+			OldPrice: &oldPrice,
+			NewPrice: &newPrice,
+		})
+		if err != nil {
+			return queued, err
+		}
+		queued += count
+	}
+	if before.Stock <= 0 && after.Stock > 0 {
+		count, err := queueForWatchers(tx, after, "notify_back_in_stock", models.ProductNotification{
+			Type:    models.ProductNotificationBackInStock,
+			Title:   after.DisplayTitle() + " tersedia kembali",
+			Message: fmt.Sprintf("%s kembali tersedia. Segera pesan sebelum stok habis.", after.DisplayTitle()),
+		})
+		if err != nil {
+			return queued, err
+		}
+		queued += count
+	}
+	if queued > 0 {
+		log.Printf("[Notification QueueProductChange] %d notifikasi wishlist diantrekan untuk SKU %s\n", queued, after.ProductSKU)
+	}
+	return queued, nil
+}
+
+// queueForWatchers membuat notifikasi untuk setiap pemantau produk. Notifikasi sejenis yang belum
+// terkirim digabung agar penurunan harga beruntun tidak mengirim pesan berkali-kali.
+func queueForWatchers(tx *gorm.DB, product models.Product, preferenceColumn string, template models.ProductNotification) (int, error) {
+	var watchers []models.WishlistItem
+	if err := tx.Where("product_sku = ? AND "+preferenceColumn+" = ?", product.ProductSKU, true).Find(&watchers).Error; err != nil {
+		return 0, fmt.Errorf("gagal mengambil pemantau wishlist: %w", err)
+	}
+
+	for _, watcher := range watchers {
+		var pending models.ProductNotification
+		err := tx.Where("customer_id = ? AND product_sku = ? AND type = ? AND status = ?", watcher.CustomerID, product.ProductSKU, template.Type, models.ProductNotificationPending).
+			First(&pending).Error
+		if err == nil {
+			updates := map[string]interface{}{"title": template.Title, "message": template.Message, "new_price": template.NewPrice}
+			if template.OldPrice != nil && pending.OldPrice != nil {
+				// Pertahankan harga awal agar pesan menunjukkan total penurunan
+				updates["message"] = fmt.Sprintf("Harga %s turun dari %s menjadi %s.", product.DisplayTitle(), models.FormatRupiah(*pending.OldPrice), models.FormatRupiah(*template.NewPrice))
+			}
+			if err := tx.Model(&pending).Updates(updates).Error; err != nil {
+				return 0, fmt.Errorf("gagal memperbarui notifikasi wishlist: %w", err)
+			}
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("gagal memeriksa antrean notifikasi: %w", err)
+		}
+
+		notification := template
+		notification.CustomerID = watcher.CustomerID
+		notification.ProductSKU = product.ProductSKU
+		notification.Status = models.ProductNotificationPending
+		if err := tx.Create(&notification).Error; err != nil {
+			return 0, fmt.Errorf("gagal mengantrekan notifikasi wishlist: %w", err)
+		}
+	}
+	return len(watchers), nil
+}
+
+// Dispatcher mengirim notifikasi Pending ke semua channel dengan memperhatikan throttle per customer.
+type Dispatcher struct {
+	db       *gorm.DB
+	channels []Channel
+	throttle Throttle
+	Now      func() time.Time
+}
+
+func NewDispatcher(db *gorm.DB, throttle Throttle, channels ...Channel) *Dispatcher {
+	return &Dispatcher{db: db, channels: channels, throttle: throttle, Now: time.Now}
+}
+
+// DispatchPending memproses satu batch antrean dan mengembalikan jumlah notifikasi yang terkirim.
+func (d *Dispatcher) DispatchPending() (int, error) {
+	now := d.Now()
+
+	query := d.db.Where("status = ?", models.ProductNotificationPending)
+	if d.throttle.MaxPerWindow > 0 {
+		// Customer yang sudah mencapai batas dilewati di query agar antreannya tidak memenuhi batch
+		// dan menunda notifikasi customer lain
+		query = query.Where("customer_id NOT IN (?)", d.db.Model(&models.ProductNotification{}).
+			Select("customer_id").
+			Where("status = ? AND sent_at > ?", models.ProductNotificationSent, now.Add(-d.throttle.Window)).
+			Group("customer_id").
+			Having("COUNT(*) >= ?", d.throttle.MaxPerWindow))
+	}
+	var pending []models.ProductNotification
+	if err := query.Order("created_at ASC, id ASC").Limit(dispatchBatchSize).Find(&pending).Error; err != nil {
+		return 0, fmt.Errorf("gagal mengambil antrean notifikasi: %w", err)
+	}
+
+	sentInWindow := map[string]int64{}
+	recipients := map[string]Recipient{}
+	sent := 0
+	for _, notification := range pending {
+		if reason, err := d.obsoleteReason(notification); err != nil {
+			return sent, err
+		} else if reason != "" {
+			if err := d.db.Model(&notification).Updates(map[string]interface{}{"status": models.ProductNotificationCancelled, "last_error": reason}).Error; err != nil {
+				return sent, fmt.Errorf("gagal membatalkan notifikasi: %w", err)
+			}
+			continue
+		}
+
+		count, counted := sentInWindow[notification.CustomerID]
+		if !counted {
+			if err := d.db.Model(&models.ProductNotification{}).
+				Where("customer_id = ? AND status = ? AND sent_at > ?", notification.CustomerID, models.ProductNotificationSent, now.Add(-d.throttle.Window)).
+				Count(&count).Error; err != nil {
+				return sent, fmt.Errorf("gagal menghitung notifikasi terkirim: %w", err)
+			}
+		}
+		if d.throttle.MaxPerWindow > 0 && count >= int64(d.throttle.MaxPerWindow) {
+			sentInWindow[notification.CustomerID] = count
+			continue // Ditunda hingga jendela throttle berikutnya
+		}
+
+		recipient, ok := recipients[notification.CustomerID]
+		if !ok {
+			var err error
+			if recipient, err = d.loadRecipient(notification.CustomerID); err != nil {
+				return sent, err
+			}
+			recipients[notification.CustomerID] = recipient
+		}
+
+		if d.deliver(notification, recipient, now) {
+			count++
+			sent++
+		}
+		sentInWindow[notification.CustomerID] = count
+	}
+	return sent, nil
+}
+
+// obsoleteReason memeriksa ulang kondisi notifikasi; harga atau stok bisa sudah kembali sebelum dikirim.
+func (d *Dispatcher) obsoleteReason(notification models.ProductNotification) (string, error) {
+	var watcher models.WishlistItem
+	if err := d.db.Where("customer_id = ? AND product_sku = ?", notification.CustomerID, notification.ProductSKU).First(&watcher).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "produk sudah dihapus dari wishlist", nil
+		}
+		return "", fmt.Errorf("gagal memeriksa wishlist: %w", err)
+	}
+
+	var product models.Product
+	if err := d.db.Where("product_sku = ?", notification.ProductSKU).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "produk tidak ditemukan", nil
+		}
+		return "", fmt.Errorf("gagal memeriksa produk: %w", err)
+	}
+	if product.Status != "Published" {
+		return "produk tidak lagi dipublikasikan", nil
+	}
+
+	switch notification.Type {
+	case models.ProductNotificationPriceDrop:
+		if !watcher.NotifyPriceDrop {
+			return "notifikasi penurunan harga dimatikan customer", nil
+		}
+		if notification.OldPrice == nil || product.RegularPrice >= *notification.OldPrice {
+			return "harga produk sudah kembali naik", nil
+		}
+	case models.ProductNotificationBackInStock:
+		if !watcher.NotifyBackInStock {
+			return "notifikasi stok tersedia dimatikan customer", nil
+		}
+		if product.Stock <= 0 {
+			return "stok produk sudah habis lagi", nil
+		}
+	}
+	return "", nil
+}
+
+func (d *Dispatcher) loadRecipient(customerID string) (Recipient, error) {
+	var customer models.Customer
+	if err := d.db.Preload("Detail").Where("customer_id = ?", customerID).First(&customer).Error; err != nil {
+		return Recipient{}, fmt.Errorf("gagal mengambil data customer %s: %w", customerID, err)
+	}
+	return Recipient{
+		CustomerID: customer.CustomerID,
+		Email:      customer.Email,
+		Name:       strings.TrimSpace(customer.Detail.FirstName + " " + customer.Detail.LastName),
+	}, nil
+}
+
+// deliver mengirim ke channel yang belum berhasil. Notifikasi dianggap terkirim jika semua channel
+// yang tersedia berhasil; jika tidak, dicoba lagi pada dispatch berikutnya hingga maxDeliveryAttempts.
+// Channel yang mengembalikan ErrChannelUnavailable dilewati dan tidak dicatat di DeliveredChannels.
+func (d *Dispatcher) deliver(notification models.ProductNotification, recipient Recipient, now time.Time) bool {
+	delivered := []string{}
+	if notification.DeliveredChannels != "" {
+		delivered = strings.Split(notification.DeliveredChannels, ",")
+	}
+
+	var failures []string
+	for _, channel := range d.channels {
+		if slices.Contains(delivered, channel.Name()) {
+			continue
+		}
+		err := channel.Deliver(recipient, notification)
+		if errors.Is(err, ErrChannelUnavailable) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[Notification Dispatcher] Gagal mengirim notifikasi %d lewat %s: %v\n", notification.ID, channel.Name(), err)
+			failures = append(failures, channel.Name()+": "+err.Error())
+			continue
+		}
+		delivered = append(delivered, channel.Name())
+	}
+	if len(delivered) == 0 && len(failures) == 0 {
+		failures = append(failures, "tidak ada channel notifikasi yang tersedia")
+	}
+
+	updates := map[string]interface{}{"delivered_channels": strings.Join(delivered, ",")}
+	if len(failures) == 0 {
+		updates["status"] = models.ProductNotificationSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	} else {
+		attempts := notification.Attempts + 1
+		updates["attempts"] = attempts
+		updates["last_error"] = strings.Join(failures, "; ")
+		if attempts >= maxDeliveryAttempts {
+			updates["status"] = models.ProductNotificationFailed
+		}
+	}
+	if err := d.db.Model(&notification).Updates(updates).Error; err != nil {
+		log.Printf("[Notification Dispatcher] Peringatan: gagal menyimpan status notifikasi %d: %v\n", notification.ID, err)
+		return false
+	}
+	return len(failures) == 0
+}
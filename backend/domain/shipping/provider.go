@@ -0,0 +1,138 @@
+package shipping
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"backend-user/domain/models"
+)
+
+// Status pelacakan yang dikembalikan provider
+const (
+	TrackingStatusUnknown   = "Unknown"
+	TrackingStatusInTransit = "In Transit"
+	TrackingStatusDelivered = "Delivered"
+)
+
+var ErrTrackingNumberRequired = errors.New("nomor resi belum diisi untuk pengiriman ini")
+
+// Data pengiriman yang ingin dilacak
+type TrackingRequest struct {
+	Courier        string
+	TrackingNumber string
+	ShippedAt      time.Time
+}
+
+type TrackingEvent struct {
+	Time        time.Time `json:"time"`
+	Location    string    `json:"location"`
+	Description string    `json:"description"`
+}
+
+type TrackingResult struct {
+	Courier        string          `json:"courier"`
+	TrackingNumber string          `json:"tracking_number"`
+	Status         string          `json:"status"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	Events         []TrackingEvent `json:"events"` // Urut dari yang terlama
+}
+
+// Provider mengambil riwayat pelacakan paket dari layanan kurir. Implementasi untuk API kurir
+// sungguhan cukup memenuhi interface ini lalu di-inject lewat NewService.
+type Provider interface {
+	Track(request TrackingRequest) (TrackingResult, error)
+}
+
+// Track melacak shipment lewat provider kurir. Konfirmasi penerimaan yang sudah dicatat lebih
+// dipercaya daripada status dari provider.
+func Track(provider Provider, shipment models.Shipment) (TrackingResult, error) {
+	result, err := provider.Track(TrackingRequest{
+		Courier:        shipment.Courier,
+		TrackingNumber: shipment.TrackingNumber,
+		ShippedAt:      shipment.ShippedAt,
+	})
+	if err != nil {
+		return TrackingResult{}, err
+	}
+	if shipment.DeliveredAt != nil {
+		result.Status = TrackingStatusDelivered
+		result.DeliveredAt = shipment.DeliveredAt
+	}
+	return result, nil
+}
+
+// UnavailableProvider dipakai selama belum ada integrasi API kurir: status selalu Unknown tanpa
+// riwayat, sehingga status Delivered hanya berasal dari konfirmasi penerimaan oleh admin.
+type UnavailableProvider struct{}
+
+func NewUnavailableProvider() *UnavailableProvider {
+	return &UnavailableProvider{}
+}
+
+func (UnavailableProvider) Track(request TrackingRequest) (TrackingResult, error) {
+	trackingNumber := strings.TrimSpace(request.TrackingNumber)
+	if trackingNumber == "" {
+		return TrackingResult{}, ErrTrackingNumberRequired
+	}
+	return TrackingResult{
+		Courier:        request.Courier,
+		TrackingNumber: trackingNumber,
+		Status:         TrackingStatusUnknown,
+		Events:         []TrackingEvent{},
+	}, nil
+}
+
+// FakeProvider membuat riwayat pelacakan tiruan yang deterministik dari tanggal kirim. Hanya untuk
+// pengujian; jangan dipasang di server karena menganggap setiap paket diterima 60 jam setelah dikirim.
+type FakeProvider struct {
+	Now func() time.Time
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{Now: time.Now}
+}
+
+// Tahapan tiruan beserta jeda sejak paket dikirim
+var fakeTrackingSteps = []struct {
+	after       time.Duration
+	location    string
+	description string
+}{
+	{0, "Gudang PumaCon, Malang", "Paket diserahkan ke kurir"},
+	{6 * time.Hour, "Hub kurir Malang", "Paket diproses di hub asal"},
+	{24 * time.Hour, "Dalam perjalanan", "Paket dalam perjalanan ke kota tujuan"},
+	{48 * time.Hour, "Hub kurir kota tujuan", "Paket tiba di hub kota tujuan"},
+	{60 * time.Hour, "Alamat penerima", "Paket telah diterima"},
+}
+
+func (p *FakeProvider) Track(request TrackingRequest) (TrackingResult, error) {
+	trackingNumber := strings.TrimSpace(request.TrackingNumber)
+	if trackingNumber == "" {
+		return TrackingResult{}, ErrTrackingNumberRequired
+	}
+	now := time.Now()
+	if p.Now != nil {
+		now = p.Now()
+	}
+
+	result := TrackingResult{
+		Courier:        request.Courier,
+		TrackingNumber: trackingNumber,
+		Status:         TrackingStatusUnknown,
+		Events:         []TrackingEvent{},
+	}
+	for i, step := range fakeTrackingSteps {
+		eventTime := request.ShippedAt.Add(step.after)
+		if eventTime.After(now) {
+			break
+		}
+		result.Events = append(result.Events, TrackingEvent{Time: eventTime, Location: step.location, Description: step.description})
+		result.Status = TrackingStatusInTransit
+		if i == len(fakeTrackingSteps)-1 {
+			result.Status = TrackingStatusDelivered
+			result.DeliveredAt = &eventTime
+		}
+	}
+	return result, nil
+}
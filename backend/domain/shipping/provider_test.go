@@ -0,0 +1,89 @@
+package shipping
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"backend-user/domain/models"
+)
+
+var testShippedAt = time.Date(2025, time.March, 3, 8, 0, 0, 0, time.UTC)
+
+func fakeProviderAt(now time.Time) *FakeProvider {
+	return &FakeProvider{Now: func() time.Time { return now }}
+}
+
+func TestFakeProviderRequiresTrackingNumber(t *testing.T) {
+	_, err := fakeProviderAt(testShippedAt).Track(TrackingRequest{Courier: "JNE", TrackingNumber: "  ", ShippedAt: testShippedAt})
+	if !errors.Is(err, ErrTrackingNumberRequired) {
+		t.Fatalf("error = %v, want ErrTrackingNumberRequired", err)
+	}
+}
+
+func TestFakeProviderProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		now        time.Time
+		wantStatus string
+		wantEvents int
+	}{
+		{"belum dikirim", testShippedAt.Add(-time.Hour), TrackingStatusUnknown, 0},
+		{"baru diserahkan", testShippedAt, TrackingStatusInTransit, 1},
+		{"dalam perjalanan", testShippedAt.Add(30 * time.Hour), TrackingStatusInTransit, 3},
+		{"tepat sebelum diterima", testShippedAt.Add(60*time.Hour - time.Second), TrackingStatusInTransit, 4},
+		{"diterima", testShippedAt.Add(60 * time.Hour), TrackingStatusDelivered, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fakeProviderAt(tt.now).Track(TrackingRequest{Courier: "JNE", TrackingNumber: " JN123 ", ShippedAt: testShippedAt})
+			if err != nil {
+				t.Fatalf("Track() error = %v", err)
+			}
+			if result.TrackingNumber != "JN123" {
+				t.Errorf("TrackingNumber = %q, want %q", result.TrackingNumber, "JN123")
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", result.Status, tt.wantStatus)
+			}
+			if len(result.Events) != tt.wantEvents {
+				t.Fatalf("len(Events) = %d, want %d", len(result.Events), tt.wantEvents)
+			}
+			for i := 1; i < len(result.Events); i++ {
+				if !result.Events[i].Time.After(result.Events[i-1].Time) {
+					t.Errorf("event %d tidak terurut setelah event %d", i, i-1)
+				}
+			}
+			wantDelivered := tt.wantStatus == TrackingStatusDelivered
+			if (result.DeliveredAt != nil) != wantDelivered {
+				t.Errorf("DeliveredAt = %v, want set = %t", result.DeliveredAt, wantDelivered)
+			}
+			if wantDelivered && !result.DeliveredAt.Equal(testShippedAt.Add(60*time.Hour)) {
+				t.Errorf("DeliveredAt = %v, want %v", result.DeliveredAt, testShippedAt.Add(60*time.Hour))
+			}
+		})
+	}
+}
+
+func TestTrackPrefersConfirmedDelivery(t *testing.T) {
+	confirmedAt := testShippedAt.Add(20 * time.Hour)
+	shipment := models.Shipment{Courier: "JNE", TrackingNumber: "JN123", ShippedAt: testShippedAt, DeliveredAt: &confirmedAt}
+
+	result, err := Track(fakeProviderAt(testShippedAt.Add(24*time.Hour)), shipment)
+	if err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if result.Status != TrackingStatusDelivered || result.DeliveredAt == nil || !result.DeliveredAt.Equal(confirmedAt) {
+		t.Errorf("result = %+v, want Delivered at %v", result, confirmedAt)
+	}
+}
+
+func TestUnavailableProviderReportsUnknown(t *testing.T) {
+	result, err := NewUnavailableProvider().Track(TrackingRequest{Courier: "JNE", TrackingNumber: "JN123", ShippedAt: testShippedAt.Add(-30 * 24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if result.Status != TrackingStatusUnknown || len(result.Events) != 0 || result.DeliveredAt != nil {
+		t.Errorf("result = %+v, want Unknown tanpa riwayat", result)
+	}
+}
@@ -2,9 +2,11 @@ package user
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"mime/multipart"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -22,19 +24,76 @@ type Handler interface {
 	ListCustomerAddresses(c *gin.Context)
 	UpdateCustomerAddress(c *gin.Context)
 
-	ListPublicProductsAndCategories(c *gin.Context)
+	ListPublicProducts(c *gin.Context)
 	GetPublicProductDetail(c *gin.Context)
+	CompareProducts(c *gin.Context)
 	AddToCart(c *gin.Context)
 	GetCartItems(c *gin.Context)
 
 	UpdateCartItemQuantity(c *gin.Context)
 	RemoveCartItem(c *gin.Context)
+	SaveCartItemForLater(c *gin.Context)
+	MoveSavedItemToCart(c *gin.Context)
+	AddToGuestCart(c *gin.Context)
+	GetGuestCart(c *gin.Context)
+	UpdateGuestCartItemQuantity(c *gin.Context)
+	RemoveGuestCartItem(c *gin.Context)
+	ListWishlist(c *gin.Context)
+	AddWishlistItem(c *gin.Context)
+	UpdateWishlistItem(c *gin.Context)
+	RemoveWishlistItem(c *gin.Context)
+	ListNotifications(c *gin.Context)
+	MarkNotificationRead(c *gin.Context)
+	MarkAllNotificationsRead(c *gin.Context)
 
 	CreateOrder(c *gin.Context)
 	ListCustomerOrders(c *gin.Context)
+	GetCustomerOrderDetail(c *gin.Context)
+	CancelOrder(c *gin.Context)
+	ListPartsForPurchasedMachines(c *gin.Context)
+	GetOrderPaymentSchedule(c *gin.Context)
+	SubmitPaymentMilestoneProof(c *gin.Context)
+	ListWarranties(c *gin.Context)
+	CreateWarrantyClaim(c *gin.Context)
+	ListWarrantyClaims(c *gin.Context)
+	CreateServiceTicket(c *gin.Context)
+	ListServiceTickets(c *gin.Context)
+	GetServiceTicket(c *gin.Context)
+	AddServiceTicketMessage(c *gin.Context)
+	CloseServiceTicket(c *gin.Context)
+	RequestInstallation(c *gin.Context)
+	ListInstallationJobs(c *gin.Context)
+	GetRentalAvailability(c *gin.Context)
+	CreateRentalBooking(c *gin.Context)
+	ListRentalBookings(c *gin.Context)
+	GetRentalBooking(c *gin.Context)
+	CancelRentalBooking(c *gin.Context)
+	CreateReturnRequest(c *gin.Context)
+	GetShipmentTracking(c *gin.Context)
+	ListReturnRequests(c *gin.Context)
+	GetReturnRequest(c *gin.Context)
+
+	CreateCompany(c *gin.Context)
+	GetMyCompany(c *gin.Context)
+	UpdateCompany(c *gin.Context)
+	AddCompanyMember(c *gin.Context)
+	UpdateCompanyMember(c *gin.Context)
+	RemoveCompanyMember(c *gin.Context)
+	ListCompanyOrders(c *gin.Context)
+	ApproveCompanyOrder(c *gin.Context)
+	RejectCompanyOrder(c *gin.Context)
+
+	CreateQuotationRequest(c *gin.Context)
+	ListQuotationRequests(c *gin.Context)
+	GetQuotationRequest(c *gin.Context)
+	AcceptQuotation(c *gin.Context)
+	DeclineQuotationRequest(c *gin.Context)
 
 	GetNewsPageData(c *gin.Context)
 	GetNewsDetailPageData(c *gin.Context)
+
+	Search(c *gin.Context)
+	SearchSuggestions(c *gin.Context)
 }
 
 func NewHandler(svc Service) Handler {
@@ -55,8 +114,11 @@ func (h *handler) RegisterCustomer(c *gin.Context) {
 		return
 	}
 	log.Printf("[Handler RegisterCustomer] Input: %+v\n", input)
+	if input.CartToken == "" {
+		input.CartToken = guestCartToken(c)
+	}
 
-	createdCustomer, serviceErr := h.svc.RegisterCustomer(input)
+	createdCustomer, cartMerge, serviceErr := h.svc.RegisterCustomer(input)
 	if serviceErr != nil {
 		if strings.Contains(serviceErr.Error(), "email sudah terdaftar") {
 			c.JSON(http.StatusConflict, gin.H{"error": serviceErr.Error()})
@@ -66,7 +128,7 @@ func (h *handler) RegisterCustomer(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"message": "Registrasi customer berhasil. Silakan login.",
 		"customer": gin.H{ // Kirim data yang aman dan relevan
 			"customer_id": createdCustomer.CustomerID,
@@ -74,7 +136,11 @@ func (h *handler) RegisterCustomer(c *gin.Context) {
 			"last_name":   createdCustomer.Detail.LastName,
 			"email":       createdCustomer.Email,
 		},
-	})
+	}
+	if cartMerge != nil {
+		response["cart_merge"] = cartMerge
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 func (h *handler) LoginCustomer(c *gin.Context) {
@@ -85,6 +151,9 @@ func (h *handler) LoginCustomer(c *gin.Context) {
 		return
 	}
 	log.Printf("[Handler LoginCustomer] Input: %+v\n", input)
+	if input.CartToken == "" {
+		input.CartToken = guestCartToken(c)
+	}
 
 	loginResponse, serviceErr := h.svc.LoginCustomer(input) // Memanggil service login
 	if serviceErr != nil {
@@ -285,20 +354,74 @@ func (h *handler) UpdateCustomerAddress(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Alamat berhasil diupdate", "address": updatedAddress})
 }
 
-func (h *handler) ListPublicProductsAndCategories(c *gin.Context) {
-	log.Println("[Handler ListPublicProductsAndCategories] Memulai proses...")
+// parseSpecRangeQuery membaca filter angka berbentuk ?key[code]=nilai menjadi map.
+func parseSpecRangeQuery(c *gin.Context, key string) (map[string]float64, error) {
+	values := make(map[string]float64)
+	for code, raw := range c.QueryMap(key) {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%s] harus berupa angka", key, code)
+		}
+		values[code] = value
+	}
+	return values, nil
+}
+
+func (h *handler) ListPublicProducts(c *gin.Context) {
+	var query PublicProductQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter pencarian tidak valid", "details": err.Error()})
+		return
+	}
+	query.SpecValues = c.QueryMap("spec")
+	var err error
+	if query.SpecMin, err = parseSpecRangeQuery(c, "spec_min"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter pencarian tidak valid", "details": err.Error()})
+		return
+	}
+	if query.SpecMax, err = parseSpecRangeQuery(c, "spec_max"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter pencarian tidak valid", "details": err.Error()})
+		return
+	}
+	log.Printf("[Handler ListPublicProducts] Query: %+v\n", query)
 
-	data, err := h.svc.ListPublicProductsAndCategories()
+	pageData, err := h.svc.ListPublicProducts(query)
 	if err != nil {
-		log.Printf("[Handler ListPublicProductsAndCategories] Error dari service: %v\n", err)
+		log.Printf("[Handler ListPublicProducts] Error dari service: %v\n", err)
+		if strings.Contains(err.Error(), "min_price tidak boleh") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data produk dan kategori", "details": err.Error()})
 		return
 	}
 
-	log.Printf("[Handler ListPublicProductsAndCategories] Berhasil mengambil data.\n")
-	// Service mengembalikan map {"products": [...], "categories": [...]}
-	// Langsung kirim map tersebut sebagai respons JSON
-	c.JSON(http.StatusOK, data)
+	c.JSON(http.StatusOK, pageData)
+}
+
+func (h *handler) CompareProducts(c *gin.Context) {
+	// Mendukung ?skus=A,B,C maupun ?skus=A&skus=B
+	var productSKUs []string
+	for _, param := range c.QueryArray("skus") {
+		productSKUs = append(productSKUs, strings.Split(param, ",")...)
+	}
+
+	comparison, err := h.svc.CompareProducts(productSKUs)
+	if err != nil {
+		log.Printf("[Handler CompareProducts] Error dari service: %v\n", err)
+		if strings.HasPrefix(err.Error(), "pilih minimal") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "produk tidak ditemukan") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membandingkan produk", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
 }
 
 func (h *handler) GetPublicProductDetail(c *gin.Context) {
@@ -355,6 +478,10 @@ func (h *handler) AddToCart(c *gin.Context) {
 			c.JSON(http.StatusConflict, gin.H{"error": serviceErr.Error()})
 			return
 		}
+		if serviceErr.Error() == "produk memiliki varian, pilih varian terlebih dahulu" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": serviceErr.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menambahkan ke keranjang", "details": serviceErr.Error()})
 		return
 	}
@@ -376,15 +503,15 @@ func (h *handler) GetCartItems(c *gin.Context) {
 
 	log.Printf("[Handler GetCartItems] Mengambil item keranjang untuk CustomerID: %s\n", customerID)
 
-	cartItems, err := h.svc.GetCartItems(customerID)
+	cartContents, err := h.svc.GetCartItems(customerID)
 	if err != nil {
 		log.Printf("[Handler GetCartItems] Error dari service: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil isi keranjang", "details": err.Error()})
 		return
 	}
 
-	// Jika keranjang kosong, cartItems akan menjadi slice kosong, yang mana valid JSON ([])
-	c.JSON(http.StatusOK, gin.H{"cart_items": cartItems})
+	// Keranjang kosong tetap dikirim sebagai slice kosong ([]) pada cart_items dan saved_items
+	c.JSON(http.StatusOK, cartContents)
 }
 
 func (h *handler) UpdateCartItemQuantity(c *gin.Context) {
@@ -466,92 +593,204 @@ func (h *handler) RemoveCartItem(c *gin.Context) {
 	// Atau bisa juga c.Status(http.StatusNoContent) jika tidak ada body respons
 }
 
-func (h *handler) CreateOrder(c *gin.Context) {
-	// 1. Ambil CustomerID dari context yang diset oleh CustomerAuthMiddleware
+func (h *handler) SaveCartItemForLater(c *gin.Context) {
 	customerIDInterface, exists := c.Get("customer_id_from_token")
 	if !exists {
-		log.Println("[Handler CreateOrder] Error: Customer ID tidak ditemukan di context token.")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
-		return
-	}
-	customerID, ok := customerIDInterface.(string)
-	if !ok || customerID == "" {
-		log.Println("[Handler CreateOrder] Error: Format Customer ID di token salah atau kosong.")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Data autentikasi tidak valid."})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
+	customerID := customerIDInterface.(string)
 
-	// 2. Set batas memori untuk parsing multipart form (misal 10MB untuk bukti bayar)
-	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
-		log.Printf("[Handler CreateOrder] Error parsing multipart form: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal memproses form data: " + err.Error()})
+	cartItemIDUint64, err := strconv.ParseUint(c.Param("cartItemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format Cart Item ID tidak valid"})
 		return
 	}
 
-	// 3. Ambil string JSON dari field "jsonData"
-	jsonDataString := c.PostForm("jsonData")
-	if jsonDataString == "" {
-		log.Println("[Handler CreateOrder] Error: Field 'jsonData' kosong.")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Data checkout (jsonData) tidak ditemukan."})
+	cartItem, serviceErr := h.svc.SaveCartItemForLater(customerID, uint(cartItemIDUint64))
+	if serviceErr != nil {
+		log.Printf("[Handler SaveCartItemForLater] Error dari service: %v\n", serviceErr)
+		if serviceErr.Error() == "item keranjang tidak ditemukan atau bukan milik Anda" {
+			c.JSON(http.StatusNotFound, gin.H{"error": serviceErr.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan item untuk nanti", "details": serviceErr.Error()})
 		return
 	}
 
-	var inputDTO CheckoutInput // DTO dari model.go (package user)
-	if err := json.Unmarshal([]byte(jsonDataString), &inputDTO); err != nil {
-		log.Printf("[Handler CreateOrder] Error unmarshalling jsonData: %v\nInput: %s\n", err, jsonDataString)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Format data checkout tidak valid: " + err.Error()})
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Item disimpan untuk nanti",
+		"cart_item": cartItem,
+	})
+}
+
+func (h *handler) MoveSavedItemToCart(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
+	customerID := customerIDInterface.(string)
 
-	// 4. Ambil file bukti pembayaran (opsional)
-	var proofPaymentFileHeader *multipart.FileHeader
-	file, handlerFileHeader, errFile := c.Request.FormFile("proofPaymentFile") // "proofPaymentFile" harus cocok dengan key di FormData frontend
-
-	if errFile == nil && file != nil { // Ada file yang diupload
-		defer file.Close() // Penting untuk menutup file setelah selesai
-		proofPaymentFileHeader = handlerFileHeader
-		log.Printf("[Handler CreateOrder] File bukti pembayaran diterima: %s\n", proofPaymentFileHeader.Filename)
-	} else if errFile != http.ErrMissingFile {
-		// Error lain selain file tidak ada (misalnya, masalah baca form)
-		log.Printf("[Handler CreateOrder] Error saat mengambil file bukti pembayaran: %v\n", errFile)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error memproses file bukti pembayaran: " + errFile.Error()})
+	cartItemIDUint64, err := strconv.ParseUint(c.Param("cartItemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format Cart Item ID tidak valid"})
 		return
 	}
-	// Jika errFile == http.ErrMissingFile, maka proofPaymentFileHeader akan tetap nil (tidak ada file diupload), ini OK.
-
-	log.Printf("[Handler CreateOrder] CustomerID: %s, Input DTO: %+v, Ada File Bukti: %t\n", customerID, inputDTO, proofPaymentFileHeader != nil)
 
-	// 5. Panggil service CreateOrderFromCart
-	order, serviceErr := h.svc.CreateOrderFromCart(customerID, inputDTO, proofPaymentFileHeader)
+	cartItem, serviceErr := h.svc.MoveSavedItemToCart(customerID, uint(cartItemIDUint64))
 	if serviceErr != nil {
-		log.Printf("[Handler CreateOrder] Error dari service CreateOrderFromCart: %v\n", serviceErr)
-		// Tangani error spesifik dari service
-		if strings.Contains(serviceErr.Error(), "keranjang Anda kosong") ||
-			strings.Contains(serviceErr.Error(), "alamat pengiriman yang dipilih tidak valid") ||
-			strings.Contains(serviceErr.Error(), "bukti pembayaran diperlukan") {
+		log.Printf("[Handler MoveSavedItemToCart] Error dari service: %v\n", serviceErr)
+		switch {
+		case serviceErr.Error() == "item keranjang tidak ditemukan atau bukan milik Anda",
+			serviceErr.Error() == "produk tidak ditemukan atau tidak tersedia":
+			c.JSON(http.StatusNotFound, gin.H{"error": serviceErr.Error()})
+		case serviceErr.Error() == "produk memiliki varian, pilih varian terlebih dahulu":
 			c.JSON(http.StatusBadRequest, gin.H{"error": serviceErr.Error()})
-			return
-		}
-		if strings.Contains(serviceErr.Error(), "stok produk") { // Mencakup "tidak mencukupi"
+		case strings.HasPrefix(serviceErr.Error(), "stok produk"):
 			c.JSON(http.StatusConflict, gin.H{"error": serviceErr.Error()})
-			return
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memindahkan item ke keranjang", "details": serviceErr.Error()})
 		}
-		if strings.Contains(serviceErr.Error(), "produk tidak ditemukan") {
-			c.JSON(http.StatusNotFound, gin.H{"error": serviceErr.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat pesanan Anda", "details": serviceErr.Error()})
 		return
 	}
 
-	log.Printf("[Handler CreateOrder] Order berhasil dibuat dengan ID: %s\n", order.OrderID)
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Pesanan Anda berhasil dibuat!",
-		"order":   order, // Kirim kembali data order yang sudah lengkap
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Item dipindahkan ke keranjang",
+		"cart_item": cartItem,
 	})
 }
 
-func (h *handler) ListCustomerOrders(c *gin.Context) {
+// guestCartToken mengambil cart token keranjang tamu dari header X-Cart-Token.
+func guestCartToken(c *gin.Context) string {
+	return strings.TrimSpace(c.GetHeader(GuestCartTokenHeader))
+}
+
+func respondGuestCartError(c *gin.Context, err error, fallbackMessage string) {
+	switch {
+	case err.Error() == "keranjang tamu tidak ditemukan atau sudah kedaluwarsa",
+		err.Error() == "produk tidak ditemukan atau tidak tersedia",
+		err.Error() == "item keranjang tidak ditemukan atau bukan milik Anda",
+		err.Error() == "item keranjang tidak ditemukan atau Anda tidak berhak menghapusnya",
+		err.Error() == "detail produk untuk item keranjang tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "produk memiliki varian, pilih varian terlebih dahulu":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "stok produk"), err.Error() == "kuantitas tidak boleh kurang dari 1":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+	}
+}
+
+// parseGuestCartItemID membaca parameter itemId; respons 400 sudah dikirim jika formatnya salah.
+func parseGuestCartItemID(c *gin.Context) (uint, bool) {
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format Cart Item ID tidak valid"})
+		return 0, false
+	}
+	return uint(itemID), true
+}
+
+func (h *handler) AddToGuestCart(c *gin.Context) {
+	var input AddToCartInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+	log.Printf("[Handler AddToGuestCart] Menambah ke keranjang tamu: %+v\n", input)
+
+	cart, err := h.svc.AddToGuestCart(guestCartToken(c), input)
+	if err != nil {
+		log.Printf("[Handler AddToGuestCart] Error dari service: %v\n", err)
+		respondGuestCartError(c, err, "Gagal menambahkan ke keranjang")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Produk berhasil ditambahkan ke keranjang",
+		"cart":    cart,
+	})
+}
+
+func (h *handler) GetGuestCart(c *gin.Context) {
+	cartToken := guestCartToken(c)
+	if cartToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Header " + GuestCartTokenHeader + " dibutuhkan"})
+		return
+	}
+
+	cart, err := h.svc.GetGuestCart(cartToken)
+	if err != nil {
+		log.Printf("[Handler GetGuestCart] Error dari service: %v\n", err)
+		respondGuestCartError(c, err, "Gagal mengambil isi keranjang")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
+}
+
+func (h *handler) UpdateGuestCartItemQuantity(c *gin.Context) {
+	cartToken := guestCartToken(c)
+	if cartToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Header " + GuestCartTokenHeader + " dibutuhkan"})
+		return
+	}
+	itemID, ok := parseGuestCartItemID(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateCartItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	cart, err := h.svc.UpdateGuestCartItemQuantity(cartToken, itemID, input.Quantity)
+	if err != nil {
+		log.Printf("[Handler UpdateGuestCartItemQuantity] Error dari service: %v\n", err)
+		respondGuestCartError(c, err, "Gagal mengupdate kuantitas item")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Kuantitas item keranjang berhasil diupdate",
+		"cart":    cart,
+	})
+}
+
+func (h *handler) RemoveGuestCartItem(c *gin.Context) {
+	cartToken := guestCartToken(c)
+	if cartToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Header " + GuestCartTokenHeader + " dibutuhkan"})
+		return
+	}
+	itemID, ok := parseGuestCartItemID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.svc.RemoveGuestCartItem(cartToken, itemID)
+	if err != nil {
+		log.Printf("[Handler RemoveGuestCartItem] Error dari service: %v\n", err)
+		respondGuestCartError(c, err, "Gagal menghapus item dari keranjang")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item berhasil dihapus dari keranjang",
+		"cart":    cart,
+	})
+}
+
+func respondWishlistError(c *gin.Context, err error, fallbackMessage string) {
+	switch err.Error() {
+	case "produk tidak ditemukan atau tidak tersedia", "produk tidak ada di wishlist", "notifikasi tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+	}
+}
+
+func (h *handler) ListWishlist(c *gin.Context) {
 	customerIDInterface, exists := c.Get("customer_id_from_token")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
@@ -559,52 +798,1256 @@ func (h *handler) ListCustomerOrders(c *gin.Context) {
 	}
 	customerID := customerIDInterface.(string)
 
-	log.Printf("[Handler ListCustomerOrders] Mengambil riwayat pesanan untuk CustomerID: %s\n", customerID)
+	items, err := h.svc.ListWishlist(customerID)
+	if err != nil {
+		log.Printf("[Handler ListWishlist] Error dari service: %v\n", err)
+		respondWishlistError(c, err, "Gagal mengambil wishlist")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"wishlist": items})
+}
 
-	orders, err := h.svc.ListCustomerOrders(customerID)
+func (h *handler) AddWishlistItem(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input AddWishlistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	item, err := h.svc.AddWishlistItem(customerID, input)
 	if err != nil {
-		log.Printf("[Handler ListCustomerOrders] Error dari service: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil riwayat pesanan", "details": err.Error()})
+		log.Printf("[Handler AddWishlistItem] Error dari service: %v\n", err)
+		respondWishlistError(c, err, "Gagal menambahkan produk ke wishlist")
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Produk berhasil ditambahkan ke wishlist",
+		"wishlist_item": item,
+	})
+}
 
-	// Jika tidak ada pesanan, 'orders' akan menjadi slice kosong [], yang merupakan respons JSON yang valid.
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
+func (h *handler) UpdateWishlistItem(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input UpdateWishlistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid: " + err.Error()})
+		return
+	}
+
+	item, err := h.svc.UpdateWishlistItem(customerID, c.Param("productSKU"), input)
+	if err != nil {
+		log.Printf("[Handler UpdateWishlistItem] Error dari service: %v\n", err)
+		respondWishlistError(c, err, "Gagal memperbarui wishlist")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Preferensi notifikasi wishlist berhasil diperbarui",
+		"wishlist_item": item,
+	})
 }
 
-func (h *handler) GetNewsPageData(c *gin.Context) {
-	// Ambil parameter 'page' dan 'limit' dari query URL, dengan nilai default
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5")) // Default 5 berita per halaman
+func (h *handler) RemoveWishlistItem(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
 
-	if page < 1 {
-		page = 1
+	if err := h.svc.RemoveWishlistItem(customerID, c.Param("productSKU")); err != nil {
+		log.Printf("[Handler RemoveWishlistItem] Error dari service: %v\n", err)
+		respondWishlistError(c, err, "Gagal menghapus produk dari wishlist")
+		return
 	}
-	if limit < 1 {
-		limit = 5
+	c.JSON(http.StatusOK, gin.H{"message": "Produk berhasil dihapus dari wishlist"})
+}
+
+func (h *handler) ListNotifications(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
 	}
+	customerID := customerIDInterface.(string)
 
-	pageData, err := h.svc.GetNewsPageData(page, limit)
+	unreadOnly := c.Query("unread") == "true"
+	feed, err := h.svc.ListNotifications(customerID, unreadOnly)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data berita", "details": err.Error()})
+		log.Printf("[Handler ListNotifications] Error dari service: %v\n", err)
+		respondWishlistError(c, err, "Gagal mengambil notifikasi")
 		return
 	}
-
-	c.JSON(http.StatusOK, pageData)
+	c.JSON(http.StatusOK, feed)
 }
 
-func (h *handler) GetNewsDetailPageData(c *gin.Context) {
-	newsID := c.Param("newsId") // Ambil ID dari URL
+func (h *handler) MarkNotificationRead(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
 
-	pageData, err := h.svc.GetNewsDetailPageData(newsID)
+	notificationID, err := strconv.ParseUint(c.Param("notificationId"), 10, 32)
 	if err != nil {
-		if err.Error() == "postingan berita tidak ditemukan atau belum dipublikasikan" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data halaman berita", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format Notification ID tidak valid"})
 		return
 	}
 
-	c.JSON(http.StatusOK, pageData)
+	if err := h.svc.MarkNotificationRead(customerID, uint(notificationID)); err != nil {
+		log.Printf("[Handler MarkNotificationRead] Error dari service: %v\n", err)
+		respondWishlistError(c, err, "Gagal menandai notifikasi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notifikasi ditandai sudah dibaca"})
+}
+
+func (h *handler) MarkAllNotificationsRead(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	updated, err := h.svc.MarkAllNotificationsRead(customerID)
+	if err != nil {
+		log.Printf("[Handler MarkAllNotificationsRead] Error dari service: %v\n", err)
+		respondWishlistError(c, err, "Gagal menandai notifikasi")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Semua notifikasi ditandai sudah dibaca", "updated": updated})
+}
+
+// bindCheckoutForm membaca field "jsonData" dan file opsional "proofPaymentFile" dari multipart form checkout.
+// Jika gagal, respons error sudah dikirim dan ok bernilai false.
+func bindCheckoutForm(c *gin.Context, handlerName string) (input CheckoutInput, proofPaymentFileHeader *multipart.FileHeader, ok bool) {
+	// Set batas memori untuk parsing multipart form (misal 10MB untuk bukti bayar)
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
+		log.Printf("[Handler %s] Error parsing multipart form: %v\n", handlerName, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal memproses form data: " + err.Error()})
+		return input, nil, false
+	}
+
+	jsonDataString := c.PostForm("jsonData")
+	if jsonDataString == "" {
+		log.Printf("[Handler %s] Error: Field 'jsonData' kosong.\n", handlerName)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data checkout (jsonData) tidak ditemukan."})
+		return input, nil, false
+	}
+
+	if err := json.Unmarshal([]byte(jsonDataString), &input); err != nil {
+		log.Printf("[Handler %s] Error unmarshalling jsonData: %v\nInput: %s\n", handlerName, err, jsonDataString)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format data checkout tidak valid: " + err.Error()})
+		return input, nil, false
+	}
+
+	// File bukti pembayaran (opsional); "proofPaymentFile" harus cocok dengan key di FormData frontend
+	file, handlerFileHeader, errFile := c.Request.FormFile("proofPaymentFile")
+	if errFile == nil && file != nil {
+		file.Close() // Header tetap bisa dibuka ulang saat file disimpan
+		proofPaymentFileHeader = handlerFileHeader
+		log.Printf("[Handler %s] File bukti pembayaran diterima: %s\n", handlerName, proofPaymentFileHeader.Filename)
+	} else if errFile != http.ErrMissingFile {
+		log.Printf("[Handler %s] Error saat mengambil file bukti pembayaran: %v\n", handlerName, errFile)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error memproses file bukti pembayaran: " + errFile.Error()})
+		return input, nil, false
+	}
+	// Jika errFile == http.ErrMissingFile, proofPaymentFileHeader tetap nil (tidak ada file diupload), ini OK.
+
+	return input, proofPaymentFileHeader, true
+}
+
+func (h *handler) CreateOrder(c *gin.Context) {
+	// 1. Ambil CustomerID dari context yang diset oleh CustomerAuthMiddleware
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		log.Println("[Handler CreateOrder] Error: Customer ID tidak ditemukan di context token.")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID, ok := customerIDInterface.(string)
+	if !ok || customerID == "" {
+		log.Println("[Handler CreateOrder] Error: Format Customer ID di token salah atau kosong.")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Data autentikasi tidak valid."})
+		return
+	}
+
+	// 2-4. Ambil data checkout (jsonData) dan file bukti pembayaran dari multipart form
+	inputDTO, proofPaymentFileHeader, ok := bindCheckoutForm(c, "CreateOrder")
+	if !ok {
+		return
+	}
+
+	log.Printf("[Handler CreateOrder] CustomerID: %s, Input DTO: %+v, Ada File Bukti: %t\n", customerID, inputDTO, proofPaymentFileHeader != nil)
+
+	// 5. Panggil service CreateOrderFromCart
+	order, serviceErr := h.svc.CreateOrderFromCart(customerID, inputDTO, proofPaymentFileHeader)
+	if serviceErr != nil {
+		log.Printf("[Handler CreateOrder] Error dari service CreateOrderFromCart: %v\n", serviceErr)
+		// Tangani error spesifik dari service
+		if strings.Contains(serviceErr.Error(), "keranjang Anda kosong") ||
+			strings.Contains(serviceErr.Error(), "alamat pengiriman yang dipilih tidak valid") ||
+			strings.Contains(serviceErr.Error(), "bukti pembayaran diperlukan") ||
+			strings.Contains(serviceErr.Error(), "belum terdaftar sebagai anggota perusahaan") ||
+			strings.Contains(serviceErr.Error(), "item keranjang yang dipilih tidak valid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": serviceErr.Error()})
+			return
+		}
+		if strings.Contains(serviceErr.Error(), "stok produk") { // Mencakup "tidak mencukupi"
+			c.JSON(http.StatusConflict, gin.H{"error": serviceErr.Error()})
+			return
+		}
+		if strings.Contains(serviceErr.Error(), "produk tidak ditemukan") {
+			c.JSON(http.StatusNotFound, gin.H{"error": serviceErr.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat pesanan Anda", "details": serviceErr.Error()})
+		return
+	}
+
+	log.Printf("[Handler CreateOrder] Order berhasil dibuat dengan ID: %s\n", order.OrderID)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Pesanan Anda berhasil dibuat!",
+		"order":   order, // Kirim kembali data order yang sudah lengkap
+	})
+}
+
+func (h *handler) ListCustomerOrders(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	log.Printf("[Handler ListCustomerOrders] Mengambil riwayat pesanan untuk CustomerID: %s\n", customerID)
+
+	orders, err := h.svc.ListCustomerOrders(customerID)
+	if err != nil {
+		log.Printf("[Handler ListCustomerOrders] Error dari service: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil riwayat pesanan", "details": err.Error()})
+		return
+	}
+
+	// Booking sewa ditampilkan bersama riwayat pesanan
+	rentals, err := h.svc.ListRentalBookings(customerID)
+	if err != nil {
+		log.Printf("[Handler ListCustomerOrders] Error mengambil booking sewa: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil riwayat sewa", "details": err.Error()})
+		return
+	}
+
+	// Jika tidak ada pesanan, 'orders' akan menjadi slice kosong [], yang merupakan respons JSON yang valid.
+	c.JSON(http.StatusOK, gin.H{"orders": orders, "rentals": rentals})
+}
+
+func (h *handler) GetCustomerOrderDetail(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	order, err := h.svc.GetCustomerOrderDetail(customerID, c.Param("orderId"))
+	if err != nil {
+		log.Printf("[Handler GetCustomerOrderDetail] Error dari service: %v\n", err)
+		if err.Error() == "pesanan tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil detail pesanan", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
+
+func (h *handler) GetShipmentTracking(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	tracking, err := h.svc.GetShipmentTracking(customerID, c.Param("orderId"), c.Param("shipmentId"))
+	if err != nil {
+		log.Printf("[Handler GetShipmentTracking] Error dari service: %v\n", err)
+		switch err.Error() {
+		case "pengiriman tidak ditemukan":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "nomor resi belum diisi untuk pengiriman ini":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal melacak pengiriman", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tracking": tracking})
+}
+
+func (h *handler) CancelOrder(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input CancelOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+	order, err := h.svc.CancelOrder(customerID, c.Param("orderId"), input)
+	if err != nil {
+		log.Printf("[Handler CancelOrder] Error dari service: %v\n", err)
+		switch {
+		case err.Error() == "pesanan tidak ditemukan":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "pesanan tidak dapat dibatalkan"):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membatalkan pesanan", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pesanan berhasil dibatalkan", "order": order})
+}
+
+func (h *handler) GetOrderPaymentSchedule(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	schedule, err := h.svc.GetOrderPaymentSchedule(customerID, c.Param("orderId"))
+	if err != nil {
+		if err.Error() == "pesanan tidak ditemukan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil jadwal pembayaran", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *handler) SubmitPaymentMilestoneProof(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	milestoneID, err := strconv.ParseUint(c.Param("milestoneId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID termin tidak valid"})
+		return
+	}
+
+	proofPaymentFileHeader, err := c.FormFile("proofPaymentFile")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File bukti pembayaran (proofPaymentFile) wajib diunggah"})
+		return
+	}
+
+	schedule, err := h.svc.SubmitPaymentMilestoneProof(customerID, c.Param("orderId"), uint(milestoneID), proofPaymentFileHeader)
+	if err != nil {
+		log.Printf("[Handler SubmitPaymentMilestoneProof] Error dari service: %v\n", err)
+		switch err.Error() {
+		case "pesanan tidak ditemukan", "termin pembayaran tidak ditemukan":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "pesanan sudah dibatalkan", "termin pembayaran sudah dibayar atau sedang diverifikasi":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengunggah bukti pembayaran termin", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Bukti pembayaran termin berhasil diunggah", "payment_schedule": schedule})
+}
+
+const maxWarrantyClaimPhotos = 5
+
+var allowedWarrantyClaimPhotoExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true}
+
+func (h *handler) ListWarranties(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	warranties, err := h.svc.ListWarranties(customerID)
+	if err != nil {
+		log.Printf("[Handler ListWarranties] Error dari service: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar garansi", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"warranties": warranties})
+}
+
+func (h *handler) CreateWarrantyClaim(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	warrantyID, err := strconv.ParseUint(c.Param("warrantyId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID garansi tidak valid"})
+		return
+	}
+
+	if err := c.Request.ParseMultipartForm(20 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal memproses form data", "details": err.Error()})
+		return
+	}
+	description := strings.TrimSpace(c.Request.FormValue("description"))
+	if description == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Deskripsi kerusakan (description) wajib diisi"})
+		return
+	}
+
+	var photoFileHeaders []*multipart.FileHeader
+	if c.Request.MultipartForm != nil {
+		photoFileHeaders = c.Request.MultipartForm.File["photos"]
+	}
+	if len(photoFileHeaders) > maxWarrantyClaimPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Maksimal %d foto per klaim garansi", maxWarrantyClaimPhotos)})
+		return
+	}
+	for _, fileHeader := range photoFileHeaders {
+		if !allowedWarrantyClaimPhotoExts[strings.ToLower(filepath.Ext(fileHeader.Filename))] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format foto tidak didukung, gunakan jpg, jpeg, png, atau webp"})
+			return
+		}
+	}
+
+	claim, err := h.svc.CreateWarrantyClaim(customerID, uint(warrantyID), description, photoFileHeaders)
+	if err != nil {
+		log.Printf("[Handler CreateWarrantyClaim] Error dari service: %v\n", err)
+		switch err.Error() {
+		case "garansi tidak ditemukan":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "masa garansi sudah berakhir", "masih ada klaim garansi yang sedang diproses untuk unit ini":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengajukan klaim garansi", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Klaim garansi berhasil diajukan", "claim": claim})
+}
+
+func (h *handler) ListWarrantyClaims(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	claims, err := h.svc.ListWarrantyClaims(customerID)
+	if err != nil {
+		log.Printf("[Handler ListWarrantyClaims] Error dari service: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil klaim garansi", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"claims": claims})
+}
+
+const maxServiceTicketAttachments = 5
+
+var allowedServiceTicketAttachmentExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".pdf": true}
+
+// serviceTicketAttachments mengambil lampiran dari key "attachments"; respons error sudah ditulis jika ok == false.
+func serviceTicketAttachments(c *gin.Context) ([]*multipart.FileHeader, bool) {
+	if c.Request.MultipartForm == nil {
+		return nil, true
+	}
+	fileHeaders := c.Request.MultipartForm.File["attachments"]
+	if len(fileHeaders) > maxServiceTicketAttachments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Maksimal %d lampiran per pesan", maxServiceTicketAttachments)})
+		return nil, false
+	}
+	for _, fileHeader := range fileHeaders {
+		if !allowedServiceTicketAttachmentExts[strings.ToLower(filepath.Ext(fileHeader.Filename))] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format lampiran tidak didukung, gunakan jpg, jpeg, png, webp, atau pdf"})
+			return nil, false
+		}
+	}
+	return fileHeaders, true
+}
+
+const maxReturnRequestPhotos = 5
+
+func respondReturnRequestError(c *gin.Context, err error, fallbackMessage string) {
+	switch {
+	case err.Error() == "pesanan tidak ditemukan", err.Error() == "item pesanan tidak ditemukan", err.Error() == "retur tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case strings.HasPrefix(err.Error(), "retur tidak dapat diajukan"):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+	}
+}
+
+func (h *handler) CreateReturnRequest(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input CreateReturnRequestInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+	if strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Alasan retur (reason) wajib diisi"})
+		return
+	}
+
+	var photoFileHeaders []*multipart.FileHeader
+	if c.Request.MultipartForm != nil {
+		photoFileHeaders = c.Request.MultipartForm.File["photos"]
+	}
+	if len(photoFileHeaders) > maxReturnRequestPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Maksimal %d foto per pengajuan retur", maxReturnRequestPhotos)})
+		return
+	}
+	for _, fileHeader := range photoFileHeaders {
+		if !allowedWarrantyClaimPhotoExts[strings.ToLower(filepath.Ext(fileHeader.Filename))] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format foto tidak didukung, gunakan jpg, jpeg, png, atau webp"})
+			return
+		}
+	}
+
+	returnRequest, err := h.svc.CreateReturnRequest(customerID, c.Param("orderId"), input, photoFileHeaders)
+	if err != nil {
+		log.Printf("[Handler CreateReturnRequest] Error dari service: %v\n", err)
+		respondReturnRequestError(c, err, "Gagal mengajukan retur")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Pengajuan retur berhasil dikirim", "return": returnRequest})
+}
+
+func (h *handler) ListReturnRequests(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	returnRequests, err := h.svc.ListReturnRequests(customerID)
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal mengambil daftar retur")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"returns": returnRequests})
+}
+
+func (h *handler) GetReturnRequest(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	returnRequest, err := h.svc.GetReturnRequest(customerID, c.Param("rmaId"))
+	if err != nil {
+		respondReturnRequestError(c, err, "Gagal mengambil detail retur")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"return": returnRequest})
+}
+
+func respondServiceTicketError(c *gin.Context, err error, fallbackMessage string) {
+	switch err.Error() {
+	case "tiket layanan tidak ditemukan", "item pesanan tidak ditemukan", "nomor seri tidak ditemukan pada pembelian anda":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "nomor seri bukan bagian dari item pesanan tersebut":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "tiket layanan sudah ditutup":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": err.Error()})
+	}
+}
+
+func (h *handler) CreateServiceTicket(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input CreateServiceTicketInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+	attachments, ok := serviceTicketAttachments(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := h.svc.CreateServiceTicket(customerID, input, attachments)
+	if err != nil {
+		log.Printf("[Handler CreateServiceTicket] Error dari service: %v\n", err)
+		respondServiceTicketError(c, err, "Gagal membuat tiket layanan")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Tiket layanan berhasil dibuat", "ticket": ticket})
+}
+
+func (h *handler) ListServiceTickets(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	tickets, err := h.svc.ListServiceTickets(customerID)
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal mengambil tiket layanan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tickets": tickets})
+}
+
+func (h *handler) GetServiceTicket(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	ticket, err := h.svc.GetServiceTicket(customerID, c.Param("ticketId"))
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal mengambil tiket layanan")
+		return
+	}
+	c.JSON(http.StatusOK, ticket)
+}
+
+func (h *handler) AddServiceTicketMessage(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input ServiceTicketMessageInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+	attachments, ok := serviceTicketAttachments(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := h.svc.AddServiceTicketMessage(customerID, c.Param("ticketId"), input, attachments)
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal mengirim pesan tiket")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Pesan berhasil dikirim", "ticket": ticket})
+}
+
+func (h *handler) CloseServiceTicket(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	ticket, err := h.svc.CloseServiceTicket(customerID, c.Param("ticketId"))
+	if err != nil {
+		respondServiceTicketError(c, err, "Gagal menutup tiket layanan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tiket layanan berhasil ditutup", "ticket": ticket})
+}
+
+func (h *handler) RequestInstallation(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input RequestInstallationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+
+	job, err := h.svc.RequestInstallation(customerID, c.Param("orderId"), input)
+	if err != nil {
+		log.Printf("[Handler RequestInstallation] Error dari service: %v\n", err)
+		switch {
+		case err.Error() == "pesanan tidak ditemukan":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err.Error() == "pesanan sudah memiliki jadwal instalasi aktif":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "instalasi tidak valid"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengajukan instalasi", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Permintaan instalasi berhasil diajukan", "installation_job": job})
+}
+
+func (h *handler) ListInstallationJobs(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	jobs, err := h.svc.ListInstallationJobs(customerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil jadwal instalasi", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"installation_jobs": jobs})
+}
+
+func respondRentalError(c *gin.Context, err error, fallbackMessage string) {
+	msg := err.Error()
+	switch {
+	case msg == "produk sewa tidak ditemukan", msg == "unit sewa tidak ditemukan", msg == "booking sewa tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "jadwal sewa bentrok"), msg == "tidak ada unit sewa yang tersedia pada tanggal tersebut",
+		msg == "booking sewa tidak dapat dibatalkan":
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "tanggal sewa tidak valid"), msg == "alamat pengiriman yang dipilih tidak valid atau bukan milik Anda":
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallbackMessage, "details": msg})
+	}
+}
+
+func (h *handler) GetRentalAvailability(c *gin.Context) {
+	// Contoh: /products/SKU00000001/rental-availability?from=2025-07-01&to=2025-07-31
+	var query RentalAvailabilityQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter tanggal tidak valid", "details": err.Error()})
+		return
+	}
+	availability, err := h.svc.GetRentalAvailability(c.Param("productSKU"), query)
+	if err != nil {
+		respondRentalError(c, err, "Gagal mengambil ketersediaan sewa")
+		return
+	}
+	c.JSON(http.StatusOK, availability)
+}
+
+func (h *handler) CreateRentalBooking(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input CreateRentalBookingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+	rental, err := h.svc.CreateRentalBooking(customerID, input)
+	if err != nil {
+		respondRentalError(c, err, "Gagal membuat booking sewa")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Booking sewa berhasil dibuat", "rental": rental})
+}
+
+func (h *handler) ListRentalBookings(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	rentals, err := h.svc.ListRentalBookings(customerID)
+	if err != nil {
+		respondRentalError(c, err, "Gagal mengambil booking sewa")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rentals": rentals})
+}
+
+func (h *handler) GetRentalBooking(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	rental, err := h.svc.GetRentalBooking(customerID, c.Param("bookingId"))
+	if err != nil {
+		respondRentalError(c, err, "Gagal mengambil booking sewa")
+		return
+	}
+	c.JSON(http.StatusOK, rental)
+}
+
+func (h *handler) CancelRentalBooking(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	rental, err := h.svc.CancelRentalBooking(customerID, c.Param("bookingId"))
+	if err != nil {
+		respondRentalError(c, err, "Gagal membatalkan booking sewa")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Booking sewa berhasil dibatalkan", "rental": rental})
+}
+
+func (h *handler) ListPartsForPurchasedMachines(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	machines, err := h.svc.ListPartsForPurchasedMachines(customerID)
+	if err != nil {
+		log.Printf("[Handler ListPartsForPurchasedMachines] Error dari service: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil suku cadang untuk mesin yang dibeli", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"machines": machines})
+}
+
+// respondQuotationRequestError memetakan error service RFQ ke status HTTP.
+func respondQuotationRequestError(c *gin.Context, err error, fallback string) {
+	msg := err.Error()
+	switch {
+	case msg == "permintaan penawaran tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case msg == "penawaran belum tersedia atau sudah diproses",
+		strings.HasPrefix(msg, "penawaran sudah kedaluwarsa"),
+		strings.Contains(msg, "stok produk"):
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case strings.Contains(msg, "tidak ditemukan atau tidak tersedia"),
+		strings.Contains(msg, "disebutkan lebih dari sekali"),
+		strings.Contains(msg, "alamat pengiriman yang dipilih tidak valid"),
+		strings.Contains(msg, "bukti pembayaran diperlukan"),
+		strings.Contains(msg, "belum terdaftar sebagai anggota perusahaan"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback, "details": msg})
+	}
+}
+
+// respondCompanyError memetakan error service akun perusahaan ke status HTTP.
+func respondCompanyError(c *gin.Context, err error, fallback string) {
+	msg := err.Error()
+	switch msg {
+	case "anda belum terdaftar sebagai anggota perusahaan", "customer dengan email tersebut tidak ditemukan",
+		"anggota perusahaan tidak ditemukan", "pesanan tidak ditemukan":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case "hanya approver perusahaan yang dapat melakukan aksi ini":
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	case "anda sudah terdaftar sebagai anggota perusahaan", "customer tersebut sudah terdaftar di perusahaan",
+		"npwp sudah terdaftar untuk perusahaan lain", "perusahaan harus memiliki minimal satu approver",
+		"pesanan tidak sedang menunggu persetujuan":
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case "npwp tidak valid: harus 15 atau 16 digit":
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback, "details": msg})
+	}
+}
+
+func (h *handler) CreateCompany(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input CompanyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+
+	company, err := h.svc.CreateCompany(customerID, input)
+	if err != nil {
+		respondCompanyError(c, err, "Gagal membuat akun perusahaan")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Akun perusahaan berhasil dibuat", "company": company})
+}
+
+func (h *handler) GetMyCompany(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	company, err := h.svc.GetMyCompany(customerID)
+	if err != nil {
+		respondCompanyError(c, err, "Gagal mengambil data perusahaan")
+		return
+	}
+	c.JSON(http.StatusOK, company)
+}
+
+func (h *handler) UpdateCompany(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input CompanyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+
+	company, err := h.svc.UpdateCompany(customerID, input)
+	if err != nil {
+		respondCompanyError(c, err, "Gagal memperbarui data perusahaan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Data perusahaan berhasil diperbarui", "company": company})
+}
+
+func (h *handler) AddCompanyMember(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input AddCompanyMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+
+	company, err := h.svc.AddCompanyMember(customerID, input)
+	if err != nil {
+		respondCompanyError(c, err, "Gagal menambahkan anggota perusahaan")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Anggota perusahaan berhasil ditambahkan", "company": company})
+}
+
+func (h *handler) UpdateCompanyMember(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input UpdateCompanyMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+
+	company, err := h.svc.UpdateCompanyMember(customerID, c.Param("customerId"), input)
+	if err != nil {
+		respondCompanyError(c, err, "Gagal memperbarui peran anggota")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Peran anggota berhasil diperbarui", "company": company})
+}
+
+func (h *handler) RemoveCompanyMember(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	if err := h.svc.RemoveCompanyMember(customerID, c.Param("customerId")); err != nil {
+		respondCompanyError(c, err, "Gagal mengeluarkan anggota perusahaan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Anggota berhasil dikeluarkan dari perusahaan"})
+}
+
+func (h *handler) ListCompanyOrders(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	// Filter opsional, mis. ?status=Awaiting Approval untuk antrean persetujuan
+	orders, err := h.svc.ListCompanyOrders(customerID, c.Query("status"))
+	if err != nil {
+		respondCompanyError(c, err, "Gagal mengambil riwayat pesanan perusahaan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+func (h *handler) ApproveCompanyOrder(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	order, err := h.svc.ApproveCompanyOrder(customerID, c.Param("orderId"))
+	if err != nil {
+		respondCompanyError(c, err, "Gagal menyetujui pesanan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pesanan berhasil disetujui", "order": order})
+}
+
+func (h *handler) RejectCompanyOrder(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input RejectCompanyOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Alasan penolakan wajib diisi", "details": err.Error()})
+		return
+	}
+
+	order, err := h.svc.RejectCompanyOrder(customerID, c.Param("orderId"), input)
+	if err != nil {
+		respondCompanyError(c, err, "Gagal menolak pesanan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pesanan ditolak dan dibatalkan", "order": order})
+}
+
+func (h *handler) CreateQuotationRequest(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	var input CreateQuotationRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Input tidak valid", "details": err.Error()})
+		return
+	}
+
+	request, err := h.svc.CreateQuotationRequest(customerID, input)
+	if err != nil {
+		log.Printf("[Handler CreateQuotationRequest] Error dari service: %v\n", err)
+		respondQuotationRequestError(c, err, "Gagal mengajukan permintaan penawaran")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Permintaan penawaran berhasil dikirim", "quotation_request": request})
+}
+
+func (h *handler) ListQuotationRequests(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	requests, err := h.svc.ListQuotationRequests(customerID)
+	if err != nil {
+		log.Printf("[Handler ListQuotationRequests] Error dari service: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar permintaan penawaran", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quotation_requests": requests})
+}
+
+func (h *handler) GetQuotationRequest(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	request, err := h.svc.GetQuotationRequest(customerID, c.Param("requestId"))
+	if err != nil {
+		respondQuotationRequestError(c, err, "Gagal mengambil permintaan penawaran")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quotation_request": request})
+}
+
+func (h *handler) AcceptQuotation(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	inputDTO, proofPaymentFileHeader, ok := bindCheckoutForm(c, "AcceptQuotation")
+	if !ok {
+		return
+	}
+
+	order, err := h.svc.AcceptQuotation(customerID, c.Param("requestId"), inputDTO, proofPaymentFileHeader)
+	if err != nil {
+		log.Printf("[Handler AcceptQuotation] Error dari service: %v\n", err)
+		respondQuotationRequestError(c, err, "Gagal memproses penawaran menjadi pesanan")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Penawaran diterima, pesanan Anda berhasil dibuat!",
+		"order":   order,
+	})
+}
+
+func (h *handler) DeclineQuotationRequest(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id_from_token")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Customer ID tidak ditemukan."})
+		return
+	}
+	customerID := customerIDInterface.(string)
+
+	request, err := h.svc.DeclineQuotationRequest(customerID, c.Param("requestId"))
+	if err != nil {
+		respondQuotationRequestError(c, err, "Gagal menolak penawaran")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Penawaran berhasil ditolak", "quotation_request": request})
+}
+
+func (h *handler) GetNewsPageData(c *gin.Context) {
+	// Ambil parameter 'page' dan 'limit' dari query URL, dengan nilai default
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5")) // Default 5 berita per halaman
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 5
+	}
+
+	pageData, err := h.svc.GetNewsPageData(page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data berita", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageData)
+}
+
+func (h *handler) GetNewsDetailPageData(c *gin.Context) {
+	newsID := c.Param("newsId") // Ambil ID dari URL
+
+	pageData, err := h.svc.GetNewsDetailPageData(newsID)
+	if err != nil {
+		if err.Error() == "postingan berita tidak ditemukan atau belum dipublikasikan" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil data halaman berita", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pageData)
+}
+
+func (h *handler) Search(c *gin.Context) {
+	var query SearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil || strings.TrimSpace(query.Query) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'q' wajib diisi dan parameter lain harus valid"})
+		return
+	}
+
+	results, err := h.svc.Search(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal melakukan pencarian", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *handler) SearchSuggestions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "8"))
+	if limit < 1 || limit > 20 {
+		limit = 8
+	}
+
+	suggestions, err := h.svc.SearchSuggestions(c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil saran pencarian", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
 }
@@ -3,6 +3,8 @@ package user
 import (
 	"time"
 
+	"backend-user/domain/models"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -13,12 +15,14 @@ type CustomerRegisterInput struct {
 	Email     string `json:"email" binding:"required,email"`
 	Phone     string `json:"phone"`
 	Password  string `json:"password" binding:"required,min=6"`
+	CartToken string `json:"cart_token"` // Opsional, keranjang tamu yang digabung setelah registrasi
 }
 
 // Input untuk login
 type CustomerLoginInput struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"`
+	CartToken string `json:"cart_token"` // Opsional, keranjang tamu yang digabung setelah login
 }
 
 // Respons untuk login
@@ -28,6 +32,8 @@ type CustomerLoginResponse struct {
 	FirstName  string `json:"first_name"`
 	LastName   string `json:"last_name"`
 	Email      string `json:"email"`
+
+	CartMerge *CartMergeResult `json:"cart_merge,omitempty"`
 }
 
 // Input untuk update profil
@@ -61,29 +67,130 @@ type CustomerJwtCustomClaims struct {
 	jwt.RegisteredClaims
 }
 
+// Claims cart token keranjang tamu
+type GuestCartClaims struct {
+	GuestCartID string `json:"guest_cart_id"`
+	jwt.RegisteredClaims
+}
+
 // DTO untuk tampilan produk publik (grid)
 type PublicProductGridItem struct {
 	ProductSKU   string  `json:"product_sku"`
 	CategoryName string  `json:"category_name"`
 	Title        string  `json:"title"`
+	Brand        string  `json:"brand,omitempty"`
+	Stock        int     `json:"stock"`
 	RegularPrice float64 `json:"regular_price"`
-	ImageUrl     string  `json:"image_url"`
+	ImageUrl     string  `json:"image_url"` // Gambar utama produk
+	ImageAlt     string  `json:"image_alt"`
+	VariantCount int     `json:"variant_count"`
+}
+
+// DTO untuk gambar produk publik, sudah terurut sesuai position
+type PublicProductImage struct {
+	Url       string `json:"url"`
+	AltText   string `json:"alt_text"`
+	IsPrimary bool   `json:"is_primary"`
 }
 
 // DTO untuk detail produk publik
 type PublicProductDetail struct {
-	ProductSKU     string     `json:"product_sku"`
-	Title          string     `json:"title"`
-	Brand          string     `json:"brand,omitempty"`
-	CategoryName   string     `json:"category_name"`
-	PowerSource    string     `json:"power_source,omitempty"`
-	WarrantyPeriod string     `json:"warranty_period,omitempty"`
-	ProductionDate *time.Time `json:"production_date,omitempty"`
-	Descriptions   string     `json:"descriptions,omitempty"`
-	Stock          int        `json:"stock"`
-	Status         string     `json:"status"`
-	RegularPrice   float64    `json:"regular_price"`
-	Images         []string   `json:"images"`
+	ProductSKU     string                       `json:"product_sku"`
+	Title          string                       `json:"title"`
+	Brand          string                       `json:"brand,omitempty"`
+	CategoryName   string                       `json:"category_name"`
+	PowerSource    string                       `json:"power_source,omitempty"`
+	WarrantyPeriod string                       `json:"warranty_period,omitempty"`
+	ProductionDate *time.Time                   `json:"production_date,omitempty"`
+	Descriptions   string                       `json:"descriptions,omitempty"`
+	Stock          int                          `json:"stock"`
+	Status         string                       `json:"status"`
+	RegularPrice   float64                      `json:"regular_price"`
+	Images         []string                     `json:"images"`
+	ImageDetails   []PublicProductImage         `json:"image_details"`
+	Specifications []PublicProductSpecification `json:"specifications"`
+	ParentSKU      string                       `json:"parent_sku,omitempty"`
+	VariantName    string                       `json:"variant_name,omitempty"`
+	// Pilihan varian dalam satu produk induk, kosong jika produk tidak memiliki varian
+	Variants []PublicProductVariant `json:"variants"`
+	// true jika SKU ini adalah induk yang memiliki varian; varian harus dipilih sebelum AddToCart
+	RequiresVariantSelection bool                   `json:"requires_variant_selection"`
+	CompatibleParts          []PublicCompatiblePart `json:"compatible_parts"`
+	// Diisi jika produk juga dapat disewa
+	Rental *PublicRentalRates `json:"rental,omitempty"`
+}
+
+// Tarif 0 berarti periode tersebut tidak ditawarkan
+type PublicRentalRates struct {
+	DailyRate   float64 `json:"daily_rate"`
+	WeeklyRate  float64 `json:"weekly_rate"`
+	MonthlyRate float64 `json:"monthly_rate"`
+	Deposit     float64 `json:"deposit"`
+}
+
+type PublicCompatiblePart struct {
+	ProductSKU   string  `json:"product_sku"`
+	Title        string  `json:"title"`
+	RegularPrice float64 `json:"regular_price"`
+	Stock        int     `json:"stock"`
+	ImageUrl     string  `json:"image_url"`
+	Notes        string  `json:"notes,omitempty"`
+}
+
+// Suku cadang untuk satu mesin yang pernah dibeli customer
+type PurchasedMachineParts struct {
+	MachineSKU    string                 `json:"machine_sku"`
+	MachineTitle  string                 `json:"machine_title"`
+	LastOrderedAt time.Time              `json:"last_ordered_at"`
+	Parts         []PublicCompatiblePart `json:"parts"`
+}
+
+type PublicProductVariant struct {
+	ProductSKU   string  `json:"product_sku"`
+	VariantName  string  `json:"variant_name"`
+	PowerSource  string  `json:"power_source,omitempty"`
+	RegularPrice float64 `json:"regular_price"`
+	Stock        int     `json:"stock"`
+	IsSelected   bool    `json:"is_selected"`
+}
+
+// DTO spesifikasi teknis produk, terurut sesuai skema atribut kategori
+type PublicProductSpecification struct {
+	Code        string   `json:"code"`
+	Label       string   `json:"label"`
+	DataType    string   `json:"data_type"`
+	Unit        string   `json:"unit,omitempty"`
+	NumberValue *float64 `json:"number_value,omitempty"`
+	Value       string   `json:"value"` // Nilai siap tampil, misal "7.5 kW"
+}
+
+// Kolom header pada tabel perbandingan produk
+type ProductComparisonColumn struct {
+	ProductSKU   string `json:"product_sku"`
+	Title        string `json:"title"`
+	CategoryName string `json:"category_name"`
+	ImageUrl     string `json:"image_url"`
+}
+
+// Satu sel perbandingan. Produk yang tidak memiliki nilai selalu diisi Value "-" dan Missing true.
+type ProductComparisonCell struct {
+	Value       string   `json:"value"`
+	NumberValue *float64 `json:"number_value,omitempty"`
+	Missing     bool     `json:"missing"`
+}
+
+type ProductComparisonRow struct {
+	Key         string                  `json:"key"`   // Misal "regular_price" atau "spec:motor_power"
+	Group       string                  `json:"group"` // "general" atau "specification"
+	Label       string                  `json:"label"`
+	Unit        string                  `json:"unit,omitempty"`
+	Cells       []ProductComparisonCell `json:"cells"` // Sejajar dengan urutan Products
+	IsDifferent bool                    `json:"is_different"`
+}
+
+type ProductComparison struct {
+	Products []ProductComparisonColumn `json:"products"`
+	Rows     []ProductComparisonRow    `json:"rows"`
 }
 
 // DTO untuk list kategori publik
@@ -92,6 +199,63 @@ type PublicProductCategoryView struct {
 	CategoryName string `json:"category_name"`
 }
 
+// Query string untuk GET /products
+type PublicProductQuery struct {
+	Search       string   `form:"q"`
+	CategoryID   string   `form:"category"`
+	Brands       []string `form:"brand"`        // Bisa diulang: ?brand=A&brand=B
+	PowerSources []string `form:"power_source"` // Bisa diulang
+	MinPrice     *float64 `form:"min_price" binding:"omitempty,min=0"`
+	MaxPrice     *float64 `form:"max_price" binding:"omitempty,min=0"`
+	InStock      bool     `form:"in_stock"`
+	Sort         string   `form:"sort" binding:"omitempty,oneof=newest price_asc price_desc popularity"`
+	Page         int      `form:"page" binding:"omitempty,min=1"`
+	Limit        int      `form:"limit" binding:"omitempty,min=1,max=100"`
+	// Filter spesifikasi dari ?spec[code]=nilai, ?spec_min[code]=angka dan ?spec_max[code]=angka, diisi oleh handler
+	SpecValues map[string]string  `form:"-"`
+	SpecMin    map[string]float64 `form:"-"`
+	SpecMax    map[string]float64 `form:"-"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+type PriceRangeFacet struct {
+	MinPrice float64 `json:"min_price"`
+	MaxPrice float64 `json:"max_price"`
+}
+
+// Facet untuk atribut spesifikasi yang filterable. Values untuk enum/text, MinValue/MaxValue untuk number.
+type SpecificationFacet struct {
+	Code     string       `json:"code"`
+	Label    string       `json:"label"`
+	DataType string       `json:"data_type"`
+	Unit     string       `json:"unit,omitempty"`
+	Values   []FacetCount `json:"values,omitempty"`
+	MinValue *float64     `json:"min_value,omitempty"`
+	MaxValue *float64     `json:"max_value,omitempty"`
+}
+
+// Setiap facet dihitung dengan semua filter aktif kecuali filter facet itu sendiri.
+// Specifications hanya diisi jika filter kategori aktif, karena skema atribut berbeda per kategori.
+type ProductCatalogFacets struct {
+	Categories     []FacetCount         `json:"categories"`
+	Brands         []FacetCount         `json:"brands"`
+	PowerSources   []FacetCount         `json:"power_sources"`
+	PriceRange     PriceRangeFacet      `json:"price_range"`
+	Specifications []SpecificationFacet `json:"specifications"`
+}
+
+type ProductCatalogPage struct {
+	Products   []PublicProductGridItem     `json:"products"`
+	Categories []PublicProductCategoryView `json:"categories"`
+	Facets     ProductCatalogFacets        `json:"facets"`
+	Pagination PaginationData              `json:"pagination"`
+}
+
 // DTO untuk item di keranjang
 type AddToCartInput struct {
 	ProductSKU string `json:"product_sku" binding:"required"`
@@ -103,11 +267,181 @@ type UpdateCartItemInput struct {
 	Quantity int `json:"quantity" binding:"required,min=1"`
 }
 
+// Isi keranjang customer; total hanya menghitung item aktif
+type CartContents struct {
+	CartItems  []models.Cart `json:"cart_items"`
+	SavedItems []models.Cart `json:"saved_items"`
+	Total      float64       `json:"total"`
+}
+
+type AddWishlistItemInput struct {
+	ProductSKU        string `json:"product_sku" binding:"required"`
+	NotifyPriceDrop   *bool  `json:"notify_price_drop"`    // Default true
+	NotifyBackInStock *bool  `json:"notify_back_in_stock"` // Default true
+}
+
+type UpdateWishlistItemInput struct {
+	NotifyPriceDrop   *bool `json:"notify_price_drop"`
+	NotifyBackInStock *bool `json:"notify_back_in_stock"`
+}
+
+type WishlistItemView struct {
+	ProductSKU        string    `json:"product_sku"`
+	Title             string    `json:"title"`
+	Image             string    `json:"image"`
+	Status            string    `json:"status"`
+	CurrentPrice      float64   `json:"current_price"`
+	PriceWhenAdded    float64   `json:"price_when_added"`
+	PriceDrop         float64   `json:"price_drop"` // Selisih sejak ditambahkan, 0 jika harga tidak turun
+	Stock             int       `json:"stock"`
+	InStock           bool      `json:"in_stock"`
+	NotifyPriceDrop   bool      `json:"notify_price_drop"`
+	NotifyBackInStock bool      `json:"notify_back_in_stock"`
+	AddedAt           time.Time `json:"added_at"`
+}
+
+type CustomerNotificationView struct {
+	ID         uint       `json:"id"`
+	Type       string     `json:"type"`
+	Title      string     `json:"title"`
+	Message    string     `json:"message"`
+	ProductSKU string     `json:"product_sku"`
+	IsRead     bool       `json:"is_read"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type NotificationFeed struct {
+	Notifications []CustomerNotificationView `json:"notifications"`
+	UnreadCount   int64                      `json:"unread_count"`
+}
+
+type GuestCartItemView struct {
+	ID           uint    `json:"id"`
+	ProductSKU   string  `json:"product_sku"`
+	Image        string  `json:"image"`
+	Title        string  `json:"title"`
+	RegularPrice float64 `json:"regular_price"`
+	Quantity     int     `json:"quantity"`
+	Total        float64 `json:"total"`
+}
+
+// Keranjang tamu beserta cart token terbaru yang harus disimpan client
+type GuestCartView struct {
+	CartToken string              `json:"cart_token"`
+	ExpiresAt time.Time           `json:"expires_at"`
+	Items     []GuestCartItemView `json:"items"`
+	Total     float64             `json:"total"`
+}
+
+// Hasil penggabungan satu produk keranjang tamu ke keranjang customer
+type CartMergeItem struct {
+	ProductSKU    string `json:"product_sku"`
+	Title         string `json:"title"`
+	GuestQuantity int    `json:"guest_quantity"`
+	AddedQuantity int    `json:"added_quantity"`
+	CartQuantity  int    `json:"cart_quantity"` // Kuantitas di keranjang customer setelah digabung
+	Status        string `json:"status"`        // merged, adjusted, skipped
+	Reason        string `json:"reason,omitempty"`
+}
+
+type CartMergeResult struct {
+	Items []CartMergeItem `json:"items"`
+}
+
 // DTO untuk input checkout
 type CheckoutInput struct {
 	SelectedAddressID uint   `json:"selected_address_id" binding:"required"`
 	PaymentMethod     string `json:"payment_method" binding:"required"`
 	Notes             string `json:"notes"`
+	OnBehalfOfCompany bool   `json:"on_behalf_of_company"` // Order dicatat atas nama perusahaan customer
+	CartItemIDs       []uint `json:"cart_item_ids"`        // Opsional, checkout sebagian item keranjang; kosong berarti semua item aktif
+}
+
+type CompanyInput struct {
+	LegalName         string   `json:"legal_name" binding:"required,max=255"`
+	NPWP              string   `json:"npwp" binding:"required"`
+	BillingAddress    string   `json:"billing_address" binding:"required"`
+	ApprovalThreshold *float64 `json:"approval_threshold" binding:"omitempty,gt=0"`
+}
+
+type AddCompanyMemberInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=buyer approver"`
+}
+
+type UpdateCompanyMemberInput struct {
+	Role string `json:"role" binding:"required,oneof=buyer approver"`
+}
+
+type RejectCompanyOrderInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type CompanyMemberView struct {
+	CustomerID string    `json:"customer_id"`
+	FullName   string    `json:"full_name"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	JoinedAt   time.Time `json:"joined_at"`
+}
+
+type CompanyView struct {
+	CompanyID         string              `json:"company_id"`
+	LegalName         string              `json:"legal_name"`
+	NPWP              string              `json:"npwp"`
+	BillingAddress    string              `json:"billing_address"`
+	ApprovalThreshold *float64            `json:"approval_threshold"`
+	MyRole            string              `json:"my_role"`
+	Members           []CompanyMemberView `json:"members"`
+}
+
+// Riwayat order perusahaan, ditambah siapa pembeli yang membuatnya
+type CompanyOrderHistoryItem struct {
+	OrderHistoryItem
+	PlacedByCustomerID string `json:"placed_by_customer_id"`
+	PlacedByName       string `json:"placed_by_name"`
+	ApprovedBy         string `json:"approved_by,omitempty"`
+}
+
+type QuotationRequestItemInput struct {
+	ProductSKU string `json:"product_sku" binding:"required"`
+	Quantity   int    `json:"quantity" binding:"required,min=1"`
+}
+
+// Input customer untuk mengajukan permintaan penawaran (RFQ)
+type CreateQuotationRequestInput struct {
+	Items        []QuotationRequestItemInput `json:"items" binding:"required,min=1,dive"`
+	SiteLocation string                      `json:"site_location" binding:"required"`
+	Notes        string                      `json:"notes"`
+}
+
+type QuotationLineView struct {
+	ProductSKU string  `json:"product_sku"`
+	Title      string  `json:"title"`
+	Quantity   int     `json:"quantity"`
+	UnitPrice  float64 `json:"unit_price,omitempty"`
+	SubTotal   float64 `json:"sub_total,omitempty"`
+}
+
+type QuotationView struct {
+	QuotationID string              `json:"quotation_id"`
+	ValidUntil  time.Time           `json:"valid_until"`
+	IsExpired   bool                `json:"is_expired"`
+	Terms       string              `json:"terms"`
+	TotalAmount float64             `json:"total_amount"`
+	Items       []QuotationLineView `json:"items"`
+	OrderID     string              `json:"order_id,omitempty"` // Diisi setelah quotation diterima
+}
+
+type QuotationRequestView struct {
+	RequestID    string              `json:"request_id"`
+	SiteLocation string              `json:"site_location"`
+	Notes        string              `json:"notes"`
+	Status       string              `json:"status"`
+	CreatedAt    time.Time           `json:"created_at"`
+	Items        []QuotationLineView `json:"items"`
+	Quotation    *QuotationView      `json:"quotation,omitempty"`
 }
 
 // DTO untuk riwayat pesanan di halaman list akun
@@ -117,6 +451,324 @@ type OrderHistoryItem struct {
 	GrandTotal    float64   `json:"grand_total"`
 	OrderStatus   string    `json:"order_status"`
 	ItemImages    []string  `json:"item_images"`
+
+	OutstandingBalance float64 `json:"outstanding_balance"`
+	HasPaymentSchedule bool    `json:"has_payment_schedule"`
+
+	// order atau rental; untuk rental OrderID berisi booking ID dan GrandTotal berisi biaya sewa
+	EntryType string             `json:"entry_type"`
+	Rental    *RentalHistoryInfo `json:"rental,omitempty"`
+}
+
+type RentalHistoryInfo struct {
+	BookingID            string    `json:"booking_id"`
+	ProductSKU           string    `json:"product_sku"`
+	ProductTitleSnapshot string    `json:"product_title_snapshot"`
+	StartDate            time.Time `json:"start_date"`
+	EndDate              time.Time `json:"end_date"`
+	RentalDays           int       `json:"rental_days"`
+	Deposit              float64   `json:"deposit"`
+}
+
+type CustomerOrderItemView struct {
+	OrderItemID          uint    `json:"order_item_id"`
+	ProductSKU           string  `json:"product_sku"`
+	ProductTitleSnapshot string  `json:"product_title_snapshot"`
+	ProductImageSnapshot string  `json:"product_image_snapshot"`
+	PriceAtOrder         float64 `json:"price_at_order"`
+	Quantity             int     `json:"quantity"`
+	SubTotal             float64 `json:"sub_total"`
+}
+
+type OrderStatusTimelineEntry struct {
+	Status    string    `json:"status"`
+	Note      string    `json:"note,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// Satu paket pengiriman; order besar bisa terdiri dari beberapa paket
+type ShipmentView struct {
+	ShipmentID       string                `json:"shipment_id"`
+	Courier          string                `json:"courier"`
+	TrackingNumber   string                `json:"tracking_number"`
+	Status           string                `json:"status"` // In Transit, Delivered
+	ShippedAt        time.Time             `json:"shipped_at"`
+	EstimatedArrival *time.Time            `json:"estimated_arrival"`
+	Items            []ShipmentItemView    `json:"items"`
+	Delivery         *ShipmentDeliveryView `json:"delivery"` // null sampai paket dikonfirmasi diterima
+}
+
+type ShipmentItemView struct {
+	OrderItemID  uint   `json:"order_item_id"`
+	ProductSKU   string `json:"product_sku"`
+	ProductTitle string `json:"product_title"`
+	Quantity     int    `json:"quantity"`
+}
+
+type ShipmentDeliveryView struct {
+	RecipientName string    `json:"recipient_name"`
+	DeliveredAt   time.Time `json:"delivered_at"`
+	Photo         string    `json:"photo,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+}
+
+type CustomerOrderDetailView struct {
+	OrderID        string    `json:"order_id"`
+	OrderDateTime  time.Time `json:"order_date_time"`
+	OrderStatus    string    `json:"order_status"`
+	PaymentMethod  string    `json:"payment_method"`
+	PaymentStatus  string    `json:"payment_status"` // Paid, Partially Paid, Awaiting Verification, Unpaid, Canceled
+	ProofOfPayment string    `json:"proof_of_payment"`
+	ProofStatus    string    `json:"proof_status"` // Not Uploaded, Uploaded, Verified, Per Milestone
+	Notes          string    `json:"notes"`
+
+	CompanyLegalName        string `json:"company_legal_name,omitempty"`
+	ShippingAddressSnapshot string `json:"shipping_address_snapshot"`
+
+	Subtotal           float64 `json:"subtotal"`
+	ShippingCost       float64 `json:"shipping_cost"` // Order belum menyimpan ongkos kirim; selalu 0 seperti detail order admin
+	GrandTotal         float64 `json:"grand_total"`
+	OutstandingBalance float64 `json:"outstanding_balance"`
+
+	Items           []CustomerOrderItemView    `json:"items"`
+	PaymentSchedule []PaymentMilestoneView     `json:"payment_schedule"` // Kosong jika dibayar sekaligus
+	Timeline        []OrderStatusTimelineEntry `json:"timeline"`
+	Shipments       []ShipmentView             `json:"shipments"` // Rincian per paket; kosong sampai pesanan dikirim
+	Refund          *OrderRefundView           `json:"refund"`    // null jika tidak ada pengembalian dana
+}
+
+type OrderRefundView struct {
+	RefundID         string     `json:"refund_id"`
+	Amount           float64    `json:"amount"`
+	Status           string     `json:"status"` // Pending, Processing, Paid
+	PaymentReference string     `json:"payment_reference,omitempty"`
+	PaidAt           *time.Time `json:"paid_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Rekening refund hanya dipakai jika pesanan sudah (sebagian) dibayar
+type CancelOrderInput struct {
+	Reason              string `json:"reason" binding:"required,max=1000"`
+	RefundBankName      string `json:"refund_bank_name" binding:"max=100"`
+	RefundAccountNumber string `json:"refund_account_number" binding:"max=50"`
+	RefundAccountHolder string `json:"refund_account_holder" binding:"max=255"`
+}
+
+type PaymentMilestoneView struct {
+	ID              uint       `json:"id"`
+	Sequence        int        `json:"sequence"`
+	Label           string     `json:"label"`
+	Percentage      *float64   `json:"percentage,omitempty"`
+	Amount          float64    `json:"amount"`
+	DueDate         time.Time  `json:"due_date"`
+	Status          string     `json:"status"`
+	IsOverdue       bool       `json:"is_overdue"`
+	ProofOfPayment  string     `json:"proof_of_payment,omitempty"`
+	SubmittedAt     *time.Time `json:"submitted_at,omitempty"`
+	VerifiedAt      *time.Time `json:"verified_at,omitempty"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+}
+
+type OrderPaymentSchedule struct {
+	OrderID            string                 `json:"order_id"`
+	GrandTotal         float64                `json:"grand_total"`
+	OutstandingBalance float64                `json:"outstanding_balance"`
+	Milestones         []PaymentMilestoneView `json:"milestones"`
+}
+
+type WarrantyView struct {
+	WarrantyID    uint      `json:"warranty_id"`
+	OrderID       string    `json:"order_id"`
+	ProductSKU    string    `json:"product_sku"`
+	ProductTitle  string    `json:"product_title"`
+	UnitIndex     int       `json:"unit_index"`
+	SerialNumber  string    `json:"serial_number,omitempty"`
+	StartDate     time.Time `json:"start_date"`
+	EndDate       time.Time `json:"end_date"`
+	IsActive      bool      `json:"is_active"`
+	DaysRemaining int       `json:"days_remaining"`
+	OpenClaimID   string    `json:"open_claim_id,omitempty"` // Klaim yang masih berjalan, jika ada
+	TotalClaims   int       `json:"total_claims"`
+}
+
+type WarrantyClaimView struct {
+	ClaimID         string     `json:"claim_id"`
+	WarrantyID      uint       `json:"warranty_id"`
+	OrderID         string     `json:"order_id"`
+	ProductSKU      string     `json:"product_sku"`
+	ProductTitle    string     `json:"product_title"`
+	SerialNumber    string     `json:"serial_number,omitempty"`
+	Description     string     `json:"description"`
+	Status          string     `json:"status"`
+	ResolutionNotes string     `json:"resolution_notes,omitempty"`
+	Photos          []string   `json:"photos"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Dikirim sebagai multipart form; foto barang diunggah lewat key "photos"
+type CreateReturnRequestInput struct {
+	OrderItemID         uint   `form:"order_item_id" binding:"required"`
+	Quantity            int    `form:"quantity" binding:"required,min=1"`
+	Reason              string `form:"reason" binding:"required,max=2000"`
+	ReturnMethod        string `form:"return_method" binding:"required,oneof=Pickup 'Drop Off' Courier"`
+	RefundBankName      string `form:"refund_bank_name" binding:"max=100"`
+	RefundAccountNumber string `form:"refund_account_number" binding:"max=50"`
+	RefundAccountHolder string `form:"refund_account_holder" binding:"max=255"`
+}
+
+type ReturnRequestView struct {
+	RMAID           string           `json:"rma_id"`
+	OrderID         string           `json:"order_id"`
+	OrderItemID     uint             `json:"order_item_id"`
+	ProductSKU      string           `json:"product_sku"`
+	ProductTitle    string           `json:"product_title"`
+	Quantity        int              `json:"quantity"`
+	Reason          string           `json:"reason"`
+	ReturnMethod    string           `json:"return_method"`
+	Status          string           `json:"status"`
+	AdminNotes      string           `json:"admin_notes,omitempty"`
+	Resolution      string           `json:"resolution,omitempty"` // Refund, Replacement, Repair
+	ResolutionNotes string           `json:"resolution_notes,omitempty"`
+	Refund          *OrderRefundView `json:"refund,omitempty"`
+	Photos          []string         `json:"photos"`
+	ReceivedAt      *time.Time       `json:"received_at,omitempty"`
+	ResolvedAt      *time.Time       `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// Dikirim sebagai multipart form; lampiran diunggah lewat key "attachments"
+type CreateServiceTicketInput struct {
+	Subject      string `form:"subject" binding:"required,max=255"`
+	Category     string `form:"category" binding:"required,oneof=Maintenance Installation Repair Other"`
+	Priority     string `form:"priority" binding:"omitempty,oneof=Low Normal High Urgent"`
+	Message      string `form:"message" binding:"required"`
+	OrderItemID  *uint  `form:"order_item_id"`
+	SerialNumber string `form:"serial_number" binding:"max=100"`
+}
+
+type ServiceTicketMessageInput struct {
+	Message string `form:"message" binding:"required"`
+}
+
+type ServiceTicketAttachmentView struct {
+	FileName string `json:"file_name"`
+	FilePath string `json:"file_path"`
+}
+
+type ServiceTicketMessageView struct {
+	ID          uint                          `json:"id"`
+	SenderType  string                        `json:"sender_type"`
+	SenderName  string                        `json:"sender_name"`
+	Message     string                        `json:"message"`
+	Attachments []ServiceTicketAttachmentView `json:"attachments"`
+	CreatedAt   time.Time                     `json:"created_at"`
+}
+
+type ServiceTicketView struct {
+	TicketID       string                     `json:"ticket_id"`
+	Subject        string                     `json:"subject"`
+	Category       string                     `json:"category"`
+	Priority       string                     `json:"priority"`
+	Status         string                     `json:"status"`
+	OrderItemID    *uint                      `json:"order_item_id"`
+	ProductSKU     string                     `json:"product_sku,omitempty"`
+	SerialNumber   string                     `json:"serial_number,omitempty"`
+	DepartmentName string                     `json:"department_name,omitempty"`
+	AssignedToName string                     `json:"assigned_to_name,omitempty"`
+	LastMessageAt  time.Time                  `json:"last_message_at"`
+	ResolvedAt     *time.Time                 `json:"resolved_at,omitempty"`
+	ClosedAt       *time.Time                 `json:"closed_at,omitempty"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	Messages       []ServiceTicketMessageView `json:"messages,omitempty"` // Hanya diisi pada detail tiket
+}
+
+type RequestInstallationInput struct {
+	SiteAddress        string `json:"site_address"` // Kosong = alamat pengiriman order
+	RequestedStartDate string `json:"requested_start_date" binding:"required,datetime=2006-01-02"`
+	RequestedEndDate   string `json:"requested_end_date" binding:"required,datetime=2006-01-02"`
+	Notes              string `json:"notes"`
+}
+
+type InstallationJobView struct {
+	JobID              string     `json:"job_id"`
+	OrderID            string     `json:"order_id"`
+	SiteAddress        string     `json:"site_address"`
+	RequestedStartDate time.Time  `json:"requested_start_date"`
+	RequestedEndDate   time.Time  `json:"requested_end_date"`
+	ScheduledStart     *time.Time `json:"scheduled_start"`
+	ScheduledEnd       *time.Time `json:"scheduled_end"`
+	Status             string     `json:"status"`
+	Notes              string     `json:"notes,omitempty"`
+	Technicians        []string   `json:"technicians"` // Nama teknisi yang ditugaskan
+	ChecklistTotal     int        `json:"checklist_total"`
+	ChecklistDone      int        `json:"checklist_done"`
+	SignedOffBy        string     `json:"signed_off_by,omitempty"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+type RentalAvailabilityQuery struct {
+	From string `form:"from" binding:"omitempty,datetime=2006-01-02"` // Kosong = hari ini
+	To   string `form:"to" binding:"omitempty,datetime=2006-01-02"`   // Kosong = 30 hari dari from
+}
+
+type RentalBookedPeriod struct {
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+}
+
+type RentalUnitAvailability struct {
+	RentalUnitID  uint                 `json:"rental_unit_id"`
+	UnitCode      string               `json:"unit_code"`
+	IsAvailable   bool                 `json:"is_available"` // Bebas selama seluruh rentang from..to
+	BookedPeriods []RentalBookedPeriod `json:"booked_periods"`
+}
+
+type RentalAvailabilityView struct {
+	ProductSKU  string                   `json:"product_sku"`
+	Title       string                   `json:"title"`
+	Rates       PublicRentalRates        `json:"rates"`
+	From        time.Time                `json:"from"`
+	To          time.Time                `json:"to"`
+	RentalDays  int                      `json:"rental_days"`
+	RentalPrice float64                  `json:"rental_price"` // Perkiraan biaya sewa untuk rentang from..to
+	Units       []RentalUnitAvailability `json:"units"`
+}
+
+type CreateRentalBookingInput struct {
+	ProductSKU        string `json:"product_sku" binding:"required"`
+	RentalUnitID      uint   `json:"rental_unit_id"` // Kosong = unit tersedia pertama
+	StartDate         string `json:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate           string `json:"end_date" binding:"required,datetime=2006-01-02"` // Inklusif
+	SelectedAddressID uint   `json:"selected_address_id" binding:"required"`
+	Notes             string `json:"notes"`
+}
+
+type RentalBookingView struct {
+	BookingID               string     `json:"booking_id"`
+	RentalUnitID            uint       `json:"rental_unit_id"`
+	UnitCode                string     `json:"unit_code"`
+	ProductSKU              string     `json:"product_sku"`
+	ProductTitleSnapshot    string     `json:"product_title_snapshot"`
+	StartDate               time.Time  `json:"start_date"`
+	EndDate                 time.Time  `json:"end_date"`
+	RentalDays              int        `json:"rental_days"`
+	RentalPrice             float64    `json:"rental_price"`
+	Deposit                 float64    `json:"deposit"`
+	Status                  string     `json:"status"`
+	ShippingAddressSnapshot string     `json:"shipping_address_snapshot"`
+	Notes                   string     `json:"notes,omitempty"`
+	HandedOverAt            *time.Time `json:"handed_over_at,omitempty"`
+	ReturnedAt              *time.Time `json:"returned_at,omitempty"`
+	ReturnCondition         string     `json:"return_condition,omitempty"`
+	InspectionNotes         string     `json:"inspection_notes,omitempty"`
+	DamageCharge            float64    `json:"damage_charge"`
+	DepositRefund           float64    `json:"deposit_refund"`
+	CanceledAt              *time.Time `json:"canceled_at,omitempty"`
+	CreatedAt               time.Time  `json:"created_at"`
 }
 
 type PublicNewsListItem struct {
@@ -167,3 +819,49 @@ type NewsDetailPageData struct {
 	Categories  []PublicNewsCategoryWithCount `json:"categories"`   // Menggunakan DTO yang sudah ada
 	RecentPosts []PublicNewsListItem          `json:"recent_posts"` // Menggunakan DTO yang sudah ada
 }
+
+// Query string untuk GET /search
+type SearchQuery struct {
+	Query string `form:"q" binding:"required"`
+	Type  string `form:"type" binding:"omitempty,oneof=all products news"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=50"` // Batas hasil per tipe
+}
+
+// Hit produk dari full-text search. TitleHighlight dan Snippet berupa HTML yang sudah di-escape;
+// satu-satunya markup di dalamnya adalah penanda <mark>
+type ProductSearchHit struct {
+	ProductSKU     string  `json:"product_sku"`
+	Title          string  `json:"title"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+	CategoryName   string  `json:"category_name"`
+	Brand          string  `json:"brand,omitempty"`
+	RegularPrice   float64 `json:"regular_price"`
+	ImageUrl       string  `json:"image_url"`
+	Rank           float64 `json:"rank"`
+}
+
+type NewsSearchHit struct {
+	NewsID          string    `json:"news_id"`
+	Title           string    `json:"title"`
+	TitleHighlight  string    `json:"title_highlight"`
+	Snippet         string    `json:"snippet"`
+	Image           string    `json:"image"`
+	PublicationDate time.Time `json:"publication_date"`
+	Rank            float64   `json:"rank"`
+}
+
+type SearchResults struct {
+	Query         string             `json:"query"`
+	Products      []ProductSearchHit `json:"products"`
+	News          []NewsSearchHit    `json:"news"`
+	TotalProducts int64              `json:"total_products"`
+	TotalNews     int64              `json:"total_news"`
+}
+
+// Saran typeahead, Type berisi "product" atau "news"
+type SearchSuggestion struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
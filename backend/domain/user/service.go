@@ -10,11 +10,16 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"backend-user/domain/models"
+	"backend-user/domain/shipping"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -22,15 +27,23 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
 	defaultCustomerImagePath  = "uploads/images/profile/avatar-1.jpg"
 	customerJwtExpirationTime = 7 * 24 * time.Hour
+
+	// Header tempat client mengirim cart token keranjang tamu
+	GuestCartTokenHeader   = "X-Cart-Token"
+	guestCartLifetime      = 30 * 24 * time.Hour
+	guestCartTokenAudience = "guest-cart"
 )
 
+var errGuestCartNotFound = errors.New("keranjang tamu tidak ditemukan atau sudah kedaluwarsa")
+
 type Service interface {
-	RegisterCustomer(input CustomerRegisterInput) (models.Customer, error)
+	RegisterCustomer(input CustomerRegisterInput) (models.Customer, *CartMergeResult, error)
 	LoginCustomer(input CustomerLoginInput) (CustomerLoginResponse, error)
 	GetCustomerProfile(customerID string) (models.Customer, error)
 	UpdateCustomerProfile(customerID string, input CustomerProfileUpdateInput) (*models.Customer, error)
@@ -40,27 +53,86 @@ type Service interface {
 	ListCustomerAddresses(customerID string) ([]models.CustomerAddress, error)
 	UpdateCustomerAddress(customerID string, addressID uint, input UpsertCustomerAddressInput) (models.CustomerAddress, error)
 
-	ListPublicProductsAndCategories() (map[string]interface{}, error)
+	ListPublicProducts(query PublicProductQuery) (ProductCatalogPage, error)
 	GetPublicProductDetail(productSKU string) (PublicProductDetail, error)
+	CompareProducts(productSKUs []string) (ProductComparison, error)
 	AddToCart(customerID string, input AddToCartInput) (models.Cart, error)
-	GetCartItems(customerID string) ([]models.Cart, error)
+	GetCartItems(customerID string) (CartContents, error)
 	UpdateCartItemQuantity(customerID string, cartItemID uint, newQuantity int) (models.Cart, error)
 	RemoveCartItem(customerID string, cartItemID uint) error
+	SaveCartItemForLater(customerID string, cartItemID uint) (models.Cart, error)
+	MoveSavedItemToCart(customerID string, cartItemID uint) (models.Cart, error)
+	AddToGuestCart(cartToken string, input AddToCartInput) (GuestCartView, error)
+	GetGuestCart(cartToken string) (GuestCartView, error)
+	UpdateGuestCartItemQuantity(cartToken string, itemID uint, newQuantity int) (GuestCartView, error)
+	RemoveGuestCartItem(cartToken string, itemID uint) (GuestCartView, error)
+	PurgeExpiredGuestCarts() (int64, error)
+	ListWishlist(customerID string) ([]WishlistItemView, error)
+	AddWishlistItem(customerID string, input AddWishlistItemInput) (WishlistItemView, error)
+	UpdateWishlistItem(customerID, productSKU string, input UpdateWishlistItemInput) (WishlistItemView, error)
+	RemoveWishlistItem(customerID, productSKU string) error
+	ListNotifications(customerID string, unreadOnly bool) (NotificationFeed, error)
+	MarkNotificationRead(customerID string, notificationID uint) error
+	MarkAllNotificationsRead(customerID string) (int64, error)
 
 	CreateOrderFromCart(customerID string, input CheckoutInput, proofPaymentFile *multipart.FileHeader) (models.Order, error)
 	ListCustomerOrders(customerID string) ([]OrderHistoryItem, error)
 
+	CreateCompany(customerID string, input CompanyInput) (CompanyView, error)
+	GetMyCompany(customerID string) (CompanyView, error)
+	UpdateCompany(customerID string, input CompanyInput) (CompanyView, error)
+	AddCompanyMember(customerID string, input AddCompanyMemberInput) (CompanyView, error)
+	UpdateCompanyMember(customerID, memberCustomerID string, input UpdateCompanyMemberInput) (CompanyView, error)
+	RemoveCompanyMember(customerID, memberCustomerID string) error
+	ListCompanyOrders(customerID, statusFilter string) ([]CompanyOrderHistoryItem, error)
+	ApproveCompanyOrder(customerID, orderID string) (CompanyOrderHistoryItem, error)
+	RejectCompanyOrder(customerID, orderID string, input RejectCompanyOrderInput) (CompanyOrderHistoryItem, error)
+	ListPartsForPurchasedMachines(customerID string) ([]PurchasedMachineParts, error)
+	GetOrderPaymentSchedule(customerID, orderID string) (OrderPaymentSchedule, error)
+	SubmitPaymentMilestoneProof(customerID, orderID string, milestoneID uint, proofPaymentFileHeader *multipart.FileHeader) (OrderPaymentSchedule, error)
+	ListWarranties(customerID string) ([]WarrantyView, error)
+	CreateWarrantyClaim(customerID string, warrantyID uint, description string, photoFileHeaders []*multipart.FileHeader) (WarrantyClaimView, error)
+	ListWarrantyClaims(customerID string) ([]WarrantyClaimView, error)
+	CreateServiceTicket(customerID string, input CreateServiceTicketInput, attachments []*multipart.FileHeader) (ServiceTicketView, error)
+	ListServiceTickets(customerID string) ([]ServiceTicketView, error)
+	GetServiceTicket(customerID, ticketID string) (ServiceTicketView, error)
+	AddServiceTicketMessage(customerID, ticketID string, input ServiceTicketMessageInput, attachments []*multipart.FileHeader) (ServiceTicketView, error)
+	CloseServiceTicket(customerID, ticketID string) (ServiceTicketView, error)
+	RequestInstallation(customerID, orderID string, input RequestInstallationInput) (InstallationJobView, error)
+	ListInstallationJobs(customerID string) ([]InstallationJobView, error)
+	GetCustomerOrderDetail(customerID, orderID string) (CustomerOrderDetailView, error)
+	CancelOrder(customerID, orderID string, input CancelOrderInput) (CustomerOrderDetailView, error)
+	GetShipmentTracking(customerID, orderID, shipmentID string) (shipping.TrackingResult, error)
+	GetRentalAvailability(productSKU string, query RentalAvailabilityQuery) (RentalAvailabilityView, error)
+	CreateRentalBooking(customerID string, input CreateRentalBookingInput) (RentalBookingView, error)
+	ListRentalBookings(customerID string) ([]RentalBookingView, error)
+	GetRentalBooking(customerID, bookingID string) (RentalBookingView, error)
+	CancelRentalBooking(customerID, bookingID string) (RentalBookingView, error)
+	CreateReturnRequest(customerID, orderID string, input CreateReturnRequestInput, photoFileHeaders []*multipart.FileHeader) (ReturnRequestView, error)
+	ListReturnRequests(customerID string) ([]ReturnRequestView, error)
+	GetReturnRequest(customerID, rmaID string) (ReturnRequestView, error)
+
+	CreateQuotationRequest(customerID string, input CreateQuotationRequestInput) (QuotationRequestView, error)
+	ListQuotationRequests(customerID string) ([]QuotationRequestView, error)
+	GetQuotationRequest(customerID, requestID string) (QuotationRequestView, error)
+	AcceptQuotation(customerID, requestID string, input CheckoutInput, proofPaymentFileHeader *multipart.FileHeader) (models.Order, error)
+	DeclineQuotationRequest(customerID, requestID string) (QuotationRequestView, error)
+
 	GetNewsPageData(page, limit int) (NewsPageData, error)
 	GetNewsDetailPageData(newsID string) (NewsDetailPageData, error)
+
+	Search(query SearchQuery) (SearchResults, error)
+	SearchSuggestions(term string, limit int) ([]SearchSuggestion, error)
 }
 
-func NewService(db *gorm.DB, jwtSecret []byte) Service {
-	return &service{db: db, jwtSecret: jwtSecret}
+func NewService(db *gorm.DB, jwtSecret []byte, tracker shipping.Provider) Service {
+	return &service{db: db, jwtSecret: jwtSecret, tracker: tracker}
 }
 
 type service struct {
 	db        *gorm.DB
 	jwtSecret []byte
+	tracker   shipping.Provider
 }
 
 func (s *service) generateJWTToken(customer models.Customer) (string, error) {
@@ -84,7 +156,7 @@ func (s *service) generateJWTToken(customer models.Customer) (string, error) {
 	return signedToken, nil
 }
 
-func (s *service) RegisterCustomer(input CustomerRegisterInput) (models.Customer, error) {
+func (s *service) RegisterCustomer(input CustomerRegisterInput) (models.Customer, *CartMergeResult, error) {
 	log.Printf("[Service RegisterCustomer] Memulai registrasi untuk email: %s\n", input.Email)
 	var finalCreatedCustomer models.Customer
 
@@ -152,11 +224,11 @@ func (s *service) RegisterCustomer(input CustomerRegisterInput) (models.Customer
 	})
 
 	if err != nil {
-		return models.Customer{}, err // Kembalikan error dari transaksi
+		return models.Customer{}, nil, err // Kembalikan error dari transaksi
 	}
 
 	log.Printf("[Service RegisterCustomer] Customer '%s' (ID: %s) berhasil diregistrasi.\n", finalCreatedCustomer.Email, finalCreatedCustomer.CustomerID)
-	return finalCreatedCustomer, nil
+	return finalCreatedCustomer, s.mergeGuestCartOnAuth(finalCreatedCustomer.CustomerID, input.CartToken), nil
 }
 
 func (s *service) generateJWTTokenForCustomer(customer models.Customer) (string, error) {
@@ -207,6 +279,7 @@ func (s *service) LoginCustomer(input CustomerLoginInput) (CustomerLoginResponse
 		response.FirstName = customer.Detail.FirstName
 		response.LastName = customer.Detail.LastName
 	}
+	response.CartMerge = s.mergeGuestCartOnAuth(customer.CustomerID, input.CartToken)
 
 	log.Printf("[Service LoginCustomer] Customer %s login berhasil.\n", customer.Email)
 	return response, nil
@@ -396,72 +469,305 @@ func (s *service) UpdateCustomerAddress(customerID string, addressID uint, input
 	return addressToUpdate, nil
 }
 
-func (s *service) ListPublicProductsAndCategories() (map[string]interface{}, error) {
-	log.Println("[Service ListPublicProductsAndCategories] Memulai proses...")
+// primaryProductImage mengembalikan gambar utama produk, atau gambar pertama jika belum ada yang ditandai.
+func primaryProductImage(images []models.ProductImage) (models.ProductImage, bool) {
+	for _, img := range images {
+		if img.IsPrimary && img.Image != "" {
+			return img, true
+		}
+	}
+	for _, img := range images {
+		if img.Image != "" {
+			return img, true
+		}
+	}
+	return models.ProductImage{}, false
+}
 
-	var productsFromDB []models.Product
-	var categoriesFromDB []models.ProductCategory
+const (
+	defaultCatalogPageSize = 12
+)
 
-	// 1. Ambil Produk yang "Published"
-	if err := s.db.
-		Preload("Images").          // Untuk gambar pertama
-		Preload("ProductCategory"). // <<< UNTUK MENGAMBIL DATA KATEGORI TERKAIT
-		Where("status = ?", "Published").
-		Order("created_at desc").
-		Find(&productsFromDB).Error; err != nil {
-		log.Printf("[Service ListPublicProductsAndCategories] Error mengambil produk: %v\n", err)
-		return nil, fmt.Errorf("gagal mengambil daftar produk: %w", err)
-	}
-
-	// Mapping produk ke DTO PublicProductGridItem
-	publicProducts := make([]PublicProductGridItem, 0, len(productsFromDB))
-	for _, p := range productsFromDB { // p adalah models.Product
-		var imageUrl string
-		if len(p.Images) > 0 && p.Images[0].Image != "" {
-			imageUrl = p.Images[0].Image
+// publicCatalogScope membangun query produk Published dengan filter dari query string.
+// skipFacet berisi nama facet yang filternya diabaikan saat menghitung facet tersebut.
+func (s *service) publicCatalogScope(query PublicProductQuery, skipFacet string) *gorm.DB {
+	// Varian tidak tampil sendiri di katalog, melainkan lewat halaman produk induknya
+	db := s.db.Model(&models.Product{}).Where("products.status = ? AND products.parent_sku IS NULL", "Published")
+
+	if tsQuery := buildPrefixTsQuery(query.Search); tsQuery != "" {
+		db = db.Where("products.search_vector @@ to_tsquery('simple', ?)", tsQuery)
+	}
+	if query.CategoryID != "" && skipFacet != "category" {
+		db = db.Where("products.product_category = ?", query.CategoryID)
+	}
+	if len(query.Brands) > 0 && skipFacet != "brand" {
+		db = db.Where("products.brand IN ?", query.Brands)
+	}
+	if len(query.PowerSources) > 0 && skipFacet != "power_source" {
+		db = db.Where("products.power_source IN ?", query.PowerSources)
+	}
+	if skipFacet != "price" {
+		if query.MinPrice != nil {
+			db = db.Where("products.regular_price >= ?", *query.MinPrice)
+		}
+		if query.MaxPrice != nil {
+			db = db.Where("products.regular_price <= ?", *query.MaxPrice)
+		}
+	}
+	if query.InStock {
+		db = db.Where(`(products.stock > 0 OR EXISTS (SELECT 1 FROM products variants
+			WHERE variants.parent_sku = products.product_sku AND variants.status = 'Published' AND variants.stock > 0))`)
+	}
+	for code, value := range query.SpecValues {
+		if skipFacet != "spec:"+code {
+			db = db.Where(productSpecificationCondition("product_specifications.text_value = ?"), code, value)
+		}
+	}
+	for code, minValue := range query.SpecMin {
+		if skipFacet != "spec:"+code {
+			db = db.Where(productSpecificationCondition("product_specifications.number_value >= ?"), code, minValue)
+		}
+	}
+	for code, maxValue := range query.SpecMax {
+		if skipFacet != "spec:"+code {
+			db = db.Where(productSpecificationCondition("product_specifications.number_value <= ?"), code, maxValue)
 		}
+	}
+	return db
+}
+
+// publishedVariantCounts menghitung jumlah varian Published untuk setiap produk induk di halaman.
+func (s *service) publishedVariantCounts(products []models.Product) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(products) == 0 {
+		return counts, nil
+	}
+	productSKUs := make([]string, 0, len(products))
+	for _, p := range products {
+		productSKUs = append(productSKUs, p.ProductSKU)
+	}
+
+	var rows []struct {
+		ParentSKU string
+		Count     int
+	}
+	if err := s.db.Model(&models.Product{}).
+		Select("parent_sku, COUNT(*) AS count").
+		Where("parent_sku IN ? AND status = ?", productSKUs, "Published").
+		Group("parent_sku").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ParentSKU] = row.Count
+	}
+	return counts, nil
+}
 
-		var categoryNameFromProduct string
-		if p.ProductCategory.CategoryID != "" { // Cek apakah ProductCategory terisi (hasil preload)
-			categoryNameFromProduct = p.ProductCategory.CategoryName
+// specificationOwnerCondition mencocokkan spesifikasi milik produk itu sendiri atau milik varian Published-nya.
+const specificationOwnerCondition = `(product_specifications.product_sku = products.product_sku
+	OR product_specifications.product_sku IN (SELECT variants.product_sku FROM products variants
+		WHERE variants.parent_sku = products.product_sku AND variants.status = 'Published'))`
+
+// productSpecificationCondition membungkus kondisi nilai spesifikasi dalam EXISTS berdasarkan kode atribut.
+func productSpecificationCondition(valueCondition string) string {
+	return `EXISTS (SELECT 1 FROM product_specifications
+		JOIN product_category_attributes ON product_category_attributes.id = product_specifications.attribute_id
+		WHERE ` + specificationOwnerCondition + `
+		AND product_category_attributes.code = ? AND ` + valueCondition + `)`
+}
+
+// specificationFacets menghitung facet untuk atribut filterable di kategori yang sedang difilter.
+func (s *service) specificationFacets(query PublicProductQuery) ([]SpecificationFacet, error) {
+	facets := []SpecificationFacet{}
+	if query.CategoryID == "" {
+		return facets, nil
+	}
+
+	var attributes []models.ProductCategoryAttribute
+	if err := s.db.Where("category_id = ? AND is_filterable = ?", query.CategoryID, true).
+		Order("position ASC, id ASC").Find(&attributes).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil atribut kategori: %w", err)
+	}
+
+	for _, attr := range attributes {
+		facet := SpecificationFacet{Code: attr.Code, Label: attr.Label, DataType: attr.DataType, Unit: attr.Unit}
+		specQuery := s.publicCatalogScope(query, "spec:"+attr.Code).
+			Joins("JOIN product_specifications ON "+specificationOwnerCondition).
+			Where("product_specifications.attribute_id = ?", attr.ID)
+
+		if attr.DataType == models.AttributeTypeNumber {
+			var numberRange struct {
+				MinValue *float64
+				MaxValue *float64
+			}
+			if err := specQuery.
+				Select("MIN(product_specifications.number_value) AS min_value, MAX(product_specifications.number_value) AS max_value").
+				Scan(&numberRange).Error; err != nil {
+				return nil, fmt.Errorf("gagal menghitung rentang atribut %s: %w", attr.Code, err)
+			}
+			facet.MinValue = numberRange.MinValue
+			facet.MaxValue = numberRange.MaxValue
 		} else {
-			log.Printf("[Service ListPublicProductsAndCategories] Peringatan: Produk SKU %s tidak memiliki detail kategori yang ter-preload atau CategoryID tidak valid.\n", p.ProductSKU)
+			facet.Values = []FacetCount{}
+			if err := specQuery.
+				Select("product_specifications.text_value AS value, COUNT(DISTINCT products.product_sku) AS count").
+				Group("product_specifications.text_value").
+				Order("product_specifications.text_value ASC").
+				Scan(&facet.Values).Error; err != nil {
+				return nil, fmt.Errorf("gagal menghitung facet atribut %s: %w", attr.Code, err)
+			}
 		}
+		facets = append(facets, facet)
+	}
+	return facets, nil
+}
+
+func (s *service) ListPublicProducts(query PublicProductQuery) (ProductCatalogPage, error) {
+	log.Printf("[Service ListPublicProducts] Query: %+v\n", query)
+	var pageData ProductCatalogPage
+
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return pageData, errors.New("min_price tidak boleh lebih besar dari max_price")
+	}
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit < 1 {
+		limit = defaultCatalogPageSize
+	}
+
+	// 1. Hitung total untuk pagination
+	var totalProducts int64
+	if err := s.publicCatalogScope(query, "").Count(&totalProducts).Error; err != nil {
+		log.Printf("[Service ListPublicProducts] Error menghitung produk: %v\n", err)
+		return pageData, fmt.Errorf("gagal menghitung produk: %w", err)
+	}
+	pageData.Pagination = PaginationData{
+		CurrentPage:  page,
+		TotalRecords: totalProducts,
+		TotalPages:   int(math.Ceil(float64(totalProducts) / float64(limit))),
+	}
+
+	// 2. Ambil produk sesuai halaman dan urutan
+	listQuery := s.publicCatalogScope(query, "").
+		Preload("Images", models.OrderedProductImages).
+		Preload("ProductCategory")
+	switch query.Sort {
+	case "price_asc":
+		listQuery = listQuery.Order("products.regular_price ASC, products.product_sku ASC")
+	case "price_desc":
+		listQuery = listQuery.Order("products.regular_price DESC, products.product_sku ASC")
+	case "popularity":
+		// Popularitas = total kuantitas terjual dari order_items
+		listQuery = listQuery.
+			Joins("LEFT JOIN (SELECT product_sku, SUM(quantity) AS sold_quantity FROM order_items GROUP BY product_sku) popularity ON popularity.product_sku = products.product_sku").
+			Order("COALESCE(popularity.sold_quantity, 0) DESC, products.created_at DESC")
+	default:
+		listQuery = listQuery.Order("products.created_at DESC, products.product_sku DESC")
+	}
 
-		publicProducts = append(publicProducts, PublicProductGridItem{
+	var productsFromDB []models.Product
+	if err := listQuery.Limit(limit).Offset((page - 1) * limit).Find(&productsFromDB).Error; err != nil {
+		log.Printf("[Service ListPublicProducts] Error mengambil produk: %v\n", err)
+		return pageData, fmt.Errorf("gagal mengambil daftar produk: %w", err)
+	}
+
+	variantCounts, err := s.publishedVariantCounts(productsFromDB)
+	if err != nil {
+		log.Printf("[Service ListPublicProducts] Error menghitung varian: %v\n", err)
+		return pageData, fmt.Errorf("gagal menghitung varian produk: %w", err)
+	}
+
+	pageData.Products = make([]PublicProductGridItem, 0, len(productsFromDB))
+	for _, p := range productsFromDB {
+		var imageUrl, imageAlt string
+		if primaryImage, ok := primaryProductImage(p.Images); ok {
+			imageUrl = primaryImage.Image
+			imageAlt = primaryImage.AltText
+		}
+		pageData.Products = append(pageData.Products, PublicProductGridItem{
 			ProductSKU:   p.ProductSKU,
 			Title:        p.Title,
-			CategoryName: categoryNameFromProduct, // <<< PASTIKAN INI DIISI DENGAN BENAR
+			Brand:        p.Brand,
+			Stock:        p.Stock,
+			CategoryName: p.ProductCategory.CategoryName,
 			RegularPrice: p.RegularPrice,
 			ImageUrl:     imageUrl,
+			ImageAlt:     imageAlt,
+			VariantCount: variantCounts[p.ProductSKU],
 		})
 	}
 
-	// 2. Ambil Kategori Produk yang "Published" (atau "active" sesuai status di tabel product_categories)
+	// 3. Facet counts
+	pageData.Facets.Categories = []FacetCount{}
+	if err := s.publicCatalogScope(query, "category").
+		Select("products.product_category AS value, product_categories.category_name AS label, COUNT(*) AS count").
+		Joins("JOIN product_categories ON product_categories.category_id = products.product_category").
+		Group("products.product_category, product_categories.category_name").
+		Order("product_categories.category_name ASC").
+		Scan(&pageData.Facets.Categories).Error; err != nil {
+		log.Printf("[Service ListPublicProducts] Error menghitung facet kategori: %v\n", err)
+		return pageData, fmt.Errorf("gagal menghitung facet kategori: %w", err)
+	}
+
+	pageData.Facets.Brands = []FacetCount{}
+	if err := s.publicCatalogScope(query, "brand").
+		Select("products.brand AS value, COUNT(*) AS count").
+		Where("products.brand <> ''").
+		Group("products.brand").
+		Order("products.brand ASC").
+		Scan(&pageData.Facets.Brands).Error; err != nil {
+		log.Printf("[Service ListPublicProducts] Error menghitung facet brand: %v\n", err)
+		return pageData, fmt.Errorf("gagal menghitung facet brand: %w", err)
+	}
+
+	pageData.Facets.PowerSources = []FacetCount{}
+	if err := s.publicCatalogScope(query, "power_source").
+		Select("products.power_source AS value, COUNT(*) AS count").
+		Where("products.power_source <> ''").
+		Group("products.power_source").
+		Order("products.power_source ASC").
+		Scan(&pageData.Facets.PowerSources).Error; err != nil {
+		log.Printf("[Service ListPublicProducts] Error menghitung facet sumber daya: %v\n", err)
+		return pageData, fmt.Errorf("gagal menghitung facet sumber daya: %w", err)
+	}
+
+	if err := s.publicCatalogScope(query, "price").
+		Select("COALESCE(MIN(products.regular_price), 0) AS min_price, COALESCE(MAX(products.regular_price), 0) AS max_price").
+		Scan(&pageData.Facets.PriceRange).Error; err != nil {
+		log.Printf("[Service ListPublicProducts] Error menghitung rentang harga: %v\n", err)
+		return pageData, fmt.Errorf("gagal menghitung rentang harga: %w", err)
+	}
+
+	specFacets, err := s.specificationFacets(query)
+	if err != nil {
+		log.Printf("[Service ListPublicProducts] Error menghitung facet spesifikasi: %v\n", err)
+		return pageData, err
+	}
+	pageData.Facets.Specifications = specFacets
+
+	// 4. Daftar kategori Published untuk navigasi (sama seperti sebelumnya)
+	var categoriesFromDB []models.ProductCategory
 	if err := s.db.
-		Where("status = ?", "published"). // Hanya kategori yang published/active
+		Where("status = ?", "published").
 		Order("category_name asc").
 		Find(&categoriesFromDB).Error; err != nil {
-		log.Printf("[Service ListPublicProductsAndCategories] Error mengambil kategori produk: %v\n", err)
-		return nil, fmt.Errorf("gagal mengambil daftar kategori produk: %w", err)
+		log.Printf("[Service ListPublicProducts] Error mengambil kategori produk: %v\n", err)
+		return pageData, fmt.Errorf("gagal mengambil daftar kategori produk: %w", err)
 	}
-
-	// Mapping kategori ke DTO PublicProductCategoryView
-	publicCategories := make([]PublicProductCategoryView, 0, len(categoriesFromDB))
+	pageData.Categories = make([]PublicProductCategoryView, 0, len(categoriesFromDB))
 	for _, cat := range categoriesFromDB {
-		publicCategories = append(publicCategories, PublicProductCategoryView{
+		pageData.Categories = append(pageData.Categories, PublicProductCategoryView{
 			CategoryID:   cat.CategoryID,
 			CategoryName: cat.CategoryName,
 		})
 	}
 
-	responseData := map[string]interface{}{
-		"products":   publicProducts,
-		"categories": publicCategories,
-	}
-
-	log.Printf("[Service ListPublicProductsAndCategories] Berhasil mengambil %d produk dan %d kategori.\n", len(publicProducts), len(publicCategories))
-	return responseData, nil
+	log.Printf("[Service ListPublicProducts] Berhasil mengambil %d dari %d produk.\n", len(pageData.Products), totalProducts)
+	return pageData, nil
 }
 
 func (s *service) GetPublicProductDetail(productSKU string) (PublicProductDetail, error) {
@@ -471,8 +777,10 @@ func (s *service) GetPublicProductDetail(productSKU string) (PublicProductDetail
 
 	// Ambil produk yang statusnya "Published"
 	if err := s.db.
-		Preload("Images").
+		Preload("Images", models.OrderedProductImages).
 		Preload("ProductCategory").
+		Preload("Specifications", models.OrderedProductSpecifications).
+		Preload("Specifications.Attribute").
 		Where("product_sku = ? AND status = ?", productSKU, "Published").
 		First(&productFromDB).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -483,11 +791,47 @@ func (s *service) GetPublicProductDetail(productSKU string) (PublicProductDetail
 		return publicProductDetail, fmt.Errorf("gagal mengambil detail produk: %w", err)
 	}
 
+	// Varian mewarisi konten bersama dari induknya jika tidak diisi sendiri
+	rootSKU := productFromDB.ProductSKU
+	if productFromDB.ParentSKU != nil {
+		rootSKU = *productFromDB.ParentSKU
+		var parent models.Product
+		if err := s.db.Preload("Images", models.OrderedProductImages).Where("product_sku = ?", rootSKU).First(&parent).Error; err == nil {
+			if len(productFromDB.Images) == 0 {
+				productFromDB.Images = parent.Images
+			}
+			if productFromDB.Descriptions == "" {
+				productFromDB.Descriptions = parent.Descriptions
+			}
+			if productFromDB.Brand == "" {
+				productFromDB.Brand = parent.Brand
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[Service GetPublicProductDetail] Error mengambil produk induk %s: %v\n", rootSKU, err)
+			return publicProductDetail, fmt.Errorf("gagal mengambil produk induk: %w", err)
+		}
+	}
+
+	var variants []models.Product
+	if err := s.db.Where("parent_sku = ? AND status = ?", rootSKU, "Published").
+		Order("regular_price ASC, product_sku ASC").
+		Find(&variants).Error; err != nil {
+		log.Printf("[Service GetPublicProductDetail] Error mengambil varian untuk %s: %v\n", rootSKU, err)
+		return publicProductDetail, fmt.Errorf("gagal mengambil varian produk: %w", err)
+	}
+
 	// Mapping ke PublicProductDetail DTO
 	imageUrls := make([]string, 0, len(productFromDB.Images))
+	imageDetails := make([]PublicProductImage, 0, len(productFromDB.Images))
+	primaryImage, _ := primaryProductImage(productFromDB.Images)
 	for _, img := range productFromDB.Images {
 		if img.Image != "" {
 			imageUrls = append(imageUrls, img.Image) // Path relatif
+			imageDetails = append(imageDetails, PublicProductImage{
+				Url:       img.Image,
+				AltText:   img.AltText,
+				IsPrimary: img.ID == primaryImage.ID,
+			})
 		}
 	}
 
@@ -504,26 +848,296 @@ func (s *service) GetPublicProductDetail(productSKU string) (PublicProductDetail
 		Status:         productFromDB.Status,
 		RegularPrice:   productFromDB.RegularPrice,
 		Images:         imageUrls,
+		ImageDetails:   imageDetails,
+		Specifications: make([]PublicProductSpecification, 0, len(productFromDB.Specifications)),
+		VariantName:    productFromDB.VariantName,
+		Variants:       make([]PublicProductVariant, 0, len(variants)),
+		// Induk yang memiliki varian tidak bisa dibeli langsung
+		RequiresVariantSelection: productFromDB.ParentSKU == nil && len(variants) > 0,
+	}
+	if productFromDB.ParentSKU != nil {
+		publicProductDetail.ParentSKU = *productFromDB.ParentSKU
+	}
+	if productFromDB.IsRentable {
+		publicProductDetail.Rental = &PublicRentalRates{
+			DailyRate:   productFromDB.RentalDailyRate,
+			WeeklyRate:  productFromDB.RentalWeeklyRate,
+			MonthlyRate: productFromDB.RentalMonthlyRate,
+			Deposit:     productFromDB.RentalDeposit,
+		}
+	}
+
+	// Suku cadang yang kompatibel dengan produk ini atau dengan induknya
+	compatibleParts, err := s.compatiblePartsFor(uniqueStrings([]string{productFromDB.ProductSKU, rootSKU}))
+	if err != nil {
+		log.Printf("[Service GetPublicProductDetail] Error mengambil suku cadang kompatibel untuk %s: %v\n", productSKU, err)
+		return publicProductDetail, fmt.Errorf("gagal mengambil suku cadang kompatibel: %w", err)
+	}
+	publicProductDetail.CompatibleParts = compatibleParts
+	for _, variant := range variants {
+		publicProductDetail.Variants = append(publicProductDetail.Variants, PublicProductVariant{
+			ProductSKU:   variant.ProductSKU,
+			VariantName:  variant.VariantName,
+			PowerSource:  variant.PowerSource,
+			RegularPrice: variant.RegularPrice,
+			Stock:        variant.Stock,
+			IsSelected:   variant.ProductSKU == productFromDB.ProductSKU,
+		})
+	}
+	for _, spec := range productFromDB.Specifications {
+		publicSpec := PublicProductSpecification{
+			Code:     spec.Attribute.Code,
+			Label:    spec.Attribute.Label,
+			DataType: spec.Attribute.DataType,
+			Unit:     spec.Attribute.Unit,
+			Value:    spec.TextValue,
+		}
+		if spec.Attribute.DataType == models.AttributeTypeNumber && spec.NumberValue != nil {
+			publicSpec.NumberValue = spec.NumberValue
+			publicSpec.Value = strings.TrimSpace(strconv.FormatFloat(*spec.NumberValue, 'f', -1, 64) + " " + spec.Attribute.Unit)
+		}
+		publicProductDetail.Specifications = append(publicProductDetail.Specifications, publicSpec)
 	}
 
 	log.Printf("[Service GetPublicProductDetail] Detail untuk SKU %s berhasil diambil.\n", productSKU)
 	return publicProductDetail, nil
 }
 
+const (
+	minCompareProducts     = 2
+	maxCompareProducts     = 4
+	comparisonMissingValue = "-"
+)
+
+func (s *service) CompareProducts(productSKUs []string) (ProductComparison, error) {
+	log.Printf("[Service CompareProducts] SKU: %v\n", productSKUs)
+	comparison := ProductComparison{
+		Products: []ProductComparisonColumn{},
+		Rows:     []ProductComparisonRow{},
+	}
+
+	// SKU duplikat diabaikan agar kolom tidak berulang
+	uniqueSKUs := make([]string, 0, len(productSKUs))
+	seenSKUs := make(map[string]bool)
+	for _, sku := range productSKUs {
+		sku = strings.TrimSpace(sku)
+		if sku != "" && !seenSKUs[sku] {
+			seenSKUs[sku] = true
+			uniqueSKUs = append(uniqueSKUs, sku)
+		}
+	}
+	if len(uniqueSKUs) < minCompareProducts || len(uniqueSKUs) > maxCompareProducts {
+		return comparison, fmt.Errorf("pilih minimal %d dan maksimal %d produk untuk dibandingkan", minCompareProducts, maxCompareProducts)
+	}
+
+	details := make([]PublicProductDetail, 0, len(uniqueSKUs))
+	for _, sku := range uniqueSKUs {
+		detail, err := s.GetPublicProductDetail(sku)
+		if err != nil {
+			if err.Error() == "produk tidak ditemukan atau tidak tersedia" {
+				return comparison, fmt.Errorf("produk tidak ditemukan atau tidak tersedia: %s", sku)
+			}
+			return comparison, err
+		}
+		details = append(details, detail)
+
+		var imageUrl string
+		for _, img := range detail.ImageDetails {
+			if img.IsPrimary {
+				imageUrl = img.Url
+				break
+			}
+		}
+		comparison.Products = append(comparison.Products, ProductComparisonColumn{
+			ProductSKU:   detail.ProductSKU,
+			Title:        detail.Title,
+			CategoryName: detail.CategoryName,
+			ImageUrl:     imageUrl,
+		})
+	}
+
+	textCell := func(value string) ProductComparisonCell {
+		if strings.TrimSpace(value) == "" {
+			return ProductComparisonCell{Value: comparisonMissingValue, Missing: true}
+		}
+		return ProductComparisonCell{Value: value}
+	}
+	numberCell := func(value float64) ProductComparisonCell {
+		return ProductComparisonCell{Value: strconv.FormatFloat(value, 'f', -1, 64), NumberValue: &value}
+	}
+
+	// 1. Baris field umum dari PublicProductDetail
+	generalRows := []struct {
+		key   string
+		label string
+		cell  func(d PublicProductDetail) ProductComparisonCell
+	}{
+		{"category_name", "Kategori", func(d PublicProductDetail) ProductComparisonCell { return textCell(d.CategoryName) }},
+		{"variant_name", "Varian", func(d PublicProductDetail) ProductComparisonCell { return textCell(d.VariantName) }},
+		{"brand", "Brand", func(d PublicProductDetail) ProductComparisonCell { return textCell(d.Brand) }},
+		{"power_source", "Sumber Daya", func(d PublicProductDetail) ProductComparisonCell { return textCell(d.PowerSource) }},
+		{"warranty_period", "Garansi", func(d PublicProductDetail) ProductComparisonCell { return textCell(d.WarrantyPeriod) }},
+		{"production_date", "Tanggal Produksi", func(d PublicProductDetail) ProductComparisonCell {
+			if d.ProductionDate == nil {
+				return textCell("")
+			}
+			return textCell(d.ProductionDate.Format("2006-01-02"))
+		}},
+		{"regular_price", "Harga", func(d PublicProductDetail) ProductComparisonCell { return numberCell(d.RegularPrice) }},
+		{"stock", "Stok", func(d PublicProductDetail) ProductComparisonCell { return numberCell(float64(d.Stock)) }},
+	}
+	for _, general := range generalRows {
+		row := ProductComparisonRow{Key: general.key, Group: "general", Label: general.label}
+		for _, detail := range details {
+			row.Cells = append(row.Cells, general.cell(detail))
+		}
+		comparison.Rows = append(comparison.Rows, row)
+	}
+
+	// 2. Baris spesifikasi: gabungan atribut semua produk. Atribut dengan code, tipe dan satuan
+	// yang sama dari kategori berbeda disejajarkan dalam satu baris.
+	specRowIndex := make(map[string]int)
+	var specRows []ProductComparisonRow
+	for column, detail := range details {
+		for _, spec := range detail.Specifications {
+			rowKey := spec.Code + "|" + spec.DataType + "|" + spec.Unit
+			index, ok := specRowIndex[rowKey]
+			if !ok {
+				index = len(specRows)
+				specRowIndex[rowKey] = index
+				row := ProductComparisonRow{
+					Key:   "spec:" + spec.Code,
+					Group: "specification",
+					Label: spec.Label,
+					Unit:  spec.Unit,
+					Cells: make([]ProductComparisonCell, len(details)),
+				}
+				for i := range row.Cells {
+					row.Cells[i] = textCell("")
+				}
+				specRows = append(specRows, row)
+			}
+			cell := textCell(spec.Value)
+			cell.NumberValue = spec.NumberValue
+			specRows[index].Cells[column] = cell
+		}
+	}
+	comparison.Rows = append(comparison.Rows, specRows...)
+
+	// 3. Tandai baris yang nilainya berbeda antar produk
+	for i := range comparison.Rows {
+		cells := comparison.Rows[i].Cells
+		for _, cell := range cells[1:] {
+			if cell.Value != cells[0].Value || cell.Missing != cells[0].Missing {
+				comparison.Rows[i].IsDifferent = true
+				break
+			}
+		}
+	}
+
+	log.Printf("[Service CompareProducts] Perbandingan %d produk dengan %d baris berhasil dibuat.\n", len(details), len(comparison.Rows))
+	return comparison, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// compatiblePartsFor mengambil suku cadang Published yang kompatibel dengan salah satu SKU mesin.
+func (s *service) compatiblePartsFor(machineSKUs []string) ([]PublicCompatiblePart, error) {
+	parts := []PublicCompatiblePart{}
+	if len(machineSKUs) == 0 {
+		return parts, nil
+	}
+
+	var compatibilities []models.ProductCompatibility
+	if err := s.db.
+		Select("product_compatibilities.*").
+		Joins("JOIN products parts ON parts.product_sku = product_compatibilities.part_sku AND parts.status = ?", "Published").
+		Preload("Part").
+		Preload("Part.Images", models.OrderedProductImages).
+		Where("product_compatibilities.machine_sku IN ?", machineSKUs).
+		Order("parts.title ASC, product_compatibilities.id ASC").
+		Find(&compatibilities).Error; err != nil {
+		return nil, err
+	}
+
+	seenParts := make(map[string]bool, len(compatibilities))
+	for _, compatibility := range compatibilities {
+		if seenParts[compatibility.PartSKU] {
+			continue
+		}
+		seenParts[compatibility.PartSKU] = true
+
+		var imageUrl string
+		if primaryImage, ok := primaryProductImage(compatibility.Part.Images); ok {
+			imageUrl = primaryImage.Image
+		}
+		parts = append(parts, PublicCompatiblePart{
+			ProductSKU:   compatibility.Part.ProductSKU,
+			Title:        compatibility.Part.Title,
+			RegularPrice: compatibility.Part.RegularPrice,
+			Stock:        compatibility.Part.Stock,
+			ImageUrl:     imageUrl,
+			Notes:        compatibility.Notes,
+		})
+	}
+	return parts, nil
+}
+
+// loadCartProduct memvalidasi produk yang akan masuk keranjang (customer maupun tamu) dan
+// mengembalikan gambar yang disimpan sebagai snapshot keranjang.
+func loadCartProduct(tx *gorm.DB, productSKU string) (models.Product, string, error) {
+	var product models.Product
+	if err := tx.Preload("Images", models.OrderedProductImages).Where("product_sku = ? AND status = ?", productSKU, "Published").First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return product, "", errors.New("produk tidak ditemukan atau tidak tersedia")
+		}
+		return product, "", fmt.Errorf("gagal memverifikasi produk: %w", err)
+	}
+
+	// Produk induk yang memiliki varian harus dibeli lewat SKU varian
+	var variantCount int64
+	if err := tx.Model(&models.Product{}).Where("parent_sku = ? AND status = ?", product.ProductSKU, "Published").Count(&variantCount).Error; err != nil {
+		return product, "", fmt.Errorf("gagal memeriksa varian produk: %w", err)
+	}
+	if variantCount > 0 {
+		return product, "", errors.New("produk memiliki varian, pilih varian terlebih dahulu")
+	}
+
+	// Varian tanpa gambar sendiri memakai gambar produk induk
+	if len(product.Images) == 0 && product.ParentSKU != nil {
+		if err := tx.Where("product_sku = ?", *product.ParentSKU).Order("is_primary DESC, position ASC, id ASC").Find(&product.Images).Error; err != nil {
+			return product, "", fmt.Errorf("gagal mengambil gambar produk induk: %w", err)
+		}
+	}
+
+	imageToStore := defaultCustomerImagePath // Gunakan default jika produk tidak punya gambar
+	if primaryImage, ok := primaryProductImage(product.Images); ok {
+		imageToStore = primaryImage.Image
+	}
+	return product, imageToStore, nil
+}
+
 func (s *service) AddToCart(customerID string, input AddToCartInput) (models.Cart, error) {
 	log.Printf("[Service AddToCart] CustomerID: %s, ProductSKU: %s, Quantity: %d\n", customerID, input.ProductSKU, input.Quantity)
 
-	var product models.Product // Menggunakan model Product dari package admin
-	var cartEntry models.Cart  // Model Cart dari package user (sekarang dengan ProductSKU)
+	var cartEntry models.Cart // Model Cart dari package user (sekarang dengan ProductSKU)
 	var finalCartEntry models.Cart
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		// 1. Ambil detail produk dari tabel 'products'
-		if errProduct := tx.Preload("Images").Where("product_sku = ? AND status = ?", input.ProductSKU, "Published").First(&product).Error; errProduct != nil {
-			if errors.Is(errProduct, gorm.ErrRecordNotFound) {
-				return errors.New("produk tidak ditemukan atau tidak tersedia")
-			}
-			return fmt.Errorf("gagal memverifikasi produk: %w", errProduct)
+		// 1. Validasi produk dan ambil gambar untuk snapshot keranjang
+		product, imageToStore, errProduct := loadCartProduct(tx, input.ProductSKU)
+		if errProduct != nil {
+			return errProduct
 		}
 
 		// 2. Cek apakah item produk ini sudah ada di keranjang customer
@@ -535,13 +1149,6 @@ func (s *service) AddToCart(customerID string, input AddToCartInput) (models.Car
 			requestedQuantity = 1
 		}
 
-		var imageToStore string
-		if len(product.Images) > 0 && product.Images[0].Image != "" {
-			imageToStore = product.Images[0].Image
-		} else {
-			imageToStore = defaultCustomerImagePath // Gunakan default jika produk tidak punya gambar
-		}
-
 		if errors.Is(errSearchCart, gorm.ErrRecordNotFound) {
 			// Item BELUM ADA di keranjang, buat entri baru
 			if product.Stock < requestedQuantity {
@@ -551,7 +1158,7 @@ func (s *service) AddToCart(customerID string, input AddToCartInput) (models.Car
 				CustomerID:   customerID,
 				ProductSKU:   product.ProductSKU, // <<< SIMPAN ProductSKU
 				Image:        imageToStore,
-				Title:        product.Title,
+				Title:        product.DisplayTitle(),
 				RegularPrice: product.RegularPrice,
 				Quantity:     requestedQuantity,
 			}
@@ -566,7 +1173,8 @@ func (s *service) AddToCart(customerID string, input AddToCartInput) (models.Car
 				return fmt.Errorf("stok produk '%s' tidak mencukupi untuk menambah kuantitas (total diminta: %d, tersedia: %d)", product.Title, newQuantity, product.Stock)
 			}
 			cartEntry.Quantity = newQuantity
-			cartEntry.Title = product.Title               // Update jika nama produk bisa berubah
+			cartEntry.SavedForLater = false               // Produk yang ditambahkan lagi kembali ke keranjang aktif
+			cartEntry.Title = product.DisplayTitle()      // Update jika nama produk bisa berubah
 			cartEntry.RegularPrice = product.RegularPrice // Update jika harga berubah
 			cartEntry.Image = imageToStore                // Update jika gambar produk utama berubah
 
@@ -591,21 +1199,28 @@ func (s *service) AddToCart(customerID string, input AddToCartInput) (models.Car
 	return finalCartEntry, nil
 }
 
-func (s *service) GetCartItems(customerID string) ([]models.Cart, error) {
+func (s *service) GetCartItems(customerID string) (CartContents, error) {
 	var cartItems []models.Cart
 	log.Printf("[Service GetCartItems] Mengambil item keranjang untuk Customer ID: %s\n", customerID)
 
 	if err := s.db.Where("customer_id = ?", customerID).Order("created_at DESC").Find(&cartItems).Error; err != nil {
-		// gorm.ErrRecordNotFound tidak dianggap error fatal di sini, hanya berarti keranjang kosong.
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("[Service GetCartItems] Error mengambil item keranjang untuk CustomerID %s: %v\n", customerID, err)
-			return nil, fmt.Errorf("gagal mengambil item keranjang: %w", err)
+		log.Printf("[Service GetCartItems] Error mengambil item keranjang untuk CustomerID %s: %v\n", customerID, err)
+		return CartContents{}, fmt.Errorf("gagal mengambil item keranjang: %w", err)
+	}
+
+	// Item yang disimpan untuk nanti dipisah dan tidak dihitung dalam total
+	contents := CartContents{CartItems: []models.Cart{}, SavedItems: []models.Cart{}}
+	for _, item := range cartItems {
+		if item.SavedForLater {
+			contents.SavedItems = append(contents.SavedItems, item)
+			continue
 		}
-		// Jika record tidak ditemukan, kembalikan slice kosong, bukan error
+		contents.CartItems = append(contents.CartItems, item)
+		contents.Total += item.RegularPrice * float64(item.Quantity)
 	}
 
-	log.Printf("[Service GetCartItems] Ditemukan %d item di keranjang untuk CustomerID: %s\n", len(cartItems), customerID)
-	return cartItems, nil
+	log.Printf("[Service GetCartItems] Ditemukan %d item aktif dan %d item tersimpan untuk CustomerID: %s\n", len(contents.CartItems), len(contents.SavedItems), customerID)
+	return contents, nil
 }
 
 func (s *service) UpdateCartItemQuantity(customerID string, cartItemID uint, newQuantity int) (models.Cart, error) {
@@ -686,226 +1301,3159 @@ func (s *service) RemoveCartItem(customerID string, cartItemID uint) error {
 	return nil
 }
 
-func (s *service) CreateOrderFromCart(customerID string, input CheckoutInput, proofPaymentFileHeader *multipart.FileHeader) (models.Order, error) {
-	log.Printf("[Service CreateOrderFromCart] CustomerID: %s, Input: %+v, Ada File Bukti Bayar: %t\n", customerID, input, proofPaymentFileHeader != nil)
+func (s *service) SaveCartItemForLater(customerID string, cartItemID uint) (models.Cart, error) {
+	log.Printf("[Service SaveCartItemForLater] CustomerID: %s, CartItemID: %d\n", customerID, cartItemID)
 
-	var finalCreatedOrder models.Order
-	var calculatedGrandTotal float64 = 0
-	var orderItemsToCreate []models.OrderItem // Menggunakan OrderItem dari model.go
-	var proofPaymentPath string = ""
+	var cartItem models.Cart
+	if err := s.db.Where("cart_id = ? AND customer_id = ?", cartItemID, customerID).First(&cartItem).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Cart{}, errors.New("item keranjang tidak ditemukan atau bukan milik Anda")
+		}
+		return models.Cart{}, fmt.Errorf("gagal mengambil item keranjang: %w", err)
+	}
+	if cartItem.SavedForLater {
+		return cartItem, nil
+	}
 
-	// Mulai transaksi database
+	if err := s.db.Model(&cartItem).Update("saved_for_later", true).Error; err != nil {
+		return models.Cart{}, fmt.Errorf("gagal menyimpan item untuk nanti: %w", err)
+	}
+	cartItem.SavedForLater = true
+	return cartItem, nil
+}
+
+// MoveSavedItemToCart mengembalikan item tersimpan ke keranjang aktif. Stok diperiksa ulang karena
+// item bisa tersimpan cukup lama.
+func (s *service) MoveSavedItemToCart(customerID string, cartItemID uint) (models.Cart, error) {
+	log.Printf("[Service MoveSavedItemToCart] CustomerID: %s, CartItemID: %d\n", customerID, cartItemID)
+
+	var cartItem models.Cart
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		// 1. Ambil semua item dari keranjang customer (tabel carts)
-		var cartItems []models.Cart // Menggunakan Cart dari model.go
-		if err := tx.Where("customer_id = ?", customerID).Find(&cartItems).Error; err != nil {
-			log.Printf("[Service CreateOrderFromCart] Error mengambil item keranjang untuk CustomerID %s: %v\n", customerID, err)
+		if err := tx.Where("cart_id = ? AND customer_id = ?", cartItemID, customerID).First(&cartItem).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("item keranjang tidak ditemukan atau bukan milik Anda")
+			}
 			return fmt.Errorf("gagal mengambil item keranjang: %w", err)
 		}
-		if len(cartItems) == 0 {
-			log.Println("[Service CreateOrderFromCart] Keranjang kosong.")
-			return errors.New("keranjang Anda kosong, tidak bisa melanjutkan checkout")
+		if !cartItem.SavedForLater {
+			return nil
 		}
 
-		// 2. Validasi alamat pengiriman yang dipilih
-		var shippingAddress models.CustomerAddress // Menggunakan CustomerAddress dari model.go
-		if err := tx.Where("address_id = ? AND customer_id = ?", input.SelectedAddressID, customerID).First(&shippingAddress).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				log.Printf("[Service CreateOrderFromCart] Alamat pengiriman ID %d tidak ditemukan untuk CustomerID %s.\n", input.SelectedAddressID, customerID)
-				return errors.New("alamat pengiriman yang dipilih tidak valid atau bukan milik Anda")
-			}
-			log.Printf("[Service CreateOrderFromCart] Error validasi alamat CustomerID %s, AlamatID %d: %v\n", customerID, input.SelectedAddressID, err)
-			return fmt.Errorf("gagal memvalidasi alamat pengiriman: %w", err)
+		product, imageToStore, err := loadCartProduct(tx, cartItem.ProductSKU)
+		if err != nil {
+			return err
+		}
+		if product.Stock < cartItem.Quantity {
+			return fmt.Errorf("stok produk '%s' tidak mencukupi (diminta: %d, tersedia: %d)", product.Title, cartItem.Quantity, product.Stock)
 		}
-		// Buat snapshot alamat sebagai string
-		addressSnapshot := fmt.Sprintf("%s, %s, %s, %s, %s, %s",
-			shippingAddress.Title, shippingAddress.Street,
-			shippingAddress.Additional, shippingAddress.DistrictCity,
-			shippingAddress.Province, shippingAddress.PostCode)
-
-		// 3. Simpan file bukti pembayaran jika ada dan metode pembayaran memerlukannya
-		if proofPaymentFileHeader != nil {
-			// (Logika penyimpanan file yang sudah kita diskusikan sebelumnya)
-			ext := filepath.Ext(proofPaymentFileHeader.Filename)
-			// Buat nama file yang lebih aman dan unik
-			uniqueFilename := fmt.Sprintf("proof_%s_%d_%s%s", customerID, time.Now().UnixNano(), uuid.New().String()[:8], ext)
-			uploadDir := "./uploads/payments/"
-			if errMkdir := os.MkdirAll(uploadDir, os.ModePerm); errMkdir != nil {
-				return fmt.Errorf("gagal membuat direktori untuk bukti pembayaran: %w", errMkdir)
-			}
-			savePathOnDisk := filepath.Join(uploadDir, uniqueFilename)
 
-			src, errOpen := proofPaymentFileHeader.Open()
-			if errOpen != nil {
-				return fmt.Errorf("gagal membuka file bukti pembayaran: %w", errOpen)
-			}
-			defer src.Close()
+		cartItem.SavedForLater = false
+		cartItem.Title = product.DisplayTitle()
+		cartItem.RegularPrice = product.RegularPrice
+		cartItem.Image = imageToStore
+		if err := tx.Save(&cartItem).Error; err != nil {
+			return fmt.Errorf("gagal memindahkan item ke keranjang: %w", err)
+		}
+		return tx.First(&cartItem, cartItem.CartID).Error
+	})
+	if err != nil {
+		return models.Cart{}, err
+	}
+	return cartItem, nil
+}
 
-			dst, errCreate := os.Create(savePathOnDisk)
-			if errCreate != nil {
-				return fmt.Errorf("gagal membuat file tujuan bukti pembayaran: %w", errCreate)
-			}
-			defer dst.Close()
+// Status hasil penggabungan keranjang tamu
+const (
+	cartMergeMerged   = "merged"
+	cartMergeAdjusted = "adjusted"
+	cartMergeSkipped  = "skipped"
+)
 
-			if _, errCopy := io.Copy(dst, src); errCopy != nil {
-				return fmt.Errorf("gagal menyimpan file bukti pembayaran: %w", errCopy)
-			}
+func (s *service) generateGuestCartToken(cart models.GuestCart) (string, error) {
+	claims := &GuestCartClaims{
+		GuestCartID: cart.GuestCartID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(cart.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "pumacon",
+			Audience:  jwt.ClaimStrings{guestCartTokenAudience},
+		},
+	}
+	signedToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return "", fmt.Errorf("gagal membuat cart token: %w", err)
+	}
+	return signedToken, nil
+}
 
-			proofPaymentPath = strings.TrimPrefix(filepath.ToSlash(savePathOnDisk), "./")
-			log.Printf("[Service CreateOrderFromCart] Bukti pembayaran disimpan ke: %s\n", proofPaymentPath)
-		} else if input.PaymentMethod == "Manual Transfer BCA" { // Atau metode lain yang WAJIB bukti transfer
-			return errors.New("bukti pembayaran diperlukan untuk metode transfer manual yang Anda pilih")
+// findGuestCart memverifikasi cart token lalu mengunci keranjang tamu yang masih berlaku.
+func (s *service) findGuestCart(tx *gorm.DB, cartToken string) (models.GuestCart, error) {
+	var cart models.GuestCart
+	claims := &GuestCartClaims{}
+	token, err := jwt.ParseWithClaims(cartToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("metode signing tidak terduga: %v", token.Header["alg"])
 		}
+		return s.jwtSecret, nil
+	}, jwt.WithAudience(guestCartTokenAudience))
+	if err != nil || !token.Valid || claims.GuestCartID == "" {
+		return cart, errGuestCartNotFound
+	}
 
-		// 4. Generate Order ID unik
-		var nextVal int
-		if err := tx.Raw("SELECT nextval('order_id_seq')").Scan(&nextVal).Error; err != nil {
-			log.Printf("[Service CreateOrderFromCart] Error mendapatkan ID berikutnya dari sequence: %v\n", err)
-			return fmt.Errorf("gagal mendapatkan ID order: %w", err)
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("guest_cart_id = ? AND expires_at > ?", claims.GuestCartID, time.Now()).
+		First(&cart).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return cart, errGuestCartNotFound
 		}
-		// Format ID baru: "ORD" + 5 digit angka dengan padding nol (misal: ORD00001)
-		// Pastikan ukuran kolom di DB (VARCHAR 10) cukup (ORD + 5 digit = 8 karakter, jadi cukup)
-		newOrderID := fmt.Sprintf("ORD%05d", nextVal)
-		log.Printf("[Service CreateOrderFromCart] ID Order baru digenerate: %s\n", newOrderID)
+		return cart, fmt.Errorf("gagal mengambil keranjang tamu: %w", err)
+	}
+	return cart, nil
+}
 
-		// 5. Ambil detail customer (nama, email, phone) untuk denormalisasi di order header
-		var customerDetail models.CustomerDetail
-		if err := tx.Where("customer_id = ?", customerID).First(&customerDetail).Error; err != nil {
-			// Jika customer_detail tidak ada, ini masalah data. Bisa log atau return error.
-			// Untuk contoh ini, kita biarkan field nama/email/phone kosong di order jika detail tidak ada.
-			// Atau lebih baik return error jika ini data wajib.
-			log.Printf("[Service CreateOrderFromCart] Peringatan: CustomerDetail tidak ditemukan untuk CustomerID %s. Beberapa info order mungkin kosong.\n", customerID)
-			// return fmt.Errorf("detail customer tidak ditemukan: %w", err) // Pilih untuk error jika wajib
-		}
+// guestCartView memperpanjang masa berlaku keranjang tamu dan menerbitkan cart token baru.
+func (s *service) guestCartView(tx *gorm.DB, cart models.GuestCart) (GuestCartView, error) {
+	cart.ExpiresAt = time.Now().Add(guestCartLifetime)
+	if err := tx.Model(&models.GuestCart{}).Where("guest_cart_id = ?", cart.GuestCartID).Update("expires_at", cart.ExpiresAt).Error; err != nil {
+		return GuestCartView{}, fmt.Errorf("gagal memperbarui masa berlaku keranjang tamu: %w", err)
+	}
+	cartToken, err := s.generateGuestCartToken(cart)
+	if err != nil {
+		return GuestCartView{}, err
+	}
 
-		// 6. Buat OrderItem untuk setiap item di keranjang dan hitung GrandTotal
-		now := time.Now()
-		for _, itemInCart := range cartItems {
-			var product models.Product // Menggunakan model Product dari package admin
-			if err := tx.Preload("Images").Where("product_sku = ?", itemInCart.ProductSKU).First(&product).Error; err != nil {
-				return fmt.Errorf("produk dengan SKU %s di keranjang tidak ditemukan: %w", itemInCart.ProductSKU, err)
-			}
-			if product.Stock < itemInCart.Quantity {
-				return fmt.Errorf("stok produk '%s' tidak mencukupi (tersisa: %d, diminta: %d)", product.Title, product.Stock, itemInCart.Quantity)
-			}
+	var items []models.GuestCartItem
+	if err := tx.Where("guest_cart_id = ?", cart.GuestCartID).Order("created_at DESC, id DESC").Find(&items).Error; err != nil {
+		return GuestCartView{}, fmt.Errorf("gagal mengambil item keranjang tamu: %w", err)
+	}
 
-			orderItem := models.OrderItem{ // Menggunakan OrderItem dari model.go
-				OrderID:              newOrderID,
-				ProductSKU:           itemInCart.ProductSKU,
-				Quantity:             itemInCart.Quantity,
-				PriceAtOrder:         itemInCart.RegularPrice, // Ambil harga dari keranjang (snapshot)
-				ProductTitleSnapshot: itemInCart.Title,        // Ambil judul dari keranjang (snapshot)
-				ProductImageSnapshot: itemInCart.Image,        // Ambil gambar dari keranjang (snapshot)
-				// SubTotal akan di-generate DB
-				// Atribut lain seperti customer_id, date_time, payment, status, dll. TIDAK di sini sesuai DDL order_items ramping
-			}
-			orderItemsToCreate = append(orderItemsToCreate, orderItem)
-			calculatedGrandTotal += (itemInCart.RegularPrice * float64(itemInCart.Quantity))
+	view := GuestCartView{CartToken: cartToken, ExpiresAt: cart.ExpiresAt, Items: make([]GuestCartItemView, 0, len(items))}
+	for _, item := range items {
+		total := item.RegularPrice * float64(item.Quantity)
+		view.Items = append(view.Items, GuestCartItemView{
+			ID:           item.ID,
+			ProductSKU:   item.ProductSKU,
+			Image:        item.Image,
+			Title:        item.Title,
+			RegularPrice: item.RegularPrice,
+			Quantity:     item.Quantity,
+			Total:        total,
+		})
+		view.Total += total
+	}
+	return view, nil
+}
+
+func (s *service) AddToGuestCart(cartToken string, input AddToCartInput) (GuestCartView, error) {
+	log.Printf("[Service AddToGuestCart] ProductSKU: %s, Quantity: %d\n", input.ProductSKU, input.Quantity)
+
+	var view GuestCartView
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Token kosong atau kedaluwarsa memulai keranjang tamu baru
+		var cart models.GuestCart
+		var errCart error = errGuestCartNotFound
+		if cartToken != "" {
+			cart, errCart = s.findGuestCart(tx, cartToken)
+		}
+		if errors.Is(errCart, errGuestCartNotFound) {
+			cart = models.GuestCart{GuestCartID: uuid.NewString(), ExpiresAt: time.Now().Add(guestCartLifetime)}
+			if err := tx.Create(&cart).Error; err != nil {
+				return fmt.Errorf("gagal membuat keranjang tamu: %w", err)
+			}
+			log.Printf("[Service AddToGuestCart] Keranjang tamu baru dibuat: %s\n", cart.GuestCartID)
+		} else if errCart != nil {
+			return errCart
+		}
+
+		product, imageToStore, err := loadCartProduct(tx, input.ProductSKU)
+		if err != nil {
+			return err
+		}
+
+		requestedQuantity := input.Quantity
+		if requestedQuantity <= 0 {
+			requestedQuantity = 1
+		}
+
+		var item models.GuestCartItem
+		errSearch := tx.Where("guest_cart_id = ? AND product_sku = ?", cart.GuestCartID, product.ProductSKU).First(&item).Error
+		if errors.Is(errSearch, gorm.ErrRecordNotFound) {
+			if product.Stock < requestedQuantity {
+				return fmt.Errorf("stok produk '%s' tidak mencukupi (diminta: %d, tersedia: %d)", product.Title, requestedQuantity, product.Stock)
+			}
+			item = models.GuestCartItem{
+				GuestCartID:  cart.GuestCartID,
+				ProductSKU:   product.ProductSKU,
+				Image:        imageToStore,
+				Title:        product.DisplayTitle(),
+				RegularPrice: product.RegularPrice,
+				Quantity:     requestedQuantity,
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return fmt.Errorf("gagal menambahkan item ke keranjang: %w", err)
+			}
+		} else if errSearch == nil {
+			newQuantity := item.Quantity + requestedQuantity
+			if product.Stock < newQuantity {
+				return fmt.Errorf("stok produk '%s' tidak mencukupi untuk menambah kuantitas (total diminta: %d, tersedia: %d)", product.Title, newQuantity, product.Stock)
+			}
+			item.Quantity = newQuantity
+			item.Title = product.DisplayTitle()
+			item.RegularPrice = product.RegularPrice
+			item.Image = imageToStore
+			if err := tx.Save(&item).Error; err != nil {
+				return fmt.Errorf("gagal mengupdate kuantitas item di keranjang: %w", err)
+			}
+		} else {
+			return fmt.Errorf("gagal memeriksa keranjang: %w", errSearch)
+		}
+
+		view, err = s.guestCartView(tx, cart)
+		return err
+	})
+	if err != nil {
+		return GuestCartView{}, err
+	}
+	return view, nil
+}
+
+func (s *service) GetGuestCart(cartToken string) (GuestCartView, error) {
+	var view GuestCartView
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		cart, err := s.findGuestCart(tx, cartToken)
+		if err != nil {
+			return err
+		}
+		view, err = s.guestCartView(tx, cart)
+		return err
+	})
+	if err != nil {
+		return GuestCartView{}, err
+	}
+	return view, nil
+}
+
+func (s *service) UpdateGuestCartItemQuantity(cartToken string, itemID uint, newQuantity int) (GuestCartView, error) {
+	log.Printf("[Service UpdateGuestCartItemQuantity] ItemID: %d, NewQuantity: %d\n", itemID, newQuantity)
+	if newQuantity < 1 {
+		return GuestCartView{}, errors.New("kuantitas tidak boleh kurang dari 1")
+	}
+
+	var view GuestCartView
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		cart, err := s.findGuestCart(tx, cartToken)
+		if err != nil {
+			return err
+		}
+
+		var item models.GuestCartItem
+		if err := tx.Where("id = ? AND guest_cart_id = ?", itemID, cart.GuestCartID).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("item keranjang tidak ditemukan atau bukan milik Anda")
+			}
+			return fmt.Errorf("gagal mengambil item keranjang: %w", err)
+		}
+
+		var product models.Product
+		if err := tx.Where("product_sku = ?", item.ProductSKU).First(&product).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("detail produk untuk item keranjang tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil detail produk: %w", err)
+		}
+		if product.Stock < newQuantity {
+			return fmt.Errorf("stok produk '%s' tidak mencukupi (diminta: %d, tersedia: %d)", product.Title, newQuantity, product.Stock)
+		}
+
+		item.Quantity = newQuantity
+		if err := tx.Save(&item).Error; err != nil {
+			return fmt.Errorf("gagal mengupdate kuantitas item keranjang: %w", err)
+		}
+
+		view, err = s.guestCartView(tx, cart)
+		return err
+	})
+	if err != nil {
+		return GuestCartView{}, err
+	}
+	return view, nil
+}
+
+func (s *service) RemoveGuestCartItem(cartToken string, itemID uint) (GuestCartView, error) {
+	log.Printf("[Service RemoveGuestCartItem] Menghapus ItemID: %d\n", itemID)
+
+	var view GuestCartView
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		cart, err := s.findGuestCart(tx, cartToken)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ? AND guest_cart_id = ?", itemID, cart.GuestCartID).Delete(&models.GuestCartItem{})
+		if result.Error != nil {
+			return fmt.Errorf("gagal menghapus item dari keranjang: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("item keranjang tidak ditemukan atau Anda tidak berhak menghapusnya")
+		}
+
+		view, err = s.guestCartView(tx, cart)
+		return err
+	})
+	if err != nil {
+		return GuestCartView{}, err
+	}
+	return view, nil
+}
+
+// mergeGuestCart memindahkan isi keranjang tamu ke keranjang customer. SKU yang sudah ada dijumlahkan,
+// kuantitas dibatasi stok saat ini tanpa mengurangi isi keranjang customer, dan produk yang tidak lagi
+// bisa dibeli dilewati. Keranjang tamu dihapus setelah digabung.
+func (s *service) mergeGuestCart(customerID, cartToken string) (*CartMergeResult, error) {
+	result := &CartMergeResult{Items: []CartMergeItem{}}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		cart, err := s.findGuestCart(tx, cartToken)
+		if err != nil {
+			return err
+		}
+
+		var guestItems []models.GuestCartItem
+		if err := tx.Where("guest_cart_id = ?", cart.GuestCartID).Order("created_at ASC, id ASC").Find(&guestItems).Error; err != nil {
+			return fmt.Errorf("gagal mengambil item keranjang tamu: %w", err)
+		}
+
+		for _, guestItem := range guestItems {
+			line := CartMergeItem{
+				ProductSKU:    guestItem.ProductSKU,
+				Title:         guestItem.Title,
+				GuestQuantity: guestItem.Quantity,
+				Status:        cartMergeSkipped,
+			}
+
+			product, imageToStore, err := loadCartProduct(tx, guestItem.ProductSKU)
+			if err != nil {
+				if err.Error() != "produk tidak ditemukan atau tidak tersedia" && err.Error() != "produk memiliki varian, pilih varian terlebih dahulu" {
+					return err
+				}
+				line.Reason = err.Error()
+				result.Items = append(result.Items, line)
+				continue
+			}
+			line.Title = product.DisplayTitle()
+
+			var cartEntry models.Cart
+			errSearch := tx.Where("customer_id = ? AND product_sku = ?", customerID, product.ProductSKU).First(&cartEntry).Error
+			if errSearch != nil && !errors.Is(errSearch, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("gagal memeriksa keranjang: %w", errSearch)
+			}
+			inCart := errSearch == nil
+
+			desiredQuantity := cartEntry.Quantity + guestItem.Quantity
+			targetQuantity := min(desiredQuantity, product.Stock)
+			if targetQuantity <= cartEntry.Quantity {
+				line.CartQuantity = cartEntry.Quantity
+				line.Reason = fmt.Sprintf("stok produk tidak mencukupi (tersedia: %d)", product.Stock)
+				result.Items = append(result.Items, line)
+				continue
+			}
+
+			line.AddedQuantity = targetQuantity - cartEntry.Quantity
+			line.CartQuantity = targetQuantity
+			line.Status = cartMergeMerged
+			if targetQuantity < desiredQuantity {
+				line.Status = cartMergeAdjusted
+				line.Reason = fmt.Sprintf("kuantitas disesuaikan dengan stok tersedia (%d)", product.Stock)
+			}
+
+			if inCart {
+				cartEntry.Quantity = targetQuantity
+				cartEntry.SavedForLater = false
+				cartEntry.Title = product.DisplayTitle()
+				cartEntry.RegularPrice = product.RegularPrice
+				cartEntry.Image = imageToStore
+				if err := tx.Save(&cartEntry).Error; err != nil {
+					return fmt.Errorf("gagal mengupdate kuantitas item di keranjang: %w", err)
+				}
+			} else {
+				cartEntry = models.Cart{
+					CustomerID:   customerID,
+					ProductSKU:   product.ProductSKU,
+					Image:        imageToStore,
+					Title:        product.DisplayTitle(),
+					RegularPrice: product.RegularPrice,
+					Quantity:     targetQuantity,
+				}
+				if err := tx.Create(&cartEntry).Error; err != nil {
+					return fmt.Errorf("gagal menambahkan item ke keranjang: %w", err)
+				}
+			}
+			result.Items = append(result.Items, line)
+		}
+
+		if err := tx.Where("guest_cart_id = ?", cart.GuestCartID).Delete(&models.GuestCartItem{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus item keranjang tamu: %w", err)
+		}
+		if err := tx.Delete(&models.GuestCart{}, "guest_cart_id = ?", cart.GuestCartID).Error; err != nil {
+			return fmt.Errorf("gagal menghapus keranjang tamu: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Service mergeGuestCart] %d item keranjang tamu digabung ke keranjang customer %s\n", len(result.Items), customerID)
+	return result, nil
+}
+
+// mergeGuestCartOnAuth dipanggil setelah login/registrasi berhasil. Kegagalan penggabungan (termasuk
+// cart token yang tidak valid) tidak menggagalkan autentikasi, keranjang tamu cukup diabaikan.
+func (s *service) mergeGuestCartOnAuth(customerID, cartToken string) *CartMergeResult {
+	cartToken = strings.TrimSpace(cartToken)
+	if cartToken == "" {
+		return nil
+	}
+	result, err := s.mergeGuestCart(customerID, cartToken)
+	if err != nil {
+		log.Printf("[Service mergeGuestCartOnAuth] Keranjang tamu diabaikan untuk customer %s: %v\n", customerID, err)
+		return nil
+	}
+	return result
+}
+
+// PurgeExpiredGuestCarts menghapus keranjang tamu yang sudah melewati masa berlakunya.
+func (s *service) PurgeExpiredGuestCarts() (int64, error) {
+	var purged int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		expiredCarts := tx.Model(&models.GuestCart{}).Select("guest_cart_id").Where("expires_at < ?", now)
+		if err := tx.Where("guest_cart_id IN (?)", expiredCarts).Delete(&models.GuestCartItem{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus item keranjang tamu: %w", err)
+		}
+		result := tx.Where("expires_at < ?", now).Delete(&models.GuestCart{})
+		if result.Error != nil {
+			return fmt.Errorf("gagal menghapus keranjang tamu: %w", result.Error)
+		}
+		purged = result.RowsAffected
+		return nil
+	})
+	return purged, err
+}
+
+// customerNotificationFeedLimit membatasi jumlah notifikasi terbaru yang ditampilkan di feed
+const customerNotificationFeedLimit = 50
+
+func toWishlistItemView(item models.WishlistItem) WishlistItemView {
+	view := WishlistItemView{
+		ProductSKU:        item.ProductSKU,
+		Title:             item.Product.DisplayTitle(),
+		Status:            item.Product.Status,
+		CurrentPrice:      item.Product.RegularPrice,
+		PriceWhenAdded:    item.PriceWhenAdded,
+		Stock:             item.Product.Stock,
+		InStock:           item.Product.Stock > 0,
+		NotifyPriceDrop:   item.NotifyPriceDrop,
+		NotifyBackInStock: item.NotifyBackInStock,
+		AddedAt:           item.CreatedAt,
+	}
+	if item.Product.RegularPrice < item.PriceWhenAdded {
+		view.PriceDrop = item.PriceWhenAdded - item.Product.RegularPrice
+	}
+	if primaryImage, ok := primaryProductImage(item.Product.Images); ok {
+		view.Image = primaryImage.Image
+	}
+	return view
+}
+
+func findWishlistItem(tx *gorm.DB, customerID, productSKU string) (models.WishlistItem, error) {
+	var item models.WishlistItem
+	if err := tx.Preload("Product.Images", models.OrderedProductImages).Where("customer_id = ? AND product_sku = ?", customerID, productSKU).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return item, errors.New("produk tidak ada di wishlist")
+		}
+		return item, fmt.Errorf("gagal mengambil wishlist: %w", err)
+	}
+	return item, nil
+}
+
+func (s *service) ListWishlist(customerID string) ([]WishlistItemView, error) {
+	var items []models.WishlistItem
+	if err := s.db.Preload("Product.Images", models.OrderedProductImages).Where("customer_id = ?", customerID).Order("created_at DESC, id DESC").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil wishlist: %w", err)
+	}
+	views := make([]WishlistItemView, 0, len(items))
+	for _, item := range items {
+		views = append(views, toWishlistItemView(item))
+	}
+	return views, nil
+}
+
+// AddWishlistItem menambahkan produk ke wishlist. Produk yang sudah ada cukup diperbarui preferensi
+// notifikasinya, sehingga request ulang aman.
+func (s *service) AddWishlistItem(customerID string, input AddWishlistItemInput) (WishlistItemView, error) {
+	log.Printf("[Service AddWishlistItem] CustomerID: %s, ProductSKU: %s\n", customerID, input.ProductSKU)
+
+	var view WishlistItemView
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := tx.Where("product_sku = ? AND status = ?", input.ProductSKU, "Published").First(&product).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("produk tidak ditemukan atau tidak tersedia")
+			}
+			return fmt.Errorf("gagal memverifikasi produk: %w", err)
+		}
+
+		var item models.WishlistItem
+		errSearch := tx.Where("customer_id = ? AND product_sku = ?", customerID, product.ProductSKU).First(&item).Error
+		if errors.Is(errSearch, gorm.ErrRecordNotFound) {
+			item = models.WishlistItem{
+				CustomerID:        customerID,
+				ProductSKU:        product.ProductSKU,
+				PriceWhenAdded:    product.RegularPrice,
+				NotifyPriceDrop:   true,
+				NotifyBackInStock: true,
+			}
+		} else if errSearch != nil {
+			return fmt.Errorf("gagal memeriksa wishlist: %w", errSearch)
+		}
+		if input.NotifyPriceDrop != nil {
+			item.NotifyPriceDrop = *input.NotifyPriceDrop
+		}
+		if input.NotifyBackInStock != nil {
+			item.NotifyBackInStock = *input.NotifyBackInStock
+		}
+		if err := tx.Omit(clause.Associations).Save(&item).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan wishlist: %w", err)
+		}
+
+		saved, err := findWishlistItem(tx, customerID, product.ProductSKU)
+		if err != nil {
+			return err
+		}
+		view = toWishlistItemView(saved)
+		return nil
+	})
+	if err != nil {
+		return WishlistItemView{}, err
+	}
+	return view, nil
+}
+
+func (s *service) UpdateWishlistItem(customerID, productSKU string, input UpdateWishlistItemInput) (WishlistItemView, error) {
+	item, err := findWishlistItem(s.db, customerID, productSKU)
+	if err != nil {
+		return WishlistItemView{}, err
+	}
+	if input.NotifyPriceDrop != nil {
+		item.NotifyPriceDrop = *input.NotifyPriceDrop
+	}
+	if input.NotifyBackInStock != nil {
+		item.NotifyBackInStock = *input.NotifyBackInStock
+	}
+	if err := s.db.Omit(clause.Associations).Save(&item).Error; err != nil {
+		return WishlistItemView{}, fmt.Errorf("gagal memperbarui wishlist: %w", err)
+	}
+	return toWishlistItemView(item), nil
+}
+
+func (s *service) RemoveWishlistItem(customerID, productSKU string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("customer_id = ? AND product_sku = ?", customerID, productSKU).Delete(&models.WishlistItem{})
+		if result.Error != nil {
+			return fmt.Errorf("gagal menghapus produk dari wishlist: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("produk tidak ada di wishlist")
+		}
+		// Notifikasi yang belum terkirim untuk produk ini tidak relevan lagi
+		if err := tx.Where("customer_id = ? AND product_sku = ? AND status = ?", customerID, productSKU, models.ProductNotificationPending).
+			Delete(&models.ProductNotification{}).Error; err != nil {
+			return fmt.Errorf("gagal menghapus antrean notifikasi: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *service) ListNotifications(customerID string, unreadOnly bool) (NotificationFeed, error) {
+	query := s.db.Where("customer_id = ?", customerID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var notifications []models.CustomerNotification
+	if err := query.Order("created_at DESC, id DESC").Limit(customerNotificationFeedLimit).Find(&notifications).Error; err != nil {
+		return NotificationFeed{}, fmt.Errorf("gagal mengambil notifikasi: %w", err)
+	}
+
+	feed := NotificationFeed{Notifications: make([]CustomerNotificationView, 0, len(notifications))}
+	if err := s.db.Model(&models.CustomerNotification{}).Where("customer_id = ? AND read_at IS NULL", customerID).Count(&feed.UnreadCount).Error; err != nil {
+		return NotificationFeed{}, fmt.Errorf("gagal menghitung notifikasi belum dibaca: %w", err)
+	}
+	for _, notification := range notifications {
+		feed.Notifications = append(feed.Notifications, CustomerNotificationView{
+			ID:         notification.ID,
+			Type:       notification.Type,
+			Title:      notification.Title,
+			Message:    notification.Message,
+			ProductSKU: notification.ProductSKU,
+			IsRead:     notification.ReadAt != nil,
+			ReadAt:     notification.ReadAt,
+			CreatedAt:  notification.CreatedAt,
+		})
+	}
+	return feed, nil
+}
+
+func (s *service) MarkNotificationRead(customerID string, notificationID uint) error {
+	var notification models.CustomerNotification
+	if err := s.db.Where("id = ? AND customer_id = ?", notificationID, customerID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("notifikasi tidak ditemukan")
+		}
+		return fmt.Errorf("gagal mengambil notifikasi: %w", err)
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	if err := s.db.Model(&notification).Update("read_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("gagal menandai notifikasi: %w", err)
+	}
+	return nil
+}
+
+func (s *service) MarkAllNotificationsRead(customerID string) (int64, error) {
+	result := s.db.Model(&models.CustomerNotification{}).Where("customer_id = ? AND read_at IS NULL", customerID).Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("gagal menandai notifikasi: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// orderLine adalah satu baris pesanan yang siap dibuat, berasal dari keranjang atau quotation.
+type orderLine struct {
+	ProductSKU string
+	Quantity   int
+	Price      float64 // Harga per unit yang dikunci untuk pesanan ini
+	Title      string
+	Image      string // Kosong berarti memakai gambar utama produk saat ini
+}
+
+// formatAddressSnapshot menyusun alamat menjadi satu baris teks untuk disimpan sebagai snapshot.
+func formatAddressSnapshot(address models.CustomerAddress) string {
+	return fmt.Sprintf("%s, %s, %s, %s, %s, %s",
+		address.Title, address.Street,
+		address.Additional, address.DistrictCity,
+		address.Province, address.PostCode)
+}
+
+// placeOrder menjalankan pipeline pembuatan order di dalam transaksi tx: validasi alamat,
+// bukti pembayaran, nomor order, cek dan potong stok, lalu simpan order beserta itemnya.
+// Path bukti pembayaran yang tersimpan ditulis ke proofPaymentPath agar pemanggil bisa
+// menghapus file tersebut jika transaksi gagal.
+func (s *service) placeOrder(tx *gorm.DB, customerID string, input CheckoutInput, lines []orderLine, proofPaymentFileHeader *multipart.FileHeader, proofPaymentPath *string) (models.Order, error) {
+	var calculatedGrandTotal float64 = 0
+	var orderItemsToCreate []models.OrderItem // Menggunakan OrderItem dari model.go
+	serializedSKUs := make(map[string]bool)
+
+	// 1. Validasi alamat pengiriman yang dipilih
+	var shippingAddress models.CustomerAddress // Menggunakan CustomerAddress dari model.go
+	if err := tx.Where("address_id = ? AND customer_id = ?", input.SelectedAddressID, customerID).First(&shippingAddress).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[Service placeOrder] Alamat pengiriman ID %d tidak ditemukan untuk CustomerID %s.\n", input.SelectedAddressID, customerID)
+			return models.Order{}, errors.New("alamat pengiriman yang dipilih tidak valid atau bukan milik Anda")
+		}
+		log.Printf("[Service placeOrder] Error validasi alamat CustomerID %s, AlamatID %d: %v\n", customerID, input.SelectedAddressID, err)
+		return models.Order{}, fmt.Errorf("gagal memvalidasi alamat pengiriman: %w", err)
+	}
+	// Buat snapshot alamat sebagai string
+	addressSnapshot := formatAddressSnapshot(shippingAddress)
+
+	// 2. Simpan file bukti pembayaran jika ada dan metode pembayaran memerlukannya
+	if proofPaymentFileHeader != nil {
+		savedPath, err := saveProofOfPayment(customerID, proofPaymentFileHeader)
+		*proofPaymentPath = savedPath
+		if err != nil {
+			return models.Order{}, err
+		}
+		log.Printf("[Service placeOrder] Bukti pembayaran disimpan ke: %s\n", *proofPaymentPath)
+	} else if input.PaymentMethod == "Manual Transfer BCA" { // Atau metode lain yang WAJIB bukti transfer
+		return models.Order{}, errors.New("bukti pembayaran diperlukan untuk metode transfer manual yang Anda pilih")
+	}
+
+	// 3. Generate Order ID unik
+	var nextVal int
+	if err := tx.Raw("SELECT nextval('order_id_seq')").Scan(&nextVal).Error; err != nil {
+		log.Printf("[Service placeOrder] Error mendapatkan ID berikutnya dari sequence: %v\n", err)
+		return models.Order{}, fmt.Errorf("gagal mendapatkan ID order: %w", err)
+	}
+	// Format ID baru: "ORD" + 5 digit angka dengan padding nol (misal: ORD00001)
+	// Pastikan ukuran kolom di DB (VARCHAR 10) cukup (ORD + 5 digit = 8 karakter, jadi cukup)
+	newOrderID := fmt.Sprintf("ORD%05d", nextVal)
+	log.Printf("[Service placeOrder] ID Order baru digenerate: %s\n", newOrderID)
+
+	// 4. Ambil detail customer (nama, email, phone) untuk denormalisasi di order header
+	var customerDetail models.CustomerDetail
+	if err := tx.Where("customer_id = ?", customerID).First(&customerDetail).Error; err != nil {
+		// Jika customer_detail tidak ada, ini masalah data. Bisa log atau return error.
+		// Untuk contoh ini, kita biarkan field nama/email/phone kosong di order jika detail tidak ada.
+		log.Printf("[Service placeOrder] Peringatan: CustomerDetail tidak ditemukan untuk CustomerID %s. Beberapa info order mungkin kosong.\n", customerID)
+	}
+
+	// 4b. Order atas nama perusahaan: snapshot data perusahaan untuk invoice
+	var company *models.Company
+	var membership models.CompanyMember
+	if input.OnBehalfOfCompany {
+		var err error
+		membership, err = findCompanyMembership(tx, customerID)
+		if err != nil {
+			return models.Order{}, err
+		}
+		company = &models.Company{}
+		if err := tx.Where("company_id = ?", membership.CompanyID).First(company).Error; err != nil {
+			return models.Order{}, fmt.Errorf("gagal mengambil data perusahaan: %w", err)
+		}
+	}
+
+	// 5. Buat OrderItem untuk setiap baris dan hitung GrandTotal
+	now := time.Now()
+	for _, line := range lines {
+		var product models.Product // Menggunakan model Product dari package admin
+		if err := tx.Preload("Images", models.OrderedProductImages).Where("product_sku = ?", line.ProductSKU).First(&product).Error; err != nil {
+			return models.Order{}, fmt.Errorf("produk dengan SKU %s tidak ditemukan: %w", line.ProductSKU, err)
+		}
+		if product.Stock < line.Quantity {
+			return models.Order{}, fmt.Errorf("stok produk '%s' tidak mencukupi (tersisa: %d, diminta: %d)", product.Title, product.Stock, line.Quantity)
+		}
+
+		imageSnapshot := line.Image
+		if imageSnapshot == "" {
+			if primaryImage, ok := primaryProductImage(product.Images); ok {
+				imageSnapshot = primaryImage.Image
+			}
+		}
+		orderItem := models.OrderItem{ // Menggunakan OrderItem dari model.go
+			OrderID:              newOrderID,
+			ProductSKU:           line.ProductSKU,
+			Quantity:             line.Quantity,
+			PriceAtOrder:         line.Price, // Harga snapshot dari keranjang atau quotation
+			ProductTitleSnapshot: line.Title,
+			ProductImageSnapshot: imageSnapshot,
+			// SubTotal akan di-generate DB
+		}
+		orderItemsToCreate = append(orderItemsToCreate, orderItem)
+		calculatedGrandTotal += (line.Price * float64(line.Quantity))
+		if product.IsSerialized {
+			serializedSKUs[product.ProductSKU] = true
+		}
+
+		// Kurangi stok produk secara atomik; pengecekan di atas bisa basi jika order atau pembatalan lain
+		// mengubah stok bersamaan
+		result := tx.Model(&models.Product{}).
+			Where("product_sku = ? AND stock >= ?", line.ProductSKU, line.Quantity).
+			Update("stock", gorm.Expr("stock - ?", line.Quantity))
+		if result.Error != nil {
+			return models.Order{}, fmt.Errorf("gagal mengupdate stok produk %s: %w", line.ProductSKU, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return models.Order{}, fmt.Errorf("stok produk '%s' tidak mencukupi (diminta: %d)", product.Title, line.Quantity)
+		}
+	}
+
+	// 6. Buat record Order utama
+	order := models.Order{
+		OrderID:                 newOrderID,
+		CustomerID:              customerID,
+		CustomerFullname:        customerDetail.FirstName + " " + customerDetail.LastName,
+		CustomerEmail:           customerDetail.Email,      // Atau dari customer.Email jika lebih utama
+		CustomerPhone:           customerDetail.Phone,      // Atau dari customer.Phone
+		ShippingAddressID:       shippingAddress.AddressID, // Dari alamat yang divalidasi
+		ShippingAddressSnapshot: addressSnapshot,
+		OrderDateTime:           now,
+		PaymentMethod:           input.PaymentMethod,
+		OrderStatus:             "Pending", // Status awal order (sesuai DDL Anda 'Pending')
+		GrandTotal:              calculatedGrandTotal,
+		Notes:                   input.Notes,
+		ProofOfPayment:          *proofPaymentPath,
+	}
+	if company != nil {
+		order.CompanyID = &company.CompanyID
+		order.CompanyLegalName = company.LegalName
+		order.CompanyNPWP = company.NPWP
+		order.CompanyBillingAddress = company.BillingAddress
+		// Order buyer di atas batas perusahaan menunggu persetujuan approver
+		if membership.Role == models.CompanyRoleBuyer && company.ApprovalThreshold != nil && calculatedGrandTotal > *company.ApprovalThreshold {
+			order.OrderStatus = models.OrderStatusAwaitingApproval
+		}
+	}
+	if err := tx.Create(&order).Error; err != nil {
+		return models.Order{}, fmt.Errorf("gagal membuat order: %w", err)
+	}
+	if err := recordOrderStatus(tx, order.OrderID, order.OrderStatus, customerID, ""); err != nil {
+		return models.Order{}, err
+	}
+
+	// 7. Simpan semua OrderItem
+	if len(orderItemsToCreate) > 0 {
+		if err := tx.Create(&orderItemsToCreate).Error; err != nil {
+			return models.Order{}, fmt.Errorf("gagal menyimpan item order: %w", err)
+		}
+	}
+	for _, item := range orderItemsToCreate {
+		if serializedSKUs[item.ProductSKU] {
+			if err := reserveSerializedUnits(tx, item); err != nil {
+				return models.Order{}, err
+			}
+		}
+	}
+	return order, nil
+}
+
+// reserveSerializedUnits mereservasi unit bernomor seri yang tersedia untuk item order agar produk berseri
+// tidak terjual melebihi unit yang terdaftar. Admin masih dapat mengganti nomor serinya sebelum dikirim.
+func reserveSerializedUnits(tx *gorm.DB, item models.OrderItem) error {
+	var unitIDs []uint
+	if err := tx.Model(&models.ProductUnit{}).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("product_sku = ? AND status = ?", item.ProductSKU, models.ProductUnitInStock).
+		Order("id ASC").Limit(item.Quantity).
+		Pluck("id", &unitIDs).Error; err != nil {
+		return fmt.Errorf("gagal mengambil unit produk %s: %w", item.ProductSKU, err)
+	}
+	if len(unitIDs) < item.Quantity {
+		return fmt.Errorf("stok produk '%s' tidak mencukupi (unit bernomor seri tersedia: %d, diminta: %d)", item.ProductTitleSnapshot, len(unitIDs), item.Quantity)
+	}
+	if err := tx.Model(&models.ProductUnit{}).Where("id IN ?", unitIDs).
+		Updates(map[string]interface{}{"status": models.ProductUnitReserved, "order_id": item.OrderID, "order_item_id": item.OrderItemID}).Error; err != nil {
+		return fmt.Errorf("gagal mereservasi unit produk %s: %w", item.ProductSKU, err)
+	}
+	return nil
+}
+
+// saveProofOfPayment menyimpan file bukti pembayaran ke ./uploads/payments dan mengembalikan path relatifnya.
+func saveProofOfPayment(customerID string, fileHeader *multipart.FileHeader) (string, error) {
+	return saveUploadedFile(fileHeader, "./uploads/payments/", "proof_"+customerID, "bukti pembayaran")
+}
+
+// saveUploadedFile menyalin file unggahan ke uploadDir dengan nama unik dan mengembalikan path relatifnya.
+// Path tetap dikembalikan walau penyalinan gagal agar pemanggil bisa membersihkan file setengah jadi.
+func saveUploadedFile(fileHeader *multipart.FileHeader, uploadDir, filenamePrefix, label string) (string, error) {
+	ext := filepath.Ext(fileHeader.Filename)
+	// Buat nama file yang lebih aman dan unik
+	uniqueFilename := fmt.Sprintf("%s_%d_%s%s", filenamePrefix, time.Now().UnixNano(), uuid.New().String()[:8], ext)
+	if errMkdir := os.MkdirAll(uploadDir, os.ModePerm); errMkdir != nil {
+		return "", fmt.Errorf("gagal membuat direktori untuk %s: %w", label, errMkdir)
+	}
+	savePathOnDisk := filepath.Join(uploadDir, uniqueFilename)
+
+	src, errOpen := fileHeader.Open()
+	if errOpen != nil {
+		return "", fmt.Errorf("gagal membuka file %s: %w", label, errOpen)
+	}
+	defer src.Close()
+
+	dst, errCreate := os.Create(savePathOnDisk)
+	if errCreate != nil {
+		return "", fmt.Errorf("gagal membuat file tujuan %s: %w", label, errCreate)
+	}
+	defer dst.Close()
+
+	savedPath := strings.TrimPrefix(filepath.ToSlash(savePathOnDisk), "./")
+	if _, errCopy := io.Copy(dst, src); errCopy != nil {
+		return savedPath, fmt.Errorf("gagal menyimpan file %s: %w", label, errCopy)
+	}
+	return savedPath, nil
+}
+
+// removeProofOfPayment menghapus bukti pembayaran yang sempat tersimpan ketika transaksi order gagal.
+func removeProofOfPayment(proofPaymentPath string) {
+	if proofPaymentPath == "" {
+		return
+	}
+	if errRemove := os.Remove(filepath.Join(".", proofPaymentPath)); errRemove != nil {
+		log.Printf("Gagal menghapus bukti pembayaran sementara setelah transaksi gagal: %v\n", errRemove)
+	} else {
+		log.Printf("Transaksi gagal, menghapus file bukti pembayaran sementara: %s\n", proofPaymentPath)
+	}
+}
+
+// uniqueCartItemIDs membuang ID ganda pada pilihan checkout.
+func uniqueCartItemIDs(ids []uint) []uint {
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func (s *service) CreateOrderFromCart(customerID string, input CheckoutInput, proofPaymentFileHeader *multipart.FileHeader) (models.Order, error) {
+	log.Printf("[Service CreateOrderFromCart] CustomerID: %s, Input: %+v, Ada File Bukti Bayar: %t\n", customerID, input, proofPaymentFileHeader != nil)
+
+	var finalCreatedOrder models.Order
+	var proofPaymentPath string = ""
+
+	// Mulai transaksi database
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 1. Ambil item aktif dari keranjang customer (tabel carts), atau hanya item yang dipilih
+		var cartItems []models.Cart // Menggunakan Cart dari model.go
+		cartQuery := tx.Where("customer_id = ? AND saved_for_later = ?", customerID, false)
+		selectedIDs := uniqueCartItemIDs(input.CartItemIDs)
+		if len(selectedIDs) > 0 {
+			cartQuery = cartQuery.Where("cart_id IN ?", selectedIDs)
+		}
+		if err := cartQuery.Order("cart_id ASC").Find(&cartItems).Error; err != nil {
+			log.Printf("[Service CreateOrderFromCart] Error mengambil item keranjang untuk CustomerID %s: %v\n", customerID, err)
+			return fmt.Errorf("gagal mengambil item keranjang: %w", err)
+		}
+		if len(selectedIDs) > 0 && len(cartItems) != len(selectedIDs) {
+			return errors.New("item keranjang yang dipilih tidak valid atau sedang disimpan untuk nanti")
+		}
+		if len(cartItems) == 0 {
+			log.Println("[Service CreateOrderFromCart] Keranjang kosong.")
+			return errors.New("keranjang Anda kosong, tidak bisa melanjutkan checkout")
+		}
+
+		// 2. Harga, judul dan gambar diambil dari keranjang (snapshot)
+		lines := make([]orderLine, 0, len(cartItems))
+		for _, itemInCart := range cartItems {
+			lines = append(lines, orderLine{
+				ProductSKU: itemInCart.ProductSKU,
+				Quantity:   itemInCart.Quantity,
+				Price:      itemInCart.RegularPrice,
+				Title:      itemInCart.Title,
+				Image:      itemInCart.Image,
+			})
+		}
+
+		order, err := s.placeOrder(tx, customerID, input, lines, proofPaymentFileHeader, &proofPaymentPath)
+		if err != nil {
+			return err
+		}
+
+		// 3. Hapus item yang di-checkout dari keranjang customer; item lain tetap di keranjang
+		checkedOutIDs := make([]uint, 0, len(cartItems))
+		for _, itemInCart := range cartItems {
+			checkedOutIDs = append(checkedOutIDs, itemInCart.CartID)
+		}
+		if err := tx.Where("customer_id = ? AND cart_id IN ?", customerID, checkedOutIDs).Delete(&models.Cart{}).Error; err != nil {
+			log.Printf("[Service CreateOrderFromCart] Peringatan: Gagal menghapus item dari keranjang CustomerID %s: %v\n", customerID, err)
+			// Tidak menggagalkan transaksi utama
+		}
+
+		// Ambil kembali order dengan detail lengkapnya (termasuk OrderItems dan info Customer) untuk dikembalikan
+		if err := tx.Preload("OrderItems").Preload("Customer.Detail").Preload("ShippingAddress").First(&finalCreatedOrder, "order_id = ?", order.OrderID).Error; err != nil {
+			return fmt.Errorf("gagal mengambil data order lengkap setelah create: %w", err)
+		}
+		return nil // Commit transaksi
+	})
+
+	if err != nil {
+		removeProofOfPayment(proofPaymentPath) // Jika transaksi gagal dan file sempat tersimpan, coba hapus
+		return models.Order{}, err
+	}
+
+	log.Printf("[Service CreateOrderFromCart] Order %s berhasil dibuat.\n", finalCreatedOrder.OrderID)
+	return finalCreatedOrder, nil
+}
+
+// quotationExpired bernilai true jika tanggal berlaku quotation sudah lewat (berlaku sampai akhir hari ValidUntil).
+func quotationExpired(quotation models.Quotation, now time.Time) bool {
+	return now.After(quotation.ValidUntil.AddDate(0, 0, 1))
+}
+
+func toQuotationRequestView(request models.QuotationRequest) QuotationRequestView {
+	view := QuotationRequestView{
+		RequestID:    request.RequestID,
+		SiteLocation: request.SiteLocation,
+		Notes:        request.Notes,
+		Status:       request.Status,
+		CreatedAt:    request.CreatedAt,
+		Items:        make([]QuotationLineView, 0, len(request.Items)),
+	}
+	for _, item := range request.Items {
+		view.Items = append(view.Items, QuotationLineView{
+			ProductSKU: item.ProductSKU,
+			Title:      item.ProductTitleSnapshot,
+			Quantity:   item.Quantity,
+		})
+	}
+	if request.Quotation != nil {
+		quotation := request.Quotation
+		quotationView := &QuotationView{
+			QuotationID: quotation.QuotationID,
+			ValidUntil:  quotation.ValidUntil,
+			IsExpired:   request.Status == models.QuotationRequestQuoted && quotationExpired(*quotation, time.Now()),
+			Terms:       quotation.Terms,
+			TotalAmount: quotation.TotalAmount,
+			Items:       make([]QuotationLineView, 0, len(quotation.Items)),
+		}
+		if quotation.OrderID != nil {
+			quotationView.OrderID = *quotation.OrderID
+		}
+		for _, item := range quotation.Items {
+			quotationView.Items = append(quotationView.Items, QuotationLineView{
+				ProductSKU: item.ProductSKU,
+				Title:      item.ProductTitleSnapshot,
+				Quantity:   item.Quantity,
+				UnitPrice:  item.UnitPrice,
+				SubTotal:   item.UnitPrice * float64(item.Quantity),
+			})
+		}
+		view.Quotation = quotationView
+	}
+	return view
+}
+
+// findCustomerQuotationRequest memuat RFQ milik customer beserta item dan quotation-nya.
+func findCustomerQuotationRequest(tx *gorm.DB, customerID, requestID string) (models.QuotationRequest, error) {
+	var request models.QuotationRequest
+	if err := tx.Preload("Items").Preload("Quotation.Items").
+		Where("request_id = ? AND customer_id = ?", requestID, customerID).
+		First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return request, errors.New("permintaan penawaran tidak ditemukan")
+		}
+		return request, fmt.Errorf("gagal mengambil permintaan penawaran: %w", err)
+	}
+	return request, nil
+}
+
+func (s *service) CreateQuotationRequest(customerID string, input CreateQuotationRequestInput) (QuotationRequestView, error) {
+	log.Printf("[Service CreateQuotationRequest] CustomerID: %s, Jumlah item: %d\n", customerID, len(input.Items))
+
+	var created models.QuotationRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		items := make([]models.QuotationRequestItem, 0, len(input.Items))
+		seenSKUs := make(map[string]bool, len(input.Items))
+		for _, itemInput := range input.Items {
+			if seenSKUs[itemInput.ProductSKU] {
+				return fmt.Errorf("produk %s disebutkan lebih dari sekali", itemInput.ProductSKU)
+			}
+			seenSKUs[itemInput.ProductSKU] = true
+
+			var product models.Product
+			if err := tx.Where("product_sku = ? AND status = ?", itemInput.ProductSKU, "Published").First(&product).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("produk %s tidak ditemukan atau tidak tersedia", itemInput.ProductSKU)
+				}
+				return fmt.Errorf("gagal memverifikasi produk: %w", err)
+			}
+			items = append(items, models.QuotationRequestItem{
+				ProductSKU:           product.ProductSKU,
+				ProductTitleSnapshot: product.DisplayTitle(),
+				Quantity:             itemInput.Quantity,
+			})
+		}
+
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('quotation_request_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID permintaan penawaran: %w", err)
+		}
+		created = models.QuotationRequest{
+			RequestID:    fmt.Sprintf("RFQ%05d", nextVal),
+			CustomerID:   customerID,
+			SiteLocation: strings.TrimSpace(input.SiteLocation),
+			Notes:        input.Notes,
+			Status:       models.QuotationRequestSubmitted,
+			Items:        items,
+		}
+		if err := tx.Create(&created).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan permintaan penawaran: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service CreateQuotationRequest] Gagal: %v\n", err)
+		return QuotationRequestView{}, err
+	}
+
+	log.Printf("[Service CreateQuotationRequest] RFQ %s berhasil dibuat.\n", created.RequestID)
+	return toQuotationRequestView(created), nil
+}
+
+func (s *service) ListQuotationRequests(customerID string) ([]QuotationRequestView, error) {
+	var requests []models.QuotationRequest
+	if err := s.db.Preload("Items").Preload("Quotation.Items").
+		Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Find(&requests).Error; err != nil {
+		log.Printf("[Service ListQuotationRequests] Error untuk CustomerID %s: %v\n", customerID, err)
+		return nil, fmt.Errorf("gagal mengambil daftar permintaan penawaran: %w", err)
+	}
+
+	views := make([]QuotationRequestView, 0, len(requests))
+	for _, request := range requests {
+		views = append(views, toQuotationRequestView(request))
+	}
+	return views, nil
+}
+
+func (s *service) GetQuotationRequest(customerID, requestID string) (QuotationRequestView, error) {
+	request, err := findCustomerQuotationRequest(s.db, customerID, requestID)
+	if err != nil {
+		return QuotationRequestView{}, err
+	}
+	return toQuotationRequestView(request), nil
+}
+
+// AcceptQuotation mengubah quotation menjadi Order dengan harga yang ditawarkan, memakai pipeline placeOrder.
+func (s *service) AcceptQuotation(customerID, requestID string, input CheckoutInput, proofPaymentFileHeader *multipart.FileHeader) (models.Order, error) {
+	log.Printf("[Service AcceptQuotation] CustomerID: %s, RequestID: %s\n", customerID, requestID)
+
+	var finalCreatedOrder models.Order
+	var proofPaymentPath string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		request, err := findCustomerQuotationRequest(tx.Clauses(clause.Locking{Strength: "UPDATE"}), customerID, requestID)
+		if err != nil {
+			return err
+		}
+		if request.Status != models.QuotationRequestQuoted || request.Quotation == nil {
+			return errors.New("penawaran belum tersedia atau sudah diproses")
+		}
+		quotation := request.Quotation
+		if quotationExpired(*quotation, time.Now()) {
+			return errors.New("penawaran sudah kedaluwarsa, silakan ajukan permintaan baru")
+		}
+
+		lines := make([]orderLine, 0, len(quotation.Items))
+		for _, item := range quotation.Items {
+			lines = append(lines, orderLine{
+				ProductSKU: item.ProductSKU,
+				Quantity:   item.Quantity,
+				Price:      item.UnitPrice,
+				Title:      item.ProductTitleSnapshot,
+			})
+		}
+
+		// Tandai asal order agar terlihat di halaman admin
+		input.Notes = strings.TrimSpace(fmt.Sprintf("[Penawaran %s] %s", quotation.QuotationID, input.Notes))
+		order, err := s.placeOrder(tx, customerID, input, lines, proofPaymentFileHeader, &proofPaymentPath)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.Quotation{}).Where("quotation_id = ?", quotation.QuotationID).
+			Updates(map[string]interface{}{"order_id": order.OrderID, "accepted_at": now}).Error; err != nil {
+			return fmt.Errorf("gagal memperbarui quotation: %w", err)
+		}
+		if err := tx.Model(&models.QuotationRequest{}).Where("request_id = ?", request.RequestID).
+			Update("status", models.QuotationRequestAccepted).Error; err != nil {
+			return fmt.Errorf("gagal memperbarui status permintaan penawaran: %w", err)
+		}
+
+		if err := tx.Preload("OrderItems").First(&finalCreatedOrder, "order_id = ?", order.OrderID).Error; err != nil {
+			return fmt.Errorf("gagal mengambil data order lengkap setelah create: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service AcceptQuotation] Gagal untuk RequestID %s: %v\n", requestID, err)
+		removeProofOfPayment(proofPaymentPath)
+		return models.Order{}, err
+	}
+
+	log.Printf("[Service AcceptQuotation] RFQ %s diterima menjadi Order %s.\n", requestID, finalCreatedOrder.OrderID)
+	return finalCreatedOrder, nil
+}
+
+func (s *service) DeclineQuotationRequest(customerID, requestID string) (QuotationRequestView, error) {
+	var request models.QuotationRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		request, err = findCustomerQuotationRequest(tx, customerID, requestID)
+		if err != nil {
+			return err
+		}
+		if request.Status != models.QuotationRequestSubmitted && request.Status != models.QuotationRequestQuoted {
+			return errors.New("penawaran belum tersedia atau sudah diproses")
+		}
+		request.Status = models.QuotationRequestDeclined
+		return tx.Model(&models.QuotationRequest{}).Where("request_id = ?", requestID).Update("status", request.Status).Error
+	})
+	if err != nil {
+		return QuotationRequestView{}, err
+	}
+	return toQuotationRequestView(request), nil
+}
+
+// toOrderHistoryItem memetakan order (dengan OrderItems & PaymentMilestones ter-preload) ke item riwayat.
+func toOrderHistoryItem(order models.Order) OrderHistoryItem {
+	itemImages := make([]string, 0)
+	// Ambil maksimal 3 gambar dari order item untuk ditampilkan di list
+	for i, item := range order.OrderItems {
+		if i >= 3 { // Batasi hanya 3 gambar
+			break
+		}
+		if item.ProductImageSnapshot != "" {
+			itemImages = append(itemImages, item.ProductImageSnapshot)
+		}
+	}
+
+	return OrderHistoryItem{
+		OrderID:       order.OrderID,
+		OrderDateTime: order.OrderDateTime,
+		GrandTotal:    order.GrandTotal,
+		OrderStatus:   order.OrderStatus,
+		ItemImages:    itemImages,
+
+		OutstandingBalance: order.OutstandingBalance(),
+		HasPaymentSchedule: len(order.PaymentMilestones) > 0,
+		EntryType:          models.OrderHistoryEntryOrder,
+	}
+}
+
+// rentalHistoryItems memetakan booking sewa ke entri riwayat pesanan.
+func rentalHistoryItems(db *gorm.DB, bookings []models.RentalBooking) ([]OrderHistoryItem, error) {
+	productSKUs := make([]string, 0, len(bookings))
+	for _, booking := range bookings {
+		productSKUs = append(productSKUs, booking.ProductSKU)
+	}
+	images, err := models.PrimaryProductImagesBySKU(db, uniqueStrings(productSKUs))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]OrderHistoryItem, 0, len(bookings))
+	for _, booking := range bookings {
+		itemImages := []string{}
+		if image := images[booking.ProductSKU]; image != "" {
+			itemImages = append(itemImages, image)
+		}
+		items = append(items, OrderHistoryItem{
+			OrderID:       booking.BookingID,
+			OrderDateTime: booking.CreatedAt,
+			GrandTotal:    booking.RentalPrice,
+			OrderStatus:   booking.Status,
+			ItemImages:    itemImages,
+			EntryType:     models.OrderHistoryEntryRental,
+			Rental: &RentalHistoryInfo{
+				BookingID:            booking.BookingID,
+				ProductSKU:           booking.ProductSKU,
+				ProductTitleSnapshot: booking.ProductTitleSnapshot,
+				StartDate:            booking.StartDate,
+				EndDate:              booking.EndDate,
+				RentalDays:           booking.RentalDays,
+				Deposit:              booking.Deposit,
+			},
+		})
+	}
+	return items, nil
+}
+
+// recordOrderStatus mencatat perubahan status order oleh customer ke timeline pesanan.
+func recordOrderStatus(tx *gorm.DB, orderID, status, customerID, note string) error {
+	history := models.OrderStatusHistory{
+		OrderID:       orderID,
+		Status:        status,
+		Note:          strings.TrimSpace(note),
+		ChangedByType: models.OrderStatusChangedByCustomer,
+		ChangedBy:     customerID,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return fmt.Errorf("gagal mencatat riwayat status pesanan: %w", err)
+	}
+	return nil
+}
+
+// orderPaymentStatus merangkum status pembayaran order: Paid, Partially Paid, Awaiting Verification, Unpaid atau Canceled.
+// PaymentMilestones harus sudah di-preload.
+func orderPaymentStatus(order models.Order) string {
+	if order.OrderStatus == "Canceled" {
+		return "Canceled"
+	}
+	if order.OutstandingBalance() == 0 {
+		return "Paid"
+	}
+	if len(order.PaymentMilestones) == 0 {
+		if order.ProofOfPayment != "" {
+			return "Awaiting Verification"
+		}
+		return "Unpaid"
+	}
+	awaitingVerification := false
+	for _, milestone := range order.PaymentMilestones {
+		if milestone.Status == models.PaymentMilestonePaid {
+			return "Partially Paid"
+		}
+		if milestone.Status == models.PaymentMilestoneAwaitingVerification {
+			awaitingVerification = true
+		}
+	}
+	if awaitingVerification {
+		return "Awaiting Verification"
+	}
+	return "Unpaid"
+}
+
+// orderProofStatus menjelaskan bukti bayar order yang dibayar sekaligus. Order dengan termin
+// memakai bukti bayar per termin sehingga statusnya dilihat di jadwal pembayaran.
+func orderProofStatus(order models.Order) string {
+	switch {
+	case len(order.PaymentMilestones) > 0:
+		return "Per Milestone"
+	case order.ProofOfPayment == "":
+		return "Not Uploaded"
+	case order.OrderStatus == "Processed" || order.OrderStatus == "Shipped" || order.OrderStatus == "Completed":
+		return "Verified"
+	default:
+		return "Uploaded"
+	}
+}
+
+// GetCustomerOrderDetail mengembalikan detail lengkap satu order milik customer. Order milik
+// customer lain diperlakukan sama dengan order yang tidak ada.
+func (s *service) GetCustomerOrderDetail(customerID, orderID string) (CustomerOrderDetailView, error) {
+	var order models.Order
+	if err := s.db.
+		Preload("OrderItems", func(db *gorm.DB) *gorm.DB { return db.Order("order_item_id ASC") }).
+		Preload("PaymentMilestones", func(db *gorm.DB) *gorm.DB { return db.Order("sequence ASC") }).
+		Where("order_id = ? AND customer_id = ?", orderID, customerID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return CustomerOrderDetailView{}, errors.New("pesanan tidak ditemukan")
+		}
+		return CustomerOrderDetailView{}, fmt.Errorf("gagal mengambil detail pesanan: %w", err)
+	}
+	var histories []models.OrderStatusHistory
+	if err := s.db.Where("order_id = ?", orderID).Order("created_at ASC, id ASC").Find(&histories).Error; err != nil {
+		return CustomerOrderDetailView{}, fmt.Errorf("gagal mengambil riwayat status pesanan: %w", err)
+	}
+
+	view := CustomerOrderDetailView{
+		OrderID:                 order.OrderID,
+		OrderDateTime:           order.OrderDateTime,
+		OrderStatus:             order.OrderStatus,
+		PaymentMethod:           order.PaymentMethod,
+		PaymentStatus:           orderPaymentStatus(order),
+		ProofOfPayment:          order.ProofOfPayment,
+		ProofStatus:             orderProofStatus(order),
+		Notes:                   order.Notes,
+		CompanyLegalName:        order.CompanyLegalName,
+		ShippingAddressSnapshot: order.ShippingAddressSnapshot,
+		GrandTotal:              order.GrandTotal,
+		OutstandingBalance:      order.OutstandingBalance(),
+		Items:                   make([]CustomerOrderItemView, 0, len(order.OrderItems)),
+		PaymentSchedule:         toOrderPaymentSchedule(order).Milestones,
+		Timeline:                make([]OrderStatusTimelineEntry, 0, len(histories)),
+	}
+	for _, item := range order.OrderItems {
+		view.Items = append(view.Items, CustomerOrderItemView{
+			OrderItemID:          item.OrderItemID,
+			ProductSKU:           item.ProductSKU,
+			ProductTitleSnapshot: item.ProductTitleSnapshot,
+			ProductImageSnapshot: item.ProductImageSnapshot,
+			PriceAtOrder:         item.PriceAtOrder,
+			Quantity:             item.Quantity,
+			SubTotal:             item.SubTotal,
+		})
+		view.Subtotal += item.SubTotal
+	}
+	for _, history := range histories {
+		view.Timeline = append(view.Timeline, OrderStatusTimelineEntry{Status: history.Status, Note: history.Note, ChangedAt: history.CreatedAt})
+	}
+	// Order lama yang dibuat sebelum riwayat status dicatat
+	if len(view.Timeline) == 0 {
+		view.Timeline = append(view.Timeline, OrderStatusTimelineEntry{Status: order.OrderStatus, ChangedAt: order.UpdatedAt})
+	}
+	// Refund retur barang ditampilkan pada masing-masing retur, bukan di sini
+	var refund models.Refund
+	if err := s.db.Where("order_id = ? AND rma_id IS NULL", orderID).Limit(1).Find(&refund).Error; err != nil {
+		return CustomerOrderDetailView{}, fmt.Errorf("gagal mengambil data refund: %w", err)
+	}
+	if refund.RefundID != "" {
+		view.Refund = toOrderRefundView(refund)
+	}
+	var shipments []models.Shipment
+	if err := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("order_id = ?", orderID).Order("shipped_at ASC").Find(&shipments).Error; err != nil {
+		return CustomerOrderDetailView{}, fmt.Errorf("gagal mengambil data pengiriman: %w", err)
+	}
+	view.Shipments = make([]ShipmentView, 0, len(shipments))
+	for _, shipment := range shipments {
+		view.Shipments = append(view.Shipments, toShipmentView(shipment))
+	}
+	return view, nil
+}
+
+func toShipmentView(shipment models.Shipment) ShipmentView {
+	view := ShipmentView{
+		ShipmentID:       shipment.ShipmentID,
+		Courier:          shipment.Courier,
+		TrackingNumber:   shipment.TrackingNumber,
+		Status:           shipment.Status,
+		ShippedAt:        shipment.ShippedAt,
+		EstimatedArrival: shipment.EstimatedArrival,
+		Items:            make([]ShipmentItemView, 0, len(shipment.Items)),
+	}
+	for _, item := range shipment.Items {
+		view.Items = append(view.Items, ShipmentItemView{
+			OrderItemID:  item.OrderItemID,
+			ProductSKU:   item.ProductSKU,
+			ProductTitle: item.ProductTitleSnapshot,
+			Quantity:     item.Quantity,
+		})
+	}
+	if shipment.DeliveredAt != nil {
+		view.Delivery = &ShipmentDeliveryView{
+			RecipientName: shipment.RecipientName,
+			DeliveredAt:   *shipment.DeliveredAt,
+			Photo:         shipment.DeliveryPhoto,
+			Notes:         shipment.DeliveryNotes,
+		}
+	}
+	return view
+}
+
+// GetShipmentTracking melacak satu paket milik order customer lewat provider kurir. Konfirmasi
+// penerimaan yang sudah dicatat admin lebih dipercaya daripada status dari provider.
+func (s *service) GetShipmentTracking(customerID, orderID, shipmentID string) (shipping.TrackingResult, error) {
+	var shipment models.Shipment
+	if err := s.db.Joins("JOIN orders ON orders.order_id = shipments.order_id").
+		Where("shipments.shipment_id = ? AND shipments.order_id = ? AND orders.customer_id = ?", shipmentID, orderID, customerID).
+		First(&shipment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return shipping.TrackingResult{}, errors.New("pengiriman tidak ditemukan")
+		}
+		return shipping.TrackingResult{}, fmt.Errorf("gagal mengambil pengiriman: %w", err)
+	}
+	return shipping.Track(s.tracker, shipment)
+}
+
+// CancelOrder membatalkan order milik customer yang belum diproses: stok dikembalikan, reservasi unit
+// dan jadwal instalasi dibatalkan, dan refund dibuat jika sebagian pembayaran sudah terverifikasi.
+func (s *service) CancelOrder(customerID, orderID string, input CancelOrderInput) (CustomerOrderDetailView, error) {
+	log.Printf("[Service CancelOrder] CustomerID: %s, OrderID: %s\n", customerID, orderID)
+	reason := strings.TrimSpace(input.Reason)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("PaymentMilestones").
+			Where("order_id = ? AND customer_id = ?", orderID, customerID).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil pesanan: %w", err)
+		}
+		if !slices.Contains(models.CustomerCancelableOrderStatuses, order.OrderStatus) {
+			return fmt.Errorf("pesanan tidak dapat dibatalkan: status pesanan sudah %s", order.OrderStatus)
+		}
+		// Dihitung sebelum status berubah karena order Canceled tidak lagi memiliki sisa tagihan
+		paidAmount := order.GrandTotal - order.OutstandingBalance()
+
+		if err := models.ApplyOrderCancellation(tx, orderID, true); err != nil {
+			return err
+		}
+		if err := tx.Model(&order).Update("order_status", "Canceled").Error; err != nil {
+			return fmt.Errorf("gagal membatalkan pesanan: %w", err)
+		}
+		if err := recordOrderStatus(tx, orderID, "Canceled", customerID, "Dibatalkan customer: "+reason); err != nil {
+			return err
+		}
+
+		if paidAmount <= 0 {
+			return nil
+		}
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('refund_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID refund: %w", err)
+		}
+		refund := models.Refund{
+			RefundID:          fmt.Sprintf("RFD%05d", nextVal),
+			OrderID:           orderID,
+			CustomerID:        customerID,
+			Amount:            paidAmount,
+			Reason:            reason,
+			Status:            models.RefundPending,
+			BankName:          strings.TrimSpace(input.RefundBankName),
+			BankAccountNumber: strings.TrimSpace(input.RefundAccountNumber),
+			BankAccountHolder: strings.TrimSpace(input.RefundAccountHolder),
+		}
+		if err := tx.Create(&refund).Error; err != nil {
+			return fmt.Errorf("gagal membuat refund: %w", err)
+		}
+		log.Printf("[Service CancelOrder] Refund %s sebesar %.2f dibuat untuk order %s.\n", refund.RefundID, paidAmount, orderID)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service CancelOrder] Gagal untuk OrderID %s: %v\n", orderID, err)
+		return CustomerOrderDetailView{}, err
+	}
+	return s.GetCustomerOrderDetail(customerID, orderID)
+}
+
+func (s *service) ListCustomerOrders(customerID string) ([]OrderHistoryItem, error) {
+	var ordersFromDB []models.Order
+	log.Printf("[Service ListCustomerOrders] Mengambil riwayat pesanan untuk Customer ID: %s\n", customerID)
+
+	// 1. Ambil semua order milik customer, diurutkan dari yang terbaru
+	//    Sangat penting untuk Preload("OrderItems") agar kita bisa mengambil gambar produk
+	if err := s.db.
+		Preload("OrderItems").
+		Preload("PaymentMilestones").
+		Where("customer_id = ?", customerID).
+		Order("order_date_time DESC").
+		Find(&ordersFromDB).Error; err != nil {
+
+		log.Printf("[Service ListCustomerOrders] Error mengambil order untuk CustomerID %s: %v\n", customerID, err)
+		return nil, fmt.Errorf("gagal mengambil riwayat pesanan: %w", err)
+	}
+
+	// 2. Mapping hasil query ke DTO OrderHistoryItem
+	orderHistory := make([]OrderHistoryItem, 0, len(ordersFromDB))
+	for _, order := range ordersFromDB {
+		orderHistory = append(orderHistory, toOrderHistoryItem(order))
+	}
+
+	// 3. Booking sewa ikut ditampilkan di riwayat, diurutkan bersama pesanan berdasarkan waktu dibuat
+	var bookings []models.RentalBooking
+	if err := s.db.Where("customer_id = ?", customerID).Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil riwayat sewa: %w", err)
+	}
+	rentalItems, err := rentalHistoryItems(s.db, bookings)
+	if err != nil {
+		return nil, err
+	}
+	orderHistory = append(orderHistory, rentalItems...)
+	slices.SortStableFunc(orderHistory, func(a, b OrderHistoryItem) int {
+		return b.OrderDateTime.Compare(a.OrderDateTime)
+	})
+
+	log.Printf("[Service ListCustomerOrders] Ditemukan %d pesanan untuk CustomerID: %s\n", len(orderHistory), customerID)
+	return orderHistory, nil
+}
+
+var nonDigitPattern = regexp.MustCompile(`\D`)
+
+// normalizeNPWP membuang tanda baca NPWP (mis. 01.234.567.8-901.000) dan memvalidasi jumlah digitnya.
+func normalizeNPWP(npwp string) (string, error) {
+	digits := nonDigitPattern.ReplaceAllString(npwp, "")
+	if len(digits) != 15 && len(digits) != 16 {
+		return "", errors.New("npwp tidak valid: harus 15 atau 16 digit")
+	}
+	return digits, nil
+}
+
+func findCompanyMembership(tx *gorm.DB, customerID string) (models.CompanyMember, error) {
+	var membership models.CompanyMember
+	if err := tx.Where("customer_id = ?", customerID).First(&membership).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return membership, errors.New("anda belum terdaftar sebagai anggota perusahaan")
+		}
+		return membership, fmt.Errorf("gagal mengambil keanggotaan perusahaan: %w", err)
+	}
+	return membership, nil
+}
+
+func findApproverMembership(tx *gorm.DB, customerID string) (models.CompanyMember, error) {
+	membership, err := findCompanyMembership(tx, customerID)
+	if err != nil {
+		return membership, err
+	}
+	if membership.Role != models.CompanyRoleApprover {
+		return membership, errors.New("hanya approver perusahaan yang dapat melakukan aksi ini")
+	}
+	return membership, nil
+}
+
+func (s *service) loadCompanyView(tx *gorm.DB, companyID, viewerCustomerID string) (CompanyView, error) {
+	var company models.Company
+	if err := tx.Where("company_id = ?", companyID).First(&company).Error; err != nil {
+		return CompanyView{}, fmt.Errorf("gagal mengambil data perusahaan: %w", err)
+	}
+
+	var members []CompanyMemberView
+	if err := tx.Table("company_members m").
+		Select("m.customer_id, TRIM(COALESCE(d.first_name, '') || ' ' || COALESCE(d.last_name, '')) AS full_name, c.email, m.role, m.created_at AS joined_at").
+		Joins("JOIN customers c ON c.customer_id = m.customer_id").
+		Joins("LEFT JOIN customer_details d ON d.customer_id = m.customer_id").
+		Where("m.company_id = ?", companyID).
+		Order("m.created_at ASC").
+		Scan(&members).Error; err != nil {
+		return CompanyView{}, fmt.Errorf("gagal mengambil anggota perusahaan: %w", err)
+	}
+
+	view := CompanyView{
+		CompanyID:         company.CompanyID,
+		LegalName:         company.LegalName,
+		NPWP:              company.NPWP,
+		BillingAddress:    company.BillingAddress,
+		ApprovalThreshold: company.ApprovalThreshold,
+		Members:           members,
+	}
+	for _, member := range members {
+		if member.CustomerID == viewerCustomerID {
+			view.MyRole = member.Role
+		}
+	}
+	return view, nil
+}
+
+// ensureNPWPAvailable menolak NPWP yang sudah dipakai perusahaan lain.
+func ensureNPWPAvailable(tx *gorm.DB, npwp, exceptCompanyID string) error {
+	var count int64
+	if err := tx.Model(&models.Company{}).Where("npwp = ? AND company_id <> ?", npwp, exceptCompanyID).Count(&count).Error; err != nil {
+		return fmt.Errorf("gagal memeriksa NPWP: %w", err)
+	}
+	if count > 0 {
+		return errors.New("npwp sudah terdaftar untuk perusahaan lain")
+	}
+	return nil
+}
+
+// CreateCompany membuat akun perusahaan; pembuatnya otomatis menjadi approver.
+func (s *service) CreateCompany(customerID string, input CompanyInput) (CompanyView, error) {
+	npwp, err := normalizeNPWP(input.NPWP)
+	if err != nil {
+		return CompanyView{}, err
+	}
+
+	var view CompanyView
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := findCompanyMembership(tx, customerID); err == nil {
+			return errors.New("anda sudah terdaftar sebagai anggota perusahaan")
+		}
+		if err := ensureNPWPAvailable(tx, npwp, ""); err != nil {
+			return err
+		}
+
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('company_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID perusahaan: %w", err)
+		}
+		company := models.Company{
+			CompanyID:         fmt.Sprintf("CMP%05d", nextVal),
+			LegalName:         strings.TrimSpace(input.LegalName),
+			NPWP:              npwp,
+			BillingAddress:    strings.TrimSpace(input.BillingAddress),
+			ApprovalThreshold: input.ApprovalThreshold,
+		}
+		if err := tx.Create(&company).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan perusahaan: %w", err)
+		}
+		if err := tx.Create(&models.CompanyMember{CompanyID: company.CompanyID, CustomerID: customerID, Role: models.CompanyRoleApprover}).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan anggota perusahaan: %w", err)
+		}
+
+		view, err = s.loadCompanyView(tx, company.CompanyID, customerID)
+		return err
+	})
+	if err != nil {
+		log.Printf("[Service CreateCompany] Gagal untuk CustomerID %s: %v\n", customerID, err)
+		return CompanyView{}, err
+	}
+	log.Printf("[Service CreateCompany] Perusahaan %s dibuat oleh %s.\n", view.CompanyID, customerID)
+	return view, nil
+}
+
+func (s *service) GetMyCompany(customerID string) (CompanyView, error) {
+	membership, err := findCompanyMembership(s.db, customerID)
+	if err != nil {
+		return CompanyView{}, err
+	}
+	return s.loadCompanyView(s.db, membership.CompanyID, customerID)
+}
+
+func (s *service) UpdateCompany(customerID string, input CompanyInput) (CompanyView, error) {
+	npwp, err := normalizeNPWP(input.NPWP)
+	if err != nil {
+		return CompanyView{}, err
+	}
+
+	var view CompanyView
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		membership, err := findApproverMembership(tx, customerID)
+		if err != nil {
+			return err
+		}
+		if err := ensureNPWPAvailable(tx, npwp, membership.CompanyID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Company{}).Where("company_id = ?", membership.CompanyID).Updates(map[string]interface{}{
+			"legal_name":         strings.TrimSpace(input.LegalName),
+			"npwp":               npwp,
+			"billing_address":    strings.TrimSpace(input.BillingAddress),
+			"approval_threshold": input.ApprovalThreshold,
+		}).Error; err != nil {
+			return fmt.Errorf("gagal memperbarui perusahaan: %w", err)
+		}
+		view, err = s.loadCompanyView(tx, membership.CompanyID, customerID)
+		return err
+	})
+	if err != nil {
+		return CompanyView{}, err
+	}
+	return view, nil
+}
+
+func (s *service) AddCompanyMember(customerID string, input AddCompanyMemberInput) (CompanyView, error) {
+	var view CompanyView
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		membership, err := findApproverMembership(tx, customerID)
+		if err != nil {
+			return err
+		}
+
+		var customer models.Customer
+		if err := tx.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(input.Email)).First(&customer).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("customer dengan email tersebut tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mencari customer: %w", err)
+		}
+		if _, err := findCompanyMembership(tx, customer.CustomerID); err == nil {
+			return errors.New("customer tersebut sudah terdaftar di perusahaan")
+		}
+
+		if err := tx.Create(&models.CompanyMember{CompanyID: membership.CompanyID, CustomerID: customer.CustomerID, Role: input.Role}).Error; err != nil {
+			return fmt.Errorf("gagal menambahkan anggota perusahaan: %w", err)
+		}
+		view, err = s.loadCompanyView(tx, membership.CompanyID, customerID)
+		return err
+	})
+	if err != nil {
+		return CompanyView{}, err
+	}
+	return view, nil
+}
+
+// ensureAnotherApprover memastikan perusahaan tetap punya approver selain memberCustomerID.
+func ensureAnotherApprover(tx *gorm.DB, companyID, memberCustomerID string) error {
+	var count int64
+	if err := tx.Model(&models.CompanyMember{}).
+		Where("company_id = ? AND role = ? AND customer_id <> ?", companyID, models.CompanyRoleApprover, memberCustomerID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("gagal memeriksa approver perusahaan: %w", err)
+	}
+	if count == 0 {
+		return errors.New("perusahaan harus memiliki minimal satu approver")
+	}
+	return nil
+}
+
+func findCompanyMember(tx *gorm.DB, companyID, memberCustomerID string) (models.CompanyMember, error) {
+	var member models.CompanyMember
+	if err := tx.Where("company_id = ? AND customer_id = ?", companyID, memberCustomerID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return member, errors.New("anggota perusahaan tidak ditemukan")
+		}
+		return member, fmt.Errorf("gagal mengambil anggota perusahaan: %w", err)
+	}
+	return member, nil
+}
+
+func (s *service) UpdateCompanyMember(customerID, memberCustomerID string, input UpdateCompanyMemberInput) (CompanyView, error) {
+	var view CompanyView
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		membership, err := findApproverMembership(tx, customerID)
+		if err != nil {
+			return err
+		}
+		member, err := findCompanyMember(tx, membership.CompanyID, memberCustomerID)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.CompanyRoleApprover && input.Role != models.CompanyRoleApprover {
+			if err := ensureAnotherApprover(tx, membership.CompanyID, memberCustomerID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&member).Update("role", input.Role).Error; err != nil {
+			return fmt.Errorf("gagal memperbarui peran anggota: %w", err)
+		}
+		view, err = s.loadCompanyView(tx, membership.CompanyID, customerID)
+		return err
+	})
+	if err != nil {
+		return CompanyView{}, err
+	}
+	return view, nil
+}
+
+// RemoveCompanyMember mengeluarkan anggota; approver bisa mengeluarkan siapa saja, anggota lain hanya dirinya sendiri.
+func (s *service) RemoveCompanyMember(customerID, memberCustomerID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		membership, err := findCompanyMembership(tx, customerID)
+		if err != nil {
+			return err
+		}
+		if membership.Role != models.CompanyRoleApprover && customerID != memberCustomerID {
+			return errors.New("hanya approver perusahaan yang dapat melakukan aksi ini")
+		}
+		member, err := findCompanyMember(tx, membership.CompanyID, memberCustomerID)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.CompanyRoleApprover {
+			if err := ensureAnotherApprover(tx, membership.CompanyID, memberCustomerID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&member).Error
+	})
+}
+
+func toCompanyOrderHistoryItem(order models.Order) CompanyOrderHistoryItem {
+	return CompanyOrderHistoryItem{
+		OrderHistoryItem:   toOrderHistoryItem(order),
+		PlacedByCustomerID: order.CustomerID,
+		PlacedByName:       order.CustomerFullname,
+		ApprovedBy:         order.ApprovedBy,
+	}
+}
+
+func (s *service) ListCompanyOrders(customerID, statusFilter string) ([]CompanyOrderHistoryItem, error) {
+	membership, err := findCompanyMembership(s.db, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Preload("OrderItems").Preload("PaymentMilestones").
+		Where("company_id = ?", membership.CompanyID).
+		Order("order_date_time DESC")
+	if statusFilter != "" {
+		query = query.Where("order_status = ?", statusFilter)
+	}
+	var orders []models.Order
+	if err := query.Find(&orders).Error; err != nil {
+		log.Printf("[Service ListCompanyOrders] Error untuk perusahaan %s: %v\n", membership.CompanyID, err)
+		return nil, fmt.Errorf("gagal mengambil riwayat pesanan perusahaan: %w", err)
+	}
+
+	history := make([]CompanyOrderHistoryItem, 0, len(orders))
+	for _, order := range orders {
+		history = append(history, toCompanyOrderHistoryItem(order))
+	}
+	return history, nil
+}
+
+// findOrderAwaitingApproval memuat order perusahaan approver yang masih menunggu persetujuan.
+func findOrderAwaitingApproval(tx *gorm.DB, customerID, orderID string) (models.Order, error) {
+	var order models.Order
+	membership, err := findApproverMembership(tx, customerID)
+	if err != nil {
+		return order, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND company_id = ?", orderID, membership.CompanyID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return order, errors.New("pesanan tidak ditemukan")
+		}
+		return order, fmt.Errorf("gagal mengambil pesanan: %w", err)
+	}
+	if order.OrderStatus != models.OrderStatusAwaitingApproval {
+		return order, errors.New("pesanan tidak sedang menunggu persetujuan")
+	}
+	return order, nil
+}
+
+func (s *service) ApproveCompanyOrder(customerID, orderID string) (CompanyOrderHistoryItem, error) {
+	var order models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = findOrderAwaitingApproval(tx, customerID, orderID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&order).Updates(map[string]interface{}{"order_status": "Pending", "approved_by": customerID}).Error; err != nil {
+			return fmt.Errorf("gagal menyetujui pesanan: %w", err)
+		}
+		if err := recordOrderStatus(tx, orderID, "Pending", customerID, "Disetujui approver perusahaan"); err != nil {
+			return err
+		}
+		return tx.Preload("OrderItems").Preload("PaymentMilestones").First(&order, "order_id = ?", orderID).Error
+	})
+	if err != nil {
+		log.Printf("[Service ApproveCompanyOrder] Gagal untuk OrderID %s: %v\n", orderID, err)
+		return CompanyOrderHistoryItem{}, err
+	}
+	log.Printf("[Service ApproveCompanyOrder] Order %s disetujui oleh %s.\n", orderID, customerID)
+	return toCompanyOrderHistoryItem(order), nil
+}
+
+// RejectCompanyOrder membatalkan order yang ditolak approver dan mengembalikan stok produknya.
+func (s *service) RejectCompanyOrder(customerID, orderID string, input RejectCompanyOrderInput) (CompanyOrderHistoryItem, error) {
+	var order models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = findOrderAwaitingApproval(tx, customerID, orderID)
+		if err != nil {
+			return err
+		}
+
+		if err := models.ApplyOrderCancellation(tx, orderID, true); err != nil {
+			return err
+		}
+
+		notes := strings.TrimSpace(fmt.Sprintf("%s\n[Ditolak approver] %s", order.Notes, input.Reason))
+		if err := tx.Model(&order).Updates(map[string]interface{}{"order_status": "Canceled", "notes": notes}).Error; err != nil {
+			return fmt.Errorf("gagal menolak pesanan: %w", err)
+		}
+		if err := recordOrderStatus(tx, orderID, "Canceled", customerID, "Ditolak approver perusahaan: "+input.Reason); err != nil {
+			return err
+		}
+		return tx.Preload("OrderItems").Preload("PaymentMilestones").First(&order, "order_id = ?", orderID).Error
+	})
+	if err != nil {
+		log.Printf("[Service RejectCompanyOrder] Gagal untuk OrderID %s: %v\n", orderID, err)
+		return CompanyOrderHistoryItem{}, err
+	}
+	return toCompanyOrderHistoryItem(order), nil
+}
+
+func toOrderPaymentSchedule(order models.Order) OrderPaymentSchedule {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	schedule := OrderPaymentSchedule{
+		OrderID:            order.OrderID,
+		GrandTotal:         order.GrandTotal,
+		OutstandingBalance: order.OutstandingBalance(),
+		Milestones:         make([]PaymentMilestoneView, 0, len(order.PaymentMilestones)),
+	}
+	for _, milestone := range order.PaymentMilestones {
+		dueDate := time.Date(milestone.DueDate.Year(), milestone.DueDate.Month(), milestone.DueDate.Day(), 0, 0, 0, 0, time.Local)
+		schedule.Milestones = append(schedule.Milestones, PaymentMilestoneView{
+			ID:              milestone.ID,
+			Sequence:        milestone.Sequence,
+			Label:           milestone.Label,
+			Percentage:      milestone.Percentage,
+			Amount:          milestone.Amount,
+			DueDate:         milestone.DueDate,
+			Status:          milestone.Status,
+			IsOverdue:       milestone.Status != models.PaymentMilestonePaid && dueDate.Before(today),
+			ProofOfPayment:  milestone.ProofOfPayment,
+			SubmittedAt:     milestone.SubmittedAt,
+			VerifiedAt:      milestone.VerifiedAt,
+			RejectionReason: milestone.RejectionReason,
+		})
+	}
+	return schedule
+}
+
+func findCustomerOrderWithMilestones(tx *gorm.DB, customerID, orderID string) (models.Order, error) {
+	var order models.Order
+	if err := tx.Preload("PaymentMilestones", func(db *gorm.DB) *gorm.DB { return db.Order("sequence ASC") }).
+		Where("order_id = ? AND customer_id = ?", orderID, customerID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return order, errors.New("pesanan tidak ditemukan")
+		}
+		return order, fmt.Errorf("gagal mengambil pesanan: %w", err)
+	}
+	return order, nil
+}
+
+func (s *service) GetOrderPaymentSchedule(customerID, orderID string) (OrderPaymentSchedule, error) {
+	order, err := findCustomerOrderWithMilestones(s.db, customerID, orderID)
+	if err != nil {
+		return OrderPaymentSchedule{}, err
+	}
+	return toOrderPaymentSchedule(order), nil
+}
+
+// SubmitPaymentMilestoneProof mengunggah bukti bayar untuk satu termin; termin lalu menunggu verifikasi admin.
+func (s *service) SubmitPaymentMilestoneProof(customerID, orderID string, milestoneID uint, proofPaymentFileHeader *multipart.FileHeader) (OrderPaymentSchedule, error) {
+	log.Printf("[Service SubmitPaymentMilestoneProof] CustomerID: %s, OrderID: %s, Termin: %d\n", customerID, orderID, milestoneID)
+
+	var proofPaymentPath, previousProofPath string
+	var order models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = findCustomerOrderWithMilestones(tx, customerID, orderID)
+		if err != nil {
+			return err
+		}
+		if order.OrderStatus == "Canceled" {
+			return errors.New("pesanan sudah dibatalkan")
+		}
+
+		var milestone *models.PaymentMilestone
+		for i := range order.PaymentMilestones {
+			if order.PaymentMilestones[i].ID == milestoneID {
+				milestone = &order.PaymentMilestones[i]
+			}
+		}
+		if milestone == nil {
+			return errors.New("termin pembayaran tidak ditemukan")
+		}
+		if milestone.Status != models.PaymentMilestoneUnpaid && milestone.Status != models.PaymentMilestoneRejected {
+			return errors.New("termin pembayaran sudah dibayar atau sedang diverifikasi")
+		}
+
+		proofPaymentPath, err = saveProofOfPayment(customerID, proofPaymentFileHeader)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		previousProofPath = milestone.ProofOfPayment
+		milestone.ProofOfPayment = proofPaymentPath
+		milestone.Status = models.PaymentMilestoneAwaitingVerification
+		milestone.SubmittedAt = &now
+		milestone.RejectionReason = ""
+		return tx.Save(milestone).Error
+	})
+	if err != nil {
+		log.Printf("[Service SubmitPaymentMilestoneProof] Gagal: %v\n", err)
+		removeProofOfPayment(proofPaymentPath)
+		return OrderPaymentSchedule{}, err
+	}
+
+	// Bukti lama dari termin yang ditolak tidak diperlukan lagi
+	if previousProofPath != "" {
+		if errRemove := os.Remove(filepath.Join(".", previousProofPath)); errRemove != nil {
+			log.Printf("[Service SubmitPaymentMilestoneProof] Peringatan: gagal menghapus bukti lama %s: %v\n", previousProofPath, errRemove)
+		}
+	}
+	return toOrderPaymentSchedule(order), nil
+}
+
+// Klaim dengan status ini dianggap masih berjalan sehingga garansi yang sama belum bisa diklaim lagi
+var openWarrantyClaimStatuses = []string{models.WarrantyClaimSubmitted, models.WarrantyClaimInReview, models.WarrantyClaimApproved}
+
+// warrantyDaysRemaining menghitung sisa hari garansi; garansi masih berlaku sampai akhir hari EndDate.
+func warrantyDaysRemaining(warranty models.Warranty, now time.Time) int {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(warranty.EndDate.Year(), warranty.EndDate.Month(), warranty.EndDate.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(today).Hours() / 24)
+}
+
+func toWarrantyClaimView(claim models.WarrantyClaim) WarrantyClaimView {
+	view := WarrantyClaimView{
+		ClaimID:         claim.ClaimID,
+		WarrantyID:      claim.WarrantyID,
+		OrderID:         claim.Warranty.OrderID,
+		ProductSKU:      claim.Warranty.ProductSKU,
+		ProductTitle:    claim.Warranty.ProductTitleSnapshot,
+		SerialNumber:    claim.Warranty.SerialNumber,
+		Description:     claim.Description,
+		Status:          claim.Status,
+		ResolutionNotes: claim.ResolutionNotes,
+		Photos:          make([]string, 0, len(claim.Photos)),
+		ResolvedAt:      claim.ResolvedAt,
+		CreatedAt:       claim.CreatedAt,
+		UpdatedAt:       claim.UpdatedAt,
+	}
+	for _, photo := range claim.Photos {
+		view.Photos = append(view.Photos, photo.Image)
+	}
+	return view
+}
+
+func (s *service) ListWarranties(customerID string) ([]WarrantyView, error) {
+	var warranties []models.Warranty
+	if err := s.db.Where("customer_id = ?", customerID).Order("end_date DESC, id ASC").Find(&warranties).Error; err != nil {
+		log.Printf("[Service ListWarranties] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil daftar garansi: %w", err)
+	}
+
+	var claims []models.WarrantyClaim
+	if err := s.db.Select("claim_id", "warranty_id", "status").Where("customer_id = ?", customerID).Order("created_at ASC").Find(&claims).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil klaim garansi: %w", err)
+	}
+	claimCounts := make(map[uint]int)
+	openClaims := make(map[uint]string)
+	for _, claim := range claims {
+		claimCounts[claim.WarrantyID]++
+		if slices.Contains(openWarrantyClaimStatuses, claim.Status) {
+			openClaims[claim.WarrantyID] = claim.ClaimID
+		}
+	}
+
+	now := time.Now()
+	views := make([]WarrantyView, 0, len(warranties))
+	for _, warranty := range warranties {
+		daysRemaining := warrantyDaysRemaining(warranty, now)
+		view := WarrantyView{
+			WarrantyID:   warranty.ID,
+			OrderID:      warranty.OrderID,
+			ProductSKU:   warranty.ProductSKU,
+			ProductTitle: warranty.ProductTitleSnapshot,
+			UnitIndex:    warranty.UnitIndex,
+			SerialNumber: warranty.SerialNumber,
+			StartDate:    warranty.StartDate,
+			EndDate:      warranty.EndDate,
+			IsActive:     daysRemaining >= 0,
+			OpenClaimID:  openClaims[warranty.ID],
+			TotalClaims:  claimCounts[warranty.ID],
+		}
+		if view.IsActive {
+			view.DaysRemaining = daysRemaining
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+func (s *service) CreateWarrantyClaim(customerID string, warrantyID uint, description string, photoFileHeaders []*multipart.FileHeader) (WarrantyClaimView, error) {
+	log.Printf("[Service CreateWarrantyClaim] CustomerID: %s, WarrantyID: %d, Jumlah foto: %d\n", customerID, warrantyID, len(photoFileHeaders))
+
+	var savedPhotos []string
+	var claim models.WarrantyClaim
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Kunci baris garansi agar dua klaim bersamaan untuk unit yang sama tidak lolos
+		var warranty models.Warranty
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND customer_id = ?", warrantyID, customerID).
+			First(&warranty).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("garansi tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil garansi: %w", err)
+		}
+		if warrantyDaysRemaining(warranty, time.Now()) < 0 {
+			return errors.New("masa garansi sudah berakhir")
+		}
+
+		var openClaims int64
+		if err := tx.Model(&models.WarrantyClaim{}).
+			Where("warranty_id = ? AND status IN ?", warranty.ID, openWarrantyClaimStatuses).
+			Count(&openClaims).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa klaim garansi: %w", err)
+		}
+		if openClaims > 0 {
+			return errors.New("masih ada klaim garansi yang sedang diproses untuk unit ini")
+		}
+
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('warranty_claim_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID klaim garansi: %w", err)
+		}
+		claim = models.WarrantyClaim{
+			ClaimID:     fmt.Sprintf("WCL%05d", nextVal),
+			WarrantyID:  warranty.ID,
+			CustomerID:  customerID,
+			Description: description,
+			Status:      models.WarrantyClaimSubmitted,
+		}
+		for _, fileHeader := range photoFileHeaders {
+			savedPath, err := saveUploadedFile(fileHeader, "./uploads/images/warranty-claims/", "claim_"+claim.ClaimID, "foto klaim garansi")
+			if savedPath != "" {
+				savedPhotos = append(savedPhotos, savedPath)
+			}
+			if err != nil {
+				return err
+			}
+			claim.Photos = append(claim.Photos, models.WarrantyClaimPhoto{Image: savedPath})
+		}
+		if err := tx.Create(&claim).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan klaim garansi: %w", err)
+		}
+		claim.Warranty = warranty
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service CreateWarrantyClaim] Gagal: %v\n", err)
+		for _, photoPath := range savedPhotos {
+			if errRemove := os.Remove(filepath.Join(".", photoPath)); errRemove != nil {
+				log.Printf("[Service CreateWarrantyClaim] Peringatan: gagal menghapus foto %s: %v\n", photoPath, errRemove)
+			}
+		}
+		return WarrantyClaimView{}, err
+	}
+	return toWarrantyClaimView(claim), nil
+}
+
+func (s *service) ListWarrantyClaims(customerID string) ([]WarrantyClaimView, error) {
+	var claims []models.WarrantyClaim
+	if err := s.db.Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Warranty").
+		Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Find(&claims).Error; err != nil {
+		log.Printf("[Service ListWarrantyClaims] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil klaim garansi: %w", err)
+	}
+	views := make([]WarrantyClaimView, 0, len(claims))
+	for _, claim := range claims {
+		views = append(views, toWarrantyClaimView(claim))
+	}
+	return views, nil
+}
+
+func toOrderRefundView(refund models.Refund) *OrderRefundView {
+	return &OrderRefundView{
+		RefundID:         refund.RefundID,
+		Amount:           refund.Amount,
+		Status:           refund.Status,
+		PaymentReference: refund.PaymentReference,
+		PaidAt:           refund.PaidAt,
+		CreatedAt:        refund.CreatedAt,
+	}
+}
+
+// CreateReturnRequest mengajukan retur untuk satu item dari order Completed. Quantity yang diretur tidak
+// boleh melebihi quantity item dikurangi retur lain yang belum ditolak.
+func (s *service) CreateReturnRequest(customerID, orderID string, input CreateReturnRequestInput, photoFileHeaders []*multipart.FileHeader) (ReturnRequestView, error) {
+	log.Printf("[Service CreateReturnRequest] CustomerID: %s, OrderID: %s, OrderItemID: %d, Quantity: %d\n", customerID, orderID, input.OrderItemID, input.Quantity)
+
+	var savedPhotos []string
+	var returnRequest models.ReturnRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Kunci order agar dua pengajuan bersamaan tidak melebihi quantity item
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND customer_id = ?", orderID, customerID).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil pesanan: %w", err)
+		}
+		if order.OrderStatus != "Completed" {
+			return fmt.Errorf("retur tidak dapat diajukan: status pesanan masih %s, hanya pesanan Completed yang dapat diretur", order.OrderStatus)
+		}
+
+		var item models.OrderItem
+		if err := tx.Where("order_item_id = ? AND order_id = ?", input.OrderItemID, orderID).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("item pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil item pesanan: %w", err)
+		}
+		var returnedQuantity int
+		if err := tx.Model(&models.ReturnRequest{}).
+			Where("order_item_id = ? AND status <> ?", item.OrderItemID, models.ReturnRejected).
+			Select("COALESCE(SUM(quantity), 0)").
+			Row().Scan(&returnedQuantity); err != nil {
+			return fmt.Errorf("gagal memeriksa retur sebelumnya: %w", err)
+		}
+		if remaining := item.Quantity - returnedQuantity; input.Quantity > remaining {
+			return fmt.Errorf("retur tidak dapat diajukan: quantity melebihi sisa item yang dapat diretur (%d)", remaining)
+		}
+
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('rma_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID retur: %w", err)
+		}
+		returnRequest = models.ReturnRequest{
+			RMAID:                fmt.Sprintf("RMA%05d", nextVal),
+			OrderID:              orderID,
+			OrderItemID:          item.OrderItemID,
+			CustomerID:           customerID,
+			ProductSKU:           item.ProductSKU,
+			ProductTitleSnapshot: item.ProductTitleSnapshot,
+			Quantity:             input.Quantity,
+			Reason:               strings.TrimSpace(input.Reason),
+			ReturnMethod:         input.ReturnMethod,
+			Status:               models.ReturnRequested,
+			BankName:             strings.TrimSpace(input.RefundBankName),
+			BankAccountNumber:    strings.TrimSpace(input.RefundAccountNumber),
+			BankAccountHolder:    strings.TrimSpace(input.RefundAccountHolder),
+		}
+		for _, fileHeader := range photoFileHeaders {
+			savedPath, err := saveUploadedFile(fileHeader, "./uploads/images/returns/", "return_"+returnRequest.RMAID, "foto retur")
+			if savedPath != "" {
+				savedPhotos = append(savedPhotos, savedPath)
+			}
+			if err != nil {
+				return err
+			}
+			returnRequest.Photos = append(returnRequest.Photos, models.ReturnRequestPhoto{Image: savedPath})
+		}
+		if err := tx.Create(&returnRequest).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan retur: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service CreateReturnRequest] Gagal: %v\n", err)
+		for _, photoPath := range savedPhotos {
+			if errRemove := os.Remove(filepath.Join(".", photoPath)); errRemove != nil {
+				log.Printf("[Service CreateReturnRequest] Peringatan: gagal menghapus foto %s: %v\n", photoPath, errRemove)
+			}
+		}
+		return ReturnRequestView{}, err
+	}
+	log.Printf("[Service CreateReturnRequest] Retur %s dibuat untuk order %s.\n", returnRequest.RMAID, orderID)
+	return toReturnRequestView(returnRequest, nil), nil
+}
+
+func (s *service) ListReturnRequests(customerID string) ([]ReturnRequestView, error) {
+	var returnRequests []models.ReturnRequest
+	if err := s.db.Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Find(&returnRequests).Error; err != nil {
+		log.Printf("[Service ListReturnRequests] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil daftar retur: %w", err)
+	}
+	rmaIDs := make([]string, 0, len(returnRequests))
+	for _, returnRequest := range returnRequests {
+		rmaIDs = append(rmaIDs, returnRequest.RMAID)
+	}
+	refundsByRMA := make(map[string]models.Refund)
+	if len(rmaIDs) > 0 {
+		var refunds []models.Refund
+		if err := s.db.Where("rma_id IN ?", rmaIDs).Find(&refunds).Error; err != nil {
+			return nil, fmt.Errorf("gagal mengambil data refund retur: %w", err)
+		}
+		for _, refund := range refunds {
+			refundsByRMA[*refund.RMAID] = refund
+		}
+	}
+
+	views := make([]ReturnRequestView, 0, len(returnRequests))
+	for _, returnRequest := range returnRequests {
+		var refund *models.Refund
+		if found, ok := refundsByRMA[returnRequest.RMAID]; ok {
+			refund = &found
+		}
+		views = append(views, toReturnRequestView(returnRequest, refund))
+	}
+	return views, nil
+}
+
+func (s *service) GetReturnRequest(customerID, rmaID string) (ReturnRequestView, error) {
+	var returnRequest models.ReturnRequest
+	if err := s.db.Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("rma_id = ? AND customer_id = ?", rmaID, customerID).
+		First(&returnRequest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ReturnRequestView{}, errors.New("retur tidak ditemukan")
+		}
+		return ReturnRequestView{}, fmt.Errorf("gagal mengambil retur: %w", err)
+	}
+	var refunds []models.Refund
+	if err := s.db.Where("rma_id = ?", rmaID).Limit(1).Find(&refunds).Error; err != nil {
+		return ReturnRequestView{}, fmt.Errorf("gagal mengambil data refund retur: %w", err)
+	}
+	var refund *models.Refund
+	if len(refunds) > 0 {
+		refund = &refunds[0]
+	}
+	return toReturnRequestView(returnRequest, refund), nil
+}
+
+func toReturnRequestView(returnRequest models.ReturnRequest, refund *models.Refund) ReturnRequestView {
+	view := ReturnRequestView{
+		RMAID:           returnRequest.RMAID,
+		OrderID:         returnRequest.OrderID,
+		OrderItemID:     returnRequest.OrderItemID,
+		ProductSKU:      returnRequest.ProductSKU,
+		ProductTitle:    returnRequest.ProductTitleSnapshot,
+		Quantity:        returnRequest.Quantity,
+		Reason:          returnRequest.Reason,
+		ReturnMethod:    returnRequest.ReturnMethod,
+		Status:          returnRequest.Status,
+		AdminNotes:      returnRequest.AdminNotes,
+		Resolution:      returnRequest.Resolution,
+		ResolutionNotes: returnRequest.ResolutionNotes,
+		Photos:          make([]string, 0, len(returnRequest.Photos)),
+		ReceivedAt:      returnRequest.ReceivedAt,
+		ResolvedAt:      returnRequest.ResolvedAt,
+		CreatedAt:       returnRequest.CreatedAt,
+		UpdatedAt:       returnRequest.UpdatedAt,
+	}
+	for _, photo := range returnRequest.Photos {
+		view.Photos = append(view.Photos, photo.Image)
+	}
+	if refund != nil {
+		view.Refund = toOrderRefundView(*refund)
+	}
+	return view
+}
+
+const serviceTicketUploadDir = "./uploads/tickets/"
+
+// saveServiceTicketAttachments menyimpan lampiran pesan tiket. Path yang sudah tersimpan selalu dikembalikan
+// agar pemanggil bisa menghapusnya jika transaksi gagal.
+func saveServiceTicketAttachments(ticketID string, fileHeaders []*multipart.FileHeader) ([]models.ServiceTicketAttachment, []string, error) {
+	var attachments []models.ServiceTicketAttachment
+	var savedPaths []string
+	for _, fileHeader := range fileHeaders {
+		savedPath, err := saveUploadedFile(fileHeader, serviceTicketUploadDir, "ticket_"+ticketID, "lampiran tiket")
+		if savedPath != "" {
+			savedPaths = append(savedPaths, savedPath)
+		}
+		if err != nil {
+			return nil, savedPaths, err
+		}
+		attachments = append(attachments, models.ServiceTicketAttachment{FileName: filepath.Base(fileHeader.Filename), FilePath: savedPath})
+	}
+	return attachments, savedPaths, nil
+}
+
+func removeServiceTicketAttachments(savedPaths []string) {
+	for _, savedPath := range savedPaths {
+		if errRemove := os.Remove(filepath.Join(".", savedPath)); errRemove != nil {
+			log.Printf("Peringatan: gagal menghapus lampiran tiket %s: %v\n", savedPath, errRemove)
+		}
+	}
+}
+
+// toServiceTicketView menyusun tampilan tiket beserta nama departemen, teknisi dan pengirim pesan.
+func (s *service) toServiceTicketView(ticket models.ServiceTicket, withMessages bool) ServiceTicketView {
+	view := ServiceTicketView{
+		TicketID:      ticket.TicketID,
+		Subject:       ticket.Subject,
+		Category:      ticket.Category,
+		Priority:      ticket.Priority,
+		Status:        ticket.Status,
+		OrderItemID:   ticket.OrderItemID,
+		ProductSKU:    ticket.ProductSKU,
+		SerialNumber:  ticket.SerialNumber,
+		LastMessageAt: ticket.LastMessageAt,
+		ResolvedAt:    ticket.ResolvedAt,
+		ClosedAt:      ticket.ClosedAt,
+		CreatedAt:     ticket.CreatedAt,
+	}
+	if ticket.DepartmentID != nil {
+		var department models.Department
+		if err := s.db.Select("department_name").Where("department_id = ?", *ticket.DepartmentID).First(&department).Error; err == nil {
+			view.DepartmentName = department.DepartmentName
+		}
+	}
+	employeeNames := make(map[string]string)
+	employeeName := func(employeeID string) string {
+		if name, ok := employeeNames[employeeID]; ok {
+			return name
+		}
+		var employee models.Employee
+		if err := s.db.Select("full_name").Where("employee_id = ?", employeeID).First(&employee).Error; err == nil {
+			employeeNames[employeeID] = employee.FullName
+		}
+		return employeeNames[employeeID]
+	}
+	if ticket.AssignedTo != nil {
+		view.AssignedToName = employeeName(*ticket.AssignedTo)
+	}
+	if !withMessages {
+		return view
+	}
+
+	view.Messages = make([]ServiceTicketMessageView, 0, len(ticket.Messages))
+	for _, message := range ticket.Messages {
+		messageView := ServiceTicketMessageView{
+			ID:          message.ID,
+			SenderType:  message.SenderType,
+			Message:     message.Message,
+			Attachments: make([]ServiceTicketAttachmentView, 0, len(message.Attachments)),
+			CreatedAt:   message.CreatedAt,
+		}
+		if message.SenderType == models.ServiceTicketSenderEmployee {
+			messageView.SenderName = employeeName(message.SenderID)
+		} else {
+			messageView.SenderName = "Anda"
+		}
+		for _, attachment := range message.Attachments {
+			messageView.Attachments = append(messageView.Attachments, ServiceTicketAttachmentView{FileName: attachment.FileName, FilePath: attachment.FilePath})
+		}
+		view.Messages = append(view.Messages, messageView)
+	}
+	return view
+}
+
+func findCustomerServiceTicket(tx *gorm.DB, customerID, ticketID string) (models.ServiceTicket, error) {
+	var ticket models.ServiceTicket
+	if err := tx.Preload("Messages", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, id ASC") }).
+		Preload("Messages.Attachments").
+		Where("ticket_id = ? AND customer_id = ?", ticketID, customerID).
+		First(&ticket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ServiceTicket{}, errors.New("tiket layanan tidak ditemukan")
+		}
+		return models.ServiceTicket{}, fmt.Errorf("gagal mengambil tiket layanan: %w", err)
+	}
+	return ticket, nil
+}
 
-			// Kurangi stok produk
-			newStock := product.Stock - itemInCart.Quantity
-			if err := tx.Model(&models.Product{}).Where("product_sku = ?", itemInCart.ProductSKU).Update("stock", newStock).Error; err != nil {
-				return fmt.Errorf("gagal mengupdate stok produk %s: %w", itemInCart.ProductSKU, err)
-			}
+// findCustomerSerialOrderItem mencari order item tempat nomor seri dikirim ke customer.
+func findCustomerSerialOrderItem(tx *gorm.DB, customerID, serialNumber string) (uint, error) {
+	var unit models.ProductUnit
+	err := tx.Where("serial_number = ? AND order_item_id IS NOT NULL AND order_id IN (?)", serialNumber,
+		tx.Model(&models.Order{}).Select("order_id").Where("customer_id = ?", customerID)).
+		First(&unit).Error
+	if err == nil {
+		return *unit.OrderItemID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("gagal memeriksa nomor seri: %w", err)
+	}
+
+	var warranty models.Warranty
+	if err := tx.Where("serial_number = ? AND customer_id = ?", serialNumber, customerID).First(&warranty).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("nomor seri tidak ditemukan pada pembelian anda")
 		}
+		return 0, fmt.Errorf("gagal memeriksa nomor seri: %w", err)
+	}
+	return warranty.OrderItemID, nil
+}
 
-		// 7. Buat record Order utama
-		order := models.Order{
-			OrderID:                 newOrderID,
-			CustomerID:              customerID,
-			CustomerFullname:        customerDetail.FirstName + " " + customerDetail.LastName,
-			CustomerEmail:           customerDetail.Email,      // Atau dari customer.Email jika lebih utama
-			CustomerPhone:           customerDetail.Phone,      // Atau dari customer.Phone
-			ShippingAddressID:       shippingAddress.AddressID, // Dari alamat yang divalidasi
-			ShippingAddressSnapshot: addressSnapshot,
-			OrderDateTime:           now,
-			PaymentMethod:           input.PaymentMethod,
-			OrderStatus:             "Pending", // Status awal order (sesuai DDL Anda 'Pending')
-			GrandTotal:              calculatedGrandTotal,
-			Notes:                   input.Notes,
-			ProofOfPayment:          proofPaymentPath,
+func (s *service) CreateServiceTicket(customerID string, input CreateServiceTicketInput, attachments []*multipart.FileHeader) (ServiceTicketView, error) {
+	log.Printf("[Service CreateServiceTicket] CustomerID: %s, Kategori: %s, Lampiran: %d\n", customerID, input.Category, len(attachments))
+
+	var savedPaths []string
+	var ticket models.ServiceTicket
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ticket = models.ServiceTicket{
+			CustomerID:   customerID,
+			Subject:      strings.TrimSpace(input.Subject),
+			Category:     input.Category,
+			Priority:     input.Priority,
+			Status:       models.ServiceTicketOpen,
+			SerialNumber: strings.TrimSpace(input.SerialNumber),
 		}
-		if err := tx.Create(&order).Error; err != nil {
-			return fmt.Errorf("gagal membuat order: %w", err)
+		if ticket.Priority == "" {
+			ticket.Priority = models.ServiceTicketPriorityNormal
 		}
 
-		// 8. Simpan semua OrderItem
-		if len(orderItemsToCreate) > 0 {
-			if err := tx.Create(&orderItemsToCreate).Error; err != nil {
-				return fmt.Errorf("gagal menyimpan item order: %w", err)
+		// Nomor seri harus berasal dari unit yang dikirim ke customer ini atau tercatat di garansinya
+		if ticket.SerialNumber != "" {
+			serialOrderItemID, err := findCustomerSerialOrderItem(tx, customerID, ticket.SerialNumber)
+			if err != nil {
+				return err
+			}
+			if input.OrderItemID == nil {
+				input.OrderItemID = &serialOrderItemID
+			} else if *input.OrderItemID != serialOrderItemID {
+				return errors.New("nomor seri bukan bagian dari item pesanan tersebut")
+			}
+		}
+		if input.OrderItemID != nil {
+			var item models.OrderItem
+			if err := tx.Joins("JOIN orders ON orders.order_id = order_items.order_id").
+				Where("order_items.order_item_id = ? AND orders.customer_id = ?", *input.OrderItemID, customerID).
+				First(&item).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("item pesanan tidak ditemukan")
+				}
+				return fmt.Errorf("gagal memeriksa item pesanan: %w", err)
 			}
+			ticket.OrderItemID = &item.OrderItemID
+			ticket.ProductSKU = item.ProductSKU
 		}
 
-		// 9. Hapus item dari keranjang customer setelah checkout berhasil
-		if err := tx.Where("customer_id = ?", customerID).Delete(&models.Cart{}).Error; err != nil {
-			log.Printf("[Service CreateOrderFromCart] Peringatan: Gagal menghapus item dari keranjang CustomerID %s: %v\n", customerID, err)
-			// Tidak menggagalkan transaksi utama
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('service_ticket_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID tiket: %w", err)
 		}
+		ticket.TicketID = fmt.Sprintf("TCK%05d", nextVal)
 
-		// Ambil kembali order dengan detail lengkapnya (termasuk OrderItems dan info Customer) untuk dikembalikan
-		if err := tx.Preload("OrderItems").Preload("Customer.Detail").Preload("ShippingAddress").First(&finalCreatedOrder, "order_id = ?", newOrderID).Error; err != nil {
-			return fmt.Errorf("gagal mengambil data order lengkap setelah create: %w", err)
+		savedAttachments, paths, err := saveServiceTicketAttachments(ticket.TicketID, attachments)
+		savedPaths = paths
+		if err != nil {
+			return err
 		}
-		return nil // Commit transaksi
+		now := time.Now()
+		ticket.LastMessageAt = now
+		ticket.Messages = []models.ServiceTicketMessage{{
+			SenderType:  models.ServiceTicketSenderCustomer,
+			SenderID:    customerID,
+			Message:     strings.TrimSpace(input.Message),
+			Attachments: savedAttachments,
+		}}
+		if err := tx.Create(&ticket).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan tiket layanan: %w", err)
+		}
+		return nil
 	})
+	if err != nil {
+		log.Printf("[Service CreateServiceTicket] Gagal: %v\n", err)
+		removeServiceTicketAttachments(savedPaths)
+		return ServiceTicketView{}, err
+	}
+	return s.toServiceTicketView(ticket, true), nil
+}
+
+func (s *service) ListServiceTickets(customerID string) ([]ServiceTicketView, error) {
+	var tickets []models.ServiceTicket
+	if err := s.db.Where("customer_id = ?", customerID).Order("last_message_at DESC").Find(&tickets).Error; err != nil {
+		log.Printf("[Service ListServiceTickets] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil tiket layanan: %w", err)
+	}
+	views := make([]ServiceTicketView, 0, len(tickets))
+	for _, ticket := range tickets {
+		views = append(views, s.toServiceTicketView(ticket, false))
+	}
+	return views, nil
+}
 
+func (s *service) GetServiceTicket(customerID, ticketID string) (ServiceTicketView, error) {
+	ticket, err := findCustomerServiceTicket(s.db, customerID, ticketID)
 	if err != nil {
-		if proofPaymentPath != "" { // Jika transaksi gagal dan file sempat tersimpan, coba hapus
-			if errRemove := os.Remove(filepath.Join(".", proofPaymentPath)); errRemove != nil {
-				log.Printf("[Service CreateOrderFromCart] Gagal menghapus bukti pembayaran sementara setelah transaksi gagal: %v\n", errRemove)
-			} else {
-				log.Printf("[Service CreateOrderFromCart] Transaksi gagal, menghapus file bukti pembayaran sementara: %s\n", proofPaymentPath)
+		return ServiceTicketView{}, err
+	}
+	return s.toServiceTicketView(ticket, true), nil
+}
+
+func (s *service) AddServiceTicketMessage(customerID, ticketID string, input ServiceTicketMessageInput, attachments []*multipart.FileHeader) (ServiceTicketView, error) {
+	var savedPaths []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var ticket models.ServiceTicket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("ticket_id = ? AND customer_id = ?", ticketID, customerID).
+			First(&ticket).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("tiket layanan tidak ditemukan")
 			}
+			return fmt.Errorf("gagal mengambil tiket layanan: %w", err)
 		}
-		return models.Order{}, err
+		if ticket.Status == models.ServiceTicketClosed {
+			return errors.New("tiket layanan sudah ditutup")
+		}
+
+		savedAttachments, paths, err := saveServiceTicketAttachments(ticketID, attachments)
+		savedPaths = paths
+		if err != nil {
+			return err
+		}
+		message := models.ServiceTicketMessage{
+			TicketID:    ticketID,
+			SenderType:  models.ServiceTicketSenderCustomer,
+			SenderID:    customerID,
+			Message:     strings.TrimSpace(input.Message),
+			Attachments: savedAttachments,
+		}
+		if err := tx.Create(&message).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan pesan tiket: %w", err)
+		}
+
+		// Balasan customer membuka kembali tiket yang menunggu jawaban atau sudah dianggap selesai
+		switch ticket.Status {
+		case models.ServiceTicketWaitingCustomer:
+			ticket.Status = models.ServiceTicketInProgress
+		case models.ServiceTicketResolved:
+			ticket.Status = models.ServiceTicketOpen
+			ticket.ResolvedAt = nil
+		}
+		ticket.LastMessageAt = message.CreatedAt
+		return tx.Save(&ticket).Error
+	})
+	if err != nil {
+		log.Printf("[Service AddServiceTicketMessage] Gagal untuk tiket %s: %v\n", ticketID, err)
+		removeServiceTicketAttachments(savedPaths)
+		return ServiceTicketView{}, err
 	}
+	return s.GetServiceTicket(customerID, ticketID)
+}
 
-	log.Printf("[Service CreateOrderFromCart] Order %s berhasil dibuat.\n", finalCreatedOrder.OrderID)
-	return finalCreatedOrder, nil
+func (s *service) CloseServiceTicket(customerID, ticketID string) (ServiceTicketView, error) {
+	ticket, err := findCustomerServiceTicket(s.db, customerID, ticketID)
+	if err != nil {
+		return ServiceTicketView{}, err
+	}
+	if ticket.Status == models.ServiceTicketClosed {
+		return ServiceTicketView{}, errors.New("tiket layanan sudah ditutup")
+	}
+	now := time.Now()
+	if err := s.db.Model(&models.ServiceTicket{}).Where("ticket_id = ?", ticketID).
+		Updates(map[string]interface{}{"status": models.ServiceTicketClosed, "closed_at": now}).Error; err != nil {
+		return ServiceTicketView{}, fmt.Errorf("gagal menutup tiket layanan: %w", err)
+	}
+	ticket.Status = models.ServiceTicketClosed
+	ticket.ClosedAt = &now
+	return s.toServiceTicketView(ticket, true), nil
 }
 
-func (s *service) ListCustomerOrders(customerID string) ([]OrderHistoryItem, error) {
-	var ordersFromDB []models.Order
-	log.Printf("[Service ListCustomerOrders] Mengambil riwayat pesanan untuk Customer ID: %s\n", customerID)
+func (s *service) toInstallationJobViews(jobs []models.InstallationJob) ([]InstallationJobView, error) {
+	var employeeIDs []string
+	for _, job := range jobs {
+		for _, technician := range job.Technicians {
+			employeeIDs = append(employeeIDs, technician.EmployeeID)
+		}
+	}
+	employeeNames := make(map[string]string)
+	if len(employeeIDs) > 0 {
+		var employees []models.Employee
+		if err := s.db.Select("employee_id", "full_name").Where("employee_id IN ?", employeeIDs).Find(&employees).Error; err != nil {
+			return nil, fmt.Errorf("gagal mengambil data teknisi: %w", err)
+		}
+		for _, employee := range employees {
+			employeeNames[employee.EmployeeID] = employee.FullName
+		}
+	}
 
-	// 1. Ambil semua order milik customer, diurutkan dari yang terbaru
-	//    Sangat penting untuk Preload("OrderItems") agar kita bisa mengambil gambar produk
-	if err := s.db.
-		Preload("OrderItems").
+	views := make([]InstallationJobView, 0, len(jobs))
+	for _, job := range jobs {
+		view := InstallationJobView{
+			JobID:              job.JobID,
+			OrderID:            job.OrderID,
+			SiteAddress:        job.SiteAddress,
+			RequestedStartDate: job.RequestedStartDate,
+			RequestedEndDate:   job.RequestedEndDate,
+			ScheduledStart:     job.ScheduledStart,
+			ScheduledEnd:       job.ScheduledEnd,
+			Status:             job.Status,
+			Notes:              job.Notes,
+			Technicians:        make([]string, 0, len(job.Technicians)),
+			ChecklistTotal:     len(job.ChecklistItems),
+			SignedOffBy:        job.SignedOffBy,
+			CompletedAt:        job.CompletedAt,
+			CreatedAt:          job.CreatedAt,
+		}
+		for _, technician := range job.Technicians {
+			view.Technicians = append(view.Technicians, employeeNames[technician.EmployeeID])
+		}
+		for _, item := range job.ChecklistItems {
+			if item.IsDone {
+				view.ChecklistDone++
+			}
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// RequestInstallation membuat permintaan instalasi untuk order customer yang berisi produk perlu instalasi.
+func (s *service) RequestInstallation(customerID, orderID string, input RequestInstallationInput) (InstallationJobView, error) {
+	log.Printf("[Service RequestInstallation] CustomerID: %s, OrderID: %s\n", customerID, orderID)
+
+	startDate, _ := time.ParseInLocation("2006-01-02", input.RequestedStartDate, time.Local)
+	endDate, _ := time.ParseInLocation("2006-01-02", input.RequestedEndDate, time.Local)
+	now := time.Now()
+	if startDate.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)) {
+		return InstallationJobView{}, errors.New("instalasi tidak valid: tanggal mulai tidak boleh di masa lalu")
+	}
+	if endDate.Before(startDate) {
+		return InstallationJobView{}, errors.New("instalasi tidak valid: tanggal akhir tidak boleh sebelum tanggal mulai")
+	}
+
+	var job models.InstallationJob
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND customer_id = ?", orderID, customerID).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("pesanan tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil pesanan: %w", err)
+		}
+		if !slices.Contains(models.InstallableOrderStatuses, order.OrderStatus) {
+			return fmt.Errorf("instalasi tidak valid: pesanan berstatus %s belum dapat dijadwalkan instalasi", order.OrderStatus)
+		}
+
+		var installableItems int64
+		if err := tx.Model(&models.OrderItem{}).
+			Joins("JOIN products ON products.product_sku = order_items.product_sku").
+			Where("order_items.order_id = ? AND products.requires_installation = ?", orderID, true).
+			Count(&installableItems).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa produk pesanan: %w", err)
+		}
+		if installableItems == 0 {
+			return errors.New("instalasi tidak valid: pesanan tidak memiliki produk yang memerlukan instalasi")
+		}
+		var activeJobs int64
+		if err := tx.Model(&models.InstallationJob{}).
+			Where("order_id = ? AND status IN ?", orderID, models.ActiveInstallationJobStatuses).
+			Count(&activeJobs).Error; err != nil {
+			return fmt.Errorf("gagal memeriksa jadwal instalasi: %w", err)
+		}
+		if activeJobs > 0 {
+			return errors.New("pesanan sudah memiliki jadwal instalasi aktif")
+		}
+
+		siteAddress := strings.TrimSpace(input.SiteAddress)
+		if siteAddress == "" {
+			siteAddress = order.ShippingAddressSnapshot
+		}
+		if siteAddress == "" {
+			return errors.New("instalasi tidak valid: alamat lokasi wajib diisi")
+		}
+
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('installation_job_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID pekerjaan instalasi: %w", err)
+		}
+		job = models.InstallationJob{
+			JobID:              fmt.Sprintf("INS%05d", nextVal),
+			OrderID:            orderID,
+			CustomerID:         customerID,
+			SiteAddress:        siteAddress,
+			RequestedStartDate: startDate,
+			RequestedEndDate:   endDate,
+			Status:             models.InstallationJobRequested,
+			Notes:              strings.TrimSpace(input.Notes),
+		}
+		for i, description := range models.DefaultInstallationChecklist {
+			job.ChecklistItems = append(job.ChecklistItems, models.InstallationChecklistItem{Position: i + 1, Description: description})
+		}
+		return tx.Create(&job).Error
+	})
+	if err != nil {
+		log.Printf("[Service RequestInstallation] Gagal: %v\n", err)
+		return InstallationJobView{}, err
+	}
+	views, err := s.toInstallationJobViews([]models.InstallationJob{job})
+	if err != nil {
+		return InstallationJobView{}, err
+	}
+	return views[0], nil
+}
+
+func (s *service) ListInstallationJobs(customerID string) ([]InstallationJobView, error) {
+	var jobs []models.InstallationJob
+	if err := s.db.Preload("Technicians").Preload("ChecklistItems").
 		Where("customer_id = ?", customerID).
-		Order("order_date_time DESC").
-		Find(&ordersFromDB).Error; err != nil {
+		Order("created_at DESC").
+		Find(&jobs).Error; err != nil {
+		log.Printf("[Service ListInstallationJobs] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil jadwal instalasi: %w", err)
+	}
+	return s.toInstallationJobViews(jobs)
+}
 
-		log.Printf("[Service ListCustomerOrders] Error mengambil order untuk CustomerID %s: %v\n", customerID, err)
-		return nil, fmt.Errorf("gagal mengambil riwayat pesanan: %w", err)
+// Batas panjang rentang agar kalender dan booking tetap wajar
+const (
+	maxRentalDays             = 365
+	maxRentalAvailabilityDays = 180
+)
+
+func findRentableProduct(db *gorm.DB, productSKU string) (models.Product, error) {
+	var product models.Product
+	if err := db.Where("product_sku = ? AND status = ? AND is_rentable = ?", productSKU, "Published", true).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Product{}, errors.New("produk sewa tidak ditemukan")
+		}
+		return models.Product{}, fmt.Errorf("gagal mengambil produk: %w", err)
 	}
+	return product, nil
+}
 
-	// 2. Mapping hasil query ke DTO OrderHistoryItem
-	orderHistory := make([]OrderHistoryItem, 0, len(ordersFromDB))
-	for _, order := range ordersFromDB {
-		itemImages := make([]string, 0)
-		// Ambil maksimal 3 gambar dari order item untuk ditampilkan di list
-		for i, item := range order.OrderItems {
-			if i >= 3 { // Batasi hanya 3 gambar
+// activeRentalBookingsBetween mengembalikan booking aktif unit-unit tersebut yang beririsan dengan start..end (inklusif).
+func activeRentalBookingsBetween(db *gorm.DB, unitIDs []uint, start, end time.Time) ([]models.RentalBooking, error) {
+	var bookings []models.RentalBooking
+	if err := db.Where("rental_unit_id IN ? AND status IN ?", unitIDs, models.ActiveRentalBookingStatuses).
+		Where("start_date <= ? AND end_date >= ?", end, start).
+		Order("start_date ASC").
+		Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("gagal memeriksa jadwal sewa: %w", err)
+	}
+	return bookings, nil
+}
+
+// GetRentalAvailability menampilkan kalender per unit: periode yang sudah dibooking dan apakah unit bebas
+// selama seluruh rentang yang diminta. Unit yang sedang maintenance / pensiun tidak ditampilkan.
+func (s *service) GetRentalAvailability(productSKU string, query RentalAvailabilityQuery) (RentalAvailabilityView, error) {
+	product, err := findRentableProduct(s.db, productSKU)
+	if err != nil {
+		return RentalAvailabilityView{}, err
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if query.From != "" {
+		from, _ = time.ParseInLocation("2006-01-02", query.From, time.Local)
+	}
+	to := from.AddDate(0, 0, 29)
+	if query.To != "" {
+		to, _ = time.ParseInLocation("2006-01-02", query.To, time.Local)
+	}
+	if to.Before(from) {
+		return RentalAvailabilityView{}, errors.New("tanggal sewa tidak valid: tanggal akhir tidak boleh sebelum tanggal mulai")
+	}
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days > maxRentalAvailabilityDays {
+		return RentalAvailabilityView{}, fmt.Errorf("tanggal sewa tidak valid: rentang kalender maksimal %d hari", maxRentalAvailabilityDays)
+	}
+
+	var units []models.RentalUnit
+	if err := s.db.Where("product_sku = ? AND status = ?", productSKU, models.RentalUnitAvailable).Order("unit_code ASC").Find(&units).Error; err != nil {
+		return RentalAvailabilityView{}, fmt.Errorf("gagal mengambil unit sewa: %w", err)
+	}
+	unitIDs := make([]uint, 0, len(units))
+	for _, unit := range units {
+		unitIDs = append(unitIDs, unit.ID)
+	}
+	bookings, err := activeRentalBookingsBetween(s.db, unitIDs, from, to)
+	if err != nil {
+		return RentalAvailabilityView{}, err
+	}
+	bookedPeriods := make(map[uint][]RentalBookedPeriod)
+	for _, booking := range bookings {
+		bookedPeriods[booking.RentalUnitID] = append(bookedPeriods[booking.RentalUnitID], RentalBookedPeriod{StartDate: booking.StartDate, EndDate: booking.EndDate})
+	}
+
+	view := RentalAvailabilityView{
+		ProductSKU: product.ProductSKU,
+		Title:      product.Title,
+		Rates: PublicRentalRates{
+			DailyRate:   product.RentalDailyRate,
+			WeeklyRate:  product.RentalWeeklyRate,
+			MonthlyRate: product.RentalMonthlyRate,
+			Deposit:     product.RentalDeposit,
+		},
+		From:        from,
+		To:          to,
+		RentalDays:  days,
+		RentalPrice: product.RentalPrice(days),
+		Units:       make([]RentalUnitAvailability, 0, len(units)),
+	}
+	for _, unit := range units {
+		periods := bookedPeriods[unit.ID]
+		if periods == nil {
+			periods = []RentalBookedPeriod{}
+		}
+		view.Units = append(view.Units, RentalUnitAvailability{
+			RentalUnitID:  unit.ID,
+			UnitCode:      unit.UnitCode,
+			IsAvailable:   len(periods) == 0,
+			BookedPeriods: periods,
+		})
+	}
+	return view, nil
+}
+
+func toRentalBookingView(booking models.RentalBooking) RentalBookingView {
+	return RentalBookingView{
+		BookingID:               booking.BookingID,
+		RentalUnitID:            booking.RentalUnitID,
+		UnitCode:                booking.RentalUnit.UnitCode,
+		ProductSKU:              booking.ProductSKU,
+		ProductTitleSnapshot:    booking.ProductTitleSnapshot,
+		StartDate:               booking.StartDate,
+		EndDate:                 booking.EndDate,
+		RentalDays:              booking.RentalDays,
+		RentalPrice:             booking.RentalPrice,
+		Deposit:                 booking.Deposit,
+		Status:                  booking.Status,
+		ShippingAddressSnapshot: booking.ShippingAddressSnapshot,
+		Notes:                   booking.Notes,
+		HandedOverAt:            booking.HandedOverAt,
+		ReturnedAt:              booking.ReturnedAt,
+		ReturnCondition:         booking.ReturnCondition,
+		InspectionNotes:         booking.InspectionNotes,
+		DamageCharge:            booking.DamageCharge,
+		DepositRefund:           booking.DepositRefund,
+		CanceledAt:              booking.CanceledAt,
+		CreatedAt:               booking.CreatedAt,
+	}
+}
+
+// CreateRentalBooking membuat booking sewa. Baris unit dikunci sebelum pengecekan jadwal
+// sehingga dua booking bersamaan tidak bisa menempati unit yang sama pada tanggal yang beririsan.
+func (s *service) CreateRentalBooking(customerID string, input CreateRentalBookingInput) (RentalBookingView, error) {
+	log.Printf("[Service CreateRentalBooking] CustomerID: %s, Produk: %s\n", customerID, input.ProductSKU)
+
+	startDate, _ := time.ParseInLocation("2006-01-02", input.StartDate, time.Local)
+	endDate, _ := time.ParseInLocation("2006-01-02", input.EndDate, time.Local)
+	now := time.Now()
+	if startDate.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)) {
+		return RentalBookingView{}, errors.New("tanggal sewa tidak valid: tanggal mulai tidak boleh di masa lalu")
+	}
+	if endDate.Before(startDate) {
+		return RentalBookingView{}, errors.New("tanggal sewa tidak valid: tanggal akhir tidak boleh sebelum tanggal mulai")
+	}
+	days := int(endDate.Sub(startDate).Hours()/24) + 1
+	if days > maxRentalDays {
+		return RentalBookingView{}, fmt.Errorf("tanggal sewa tidak valid: durasi sewa maksimal %d hari", maxRentalDays)
+	}
+
+	var bookingID string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		product, err := findRentableProduct(tx, input.ProductSKU)
+		if err != nil {
+			return err
+		}
+
+		var shippingAddress models.CustomerAddress
+		if err := tx.Where("address_id = ? AND customer_id = ?", input.SelectedAddressID, customerID).First(&shippingAddress).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("alamat pengiriman yang dipilih tidak valid atau bukan milik Anda")
+			}
+			return fmt.Errorf("gagal memvalidasi alamat pengiriman: %w", err)
+		}
+		var customerDetail models.CustomerDetail
+		if err := tx.Where("customer_id = ?", customerID).First(&customerDetail).Error; err != nil {
+			return fmt.Errorf("gagal mengambil data customer: %w", err)
+		}
+
+		unitQuery := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_sku = ? AND status = ?", input.ProductSKU, models.RentalUnitAvailable)
+		if input.RentalUnitID != 0 {
+			unitQuery = unitQuery.Where("id = ?", input.RentalUnitID)
+		}
+		var units []models.RentalUnit
+		if err := unitQuery.Order("unit_code ASC").Find(&units).Error; err != nil {
+			return fmt.Errorf("gagal mengambil unit sewa: %w", err)
+		}
+		if len(units) == 0 {
+			if input.RentalUnitID != 0 {
+				return errors.New("unit sewa tidak ditemukan")
+			}
+			return errors.New("tidak ada unit sewa yang tersedia pada tanggal tersebut")
+		}
+		unitIDs := make([]uint, 0, len(units))
+		for _, unit := range units {
+			unitIDs = append(unitIDs, unit.ID)
+		}
+		conflicts, err := activeRentalBookingsBetween(tx, unitIDs, startDate, endDate)
+		if err != nil {
+			return err
+		}
+		var selectedUnit *models.RentalUnit
+		for i := range units {
+			if !slices.ContainsFunc(conflicts, func(booking models.RentalBooking) bool { return booking.RentalUnitID == units[i].ID }) {
+				selectedUnit = &units[i]
 				break
 			}
-			if item.ProductImageSnapshot != "" {
-				itemImages = append(itemImages, item.ProductImageSnapshot)
+		}
+		if selectedUnit == nil {
+			if input.RentalUnitID != 0 {
+				conflict := conflicts[0]
+				return fmt.Errorf("jadwal sewa bentrok: unit %s sudah dibooking %s - %s", units[0].UnitCode,
+					conflict.StartDate.Format("02 Jan 2006"), conflict.EndDate.Format("02 Jan 2006"))
 			}
+			return errors.New("tidak ada unit sewa yang tersedia pada tanggal tersebut")
 		}
 
-		historyItem := OrderHistoryItem{
-			OrderID:       order.OrderID,
-			OrderDateTime: order.OrderDateTime,
-			GrandTotal:    order.GrandTotal,
-			OrderStatus:   order.OrderStatus,
-			ItemImages:    itemImages,
+		var nextVal int
+		if err := tx.Raw("SELECT nextval('rental_booking_id_seq')").Scan(&nextVal).Error; err != nil {
+			return fmt.Errorf("gagal mendapatkan ID booking sewa: %w", err)
 		}
-		orderHistory = append(orderHistory, historyItem)
+		booking := models.RentalBooking{
+			BookingID:               fmt.Sprintf("RNT%05d", nextVal),
+			CustomerID:              customerID,
+			CustomerFullname:        customerDetail.FirstName + " " + customerDetail.LastName,
+			CustomerEmail:           customerDetail.Email,
+			CustomerPhone:           customerDetail.Phone,
+			RentalUnitID:            selectedUnit.ID,
+			ProductSKU:              product.ProductSKU,
+			ProductTitleSnapshot:    product.Title,
+			StartDate:               startDate,
+			EndDate:                 endDate,
+			RentalDays:              days,
+			RentalPrice:             product.RentalPrice(days),
+			Deposit:                 product.RentalDeposit,
+			Status:                  models.RentalBookingPending,
+			ShippingAddressSnapshot: formatAddressSnapshot(shippingAddress),
+			Notes:                   strings.TrimSpace(input.Notes),
+		}
+		if err := tx.Create(&booking).Error; err != nil {
+			return fmt.Errorf("gagal menyimpan booking sewa: %w", err)
+		}
+		bookingID = booking.BookingID
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Service CreateRentalBooking] Gagal: %v\n", err)
+		return RentalBookingView{}, err
+	}
+	return s.GetRentalBooking(customerID, bookingID)
+}
+
+func (s *service) ListRentalBookings(customerID string) ([]RentalBookingView, error) {
+	var bookings []models.RentalBooking
+	if err := s.db.Preload("RentalUnit").
+		Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Find(&bookings).Error; err != nil {
+		log.Printf("[Service ListRentalBookings] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil booking sewa: %w", err)
+	}
+	views := make([]RentalBookingView, 0, len(bookings))
+	for _, booking := range bookings {
+		views = append(views, toRentalBookingView(booking))
 	}
+	return views, nil
+}
 
-	log.Printf("[Service ListCustomerOrders] Ditemukan %d pesanan untuk CustomerID: %s\n", len(orderHistory), customerID)
-	return orderHistory, nil
+func (s *service) GetRentalBooking(customerID, bookingID string) (RentalBookingView, error) {
+	var booking models.RentalBooking
+	if err := s.db.Preload("RentalUnit").
+		Where("booking_id = ? AND customer_id = ?", bookingID, customerID).
+		First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return RentalBookingView{}, errors.New("booking sewa tidak ditemukan")
+		}
+		return RentalBookingView{}, fmt.Errorf("gagal mengambil booking sewa: %w", err)
+	}
+	return toRentalBookingView(booking), nil
+}
+
+// CancelRentalBooking hanya untuk booking yang belum diserahkan dan belum dimulai.
+func (s *service) CancelRentalBooking(customerID, bookingID string) (RentalBookingView, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.RentalBooking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ? AND customer_id = ?", bookingID, customerID).
+			First(&booking).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("booking sewa tidak ditemukan")
+			}
+			return fmt.Errorf("gagal mengambil booking sewa: %w", err)
+		}
+		if booking.Status != models.RentalBookingPending && booking.Status != models.RentalBookingConfirmed {
+			return errors.New("booking sewa tidak dapat dibatalkan")
+		}
+		now := time.Now()
+		if !booking.StartDate.After(now) {
+			return errors.New("booking sewa tidak dapat dibatalkan")
+		}
+		return tx.Model(&booking).Updates(map[string]interface{}{"status": models.RentalBookingCanceled, "canceled_at": now}).Error
+	})
+	if err != nil {
+		log.Printf("[Service CancelRentalBooking] Gagal untuk %s: %v\n", bookingID, err)
+		return RentalBookingView{}, err
+	}
+	return s.GetRentalBooking(customerID, bookingID)
+}
+
+func (s *service) ListPartsForPurchasedMachines(customerID string) ([]PurchasedMachineParts, error) {
+	log.Printf("[Service ListPartsForPurchasedMachines] CustomerID: %s\n", customerID)
+	result := []PurchasedMachineParts{}
+
+	// 1. Semua SKU yang pernah dibeli (pesanan batal tidak dihitung)
+	var purchased []struct {
+		ProductSKU           string
+		ProductTitleSnapshot string
+		LastOrderedAt        time.Time
+	}
+	if err := s.db.Model(&models.OrderItem{}).
+		Select("order_items.product_sku, MAX(order_items.product_title_snapshot) AS product_title_snapshot, MAX(orders.order_date_time) AS last_ordered_at").
+		Joins("JOIN orders ON orders.order_id = order_items.order_id").
+		Where("orders.customer_id = ? AND orders.order_status <> ?", customerID, "Canceled").
+		Group("order_items.product_sku").
+		Order("last_ordered_at DESC").
+		Scan(&purchased).Error; err != nil {
+		log.Printf("[Service ListPartsForPurchasedMachines] Error mengambil riwayat item: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil riwayat pembelian: %w", err)
+	}
+	if len(purchased) == 0 {
+		return result, nil
+	}
+
+	// 2. Varian juga mewarisi kompatibilitas dari produk induknya
+	purchasedSKUs := make([]string, 0, len(purchased))
+	for _, item := range purchased {
+		purchasedSKUs = append(purchasedSKUs, item.ProductSKU)
+	}
+	var products []models.Product
+	if err := s.db.Select("product_sku, parent_sku").Where("product_sku IN ?", purchasedSKUs).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil data produk yang dibeli: %w", err)
+	}
+	parentBySKU := make(map[string]string, len(products))
+	for _, p := range products {
+		if p.ParentSKU != nil {
+			parentBySKU[p.ProductSKU] = *p.ParentSKU
+		}
+	}
+
+	// 3. Kelompokkan suku cadang per mesin, mesin tanpa suku cadang dilewati
+	for _, item := range purchased {
+		parts, err := s.compatiblePartsFor(uniqueStrings([]string{item.ProductSKU, parentBySKU[item.ProductSKU]}))
+		if err != nil {
+			log.Printf("[Service ListPartsForPurchasedMachines] Error mengambil suku cadang untuk %s: %v\n", item.ProductSKU, err)
+			return nil, fmt.Errorf("gagal mengambil suku cadang kompatibel: %w", err)
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		result = append(result, PurchasedMachineParts{
+			MachineSKU:    item.ProductSKU,
+			MachineTitle:  item.ProductTitleSnapshot,
+			LastOrderedAt: item.LastOrderedAt,
+			Parts:         parts,
+		})
+	}
+
+	log.Printf("[Service ListPartsForPurchasedMachines] %d mesin dengan suku cadang untuk CustomerID %s\n", len(result), customerID)
+	return result, nil
 }
 
 func (s *service) GetNewsPageData(page, limit int) (NewsPageData, error) {
@@ -1048,3 +4596,142 @@ func (s *service) GetNewsDetailPageData(newsID string) (NewsDetailPageData, erro
 
 	return pageData, nil
 }
+
+const (
+	defaultSearchLimit         = 10
+	defaultSuggestionLimit     = 8
+	searchHeadlineOptions      = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+	searchTitleHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+)
+
+// Sumber ts_headline di-escape lebih dulu agar hanya penanda <mark> yang sampai ke client sebagai markup.
+// searchPlainTextSQL untuk teks biasa seperti judul; searchHTMLTextSQL untuk isi HTML yang tag-nya
+// dibuang, entity bawaannya dibiarkan agar tidak di-escape dua kali.
+func searchPlainTextSQL(column string) string {
+	return "replace(replace(replace(COALESCE(" + column + ", ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+}
+
+func searchHTMLTextSQL(column string) string {
+	return "replace(replace(regexp_replace(COALESCE(" + column + ", ''), '<[^>]*>', ' ', 'g'), '<', '&lt;'), '>', '&gt;')"
+}
+
+// buildPrefixTsQuery mengubah input bebas menjadi tsquery prefix ("gen set" -> "gen:* & set:*").
+// Hanya huruf dan angka yang dipakai sehingga aman untuk to_tsquery.
+func buildPrefixTsQuery(input string) string {
+	terms := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+func (s *service) Search(query SearchQuery) (SearchResults, error) {
+	log.Printf("[Service Search] Query: %+v\n", query)
+	results := SearchResults{
+		Query:    strings.TrimSpace(query.Query),
+		Products: []ProductSearchHit{},
+		News:     []NewsSearchHit{},
+	}
+	if results.Query == "" {
+		return results, errors.New("kata kunci pencarian tidak boleh kosong")
+	}
+	limit := query.Limit
+	if limit < 1 {
+		limit = defaultSearchLimit
+	}
+
+	if query.Type == "" || query.Type == "all" || query.Type == "products" {
+		if err := s.db.Raw(`
+			SELECT COUNT(*) FROM products, websearch_to_tsquery('simple', ?) query
+			WHERE products.status = 'Published' AND products.parent_sku IS NULL AND products.search_vector @@ query`, results.Query).
+			Scan(&results.TotalProducts).Error; err != nil {
+			log.Printf("[Service Search] Error menghitung hasil produk: %v\n", err)
+			return results, fmt.Errorf("gagal mencari produk: %w", err)
+		}
+
+		if err := s.db.Raw(`
+			SELECT products.product_sku, products.title, products.brand, products.regular_price,
+				COALESCE(product_categories.category_name, '') AS category_name,
+				ts_headline('simple', `+searchPlainTextSQL("products.title")+`, query, ?) AS title_highlight,
+				ts_headline('simple', `+searchHTMLTextSQL("products.descriptions")+`, query, ?) AS snippet,
+				COALESCE((SELECT product_images.image FROM product_images
+					WHERE product_images.product_sku = products.product_sku
+					ORDER BY product_images.is_primary DESC, product_images.position ASC, product_images.id ASC
+					LIMIT 1), '') AS image_url,
+				ts_rank_cd(products.search_vector, query) AS rank
+			FROM products
+			CROSS JOIN websearch_to_tsquery('simple', ?) query
+			LEFT JOIN product_categories ON product_categories.category_id = products.product_category
+			WHERE products.status = 'Published' AND products.parent_sku IS NULL AND products.search_vector @@ query
+			ORDER BY rank DESC, products.product_sku ASC
+			LIMIT ?`, searchTitleHeadlineOptions, searchHeadlineOptions, results.Query, limit).
+			Scan(&results.Products).Error; err != nil {
+			log.Printf("[Service Search] Error mencari produk: %v\n", err)
+			return results, fmt.Errorf("gagal mencari produk: %w", err)
+		}
+	}
+
+	if query.Type == "" || query.Type == "all" || query.Type == "news" {
+		if err := s.db.Raw(`
+			SELECT COUNT(*) FROM news, websearch_to_tsquery('simple', ?) query
+			WHERE news.status = 'Published' AND news.search_vector @@ query`, results.Query).
+			Scan(&results.TotalNews).Error; err != nil {
+			log.Printf("[Service Search] Error menghitung hasil berita: %v\n", err)
+			return results, fmt.Errorf("gagal mencari berita: %w", err)
+		}
+
+		if err := s.db.Raw(`
+			SELECT news.news_id, news.title, news.image, news.publication_date,
+				ts_headline('simple', `+searchPlainTextSQL("news.title")+`, query, ?) AS title_highlight,
+				ts_headline('simple', `+searchHTMLTextSQL("news.content")+`, query, ?) AS snippet,
+				ts_rank_cd(news.search_vector, query) AS rank
+			FROM news
+			CROSS JOIN websearch_to_tsquery('simple', ?) query
+			WHERE news.status = 'Published' AND news.search_vector @@ query
+			ORDER BY rank DESC, news.publication_date DESC
+			LIMIT ?`, searchTitleHeadlineOptions, searchHeadlineOptions, results.Query, limit).
+			Scan(&results.News).Error; err != nil {
+			log.Printf("[Service Search] Error mencari berita: %v\n", err)
+			return results, fmt.Errorf("gagal mencari berita: %w", err)
+		}
+	}
+
+	log.Printf("[Service Search] Ditemukan %d produk dan %d berita untuk '%s'.\n", results.TotalProducts, results.TotalNews, results.Query)
+	return results, nil
+}
+
+func (s *service) SearchSuggestions(term string, limit int) ([]SearchSuggestion, error) {
+	suggestions := []SearchSuggestion{}
+	tsQuery := buildPrefixTsQuery(term)
+	if tsQuery == "" {
+		return suggestions, nil
+	}
+	if limit < 1 {
+		limit = defaultSuggestionLimit
+	}
+
+	// Produk didahulukan, sisa slot diisi judul berita
+	if err := s.db.Raw(`
+		SELECT * FROM (
+			(SELECT 'product' AS type, products.product_sku AS id, products.title,
+				ts_rank_cd(products.search_vector, to_tsquery('simple', ?)) AS rank, 0 AS type_order
+			FROM products
+			WHERE products.status = 'Published' AND products.parent_sku IS NULL AND products.search_vector @@ to_tsquery('simple', ?)
+			ORDER BY rank DESC LIMIT ?)
+			UNION ALL
+			(SELECT 'news' AS type, news.news_id AS id, news.title,
+				ts_rank_cd(news.search_vector, to_tsquery('simple', ?)) AS rank, 1 AS type_order
+			FROM news
+			WHERE news.status = 'Published' AND news.search_vector @@ to_tsquery('simple', ?)
+			ORDER BY rank DESC LIMIT ?)
+		) suggestions
+		ORDER BY type_order ASC, rank DESC
+		LIMIT ?`, tsQuery, tsQuery, limit, tsQuery, tsQuery, limit, limit).
+		Scan(&suggestions).Error; err != nil {
+		log.Printf("[Service SearchSuggestions] Error: %v\n", err)
+		return nil, fmt.Errorf("gagal mengambil saran pencarian: %w", err)
+	}
+	return suggestions, nil
+}
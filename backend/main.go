@@ -10,7 +10,11 @@ import (
 	"time"
 
 	"backend-user/domain/admin"
+	"backend-user/domain/document"
+	"backend-user/domain/idempotency"
 	"backend-user/domain/models"
+	"backend-user/domain/notification"
+	"backend-user/domain/shipping"
 	"backend-user/domain/user"
 
 	"github.com/gin-contrib/cors"
@@ -115,12 +119,166 @@ func CustomerAuthMiddleware(jwtSecret []byte) gin.HandlerFunc {
 			return
 		}
 
+		// Cart token keranjang tamu ditandatangani dengan secret yang sama tetapi tidak membawa customer ID
+		if claims.CustomerID == "" {
+			log.Println("[Middleware] CustomerAuthMiddleware: Token tidak membawa customer ID.")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token tidak valid"})
+			return
+		}
+
 		log.Printf("[Middleware] CustomerAuthMiddleware: Token valid. Claims: %+v\n", claims)
 		c.Set("customer_id_from_token", claims.CustomerID)
 		c.Next()
 	}
 }
 
+// setupSequences membuat sequence penomoran dokumen yang belum ada di dump database awal.
+func setupSequences(db *gorm.DB) error {
+	sequences := []string{
+		"quotation_request_id_seq",
+		"quotation_id_seq",
+		"invoice_number_seq",
+		"proforma_number_seq",
+		"company_id_seq",
+		"warranty_claim_id_seq",
+		"service_ticket_id_seq",
+		"installation_job_id_seq",
+		"rental_booking_id_seq",
+		"refund_id_seq",
+		"rma_id_seq",
+		"shipment_id_seq",
+	}
+	for _, sequence := range sequences {
+		if err := db.Exec("CREATE SEQUENCE IF NOT EXISTS " + sequence).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillWarrantyMonths mengisi warranty_months untuk produk lama yang hanya punya teks WarrantyPeriod.
+func backfillWarrantyMonths(db *gorm.DB) error {
+	var products []models.Product
+	if err := db.Select("product_sku", "warranty_period").
+		Where("warranty_months IS NULL AND TRIM(COALESCE(warranty_period, '')) <> ''").
+		Find(&products).Error; err != nil {
+		return err
+	}
+	for _, product := range products {
+		months, ok := models.ParseWarrantyPeriod(product.WarrantyPeriod)
+		if !ok {
+			log.Printf("Peringatan: periode garansi produk %s tidak dikenali: %q\n", product.ProductSKU, product.WarrantyPeriod)
+			continue
+		}
+		if months == nil {
+			continue
+		}
+		if err := db.Model(&models.Product{}).Where("product_sku = ?", product.ProductSKU).Update("warranty_months", *months).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillOrderShipments memindahkan data kurir order lama (kolom shipping_courier, tracking_number,
+// shipped_at pada orders) menjadi satu shipment berisi seluruh item order.
+func backfillOrderShipments(db *gorm.DB) error {
+	var orders []models.Order
+	if err := db.Preload("OrderItems").
+		Where("(shipped_at IS NOT NULL OR TRIM(COALESCE(tracking_number, '')) <> '')").
+		Where("NOT EXISTS (SELECT 1 FROM shipments s WHERE s.order_id = orders.order_id)").
+		Find(&orders).Error; err != nil {
+		return err
+	}
+	for _, order := range orders {
+		shipment := models.Shipment{
+			OrderID:        order.OrderID,
+			Courier:        order.ShippingCourier,
+			TrackingNumber: order.TrackingNumber,
+			ShippedAt:      order.UpdatedAt,
+			Status:         models.ShipmentInTransit,
+			Notes:          "Dipindahkan dari data pengiriman order lama",
+		}
+		if order.ShippedAt != nil {
+			shipment.ShippedAt = *order.ShippedAt
+		}
+		if order.OrderStatus == "Completed" {
+			shipment.Status = models.ShipmentDelivered
+		}
+		for _, item := range order.OrderItems {
+			shipment.Items = append(shipment.Items, models.ShipmentItem{
+				OrderItemID:          item.OrderItemID,
+				ProductSKU:           item.ProductSKU,
+				ProductTitleSnapshot: item.ProductTitleSnapshot,
+				Quantity:             item.Quantity,
+			})
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var nextVal int
+			if err := tx.Raw("SELECT nextval('shipment_id_seq')").Scan(&nextVal).Error; err != nil {
+				return err
+			}
+			shipment.ShipmentID = fmt.Sprintf("SHP%05d", nextVal)
+			return tx.Create(&shipment).Error
+		})
+		if err != nil {
+			return fmt.Errorf("order %s: %w", order.OrderID, err)
+		}
+	}
+	return nil
+}
+
+// setupSearchIndexes menyiapkan kolom tsvector untuk full-text search.
+// products memakai trigger karena ikut mengindeks nama kategori dari tabel lain,
+// news cukup memakai generated column. Semua statement aman dijalankan ulang.
+func setupSearchIndexes(db *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+		`CREATE OR REPLACE FUNCTION products_search_vector_update() RETURNS trigger AS $$
+		DECLARE
+			category_name_value text;
+		BEGIN
+			SELECT category_name INTO category_name_value FROM product_categories WHERE category_id = NEW.product_category;
+			NEW.search_vector :=
+				setweight(to_tsvector('simple', COALESCE(NEW.title, '')), 'A') ||
+				setweight(to_tsvector('simple', COALESCE(NEW.brand, '')), 'B') ||
+				setweight(to_tsvector('simple', COALESCE(category_name_value, '')), 'B') ||
+				setweight(to_tsvector('simple', regexp_replace(COALESCE(NEW.descriptions, ''), '<[^>]*>', ' ', 'g')), 'C');
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS products_search_vector_trigger ON products`,
+		`CREATE TRIGGER products_search_vector_trigger BEFORE INSERT OR UPDATE ON products
+			FOR EACH ROW EXECUTE FUNCTION products_search_vector_update()`,
+		// Jika nama kategori berubah, hitung ulang vector produk di kategori tersebut
+		`CREATE OR REPLACE FUNCTION product_categories_search_vector_refresh() RETURNS trigger AS $$
+		BEGIN
+			IF NEW.category_name IS DISTINCT FROM OLD.category_name THEN
+				UPDATE products SET product_category = product_category WHERE product_category = NEW.category_id;
+			END IF;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS product_categories_search_vector_trigger ON product_categories`,
+		`CREATE TRIGGER product_categories_search_vector_trigger AFTER UPDATE ON product_categories
+			FOR EACH ROW EXECUTE FUNCTION product_categories_search_vector_refresh()`,
+		`UPDATE products SET title = title WHERE search_vector IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+		`ALTER TABLE news ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+			setweight(to_tsvector('simple', regexp_replace(COALESCE(content, ''), '<[^>]*>', ' ', 'g')), 'B')
+		) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_news_search_vector ON news USING GIN (search_vector)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	fmt.Println("Index pencarian siap.")
+	return nil
+}
+
 func init() {
 
 	err := godotenv.Load()
@@ -165,41 +323,152 @@ func main() {
 		&models.ProductCategory{},
 		&models.Product{},
 		&models.ProductImage{},
+		&models.ProductCategoryAttribute{},
+		&models.ProductSpecification{},
+		&models.ProductCompatibility{},
 
 		&models.Customer{},
 		&models.CustomerDetail{},
 		&models.CustomerAddress{},
 		&models.Cart{},
+		&models.GuestCart{},
+		&models.GuestCartItem{},
+		&models.WishlistItem{},
+		&models.ProductNotification{},
+		&models.CustomerNotification{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderStatusHistory{},
+		&models.Shipment{},
+		&models.ShipmentItem{},
+		&models.Refund{},
+		&models.ReturnRequest{},
+		&models.ReturnRequestPhoto{},
+		&models.StockMovement{},
+		&models.OrderDocument{},
+		&models.PaymentMilestone{},
+		&models.Company{},
+		&models.CompanyMember{},
+		&models.ProductUnit{},
+		&models.Warranty{},
+		&models.WarrantyClaim{},
+		&models.WarrantyClaimPhoto{},
+		&models.ServiceTicket{},
+		&models.ServiceTicketMessage{},
+		&models.ServiceTicketAttachment{},
+		&models.InstallationJob{},
+		&models.InstallationJobTechnician{},
+		&models.InstallationChecklistItem{},
+		&models.RentalUnit{},
+		&models.RentalBooking{},
 		&models.NewsCategory{},
 		&models.NewsPost{},
+		&models.IdempotencyRecord{},
+		&models.QuotationRequest{},
+		&models.QuotationRequestItem{},
+		&models.Quotation{},
+		&models.QuotationItem{},
 	)
 	if errMigrate != nil {
 		panic("Gagal migrasi database: " + errMigrate.Error())
 	}
 	fmt.Println("Migrasi database berhasil.")
 
-	usersvc := user.NewService(db, []byte(jwtSecretUser))
+	if err := setupSequences(db); err != nil {
+		panic("Gagal menyiapkan sequence: " + err.Error())
+	}
+
+	if err := backfillWarrantyMonths(db); err != nil {
+		panic("Gagal mengisi durasi garansi produk: " + err.Error())
+	}
+
+	if err := backfillOrderShipments(db); err != nil {
+		panic("Gagal memindahkan data pengiriman order lama: " + err.Error())
+	}
+
+	if err := setupSearchIndexes(db); err != nil {
+		panic("Gagal menyiapkan index pencarian: " + err.Error())
+	}
+
+	// Ganti dengan implementasi API kurir sungguhan saat integrasi tersedia
+	trackingProvider := shipping.NewUnavailableProvider()
+
+	usersvc := user.NewService(db, []byte(jwtSecretUser), trackingProvider)
 	userhandler := user.NewHandler(usersvc)
 
-	adminsvc := admin.NewService(db, []byte(jwtSecretAdmin))
+	adminsvc := admin.NewService(db, []byte(jwtSecretAdmin), trackingProvider)
 	adminhandler := admin.NewHandler(adminsvc)
 
+	documentsvc := document.NewService(db, document.DefaultCompanyProfile())
+	documenthandler := document.NewHandler(documentsvc)
+
+	// Respons request ber-Idempotency-Key disimpan 24 jam lalu dibersihkan berkala, begitu juga keranjang tamu kedaluwarsa.
+	// Key yang sedang diproses dikunci 1 menit dan diperpanjang selama request masih berjalan.
+	idempotent := idempotency.Middleware(db, 24*time.Hour, time.Minute)
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if purged, err := idempotency.PurgeExpired(db); err != nil {
+				log.Printf("Peringatan: gagal membersihkan idempotency key: %v\n", err)
+			} else if purged > 0 {
+				log.Printf("%d idempotency key kedaluwarsa dihapus\n", purged)
+			}
+			if purged, err := usersvc.PurgeExpiredGuestCarts(); err != nil {
+				log.Printf("Peringatan: gagal membersihkan keranjang tamu: %v\n", err)
+			} else if purged > 0 {
+				log.Printf("%d keranjang tamu kedaluwarsa dihapus\n", purged)
+			}
+		}
+	}()
+
+	// Notifikasi wishlist maksimal 3 per customer per 24 jam. Email masih stub (LogEmailSender) sampai
+	// server email dikonfigurasi, jadi saat ini notifikasi hanya benar-benar terkirim lewat feed in-app.
+	notificationDispatcher := notification.NewDispatcher(db,
+		notification.Throttle{MaxPerWindow: 3, Window: 24 * time.Hour},
+		notification.NewEmailChannel(notification.NewLogEmailSender()),
+		notification.NewInAppChannel(db),
+	)
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if sent, err := notificationDispatcher.DispatchPending(); err != nil {
+				log.Printf("Peringatan: gagal mengirim notifikasi wishlist: %v\n", err)
+			} else if sent > 0 {
+				log.Printf("%d notifikasi wishlist terkirim\n", sent)
+			}
+		}
+	}()
+
 	r := gin.Default()
 
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:5174", "http://localhost:5173"}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", idempotency.HeaderKey, user.GuestCartTokenHeader}
+	config.ExposeHeaders = []string{idempotency.HeaderReplayed}
 	r.Use(cors.New(config))
 
 	r.Static("/uploads", "./uploads")
 
-	r.GET("/products", userhandler.ListPublicProductsAndCategories)
+	r.GET("/products", userhandler.ListPublicProducts)
+	r.GET("/products/compare", userhandler.CompareProducts)
 	r.GET("/products/:productSKU", userhandler.GetPublicProductDetail)
+	r.GET("/products/:productSKU/rental-availability", userhandler.GetRentalAvailability)
 	r.GET("/news", userhandler.GetNewsPageData)
 	r.GET("/news/:newsId", userhandler.GetNewsDetailPageData)
+	r.GET("/search", userhandler.Search)
+	r.GET("/search/suggestions", userhandler.SearchSuggestions)
+
+	// Keranjang tamu untuk pengunjung yang belum login, digabung saat login/registrasi
+	guestCart := r.Group("/guest-cart")
+	{
+		guestCart.GET("", userhandler.GetGuestCart)
+		guestCart.POST("/items", userhandler.AddToGuestCart)
+		guestCart.PUT("/items/:itemId", userhandler.UpdateGuestCartItemQuantity)
+		guestCart.DELETE("/items/:itemId", userhandler.RemoveGuestCartItem)
+	}
 
 	userApi := r.Group("/user")
 	{
@@ -214,59 +483,168 @@ func main() {
 			authenticatedUser.PUT("/password", userhandler.ChangeCustomerPassword)
 
 			authenticatedUser.GET("/addresses", userhandler.ListCustomerAddresses)
-			authenticatedUser.POST("/addresses", userhandler.AddCustomerAddress)
+			authenticatedUser.POST("/addresses", idempotent, userhandler.AddCustomerAddress)
 			authenticatedUser.PUT("/addresses/:addressId", userhandler.UpdateCustomerAddress)
-			authenticatedUser.POST("/cart", userhandler.AddToCart)
+			authenticatedUser.POST("/cart", idempotent, userhandler.AddToCart)
 			authenticatedUser.GET("/cart", userhandler.GetCartItems)
 			authenticatedUser.PUT("/cart/:cartItemId", userhandler.UpdateCartItemQuantity)
 			authenticatedUser.DELETE("/cart/:cartItemId", userhandler.RemoveCartItem)
-
-			authenticatedUser.POST("/orders", userhandler.CreateOrder)
+			authenticatedUser.POST("/cart/:cartItemId/save-for-later", userhandler.SaveCartItemForLater)
+			authenticatedUser.POST("/cart/:cartItemId/move-to-cart", userhandler.MoveSavedItemToCart)
+
+			authenticatedUser.GET("/wishlist", userhandler.ListWishlist)
+			authenticatedUser.POST("/wishlist", idempotent, userhandler.AddWishlistItem)
+			authenticatedUser.PUT("/wishlist/:productSKU", userhandler.UpdateWishlistItem)
+			authenticatedUser.DELETE("/wishlist/:productSKU", userhandler.RemoveWishlistItem)
+			authenticatedUser.GET("/notifications", userhandler.ListNotifications)
+			authenticatedUser.POST("/notifications/read-all", userhandler.MarkAllNotificationsRead)
+			authenticatedUser.POST("/notifications/:notificationId/read", userhandler.MarkNotificationRead)
+
+			authenticatedUser.POST("/orders", idempotent, userhandler.CreateOrder)
 			authenticatedUser.GET("/orders", userhandler.ListCustomerOrders)
+			authenticatedUser.GET("/orders/compatible-parts", userhandler.ListPartsForPurchasedMachines)
+			authenticatedUser.GET("/orders/:orderId", userhandler.GetCustomerOrderDetail)
+			authenticatedUser.POST("/orders/:orderId/cancel", userhandler.CancelOrder)
+			authenticatedUser.GET("/orders/:orderId/shipments/:shipmentId/tracking", userhandler.GetShipmentTracking)
+			authenticatedUser.GET("/orders/:orderId/documents/:documentType", documenthandler.DownloadOrderDocumentForCustomer)
+			authenticatedUser.GET("/orders/:orderId/payments", userhandler.GetOrderPaymentSchedule)
+			authenticatedUser.POST("/orders/:orderId/payments/:milestoneId/proof", userhandler.SubmitPaymentMilestoneProof)
+			authenticatedUser.POST("/orders/:orderId/installation-job", userhandler.RequestInstallation)
+			authenticatedUser.POST("/orders/:orderId/returns", userhandler.CreateReturnRequest)
+			authenticatedUser.GET("/returns", userhandler.ListReturnRequests)
+			authenticatedUser.GET("/returns/:rmaId", userhandler.GetReturnRequest)
+			authenticatedUser.GET("/installation-jobs", userhandler.ListInstallationJobs)
+			authenticatedUser.POST("/rentals", userhandler.CreateRentalBooking)
+			authenticatedUser.GET("/rentals", userhandler.ListRentalBookings)
+			authenticatedUser.GET("/rentals/:bookingId", userhandler.GetRentalBooking)
+			authenticatedUser.POST("/rentals/:bookingId/cancel", userhandler.CancelRentalBooking)
+			authenticatedUser.GET("/warranties", userhandler.ListWarranties)
+			authenticatedUser.POST("/warranties/:warrantyId/claims", userhandler.CreateWarrantyClaim)
+			authenticatedUser.GET("/warranty-claims", userhandler.ListWarrantyClaims)
+			authenticatedUser.POST("/service-tickets", userhandler.CreateServiceTicket)
+			authenticatedUser.GET("/service-tickets", userhandler.ListServiceTickets)
+			authenticatedUser.GET("/service-tickets/:ticketId", userhandler.GetServiceTicket)
+			authenticatedUser.POST("/service-tickets/:ticketId/messages", userhandler.AddServiceTicketMessage)
+			authenticatedUser.POST("/service-tickets/:ticketId/close", userhandler.CloseServiceTicket)
+
+			authenticatedUser.POST("/company", userhandler.CreateCompany)
+			authenticatedUser.GET("/company", userhandler.GetMyCompany)
+			authenticatedUser.PUT("/company", userhandler.UpdateCompany)
+			authenticatedUser.POST("/company/members", userhandler.AddCompanyMember)
+			authenticatedUser.PUT("/company/members/:customerId", userhandler.UpdateCompanyMember)
+			authenticatedUser.DELETE("/company/members/:customerId", userhandler.RemoveCompanyMember)
+			authenticatedUser.GET("/company/orders", userhandler.ListCompanyOrders)
+			authenticatedUser.POST("/company/orders/:orderId/approve", userhandler.ApproveCompanyOrder)
+			authenticatedUser.POST("/company/orders/:orderId/reject", userhandler.RejectCompanyOrder)
+
+			authenticatedUser.POST("/quotation-requests", userhandler.CreateQuotationRequest)
+			authenticatedUser.GET("/quotation-requests", userhandler.ListQuotationRequests)
+			authenticatedUser.GET("/quotation-requests/:requestId", userhandler.GetQuotationRequest)
+			authenticatedUser.POST("/quotation-requests/:requestId/accept", userhandler.AcceptQuotation)
+			authenticatedUser.POST("/quotation-requests/:requestId/decline", userhandler.DeclineQuotationRequest)
 		}
 	}
 
 	adminApiRoutes := r.Group("/admin")
 	{
 		adminApiRoutes.GET("/profile", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetAdminProfile)
-		adminApiRoutes.POST("/add-employee", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.AddEmployee)
+		adminApiRoutes.POST("/add-employee", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddEmployee)
 		adminApiRoutes.POST("/register", adminhandler.RegisterAdmin)
 		adminApiRoutes.POST("/login", adminhandler.LoginAdmin)
 		adminApiRoutes.GET("/employees", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListEmployees)
 		adminApiRoutes.GET("/employees/:employeeId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetEmployeeByID)
 		adminApiRoutes.DELETE("/employees/:employeeId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteEmployee)
 		adminApiRoutes.PUT("/employees/:employeeId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateEmployee)
-		adminApiRoutes.POST("/departments", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.AddDepartment)
+		adminApiRoutes.POST("/departments", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddDepartment)
 		adminApiRoutes.GET("/departments/list", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListActiveDepartmentsForDropdown)
 		adminApiRoutes.GET("/departments", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListDepartments)
 		adminApiRoutes.DELETE("/departments/:departmentId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteDepartment)
 		adminApiRoutes.PUT("/departments/:departmentId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateDepartment)
 		adminApiRoutes.GET("/departments/:departmentId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetDepartmentByID)
-		adminApiRoutes.POST("/add-product-category", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.AddProductCategory)
+		adminApiRoutes.POST("/add-product-category", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddProductCategory)
 		adminApiRoutes.GET("/product-categories", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListProductCategories)
 		adminApiRoutes.GET("/product-categories/:categoryId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetProductCategoryByID)
 		adminApiRoutes.PUT("/product-categories/:categoryId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateProductCategory)
 		adminApiRoutes.DELETE("/product-categories/:categoryId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteProductCategory)
+		adminApiRoutes.PUT("/product-categories/:categoryId/attributes", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateProductCategoryAttributes)
 		adminApiRoutes.GET("/product-categories/list-active", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListActiveProductCategories)
-		adminApiRoutes.POST("/products", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.AddProduct)
+		adminApiRoutes.POST("/products", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddProduct)
 		adminApiRoutes.GET("/products", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListProducts)
 		adminApiRoutes.GET("/products/:productSKU", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetProductBySKU)
 		adminApiRoutes.PUT("/products/:productSKU", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateProduct)
 		adminApiRoutes.DELETE("/products/:productSKU", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteProduct)
+		adminApiRoutes.POST("/products/:productSKU/images", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddProductImages)
+		adminApiRoutes.PUT("/products/:productSKU/images/order", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ReorderProductImages)
+		adminApiRoutes.PUT("/products/:productSKU/images/:imageId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateProductImage)
+		adminApiRoutes.PUT("/products/:productSKU/images/:imageId/primary", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.SetPrimaryProductImage)
+		adminApiRoutes.DELETE("/products/:productSKU/images/:imageId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteProductImage)
+		adminApiRoutes.GET("/products/:productSKU/compatible-parts", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListCompatibleParts)
+		adminApiRoutes.PUT("/products/:productSKU/compatible-parts", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.SetCompatibleParts)
+		adminApiRoutes.POST("/products/:productSKU/units", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.RegisterProductUnits)
+		adminApiRoutes.GET("/products/:productSKU/units", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListProductUnits)
+		adminApiRoutes.DELETE("/products/:productSKU/units/:unitId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteProductUnit)
+		adminApiRoutes.GET("/products/:productSKU/stock-movements", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListStockMovements)
+		adminApiRoutes.POST("/products/:productSKU/rental-units", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddRentalUnits)
+		adminApiRoutes.GET("/products/:productSKU/rental-units", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListRentalUnits)
+		adminApiRoutes.PUT("/rental-units/:unitId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateRentalUnit)
+		adminApiRoutes.GET("/units/:serialNumber", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.LookupProductUnit)
 		adminApiRoutes.GET("/orders", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListAllOrders)
 		adminApiRoutes.GET("/orders/:orderId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetOrderDetailForAdmin)
+		adminApiRoutes.GET("/orders/:orderId/documents/:documentType", AdminAuthMiddleware([]byte(jwtSecretAdmin)), documenthandler.DownloadOrderDocumentForAdmin)
+		adminApiRoutes.PUT("/orders/:orderId/payment-schedule", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.SetPaymentSchedule)
+		adminApiRoutes.PUT("/orders/:orderId/payment-schedule/:milestoneId/verification", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.VerifyPaymentMilestone)
+		adminApiRoutes.GET("/reports/overdue-installments", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListOverdueInstallments)
 		adminApiRoutes.PUT("/orders/:orderId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateOrderStatus)
+		adminApiRoutes.PUT("/orders/:orderId/units", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.AssignOrderUnits)
+		adminApiRoutes.POST("/orders/:orderId/shipments", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.CreateShipment)
+		adminApiRoutes.POST("/shipments/:shipmentId/delivery", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ConfirmShipmentDelivery)
+		adminApiRoutes.GET("/shipments/:shipmentId/tracking", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetShipmentTracking)
+		adminApiRoutes.POST("/orders/:orderId/installation-jobs", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.CreateInstallationJob)
+		adminApiRoutes.GET("/installation-jobs", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListInstallationJobs)
+		adminApiRoutes.GET("/installation-jobs/:jobId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetInstallationJob)
+		adminApiRoutes.PUT("/installation-jobs/:jobId/schedule", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ScheduleInstallationJob)
+		adminApiRoutes.PUT("/installation-jobs/:jobId/status", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateInstallationJobStatus)
+		adminApiRoutes.PUT("/installation-jobs/:jobId/checklist", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.SetInstallationChecklist)
+		adminApiRoutes.PUT("/installation-jobs/:jobId/checklist/:itemId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateInstallationChecklistItem)
+		adminApiRoutes.POST("/installation-jobs/:jobId/complete", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.CompleteInstallationJob)
+		adminApiRoutes.GET("/refunds", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListRefunds)
+		adminApiRoutes.GET("/refunds/:refundId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetRefund)
+		adminApiRoutes.PUT("/refunds/:refundId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateRefund)
+		adminApiRoutes.GET("/returns", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListReturnRequests)
+		adminApiRoutes.GET("/returns/:rmaId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetReturnRequest)
+		adminApiRoutes.PUT("/returns/:rmaId/review", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ReviewReturnRequest)
+		adminApiRoutes.POST("/returns/:rmaId/receive", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ReceiveReturnRequest)
+		adminApiRoutes.POST("/returns/:rmaId/inspection", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.InspectReturnRequest)
+		adminApiRoutes.POST("/returns/:rmaId/resolution", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ResolveReturnRequest)
+		adminApiRoutes.GET("/rentals", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListRentalBookings)
+		adminApiRoutes.GET("/rentals/:bookingId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetRentalBooking)
+		adminApiRoutes.PUT("/rentals/:bookingId/status", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateRentalBookingStatus)
+		adminApiRoutes.POST("/rentals/:bookingId/return-inspection", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.InspectRentalReturn)
 		adminApiRoutes.DELETE("/orders/:orderId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteOrder)
+		adminApiRoutes.GET("/quotation-requests", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListQuotationRequests)
+		adminApiRoutes.GET("/quotation-requests/:requestId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetQuotationRequest)
+		adminApiRoutes.PUT("/quotation-requests/:requestId/quotation", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpsertQuotation)
+		adminApiRoutes.GET("/companies", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListCompanies)
+		adminApiRoutes.GET("/companies/:companyId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetCompanyDetail)
+		adminApiRoutes.PUT("/warranties/:warrantyId/serial-number", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateWarrantySerialNumber)
+		adminApiRoutes.GET("/warranty-claims", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListWarrantyClaims)
+		adminApiRoutes.GET("/warranty-claims/:claimId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetWarrantyClaim)
+		adminApiRoutes.PUT("/warranty-claims/:claimId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateWarrantyClaim)
+		adminApiRoutes.GET("/service-tickets", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListServiceTickets)
+		adminApiRoutes.GET("/service-tickets/:ticketId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetServiceTicket)
+		adminApiRoutes.PUT("/service-tickets/:ticketId/assignment", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.AssignServiceTicket)
+		adminApiRoutes.PUT("/service-tickets/:ticketId/status", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateServiceTicketStatus)
+		adminApiRoutes.POST("/service-tickets/:ticketId/messages", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddServiceTicketMessage)
 		adminApiRoutes.GET("/customers", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListOrderedCustomers)
 		adminApiRoutes.GET("/customers/:customerId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetCustomerDetailForAdmin)
 		adminApiRoutes.DELETE("/customers/:customerId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteCustomer)
-		adminApiRoutes.POST("/news-categories", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.AddNewsCategory)
+		adminApiRoutes.POST("/news-categories", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddNewsCategory)
 		adminApiRoutes.GET("/news-categories", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListNewsCategories)
 		adminApiRoutes.GET("/news-categories/list-active", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListActiveNewsCategories)
 		adminApiRoutes.GET("/news-categories/:categoryId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetNewsCategoryByID)
 		adminApiRoutes.PUT("/news-categories/:categoryId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateNewsCategory)
 		adminApiRoutes.DELETE("/news-categories/:categoryId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.DeleteNewsCategory)
-		adminApiRoutes.POST("/news-posts", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.AddNewsPost)
+		adminApiRoutes.POST("/news-posts", AdminAuthMiddleware([]byte(jwtSecretAdmin)), idempotent, adminhandler.AddNewsPost)
 		adminApiRoutes.GET("/news-posts", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.ListNewsPosts)
 		adminApiRoutes.GET("/news-posts/:newsId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.GetNewsPostByID)
 		adminApiRoutes.PUT("/news-posts/:newsId", AdminAuthMiddleware([]byte(jwtSecretAdmin)), adminhandler.UpdateNewsPost)